@@ -1,46 +1,217 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"udv/internal/adapter"
 	"udv/internal/adapter/mongodb"
 	"udv/internal/adapter/postgres"
 	"udv/internal/api"
 	"udv/internal/config"
+	"udv/internal/limits"
+	"udv/internal/metrics"
 	"udv/internal/schema"
+	"udv/internal/schema_processor"
 )
 
 func main() {
+	// Initialize structured logger (LOG_LEVEL: debug, info, warn, error)
+	logLevel := slog.LevelInfo
+	if envLevel := os.Getenv("LOG_LEVEL"); envLevel != "" {
+		if err := logLevel.UnmarshalText([]byte(envLevel)); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid LOG_LEVEL %q: %v\n", envLevel, err)
+			os.Exit(1)
+		}
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+
+	// Parameter values are redacted from request logs unless explicitly disabled
+	redactParams := os.Getenv("LOG_REDACT_PARAMS") != "false"
+
+	// Request body size limit for /query (MAX_REQUEST_BODY_BYTES)
+	if envLimit := os.Getenv("MAX_REQUEST_BODY_BYTES"); envLimit != "" {
+		n, err := strconv.ParseInt(envLimit, 10, 64)
+		if err != nil || n <= 0 {
+			logger.Error("invalid MAX_REQUEST_BODY_BYTES", "value", envLimit)
+			os.Exit(1)
+		}
+		limits.MaxRequestBodyBytes = n
+	}
+
+	// Pagination and batch bounds (MAX_PAGE_LIMIT, MAX_PAGE_OFFSET, MAX_BATCH_SIZE, BATCH_CONCURRENCY, CLAMP_PAGE_LIMIT)
+	for name, target := range map[string]*int{
+		"MAX_PAGE_LIMIT":    &limits.MaxPageLimit,
+		"MAX_PAGE_OFFSET":   &limits.MaxPageOffset,
+		"MAX_BATCH_SIZE":    &limits.MaxBatchSize,
+		"BATCH_CONCURRENCY": &limits.BatchConcurrency,
+	} {
+		if envValue := os.Getenv(name); envValue != "" {
+			n, err := strconv.Atoi(envValue)
+			if err != nil || n <= 0 {
+				logger.Error("invalid "+name, "value", envValue)
+				os.Exit(1)
+			}
+			*target = n
+		}
+	}
+	if envClamp := os.Getenv("CLAMP_PAGE_LIMIT"); envClamp != "" {
+		clamp, err := strconv.ParseBool(envClamp)
+		if err != nil {
+			logger.Error("invalid CLAMP_PAGE_LIMIT", "value", envClamp)
+			os.Exit(1)
+		}
+		limits.ClampPageLimit = clamp
+	}
+
+	// ?explain=true runs queries under EXPLAIN ANALYZE, so it is off unless ENABLE_EXPLAIN=true
+	var enableExplain bool
+	if envExplain := os.Getenv("ENABLE_EXPLAIN"); envExplain != "" {
+		enabled, err := strconv.ParseBool(envExplain)
+		if err != nil {
+			logger.Error("invalid ENABLE_EXPLAIN", "value", envExplain)
+			os.Exit(1)
+		}
+		enableExplain = enabled
+	}
+
+	// Server-side statement timeout for PostgreSQL (PG_STATEMENT_TIMEOUT, e.g. "30s")
+	var pgStatementTimeout time.Duration
+	if envTimeout := os.Getenv("PG_STATEMENT_TIMEOUT"); envTimeout != "" {
+		d, err := time.ParseDuration(envTimeout)
+		if err != nil || d < 0 {
+			logger.Error("invalid PG_STATEMENT_TIMEOUT", "value", envTimeout)
+			os.Exit(1)
+		}
+		pgStatementTimeout = d
+	}
+
+	// Exact pagination totals are reused for COUNT_CACHE_TTL (e.g. "30s"; 0 counts every request)
+	countCacheTTL := api.DefaultCountCacheTTL
+	if envTTL := os.Getenv("COUNT_CACHE_TTL"); envTTL != "" {
+		d, err := time.ParseDuration(envTTL)
+		if err != nil || d < 0 {
+			logger.Error("invalid COUNT_CACHE_TTL", "value", envTTL)
+			os.Exit(1)
+		}
+		countCacheTTL = d
+	}
+
+	// Name the PostgreSQL connections report in pg_stat_activity (PG_APPLICATION_NAME; empty disables)
+	pgApplicationName := postgres.DefaultApplicationName
+	if envName, ok := os.LookupEnv("PG_APPLICATION_NAME"); ok {
+		pgApplicationName = envName
+	}
+
+	// How long shutdown waits for in-flight requests to drain (SHUTDOWN_TIMEOUT, e.g. "30s")
+	shutdownTimeout := 30 * time.Second
+	if envTimeout := os.Getenv("SHUTDOWN_TIMEOUT"); envTimeout != "" {
+		d, err := time.ParseDuration(envTimeout)
+		if err != nil || d <= 0 {
+			logger.Error("invalid SHUTDOWN_TIMEOUT", "value", envTimeout)
+			os.Exit(1)
+		}
+		shutdownTimeout = d
+	}
+
+	// Startup check of models against the PostgreSQL schema (VALIDATE_SCHEMA: strict, warn, off)
+	validateSchemaMode := os.Getenv("VALIDATE_SCHEMA")
+	switch validateSchemaMode {
+	case "":
+		validateSchemaMode = "off"
+	case "strict", "warn", "off":
+	default:
+		logger.Error("invalid VALIDATE_SCHEMA", "value", validateSchemaMode)
+		os.Exit(1)
+	}
+
+	// Invalid models are skipped unless CONFIG_STRICT=true, which fails startup on any problem
+	var strictConfig bool
+	if envStrict := os.Getenv("CONFIG_STRICT"); envStrict != "" {
+		strict, err := strconv.ParseBool(envStrict)
+		if err != nil {
+			logger.Error("invalid CONFIG_STRICT", "value", envStrict)
+			os.Exit(1)
+		}
+		strictConfig = strict
+	}
+
+	// Signed next_cursor tokens are issued only when CURSOR_SECRET (or CURSOR_SECRET_FILE) is set
+	cursorSecret, err := secretFromEnv("CURSOR_SECRET")
+	if err != nil {
+		logger.Error("failed to read CURSOR_SECRET", "error", err)
+		os.Exit(1)
+	}
+	// /admin endpoints such as truncate are destructive, so they need ADMIN_TOKEN (or ADMIN_TOKEN_FILE)
+	adminToken, err := secretFromEnv("ADMIN_TOKEN")
+	if err != nil {
+		logger.Error("failed to read ADMIN_TOKEN", "error", err)
+		os.Exit(1)
+	}
+
+	cursorTTL := api.DefaultCursorTTL
+	if envTTL := os.Getenv("CURSOR_TTL"); envTTL != "" {
+		d, err := time.ParseDuration(envTTL)
+		if err != nil || d <= 0 {
+			logger.Error("invalid CURSOR_TTL", "value", envTTL)
+			os.Exit(1)
+		}
+		cursorTTL = d
+	}
+
 	// Load configuration
 	configPath := "configs/models.json"
 	if envPath := os.Getenv("CONFIG_PATH"); envPath != "" {
 		configPath = envPath
 	}
 
-	cfg, err := config.LoadConfig(configPath)
+	var cfg *config.Config
+	var failedModels []config.ModelError
+	if strictConfig {
+		cfg, err = config.LoadConfig(configPath)
+	} else {
+		cfg, failedModels, err = config.LoadConfigTolerant(configPath)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		// Validation failures list every problem so they can all be fixed before restarting
+		var validationErr *config.ValidationError
+		if errors.As(err, &validationErr) {
+			logger.Error("invalid configuration", "path", configPath, "problems", validationErr.Problems)
+		} else {
+			logger.Error("failed to load configuration", "error", err)
+		}
 		os.Exit(1)
 	}
 
 	// Log loaded models
-	fmt.Printf("Loaded %d model(s):\n", len(cfg.Models))
 	for _, model := range cfg.Models {
-		fmt.Printf("  - %s (table: %s, primaryKey: %s)\n", model.Name, model.Table, model.PrimaryKey)
+		logger.Info("loaded model", "name", model.Name, "table", model.Table, "primary_key", model.PrimaryKey)
+	}
+	for _, failed := range failedModels {
+		logger.Error("skipped invalid model", "name", failed.Name, "index", failed.Index, "problems", failed.Problems)
+	}
+	if len(failedModels) > 0 {
+		logger.Warn("some models failed to load", "loaded", len(cfg.Models), "failed", len(failedModels))
 	}
 
 	// Initialize schema registry
 	registry := schema.NewRegistry()
 	if err := registry.LoadFromConfig(cfg); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize schema registry: %v\n", err)
+		logger.Error("failed to initialize schema registry", "error", err)
 		os.Exit(1)
 	}
 
 	// Log registry initialization
-	fmt.Printf("Schema registry initialized with %d model(s)\n", len(registry.ListModels()))
+	logger.Info("schema registry initialized", "models", len(registry.ListModels()))
 
 	// Initialize database connection based on DB_TYPE
 	dbType := os.Getenv("DB_TYPE")
@@ -53,64 +224,113 @@ func main() {
 
 	switch dbType {
 	case "mongodb":
-		mongoURI := os.Getenv("MONGODB_URI")
+		mongoURI, err := secretFromEnv("MONGODB_URI")
+		if err != nil {
+			logger.Error("failed to read MongoDB URI", "error", err)
+			os.Exit(1)
+		}
 		mongoDBName := os.Getenv("MONGODB_DATABASE")
 
 		if mongoURI == "" {
-			fmt.Fprintf(os.Stderr, "Error: MONGODB_URI not set\n")
+			logger.Error("MONGODB_URI not set")
 			os.Exit(1)
 		}
 		if mongoDBName == "" {
-			fmt.Fprintf(os.Stderr, "Error: MONGODB_DATABASE not set\n")
+			logger.Error("MONGODB_DATABASE not set")
+			os.Exit(1)
+		}
+
+		mongoOpts, err := mongoConnectOptions()
+		if err != nil {
+			logger.Error("invalid MongoDB connection options", "error", err)
 			os.Exit(1)
 		}
 
-		db, err = mongodb.Connect(mongoURI, mongoDBName)
+		db, err = mongodb.ConnectWithOptions(mongoURI, mongoDBName, mongoOpts)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to connect to MongoDB: %v\n", err)
+			logger.Error("failed to connect to MongoDB", "error", err)
 			os.Exit(1)
 		}
-		defer db.Close()
 		builder = mongodb.NewQueryBuilder()
-		fmt.Println("MongoDB connection established")
+		logger.Info("MongoDB connection established")
 
 	case "postgres", "":
-		dbURL := os.Getenv("DATABASE_URL")
+		dbURL, err := secretFromEnv("DATABASE_URL")
+		if err != nil {
+			logger.Error("failed to read PostgreSQL connection string", "error", err)
+			os.Exit(1)
+		}
 		if dbURL != "" {
-			db, err = postgres.Connect(dbURL)
+			// Assign only on success so a failed connection leaves db as a nil interface
+			pgDB, err := connectPostgres(dbURL, pgApplicationName)
 			if err != nil {
-				fmt.Printf("Warning: Could not connect to PostgreSQL: %v\n", err)
-				fmt.Println("Running in SQL-generation-only mode")
+				logger.Warn("could not connect to PostgreSQL, running in SQL-generation-only mode", "error", err)
 			} else {
-				defer db.Close()
-				fmt.Println("PostgreSQL connection established")
+				pgDB.SetStatementTimeout(pgStatementTimeout)
+				db = pgDB
+				logger.Info("PostgreSQL connection established", "statement_timeout", pgStatementTimeout.String())
 			}
 		} else {
-			fmt.Println("DATABASE_URL not set, running in SQL-generation-only mode")
+			logger.Info("DATABASE_URL not set, running in SQL-generation-only mode")
 		}
 		builder = postgres.NewQueryBuilder()
 
 	default:
-		fmt.Fprintf(os.Stderr, "Error: Unsupported DB_TYPE: %s\n", dbType)
+		logger.Error("unsupported DB_TYPE", "db_type", dbType)
+		os.Exit(1)
+	}
+
+	if err := validateSchema(validateSchemaMode, config.DefaultDatasource, db, cfg.Models, logger); err != nil {
+		logger.Error("schema validation failed", "error", err)
 		os.Exit(1)
 	}
 
-	// Health check endpoint
 	mux := http.NewServeMux()
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"status":"ok"}`)
-	})
 
-	// Register API routes
+	// Prometheus metrics endpoint
+	mux.Handle("/metrics", metrics.Handler())
+
+	// Register API routes (including /health)
 	apiSrv := api.NewWithType(registry, db, builder, dbType)
+	apiSrv.SetFailedModels(failedModels)
+	apiSrv.SetCountCacheTTL(countCacheTTL)
+	if enableExplain {
+		apiSrv.EnableExplain()
+		logger.Warn("EXPLAIN ANALYZE is enabled; explained queries run against the database")
+	}
+	if cursorSecret != "" {
+		apiSrv.EnableCursors([]byte(cursorSecret), cursorTTL)
+	}
+	if adminToken != "" {
+		apiSrv.EnableAdmin(adminToken)
+		logger.Warn("admin endpoints are enabled; /admin/truncate deletes every row of a model")
+	}
 	apiSrv.RegisterRoutes(mux)
 
+	// Named datasources serve the models that reference them
+	var datasourceDBs []adapter.Database
+	for _, ds := range cfg.Datasources {
+		dsDB, dsBuilder, err := connectDatasource(ds, pgStatementTimeout, pgApplicationName, logger)
+		if err != nil {
+			logger.Error("failed to connect datasource", "datasource", ds.Name, "error", err)
+			os.Exit(1)
+		}
+		if dsDB != nil {
+			datasourceDBs = append(datasourceDBs, dsDB)
+		}
+		if err := validateSchema(validateSchemaMode, ds.Name, dsDB, cfg.Models, logger); err != nil {
+			logger.Error("schema validation failed", "error", err)
+			os.Exit(1)
+		}
+		apiSrv.AddDatasource(ds.Name, dsDB, dsBuilder, ds.Type)
+	}
+
 	// CORS middleware
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
 		if r.Method == http.MethodOptions {
@@ -121,8 +341,181 @@ func main() {
 		mux.ServeHTTP(w, r)
 	})
 
-	fmt.Println("Server starting on :8080")
-	if err := http.ListenAndServe(":8080", handler); err != nil {
-		panic(err)
+	server := &http.Server{
+		Addr:    ":8080",
+		Handler: api.RequestIDMiddleware(api.LoggingMiddleware(logger, redactParams, handler)),
+	}
+
+	// Closing the database last lets draining requests finish their queries
+	closeDB := func() {
+		for _, dsDB := range datasourceDBs {
+			if err := dsDB.Close(); err != nil {
+				logger.Error("failed to close datasource connection", "error", err)
+			}
+		}
+		if db == nil {
+			return
+		}
+		if err := db.Close(); err != nil {
+			logger.Error("failed to close database connection", "error", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server starting", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		logger.Error("server failed", "error", err)
+		closeDB()
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	stop()
+
+	logger.Info("shutting down, draining in-flight requests", "timeout", shutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("graceful shutdown did not complete", "error", err)
+	}
+
+	closeDB()
+	logger.Info("server stopped")
+}
+
+// connectDatasource opens a named datasource. Like DATABASE_URL, an unreachable PostgreSQL
+// datasource is served in SQL-generation-only mode and returns a nil database.
+func connectDatasource(ds config.Datasource, pgStatementTimeout time.Duration, pgApplicationName string, logger *slog.Logger) (adapter.Database, adapter.QueryBuilder, error) {
+	switch ds.Type {
+	case "mongodb":
+		opts := mongodb.ConnectOptions{ReadPreference: ds.ReadPreference}
+		if ds.WriteConcern != nil {
+			opts.WriteConcern = ds.WriteConcern.WString()
+			opts.Journal = ds.WriteConcern.Journal
+		}
+		mongoDB, err := mongodb.ConnectWithOptions(ds.URL, ds.Database, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Info("MongoDB datasource connected", "datasource", ds.Name)
+		return mongoDB, mongodb.NewQueryBuilder(), nil
+
+	case "postgres":
+		pgDB, err := connectPostgres(ds.URL, pgApplicationName)
+		if err != nil {
+			logger.Warn("could not connect to PostgreSQL datasource, running it in SQL-generation-only mode", "datasource", ds.Name, "error", err)
+			return nil, postgres.NewQueryBuilder(), nil
+		}
+		pgDB.SetStatementTimeout(pgStatementTimeout)
+		logger.Info("PostgreSQL datasource connected", "datasource", ds.Name)
+		return pgDB, postgres.NewQueryBuilder(), nil
+	}
+	return nil, nil, fmt.Errorf("unsupported datasource type %q", ds.Type)
+}
+
+// validateSchema checks the models served by a datasource against its PostgreSQL schema.
+// Drift is logged as a warning, or returned as an error in strict mode. Datasources without a
+// PostgreSQL connection are not checked.
+func validateSchema(mode, datasource string, db adapter.Database, models []config.Model, logger *slog.Logger) error {
+	pgDB, ok := db.(*postgres.Database)
+	if mode == "off" || !ok {
+		return nil
+	}
+
+	var served []config.Model
+	for _, model := range models {
+		if model.DatasourceName() == datasource {
+			served = append(served, model)
+		}
+	}
+
+	drift, err := schema_processor.NewSchemaProcessor(pgDB.DB()).CheckModels(served)
+	if err != nil {
+		return err
+	}
+	if drift.Empty() {
+		logger.Info("schema validated", "datasource", datasource, "models", len(served))
+		return nil
+	}
+
+	attrs := []any{"datasource", datasource, "missing_tables", drift.MissingTables, "missing_columns", drift.MissingColumns, "type_mismatches", drift.TypeMismatches}
+	if mode == "strict" {
+		logger.Error("models do not match the database schema", attrs...)
+		return fmt.Errorf("models do not match the schema of datasource %s", datasource)
+	}
+	logger.Warn("models do not match the database schema", attrs...)
+	return nil
+}
+
+// mongoConnectOptions reads MongoDB client settings that may not fit in MONGODB_URI
+func mongoConnectOptions() (mongodb.ConnectOptions, error) {
+	opts := mongodb.ConnectOptions{
+		AuthSource:     os.Getenv("MONGODB_AUTH_SOURCE"),
+		ReplicaSet:     os.Getenv("MONGODB_REPLICA_SET"),
+		TLSCAFile:      os.Getenv("MONGODB_TLS_CA_FILE"),
+		ReadPreference: os.Getenv("MONGODB_READ_PREFERENCE"),
+		WriteConcern:   os.Getenv("MONGODB_WRITE_CONCERN"),
+	}
+
+	for name, target := range map[string]*bool{
+		"MONGODB_TLS":                      &opts.TLS,
+		"MONGODB_TLS_INSECURE_SKIP_VERIFY": &opts.TLSInsecureSkipVerify,
+		"MONGODB_WRITE_CONCERN_JOURNAL":    &opts.Journal,
+	} {
+		if v := os.Getenv(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s: %s", name, v)
+			}
+			*target = b
+		}
+	}
+
+	if v := os.Getenv("MONGODB_SERVER_SELECTION_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("invalid MONGODB_SERVER_SELECTION_TIMEOUT: %s", v)
+		}
+		opts.ServerSelectionTimeout = d
+	}
+
+	return opts, nil
+}
+
+// connectPostgres connects to dsn with application_name set, unless the DSN sets its own
+func connectPostgres(dsn, applicationName string) (*postgres.Database, error) {
+	dsn, err := postgres.WithApplicationName(dsn, applicationName)
+	if err != nil {
+		return nil, err
+	}
+	return postgres.Connect(dsn)
+}
+
+// secretFromEnv returns the value of name, or the trimmed contents of the file named by
+// name_FILE when that is set, so connection strings can be mounted as secrets
+func secretFromEnv(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return os.Getenv(name), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%s_FILE %s is empty", name, path)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return "", fmt.Errorf("%s_FILE %s must contain a single line", name, path)
 	}
+	return value, nil
 }
@@ -1,6 +1,11 @@
 package adapter
 
-import "udv/internal/planner"
+import (
+	"encoding/json"
+	"errors"
+
+	"udv/internal/planner"
+)
 
 // Database represents a generic database connection abstraction
 type Database interface {
@@ -13,6 +18,41 @@ type Database interface {
 	Exec(query interface{}, args ...interface{}) (ExecResult, error)
 }
 
+// Explainer is implemented by databases that can report how a built query executes
+type Explainer interface {
+	// Explain runs the query under EXPLAIN ANALYZE and returns the JSON plan; writes are not applied
+	Explain(query interface{}, args ...interface{}) (json.RawMessage, error)
+}
+
+// Truncater is implemented by databases that can empty a table or collection outright
+type Truncater interface {
+	// Truncate removes every row of table and returns how many it held
+	Truncate(table string) (int64, error)
+}
+
+// ErrNotMaterializedView is returned by Refresh for a table that is not a materialized view
+var ErrNotMaterializedView = errors.New("not a materialized view")
+
+// Refresher is implemented by databases with materialized views that can be refreshed on demand
+type Refresher interface {
+	// Refresh recomputes the materialized view table, without blocking reads when concurrently
+	// is set
+	Refresh(table string, concurrently bool) error
+}
+
+// Estimator is implemented by databases that can estimate a table's row count without counting
+type Estimator interface {
+	// EstimateCount returns the estimated rows of table, or false when no estimate is available
+	EstimateCount(table string) (int64, bool, error)
+}
+
+// UpsertReporter is implemented by built queries that record, once executed, whether an
+// upserting update inserted
+type UpsertReporter interface {
+	// UpsertedID returns the id of the inserted document, or nil when the update modified existing ones
+	UpsertedID() interface{}
+}
+
 // ExecResult wraps the result of an exec operation
 type ExecResult interface {
 	RowsAffected() (int64, error)
@@ -2,12 +2,16 @@ package mongodb
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	"udv/internal/dsl"
 	"udv/internal/planner"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -18,8 +22,16 @@ func NewQueryBuilder() *QueryBuilder {
 }
 
 func (qb *QueryBuilder) BuildQuery(plan *planner.QueryPlan) (interface{}, []interface{}, error) {
+	if err := plan.Pagination.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if plan.RootModel.ReadOnly && plan.Operation.Writes() {
+		return nil, nil, fmt.Errorf("model %s is read-only and does not support %s", plan.RootModel.Name, plan.Operation)
+	}
+
 	switch plan.Operation {
-	case dsl.OpSelect:
+	case dsl.OpSelect, dsl.OpAggregate, dsl.OpFirst, dsl.OpLast:
+		// Grouped and aggregating selects become a $group pipeline
 		mq, err := qb.buildFindQuery(plan)
 		return mq, nil, err
 	case dsl.OpCreate:
@@ -31,13 +43,44 @@ func (qb *QueryBuilder) BuildQuery(plan *planner.QueryPlan) (interface{}, []inte
 	case dsl.OpDelete:
 		mq, err := qb.buildDelete(plan)
 		return mq, nil, err
+	case dsl.OpCount:
+		mq, err := qb.buildCount(plan)
+		return mq, nil, err
+	case dsl.OpDistinct:
+		mq, err := qb.buildDistinct(plan)
+		return mq, nil, err
+	case dsl.OpExists:
+		mq, err := qb.buildExists(plan)
+		return mq, nil, err
 	default:
 		return nil, nil, fmt.Errorf("unsupported operation: %s", plan.Operation)
 	}
 }
 
 func (qb *QueryBuilder) buildFindQuery(plan *planner.QueryPlan) (*MongoQuery, error) {
-	filter, err := qb.buildFilterFromExpr(plan.Filters)
+	if plan.Lock != nil {
+		return nil, fmt.Errorf("row locking (FOR %s) is not supported by MongoDB", plan.Lock.Strength)
+	}
+
+	for _, sel := range plan.Select {
+		if sel.Alias != sel.Column.ColumnName {
+			return nil, fmt.Errorf("field aliases are not supported by the MongoDB builder")
+		}
+	}
+
+	if len(plan.GroupBy) > 0 || len(plan.Aggregates) > 0 {
+		if len(plan.Joins) > 0 {
+			return nil, fmt.Errorf("relation includes cannot be combined with group_by or aggregates")
+		}
+		return qb.buildGroupQuery(plan)
+	}
+
+	// Computed fields need an $addFields stage, which find cannot run
+	if len(plan.Joins) > 0 || hasVirtual(plan.RootModel.Columns) {
+		return qb.buildLookupQuery(plan)
+	}
+
+	filter, err := qb.buildPlanFilter(plan)
 	if err != nil {
 		return nil, err
 	}
@@ -52,19 +95,19 @@ func (qb *QueryBuilder) buildFindQuery(plan *planner.QueryPlan) (*MongoQuery, er
 
 	// Build sort
 	if len(plan.Sort) > 0 {
-		sortDoc := bson.D{}
-		for _, s := range plan.Sort {
-			direction := 1
-			if strings.ToLower(s.Direction) == "desc" {
-				direction = -1
-			}
-			fieldName := s.Column.ColumnName
-			if s.Column.TableAlias != "" {
-				fieldName = s.Column.TableAlias + "." + fieldName
-			}
-			sortDoc = append(sortDoc, bson.E{Key: fieldName, Value: direction})
-		}
-		opt.SetSort(sortDoc)
+		opt.SetSort(qb.buildSortDoc(plan.Sort))
+	}
+	if c := collation(plan); c != nil {
+		opt.SetCollation(c)
+	}
+
+	// Exclude hidden fields and expose the search score when sorting by it
+	projection := hiddenFieldsProjection(plan.RootModel.HiddenFields)
+	if sortsByScore(plan.Sort) {
+		projection[dsl.SearchScoreField] = bson.M{"$meta": "textScore"}
+	}
+	if len(projection) > 0 {
+		opt.SetProjection(projection)
 	}
 
 	return &MongoQuery{
@@ -75,6 +118,403 @@ func (qb *QueryBuilder) buildFindQuery(plan *planner.QueryPlan) (*MongoQuery, er
 	}, nil
 }
 
+// buildLookupQuery builds an aggregation pipeline that paginates the root collection,
+// computes its virtual fields and then joins related collections with $lookup stages
+func (qb *QueryBuilder) buildLookupQuery(plan *planner.QueryPlan) (*MongoQuery, error) {
+	filter, err := qb.buildPlanFilter(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, col := range plan.RootModel.Columns {
+		if col.Virtual != nil && col.Virtual.Mongo == nil {
+			return nil, fmt.Errorf("virtual field %s has no mongo expression", col.ColumnName)
+		}
+	}
+
+	pipeline := []bson.M{}
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.M{"$match": filter})
+	}
+
+	pipeline = append(pipeline, qb.buildSortAndPageStages(plan)...)
+	// Computed before hidden fields are dropped, since they may be derived from them
+	if virtual := virtualFields(plan.RootModel.Columns); len(virtual) > 0 {
+		pipeline = append(pipeline, bson.M{"$addFields": virtual})
+	}
+	if len(plan.RootModel.HiddenFields) > 0 {
+		pipeline = append(pipeline, bson.M{"$project": hiddenFieldsProjection(plan.RootModel.HiddenFields)})
+	}
+	pipeline = append(pipeline, qb.buildLookupStages(plan.Joins, plan.RootModel.Alias)...)
+
+	return aggregateQuery(plan, pipeline), nil
+}
+
+// buildGroupQuery builds an aggregation pipeline for group_by and aggregate queries.
+// Sort and pagination run after grouping so they can reference group fields and aggregate aliases.
+func (qb *QueryBuilder) buildGroupQuery(plan *planner.QueryPlan) (*MongoQuery, error) {
+	filter, err := qb.buildPlanFilter(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := []bson.M{}
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.M{"$match": filter})
+	}
+
+	// Group keys live under _id and are projected back to top-level fields
+	var groupID interface{}
+	project := bson.M{"_id": 0}
+	if len(plan.GroupBy) > 0 {
+		keys := bson.D{}
+		for _, g := range plan.GroupBy {
+			if g.Granularity != "" {
+				keys = append(keys, bson.E{Key: g.Alias, Value: dateTrunc(g)})
+				project[g.Alias] = "$_id." + g.Alias
+				continue
+			}
+			keys = append(keys, bson.E{Key: g.Column.ColumnName, Value: "$" + g.Column.ColumnName})
+			project[g.Column.ColumnName] = "$_id." + g.Column.ColumnName
+		}
+		groupID = keys
+	}
+
+	group := bson.M{"_id": groupID}
+	for _, agg := range plan.Aggregates {
+		// Expressions are computed in $project from the accumulated values
+		if agg.Expr != nil {
+			project[agg.Alias] = arithmeticExpr(agg.Expr)
+			continue
+		}
+		acc, err := qb.buildAccumulator(agg)
+		if err != nil {
+			return nil, err
+		}
+		group[agg.Alias] = acc
+		project[agg.Alias] = 1
+		if agg.Function == planner.AggCountDistinctFn {
+			project[agg.Alias] = accumulatedValue(agg)
+		}
+	}
+
+	pipeline = append(pipeline, bson.M{"$group": group}, bson.M{"$project": project})
+	pipeline = append(pipeline, qb.buildSortAndPageStages(plan)...)
+
+	return aggregateQuery(plan, pipeline), nil
+}
+
+// aggregateQuery wraps a select pipeline on the root collection, carrying the plan's collation
+func aggregateQuery(plan *planner.QueryPlan, pipeline []bson.M) *MongoQuery {
+	mq := &MongoQuery{
+		Collection: plan.RootModel.Table,
+		Operation:  "aggregate",
+		Pipeline:   pipeline,
+	}
+	if c := collation(plan); c != nil {
+		mq.Options = options.Aggregate().SetCollation(c)
+	}
+	return mq
+}
+
+// collation converts the plan's collation to driver options, or nil when it has none. On
+// MongoDB it applies to the whole query, so string filters and group keys compare by it too.
+func collation(plan *planner.QueryPlan) *options.Collation {
+	if plan.Collation == nil {
+		return nil
+	}
+	return &options.Collation{Locale: plan.Collation.Locale, Strength: plan.Collation.Strength}
+}
+
+// dateTrunc truncates a time bucket's field with $dateTrunc (MongoDB 5.0+). Weeks start on
+// Monday to match PostgreSQL's date_trunc.
+func dateTrunc(g planner.GroupExpr) bson.M {
+	trunc := bson.M{"date": "$" + g.Column.ColumnName, "unit": g.Granularity}
+	if g.Granularity == "week" {
+		trunc["startOfWeek"] = "monday"
+	}
+	return bson.M{"$dateTrunc": trunc}
+}
+
+// accumulatedValue projects the result of an aggregate from its $group accumulator
+func accumulatedValue(agg planner.AggregateExpr) interface{} {
+	if agg.Function == planner.AggCountDistinctFn {
+		// The accumulated set is reduced to its size, leaving out null like COUNT(DISTINCT)
+		return bson.M{"$size": bson.M{"$filter": bson.M{
+			"input": "$" + agg.Alias,
+			"cond":  bson.M{"$ne": bson.A{"$$this", nil}},
+		}}}
+	}
+	return "$" + agg.Alias
+}
+
+// arithmeticOperators maps the operators of aggregate expressions to aggregation operators
+var arithmeticOperators = map[string]string{"+": "$add", "-": "$subtract", "*": "$multiply"}
+
+// arithmeticExpr converts an aggregate expression into an aggregation expression over the
+// accumulated values; a zero divisor yields null like PostgreSQL's NULLIF
+func arithmeticExpr(expr *planner.ArithExpr) interface{} {
+	switch {
+	case expr.Aggregate != nil:
+		return accumulatedValue(*expr.Aggregate)
+	case expr.Op == "":
+		return expr.Value
+	case expr.Op == "/":
+		divisor := arithmeticExpr(expr.Right)
+		return bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{divisor, 0}},
+			nil,
+			bson.M{"$divide": bson.A{arithmeticExpr(expr.Left), divisor}},
+		}}
+	default:
+		return bson.M{arithmeticOperators[expr.Op]: bson.A{arithmeticExpr(expr.Left), arithmeticExpr(expr.Right)}}
+	}
+}
+
+// buildAccumulator converts an aggregate expression into a $group accumulator
+func (qb *QueryBuilder) buildAccumulator(agg planner.AggregateExpr) (bson.M, error) {
+	if agg.Column == nil {
+		if agg.Function == planner.AggCountFn {
+			return bson.M{"$sum": 1}, nil
+		}
+		return nil, fmt.Errorf("%s requires a field", agg.Function)
+	}
+
+	field := "$" + agg.Column.ColumnName
+	switch agg.Function {
+	case planner.AggCountFn:
+		// COUNT(field) skips null and missing values, which sort below everything else
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{field, nil}}, 1, 0}}}, nil
+	case planner.AggSumFn:
+		return bson.M{"$sum": field}, nil
+	case planner.AggAvgFn:
+		return bson.M{"$avg": field}, nil
+	case planner.AggMinFn:
+		return bson.M{"$min": field}, nil
+	case planner.AggMaxFn:
+		return bson.M{"$max": field}, nil
+	case planner.AggCountDistinctFn:
+		return bson.M{"$addToSet": field}, nil
+	default:
+		return nil, fmt.Errorf("unsupported aggregate function: %s", agg.Function)
+	}
+}
+
+// buildSortAndPageStages builds the $sort, $skip and $limit pipeline stages
+func (qb *QueryBuilder) buildSortAndPageStages(plan *planner.QueryPlan) []bson.M {
+	var stages []bson.M
+	if len(plan.Sort) > 0 {
+		stages = append(stages, bson.M{"$sort": qb.buildSortDoc(plan.Sort)})
+	}
+	if plan.Pagination.Offset > 0 {
+		stages = append(stages, bson.M{"$skip": int64(plan.Pagination.Offset)})
+	}
+	if plan.Pagination.Limit > 0 {
+		stages = append(stages, bson.M{"$limit": int64(plan.Pagination.Limit)})
+	}
+	return stages
+}
+
+// buildSortDoc builds an ordered sort document, referencing aggregate aliases by name
+func (qb *QueryBuilder) buildSortDoc(sorts []planner.SortExpr) bson.D {
+	sortDoc := bson.D{}
+	for _, s := range sorts {
+		direction := 1
+		if strings.ToLower(s.Direction) == "desc" {
+			direction = -1
+		}
+
+		var fieldName string
+		if s.Aggregate != nil {
+			fieldName = s.Aggregate.Alias
+		} else if s.Bucket != nil {
+			fieldName = s.Bucket.Alias
+		} else if s.Column != nil {
+			fieldName = s.Column.ColumnName
+		} else if s.Search != nil {
+			// Text score sorts are always by descending relevance
+			sortDoc = append(sortDoc, bson.E{Key: dsl.SearchScoreField, Value: bson.M{"$meta": "textScore"}})
+			continue
+		}
+		sortDoc = append(sortDoc, bson.E{Key: fieldName, Value: direction})
+	}
+	return sortDoc
+}
+
+// sortsByScore reports whether any sort orders by full-text search relevance
+func sortsByScore(sorts []planner.SortExpr) bool {
+	for _, s := range sorts {
+		if s.Target == planner.SortScore {
+			return true
+		}
+	}
+	return false
+}
+
+// returningProjection selects the fields read back after a write: the requested fields
+// only, or everything except hidden fields. It returns nil when no projection is needed.
+func returningProjection(plan *planner.QueryPlan) interface{} {
+	if len(plan.Returning) == 0 {
+		if len(plan.RootModel.HiddenFields) == 0 {
+			return nil
+		}
+		return hiddenFieldsProjection(plan.RootModel.HiddenFields)
+	}
+
+	projection := bson.M{"_id": 0}
+	for _, col := range plan.Returning {
+		projection[col.ColumnName] = 1
+	}
+	return projection
+}
+
+// hasVirtual reports whether any of columns is a computed field
+func hasVirtual(columns []planner.ColumnRef) bool {
+	for _, col := range columns {
+		if col.Virtual != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// virtualFields maps the computed fields among columns to their expressions, for $addFields
+func virtualFields(columns []planner.ColumnRef) bson.M {
+	fields := bson.M{}
+	for _, col := range columns {
+		if col.Virtual != nil && col.Virtual.Mongo != nil {
+			fields[col.ColumnName] = col.Virtual.Mongo
+		}
+	}
+	return fields
+}
+
+// hiddenFieldsProjection builds an exclusion projection for hidden fields
+func hiddenFieldsProjection(fields []string) bson.M {
+	projection := bson.M{}
+	for _, f := range fields {
+		projection[f] = 0
+	}
+	return projection
+}
+
+// buildLookupStages emits $lookup (and optional $unwind) stages for the joins
+// hanging off parentAlias; nested joins go into the $lookup sub-pipeline
+func (qb *QueryBuilder) buildLookupStages(joins []planner.JoinPlan, parentAlias string) []bson.M {
+	stages := []bson.M{}
+	for _, j := range joins {
+		if j.FromAlias != parentAlias {
+			continue
+		}
+
+		lookup := bson.M{
+			"from":         j.ToTable,
+			"localField":   j.On.Left.ColumnName,
+			"foreignField": j.On.Right.ColumnName,
+			"as":           j.Relation,
+		}
+		nested := []bson.M{}
+		if virtual := virtualFields(j.Virtual); len(virtual) > 0 {
+			nested = append(nested, bson.M{"$addFields": virtual})
+		}
+		if len(j.HiddenFields) > 0 {
+			nested = append(nested, bson.M{"$project": hiddenFieldsProjection(j.HiddenFields)})
+		}
+		nested = append(nested, qb.buildLookupStages(joins, j.ToAlias)...)
+		if len(nested) > 0 {
+			lookup["pipeline"] = nested
+		}
+		stages = append(stages, bson.M{"$lookup": lookup})
+
+		if j.Unwind {
+			stages = append(stages, bson.M{"$unwind": bson.M{
+				"path":                       "$" + j.Relation,
+				"preserveNullAndEmptyArrays": true,
+			}})
+		}
+	}
+	return stages
+}
+
+func (qb *QueryBuilder) buildCount(plan *planner.QueryPlan) (*MongoQuery, error) {
+	filter, err := qb.buildPlanFilter(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MongoQuery{
+		Collection: plan.RootModel.Table,
+		Operation:  "count",
+		Filter:     filter,
+	}, nil
+}
+
+// buildExists builds a document count capped at one for the plan's filters
+func (qb *QueryBuilder) buildExists(plan *planner.QueryPlan) (*MongoQuery, error) {
+	filter, err := qb.buildPlanFilter(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MongoQuery{
+		Collection: plan.RootModel.Table,
+		Operation:  "exists",
+		Filter:     filter,
+	}, nil
+}
+
+// buildDistinct builds a distinct command for the plan's single field
+func (qb *QueryBuilder) buildDistinct(plan *planner.QueryPlan) (*MongoQuery, error) {
+	if len(plan.Select) != 1 {
+		return nil, fmt.Errorf("distinct requires exactly one field")
+	}
+
+	filter, err := qb.buildPlanFilter(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MongoQuery{
+		Collection: plan.RootModel.Table,
+		Operation:  "distinct",
+		Field:      plan.Select[0].Column.ColumnName,
+		Filter:     filter,
+	}, nil
+}
+
+// buildPlanFilter builds the root filter from the plan's id (update/delete only) or filters,
+// excluding soft-deleted documents
+func (qb *QueryBuilder) buildPlanFilter(plan *planner.QueryPlan) (bson.M, error) {
+	var filter bson.M
+	if plan.ID != nil && (plan.Operation == dsl.OpUpdate || plan.Operation == dsl.OpDelete) {
+		filter = bson.M{plan.RootModel.PrimaryKey.ColumnName: plan.ID}
+	} else {
+		var err error
+		filter, err = qb.buildFilterFromExpr(plan.Filters)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A stale expected version matches no document, which the caller reports as a conflict
+	if plan.Version != nil && plan.ExpectedVersion != nil {
+		filter = andFilter(filter, bson.M{plan.Version.ColumnName: plan.ExpectedVersion})
+	}
+
+	if plan.SoftDelete == nil {
+		return filter, nil
+	}
+	return andFilter(filter, bson.M{plan.SoftDelete.ColumnName: nil}), nil
+}
+
+// andFilter adds cond to filter, returning cond alone when filter is empty
+func andFilter(filter, cond bson.M) bson.M {
+	if len(filter) == 0 {
+		return cond
+	}
+	return bson.M{"$and": bson.A{filter, cond}}
+}
+
 func (qb *QueryBuilder) buildFilterFromExpr(expr planner.FilterExpr) (bson.M, error) {
 	if expr == nil {
 		return bson.M{}, nil
@@ -85,16 +525,118 @@ func (qb *QueryBuilder) buildFilterFromExpr(expr planner.FilterExpr) (bson.M, er
 		return qb.buildComparisonFilter(f)
 	case *planner.LogicalFilterIR:
 		return qb.buildLogicalFilter(f)
+	case *planner.SearchFilterIR:
+		// $text uses the collection's text index, which should cover the model's search fields
+		return bson.M{"$text": bson.M{"$search": f.Query}}, nil
+	case *planner.ElemMatchFilterIR:
+		return qb.buildElemMatchFilter(f)
 	default:
 		return nil, fmt.Errorf("unsupported filter type: %T", expr)
 	}
 }
 
+// buildElemMatchFilter renders an element-match filter as {field: {$elemMatch: {...}}}.
+// Conditions are merged into one document; when two constrain the same key they are
+// combined with $and instead so neither overwrites the other.
+func (qb *QueryBuilder) buildElemMatchFilter(f *planner.ElemMatchFilterIR) (bson.M, error) {
+	clauses := make([]bson.M, 0, len(f.Conditions))
+	merged := bson.M{}
+	collides := false
+	for _, cond := range f.Conditions {
+		clause, err := qb.buildComparisonFilter(cond)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range clause {
+			if _, ok := merged[key]; ok {
+				collides = true
+			}
+			merged[key] = value
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if collides {
+		merged = bson.M{"$and": clauses}
+	}
+	return bson.M{f.Column.ColumnName: bson.M{"$elemMatch": merged}}, nil
+}
+
+// containsSearch reports whether a filter tree includes a full-text search
+func containsSearch(expr planner.FilterExpr) bool {
+	switch f := expr.(type) {
+	case *planner.SearchFilterIR:
+		return true
+	case *planner.LogicalFilterIR:
+		for _, node := range f.Nodes {
+			if containsSearch(node) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (qb *QueryBuilder) buildComparisonFilter(f *planner.ComparisonFilterIR) (bson.M, error) {
 	filter := make(bson.M)
 	fieldName := f.Left.ColumnName
 
-	mongoOp, mongoVal, err := qb.convertOperator(string(f.Operator), f.Value.Value)
+	if f.Right != nil {
+		return qb.buildColumnComparison(f)
+	}
+	if f.Subquery != nil {
+		return qb.buildSubqueryFilter(f)
+	}
+
+	var value interface{}
+	if f.Value != nil {
+		value = f.Value.Value
+	}
+	if f.Left.PrimaryKey {
+		var err error
+		if value, err = objectIDValue(f.Operator, fieldName, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Operator == dsl.OpSize {
+		return qb.buildSizeFilter(fieldName, value)
+	}
+
+	// between expands into a range on a single field, closed unless an end is excluded
+	if f.Operator == dsl.OpBetween {
+		low, high, err := planner.BetweenBounds(value)
+		if err != nil {
+			return nil, err
+		}
+		lowOp, highOp := "$gte", "$lte"
+		if f.ExcludeLow {
+			lowOp = "$gt"
+		}
+		if f.ExcludeHigh {
+			highOp = "$lt"
+		}
+		filter[fieldName] = bson.M{lowOp: low, highOp: high}
+		return filter, nil
+	}
+
+	if f.Left.CaseInsensitive {
+		if cond, ok, err := caseInsensitiveCondition(f.Operator, value); ok {
+			if err != nil {
+				return nil, err
+			}
+			filter[fieldName] = cond
+			return filter, nil
+		}
+	}
+
+	op := f.Operator
+	if op == dsl.OpRegex && f.Left.CaseInsensitive {
+		// Case-insensitive fields match regexes ignoring case as well
+		op = dsl.OpIRegex
+	}
+
+	mongoOp, mongoVal, err := qb.convertOperator(string(op), value)
 	if err != nil {
 		return nil, err
 	}
@@ -108,6 +650,68 @@ func (qb *QueryBuilder) buildComparisonFilter(f *planner.ComparisonFilterIR) (bs
 	return filter, nil
 }
 
+// buildColumnComparison compares two fields of the same document with $expr, e.g.
+// {$expr: {$gt: ["$updated_at", "$created_at"]}}. Equality involving a case-insensitive
+// field compares the $toLower of both sides.
+func (qb *QueryBuilder) buildColumnComparison(f *planner.ComparisonFilterIR) (bson.M, error) {
+	if !dsl.IsFieldComparisonOp(f.Operator) {
+		return nil, fmt.Errorf("operator %s cannot compare two fields", f.Operator)
+	}
+	mongoOp, _, err := qb.convertOperator(string(f.Operator), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var left, right interface{} = "$" + f.Left.ColumnName, "$" + f.Right.ColumnName
+	if (f.Left.CaseInsensitive || f.Right.CaseInsensitive) && (f.Operator == dsl.OpEqual || f.Operator == dsl.OpNotEqual) {
+		left, right = bson.M{"$toLower": left}, bson.M{"$toLower": right}
+	}
+	return bson.M{"$expr": bson.M{mongoOp: bson.A{left, right}}}, nil
+}
+
+// buildSizeFilter compares the length of an array field with $expr, e.g.
+// {$expr: {$gt: [{$size: {$ifNull: ["$tags", []]}}, 3]}}; missing and null arrays count as empty
+func (qb *QueryBuilder) buildSizeFilter(fieldName string, value interface{}) (bson.M, error) {
+	op, length, err := dsl.SizeCondition(value)
+	if err != nil {
+		return nil, err
+	}
+	mongoOp, _, err := qb.convertOperator(string(op), nil)
+	if err != nil {
+		return nil, err
+	}
+	size := bson.M{"$size": bson.M{"$ifNull": bson.A{"$" + fieldName, bson.A{}}}}
+	return bson.M{"$expr": bson.M{mongoOp: bson.A{size, length}}}, nil
+}
+
+// buildSubqueryFilter renders an in/not_in filter against another model's documents as
+// {field: {$in: *Subquery}}; the database swaps in the subquery's values before running it
+func (qb *QueryBuilder) buildSubqueryFilter(f *planner.ComparisonFilterIR) (bson.M, error) {
+	var op string
+	switch f.Operator {
+	case dsl.OpIn:
+		op = "$in"
+	case dsl.OpNotIn:
+		op = "$nin"
+	default:
+		return nil, fmt.Errorf("operator %s cannot be used with a subquery", f.Operator)
+	}
+
+	sub := f.Subquery
+	if len(sub.Select) != 1 {
+		return nil, fmt.Errorf("subquery requires exactly one field")
+	}
+	filter, err := qb.buildPlanFilter(sub)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{f.Left.ColumnName: bson.M{op: &Subquery{
+		Collection: sub.RootModel.Table,
+		Field:      sub.Select[0].Column.ColumnName,
+		Filter:     filter,
+	}}}, nil
+}
+
 func (qb *QueryBuilder) buildLogicalFilter(f *planner.LogicalFilterIR) (bson.M, error) {
 	filter := make(bson.M)
 
@@ -132,6 +736,21 @@ func (qb *QueryBuilder) buildLogicalFilter(f *planner.LogicalFilterIR) (bson.M,
 			orClauses = append(orClauses, childFilter)
 		}
 		filter["$or"] = orClauses
+	case "NOT":
+		if len(f.Nodes) != 1 {
+			return nil, fmt.Errorf("NOT filter must have exactly one node")
+		}
+		// MongoDB rejects $text anywhere under $nor
+		if containsSearch(f.Nodes[0]) {
+			return nil, fmt.Errorf("search filter cannot be negated")
+		}
+		// $nor of a single clause matches exactly the documents the clause does not,
+		// so compound children (NOT of AND/OR) keep their semantics
+		childFilter, err := qb.buildFilterFromExpr(f.Nodes[0])
+		if err != nil {
+			return nil, err
+		}
+		filter["$nor"] = []bson.M{childFilter}
 	default:
 		return nil, fmt.Errorf("unsupported logical operator: %s", f.Op)
 	}
@@ -143,6 +762,9 @@ func (qb *QueryBuilder) convertOperator(op string, value interface{}) (string, i
 	switch op {
 	case "=", "eq":
 		return "$eq", value, nil
+	case "null_safe_eq":
+		// Equality to null already matches null and missing fields
+		return "$eq", value, nil
 	case "!=", "ne":
 		return "$ne", value, nil
 	case ">", "gt":
@@ -153,9 +775,19 @@ func (qb *QueryBuilder) convertOperator(op string, value interface{}) (string, i
 		return "$lt", value, nil
 	case "<=", "lte":
 		return "$lte", value, nil
+	case "before":
+		return "$lt", value, nil
+	case "after":
+		return "$gt", value, nil
 	case "in":
+		if !isSliceValue(value) {
+			return "", nil, fmt.Errorf("in operator requires an array value, got %T", value)
+		}
 		return "$in", value, nil
 	case "not_in", "nin":
+		if !isSliceValue(value) {
+			return "", nil, fmt.Errorf("not_in operator requires an array value, got %T", value)
+		}
 		return "$nin", value, nil
 	case "like", "contains":
 		strVal, ok := value.(string)
@@ -163,6 +795,16 @@ func (qb *QueryBuilder) convertOperator(op string, value interface{}) (string, i
 			return "", nil, fmt.Errorf("like/contains operator requires string value")
 		}
 		return "$regex", strVal, nil
+	case "regex", "iregex":
+		pattern, ok := value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("%s operator requires a string pattern", op)
+		}
+		options := ""
+		if op == "iregex" {
+			options = "i"
+		}
+		return "$regex", primitive.Regex{Pattern: pattern, Options: options}, nil
 	case "is_null":
 		return "$exists", false, nil
 	case "not_null":
@@ -172,45 +814,276 @@ func (qb *QueryBuilder) convertOperator(op string, value interface{}) (string, i
 	}
 }
 
+// caseInsensitiveCondition builds the case-insensitive form of =, !=, in, not_in and starts_with
+// as anchored regexes with the "i" option. ok is false for other operators.
+func caseInsensitiveCondition(op dsl.FilterOperator, value interface{}) (cond interface{}, ok bool, err error) {
+	switch op {
+	case dsl.OpEqual, dsl.OpNotEqual, dsl.OpStartsWith:
+		s, isString := value.(string)
+		if !isString {
+			return nil, true, fmt.Errorf("%s operator on a case-insensitive field requires a string value", op)
+		}
+		switch op {
+		case dsl.OpEqual:
+			return exactMatchRegex(s), true, nil
+		case dsl.OpNotEqual:
+			return bson.M{"$not": exactMatchRegex(s)}, true, nil
+		default:
+			return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(s), Options: "i"}, true, nil
+		}
+
+	case dsl.OpIn, dsl.OpNotIn:
+		if !isSliceValue(value) {
+			return nil, true, fmt.Errorf("%s operator requires an array value, got %T", op, value)
+		}
+		rv := reflect.ValueOf(value)
+		patterns := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			s, isString := rv.Index(i).Interface().(string)
+			if !isString {
+				return nil, true, fmt.Errorf("%s operator on a case-insensitive field requires string values", op)
+			}
+			patterns = append(patterns, exactMatchRegex(s))
+		}
+		if op == dsl.OpIn {
+			return bson.M{"$in": patterns}, true, nil
+		}
+		return bson.M{"$nin": patterns}, true, nil
+
+	default:
+		return nil, false, nil
+	}
+}
+
+// exactMatchRegex matches s exactly, ignoring case
+func exactMatchRegex(s string) primitive.Regex {
+	return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(s) + "$", Options: "i"}
+}
+
+// isSliceValue reports whether value is a slice or array suitable for $in/$nin
+func isSliceValue(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if _, isBytes := value.([]byte); isBytes {
+		return false
+	}
+	kind := reflect.TypeOf(value).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// objectIDValue converts hex strings compared to the primary key with =, !=, <, <=, >, >=, in
+// or not_in into ObjectIDs, so they match documents whose _id is an ObjectID. Other values are
+// returned as is; a list mixing ObjectID hex strings with other values is rejected.
+func objectIDValue(op dsl.FilterOperator, fieldName string, value interface{}) (interface{}, error) {
+	switch op {
+	case dsl.OpEqual, dsl.OpNotEqual, dsl.OpGT, dsl.OpGTE, dsl.OpLT, dsl.OpLTE:
+		if s, ok := value.(string); ok && primitive.IsValidObjectID(s) {
+			return primitive.ObjectIDFromHex(s)
+		}
+		return value, nil
+
+	case dsl.OpIn, dsl.OpNotIn:
+		if !isSliceValue(value) {
+			return value, nil
+		}
+		rv := reflect.ValueOf(value)
+		ids := make([]interface{}, rv.Len())
+		var other interface{}
+		converted := 0
+		for i := range ids {
+			ids[i] = rv.Index(i).Interface()
+			s, ok := ids[i].(string)
+			if !ok || !primitive.IsValidObjectID(s) {
+				other = ids[i]
+				continue
+			}
+			oid, err := primitive.ObjectIDFromHex(s)
+			if err != nil {
+				return nil, err
+			}
+			ids[i] = oid
+			converted++
+		}
+		if converted == 0 {
+			return value, nil
+		}
+		if converted < len(ids) {
+			return nil, fmt.Errorf("%s operator on %s mixes ObjectID values with %v, which is not an ObjectID", op, fieldName, other)
+		}
+		return ids, nil
+
+	default:
+		return value, nil
+	}
+}
+
 func (qb *QueryBuilder) buildInsert(plan *planner.QueryPlan) (*MongoQuery, error) {
 	if len(plan.Data) == 0 {
 		return nil, fmt.Errorf("insert data required")
 	}
 
-	doc := bson.M(plan.Data)
+	now := time.Now().UTC()
+	doc := bson.M{}
+	for field, value := range plan.Data {
+		if expr, ok := value.(dsl.ValueExpr); ok {
+			// Collections have no column defaults, so DEFAULT leaves the field out
+			if expr == dsl.ExprDefault {
+				continue
+			}
+			evaluated, err := exprValue(expr, now)
+			if err != nil {
+				return nil, err
+			}
+			value = evaluated
+		}
+		doc[field] = value
+	}
+	if cols := plan.AutoTimestamps(); len(cols) > 0 {
+		for _, col := range cols {
+			doc[col] = now
+		}
+	}
 
 	return &MongoQuery{
 		Collection: plan.RootModel.Table,
 		Operation:  "insert",
 		Document:   doc,
+		Projection: returningProjection(plan),
 	}, nil
 }
 
+// exprValue evaluates an allowlisted data expression on the client, as MongoDB has no SQL
+// functions to run it server-side
+func exprValue(expr dsl.ValueExpr, now time.Time) (interface{}, error) {
+	switch expr {
+	case dsl.ExprNow:
+		return now, nil
+	case dsl.ExprCurrentDate:
+		return now.Truncate(24 * time.Hour), nil
+	case dsl.ExprUUID, dsl.ExprUUIDv4:
+		return planner.NewUUID()
+	}
+	return nil, fmt.Errorf("expression %s is not supported by MongoDB", expr)
+}
+
 func (qb *QueryBuilder) buildUpdate(plan *planner.QueryPlan) (*MongoQuery, error) {
-	filter, err := qb.buildFilterFromExpr(plan.Filters)
+	if plan.ID == nil && plan.Filters == nil && !plan.AllowFullTable {
+		return nil, fmt.Errorf("id or filters required for update operation")
+	}
+	filter, err := qb.buildPlanFilter(plan)
 	if err != nil {
 		return nil, err
 	}
 
-	updateDoc := bson.M{"$set": plan.Data}
+	if len(plan.Data) == 0 && len(plan.Increment) == 0 && len(plan.Push) == 0 && len(plan.JSONUpdates) == 0 {
+		return nil, fmt.Errorf("data, increment, push or json updates are required for update operation")
+	}
 
-	return &MongoQuery{
+	// Null values either overwrite with null or, with UnsetNulls, remove the field
+	set := bson.M{}
+	unset := bson.M{}
+	currentDate := bson.M{}
+	for field, value := range plan.Data {
+		if value == nil && plan.UnsetNulls {
+			unset[field] = ""
+			continue
+		}
+		if expr, ok := value.(dsl.ValueExpr); ok {
+			switch expr {
+			case dsl.ExprDefault:
+				return nil, fmt.Errorf("DEFAULT is not supported by MongoDB updates, as collections have no column defaults")
+			case dsl.ExprNow:
+				currentDate[field] = true
+				continue
+			}
+			if value, err = exprValue(expr, time.Now().UTC()); err != nil {
+				return nil, err
+			}
+		}
+		set[field] = value
+	}
+
+	// Keys inside embedded documents are addressed with dotted paths
+	for _, u := range plan.JSONUpdates {
+		path := u.Column + "." + strings.Join(u.Path, ".")
+		if u.Remove {
+			unset[path] = ""
+		} else {
+			set[path] = u.Value
+		}
+	}
+
+	updateDoc := bson.M{}
+	if len(set) > 0 {
+		updateDoc["$set"] = set
+	}
+	if len(unset) > 0 {
+		updateDoc["$unset"] = unset
+	}
+	inc := bson.M{}
+	for field, amount := range plan.Increment {
+		inc[field] = amount
+	}
+	if plan.Version != nil {
+		inc[plan.Version.ColumnName] = 1
+	}
+	if len(inc) > 0 {
+		updateDoc["$inc"] = inc
+	}
+	if len(plan.Push) > 0 {
+		updateDoc["$push"] = bson.M(plan.Push)
+	}
+	for _, col := range plan.AutoTimestamps() {
+		currentDate[col] = true
+	}
+	if len(currentDate) > 0 {
+		updateDoc["$currentDate"] = currentDate
+	}
+
+	mq := &MongoQuery{
 		Collection: plan.RootModel.Table,
 		Operation:  "update",
 		Filter:     filter,
 		Update:     updateDoc,
-	}, nil
+		Projection: returningProjection(plan),
+	}
+	if plan.Upsert {
+		mq.Options = options.Update().SetUpsert(true)
+	}
+	return mq, nil
 }
 
 func (qb *QueryBuilder) buildDelete(plan *planner.QueryPlan) (*MongoQuery, error) {
-	filter, err := qb.buildFilterFromExpr(plan.Filters)
+	if plan.ID == nil && plan.Filters == nil && !plan.AllowFullTable {
+		return nil, fmt.Errorf("id or filters required for delete operation")
+	}
+	filter, err := qb.buildPlanFilter(plan)
 	if err != nil {
 		return nil, err
 	}
 
+	// Deleted documents are only read back when the client asked for specific fields
+	var projection interface{}
+	if len(plan.Returning) > 0 {
+		projection = returningProjection(plan)
+	}
+
+	// Soft deletes mark the document instead of removing it
+	if plan.SoftDelete != nil {
+		return &MongoQuery{
+			Collection: plan.RootModel.Table,
+			Operation:  "update",
+			Filter:     filter,
+			Update:     bson.M{"$currentDate": bson.M{plan.SoftDelete.ColumnName: true}},
+			Projection: projection,
+		}, nil
+	}
+
 	return &MongoQuery{
 		Collection: plan.RootModel.Table,
 		Operation:  "delete",
 		Filter:     filter,
+		Projection: projection,
 	}, nil
 }
@@ -1,7 +1,11 @@
 package mongodb
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"udv/internal/config"
 	"udv/internal/dsl"
@@ -9,6 +13,8 @@ import (
 	"udv/internal/schema"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func setupMongoDBTestRegistry() *schema.Registry {
@@ -26,6 +32,9 @@ func setupMongoDBTestRegistry() *schema.Registry {
 					{Name: "active", Type: "boolean", Nullable: true},
 					{Name: "created_at", Type: "timestamp", Nullable: false},
 				},
+				Relations: []config.Relation{
+					{Name: "orders", Type: "one_to_many", TargetModel: "orders", ForeignKey: "_id", ReferenceKey: "user_id"},
+				},
 			},
 			{
 				Name:       "orders",
@@ -37,6 +46,9 @@ func setupMongoDBTestRegistry() *schema.Registry {
 					{Name: "status", Type: "string", Nullable: false},
 					{Name: "amount", Type: "decimal", Nullable: false},
 				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "_id"},
+				},
 			},
 		},
 	}
@@ -153,16 +165,15 @@ func TestBuildQuery_WithPagination(t *testing.T) {
 	}
 }
 
-func TestBuildQuery_Insert(t *testing.T) {
+func TestBuildQuery_FindSortFieldNames(t *testing.T) {
 	reg := setupMongoDBTestRegistry()
 	queryPlanner := planner.NewPlanner(reg)
 
 	dslQuery := &dsl.Query{
-		Operation: dsl.OpCreate,
-		Model:     "users",
-		Data: map[string]interface{}{
-			"name":  "John",
-			"email": "john@example.com",
+		Model: "users",
+		Sort: []dsl.Sort{
+			{Field: "age", Direction: dsl.SortDesc},
+			{Field: "name", Direction: dsl.SortAsc},
 		},
 	}
 
@@ -174,87 +185,219 @@ func TestBuildQuery_Insert(t *testing.T) {
 	builder := NewQueryBuilder()
 	query, _, err := builder.BuildQuery(plan)
 	if err != nil {
-		t.Errorf("BuildQuery error: %v", err)
+		t.Fatalf("BuildQuery error: %v", err)
 	}
 
-	mongoQuery, ok := query.(*MongoQuery)
+	// Sort keys are plain field names, not prefixed with the SQL table alias
+	expected := bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}}
+	opts, ok := query.(*MongoQuery).Options.(*options.FindOptions)
 	if !ok {
-		t.Fatalf("Expected MongoQuery, got %T", query)
+		t.Fatalf("Expected *options.FindOptions, got %T", query.(*MongoQuery).Options)
 	}
+	if !reflect.DeepEqual(opts.Sort, expected) {
+		t.Errorf("Expected sort %v, got %v", expected, opts.Sort)
+	}
+}
 
-	if mongoQuery.Operation != "insert" {
-		t.Errorf("Expected operation 'insert', got '%s'", mongoQuery.Operation)
+func TestBuildQuery_CountGroupByStatus(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:      "orders",
+		GroupBy:    []string{"status"},
+		Aggregates: []dsl.Aggregate{{Function: dsl.AggCount, Alias: "order_count"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
 	}
 
-	doc, ok := mongoQuery.Document.(bson.M)
-	if !ok {
-		t.Errorf("Expected bson.M document, got %T", mongoQuery.Document)
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
 	}
+	mongoQuery := query.(*MongoQuery)
 
-	if len(doc) == 0 {
-		t.Error("Document should not be empty")
+	want := []bson.M{
+		{"$group": bson.M{
+			"_id":         bson.D{{Key: "status", Value: "$status"}},
+			"order_count": bson.M{"$sum": 1},
+		}},
+		{"$project": bson.M{"_id": 0, "status": "$_id.status", "order_count": 1}},
+		{"$limit": int64(100)},
+	}
+	if mongoQuery.Operation != "aggregate" || mongoQuery.Collection != "orders" {
+		t.Errorf("Expected aggregate on orders, got %s on %s", mongoQuery.Operation, mongoQuery.Collection)
+	}
+	if !reflect.DeepEqual(mongoQuery.Pipeline, want) {
+		t.Errorf("Expected pipeline %v, got %v", want, mongoQuery.Pipeline)
 	}
 }
 
-func TestBuildQuery_Update(t *testing.T) {
-	reg := setupMongoDBTestRegistry()
-	queryPlanner := planner.NewPlanner(reg)
+func TestBuildQuery_CountDistinct(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
 
-	dslQuery := &dsl.Query{
-		Operation: dsl.OpUpdate,
-		Model:     "users",
-		Filters: &dsl.ComparisonFilter{
-			Field: "_id",
-			Op:    dsl.OpEqual,
-			Value: "user123",
-		},
-		Data: map[string]interface{}{
-			"email": "newemail@example.com",
-		},
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:      "orders",
+		GroupBy:    []string{"status"},
+		Aggregates: []dsl.Aggregate{{Function: dsl.AggCountDistinct, Field: "user_id", Alias: "buyers"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
 	}
 
-	plan, err := queryPlanner.PlanQuery(dslQuery)
+	want := []bson.M{
+		{"$group": bson.M{
+			"_id":    bson.D{{Key: "status", Value: "$status"}},
+			"buyers": bson.M{"$addToSet": "$user_id"},
+		}},
+		{"$project": bson.M{"_id": 0, "status": "$_id.status", "buyers": bson.M{"$size": bson.M{"$filter": bson.M{
+			"input": "$buyers",
+			"cond":  bson.M{"$ne": bson.A{"$$this", nil}},
+		}}}}},
+		{"$limit": int64(100)},
+	}
+	if got := query.(*MongoQuery).Pipeline; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected pipeline %v, got %v", want, got)
+	}
+}
+
+func TestBuildQuery_AggregateRatio(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "orders",
+		GroupBy: []string{"status"},
+		Aggregates: []dsl.Aggregate{
+			{Function: dsl.AggSum, Field: "amount", Alias: "revenue"},
+			{Function: dsl.AggCountDistinct, Field: "user_id", Alias: "buyers"},
+			{Alias: "per_buyer_pct", Expr: "revenue / buyers * 100"},
+		},
+	})
 	if err != nil {
 		t.Fatalf("PlanQuery error: %v", err)
 	}
-
-	builder := NewQueryBuilder()
-	query, _, err := builder.BuildQuery(plan)
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
 	if err != nil {
-		t.Errorf("BuildQuery error: %v", err)
+		t.Fatalf("BuildQuery error: %v", err)
 	}
 
-	mongoQuery, ok := query.(*MongoQuery)
-	if !ok {
-		t.Fatalf("Expected MongoQuery, got %T", query)
+	buyers := bson.M{"$size": bson.M{"$filter": bson.M{
+		"input": "$buyers",
+		"cond":  bson.M{"$ne": bson.A{"$$this", nil}},
+	}}}
+	want := []bson.M{
+		{"$group": bson.M{
+			"_id":     bson.D{{Key: "status", Value: "$status"}},
+			"revenue": bson.M{"$sum": "$amount"},
+			"buyers":  bson.M{"$addToSet": "$user_id"},
+		}},
+		{"$project": bson.M{
+			"_id":     0,
+			"status":  "$_id.status",
+			"revenue": 1,
+			"buyers":  buyers,
+			"per_buyer_pct": bson.M{"$multiply": bson.A{
+				bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{buyers, 0}},
+					nil,
+					bson.M{"$divide": bson.A{"$revenue", buyers}},
+				}},
+				float64(100),
+			}},
+		}},
+		{"$limit": int64(100)},
+	}
+	if got := query.(*MongoQuery).Pipeline; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected pipeline %v, got %v", want, got)
 	}
+}
 
-	if mongoQuery.Operation != "update" {
-		t.Errorf("Expected operation 'update', got '%s'", mongoQuery.Operation)
+func TestBuildQuery_GroupByTime(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+	count := []dsl.Aggregate{{Function: dsl.AggCount, Alias: "signups"}}
+
+	tests := []struct {
+		name   string
+		bucket dsl.TimeBucket
+		trunc  bson.M
+	}{
+		{"daily", dsl.TimeBucket{Field: "created_at", Granularity: "day", Alias: "day"}, bson.M{"date": "$created_at", "unit": "day"}},
+		{"weekly", dsl.TimeBucket{Field: "created_at", Granularity: "week", Alias: "week"}, bson.M{"date": "$created_at", "unit": "week", "startOfWeek": "monday"}},
+		{"monthly", dsl.TimeBucket{Field: "created_at", Granularity: "month", Alias: "month"}, bson.M{"date": "$created_at", "unit": "month"}},
 	}
 
-	updateDoc, ok := mongoQuery.Update.(bson.M)
-	if !ok {
-		t.Errorf("Expected bson.M update, got %T", mongoQuery.Update)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{
+				Model:       "users",
+				GroupByTime: []dsl.TimeBucket{tt.bucket},
+				Aggregates:  count,
+				Sort:        []dsl.Sort{{Field: tt.bucket.Alias}},
+			})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+
+			alias := tt.bucket.Alias
+			want := []bson.M{
+				{"$group": bson.M{
+					"_id":     bson.D{{Key: alias, Value: bson.M{"$dateTrunc": tt.trunc}}},
+					"signups": bson.M{"$sum": 1},
+				}},
+				{"$project": bson.M{"_id": 0, alias: "$_id." + alias, "signups": 1}},
+				{"$sort": bson.D{{Key: alias, Value: 1}}},
+				{"$limit": int64(100)},
+			}
+			if got := query.(*MongoQuery).Pipeline; !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected pipeline %v, got %v", want, got)
+			}
+		})
 	}
+}
 
-	if updateDoc["$set"] == nil {
-		t.Error("Update should contain $set operator")
+func TestBuildQuery_FieldAliasesUnsupported(t *testing.T) {
+	plan, err := planner.NewPlanner(setupMongoDBTestRegistry()).PlanQuery(&dsl.Query{
+		Model:        "users",
+		Fields:       []string{"name"},
+		FieldAliases: map[string]string{"name": "display_name"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil {
+		t.Error("Expected error for field aliases on MongoDB")
 	}
 }
 
-func TestBuildQuery_Delete(t *testing.T) {
+func TestBuildQuery_SortAfterGroup(t *testing.T) {
 	reg := setupMongoDBTestRegistry()
 	queryPlanner := planner.NewPlanner(reg)
 
 	dslQuery := &dsl.Query{
-		Operation: dsl.OpDelete,
-		Model:     "users",
+		Model:   "orders",
+		GroupBy: []string{"status"},
+		Aggregates: []dsl.Aggregate{
+			{Function: dsl.AggCount, Field: "", Alias: "order_count"},
+			{Function: dsl.AggSum, Field: "amount", Alias: "total"},
+		},
 		Filters: &dsl.ComparisonFilter{
-			Field: "_id",
-			Op:    dsl.OpEqual,
-			Value: "user123",
+			Field: "amount",
+			Op:    dsl.OpGT,
+			Value: 10,
 		},
+		Sort: []dsl.Sort{
+			{Field: "total", Direction: dsl.SortDesc},
+			{Field: "status", Direction: dsl.SortAsc},
+		},
+		Pagination: &dsl.Pagination{Limit: 5, Offset: 10},
 	}
 
 	plan, err := queryPlanner.PlanQuery(dslQuery)
@@ -265,136 +408,1999 @@ func TestBuildQuery_Delete(t *testing.T) {
 	builder := NewQueryBuilder()
 	query, _, err := builder.BuildQuery(plan)
 	if err != nil {
-		t.Errorf("BuildQuery error: %v", err)
+		t.Fatalf("BuildQuery error: %v", err)
 	}
 
-	mongoQuery, ok := query.(*MongoQuery)
+	mongoQuery := query.(*MongoQuery)
+	if mongoQuery.Operation != "aggregate" {
+		t.Fatalf("Expected operation 'aggregate', got '%s'", mongoQuery.Operation)
+	}
+
+	pipeline, ok := mongoQuery.Pipeline.([]bson.M)
 	if !ok {
-		t.Fatalf("Expected MongoQuery, got %T", query)
+		t.Fatalf("Expected []bson.M pipeline, got %T", mongoQuery.Pipeline)
 	}
 
-	if mongoQuery.Operation != "delete" {
-		t.Errorf("Expected operation 'delete', got '%s'", mongoQuery.Operation)
+	// $match -> $group -> $project -> $sort -> $skip -> $limit
+	wantStages := []string{"$match", "$group", "$project", "$sort", "$skip", "$limit"}
+	if len(pipeline) != len(wantStages) {
+		t.Fatalf("Expected %d stages, got %d: %v", len(wantStages), len(pipeline), pipeline)
+	}
+	for i, stage := range wantStages {
+		if _, ok := pipeline[i][stage]; !ok {
+			t.Errorf("Stage %d: expected %s, got %v", i, stage, pipeline[i])
+		}
 	}
 
-	if mongoQuery.Filter == nil {
-		t.Error("Filter should not be nil for delete operation")
+	group := pipeline[1]["$group"].(bson.M)
+	if !reflect.DeepEqual(group["_id"], bson.D{{Key: "status", Value: "$status"}}) {
+		t.Errorf("Unexpected group _id: %v", group["_id"])
+	}
+	if !reflect.DeepEqual(group["order_count"], bson.M{"$sum": 1}) {
+		t.Errorf("Unexpected order_count accumulator: %v", group["order_count"])
+	}
+	if !reflect.DeepEqual(group["total"], bson.M{"$sum": "$amount"}) {
+		t.Errorf("Unexpected total accumulator: %v", group["total"])
+	}
+
+	project := pipeline[2]["$project"].(bson.M)
+	if project["status"] != "$_id.status" {
+		t.Errorf("Expected status projected from _id, got %v", project["status"])
+	}
+
+	// Multi-field order and direction are preserved, with the aggregate referenced by alias
+	expectedSort := bson.D{{Key: "total", Value: -1}, {Key: "status", Value: 1}}
+	if !reflect.DeepEqual(pipeline[3]["$sort"], expectedSort) {
+		t.Errorf("Expected sort %v, got %v", expectedSort, pipeline[3]["$sort"])
 	}
 }
 
-func TestConvertOperator(t *testing.T) {
+func TestBuildQuery_SoftDelete(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid", Nullable: false},
+					{Name: "email", Type: "string", Nullable: false},
+					{Name: "deleted_at", Type: "timestamp", Nullable: true},
+				},
+				SoftDeleteField: "deleted_at",
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
 	builder := NewQueryBuilder()
 
 	tests := []struct {
-		op          string
-		value       interface{}
-		expectedOp  string
-		expectedVal interface{}
-		shouldError bool
-		checkFn     func(expected, actual interface{}) bool
+		name       string
+		query      *dsl.Query
+		wantOp     string
+		wantFilter bson.M
+		wantUpdate bson.M
 	}{
-		{"=", "test", "$eq", "test", false, func(e, a interface{}) bool { return e == a }},
-		{"!=", 42, "$ne", 42, false, func(e, a interface{}) bool { return e == a }},
-		{">", 100, "$gt", 100, false, func(e, a interface{}) bool { return e == a }},
-		{">=", 50, "$gte", 50, false, func(e, a interface{}) bool { return e == a }},
-		{"<", 10, "$lt", 10, false, func(e, a interface{}) bool { return e == a }},
-		{"<=", 5, "$lte", 5, false, func(e, a interface{}) bool { return e == a }},
-		{"in", []string{"a", "b"}, "$in", []string{"a", "b"}, false, sliceEqual},
-		{"not_in", []int{1, 2}, "$nin", []int{1, 2}, false, sliceEqual},
-		{"like", "pattern", "$regex", "pattern", false, func(e, a interface{}) bool { return e == a }},
-		{"is_null", nil, "$exists", false, false, func(e, a interface{}) bool { return e == a }},
-		{"not_null", nil, "$exists", true, false, func(e, a interface{}) bool { return e == a }},
-		{"unknown_op", "val", "", nil, true, nil},
+		{
+			name:       "find excludes deleted",
+			query:      &dsl.Query{Model: "users"},
+			wantOp:     "find",
+			wantFilter: bson.M{"deleted_at": nil},
+		},
+		{
+			name:       "find including deleted",
+			query:      &dsl.Query{Model: "users", IncludeDeleted: true},
+			wantOp:     "find",
+			wantFilter: bson.M{},
+		},
+		{
+			name:       "find with filter",
+			query:      &dsl.Query{Model: "users", Filters: &dsl.ComparisonFilter{Field: "email", Op: dsl.OpEqual, Value: "a@b.c"}},
+			wantOp:     "find",
+			wantFilter: bson.M{"$and": bson.A{bson.M{"email": "a@b.c"}, bson.M{"deleted_at": nil}}},
+		},
+		{
+			name:       "delete becomes update",
+			query:      &dsl.Query{Operation: dsl.OpDelete, Model: "users", ID: "user123"},
+			wantOp:     "update",
+			wantFilter: bson.M{"$and": bson.A{bson.M{"_id": "user123"}, bson.M{"deleted_at": nil}}},
+			wantUpdate: bson.M{"$currentDate": bson.M{"deleted_at": true}},
+		},
 	}
 
-	for _, test := range tests {
-		t.Run(test.op, func(t *testing.T) {
-			mongoOp, mongoVal, err := builder.convertOperator(test.op, test.value)
-
-			if test.shouldError && err == nil {
-				t.Errorf("Expected error for operator %q", test.op)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
 			}
-
-			if !test.shouldError && err != nil {
-				t.Errorf("Unexpected error: %v", err)
+			query, _, err := builder.BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
 			}
+			mq := query.(*MongoQuery)
 
-			if !test.shouldError {
-				if mongoOp != test.expectedOp {
-					t.Errorf("Expected operator %q, got %q", test.expectedOp, mongoOp)
-				}
-				if !test.checkFn(test.expectedVal, mongoVal) {
-					t.Errorf("Expected value %v, got %v", test.expectedVal, mongoVal)
-				}
+			if mq.Operation != tt.wantOp {
+				t.Errorf("Expected operation %s, got %s", tt.wantOp, mq.Operation)
+			}
+			if !reflect.DeepEqual(mq.Filter, tt.wantFilter) {
+				t.Errorf("Expected filter %v, got %v", tt.wantFilter, mq.Filter)
+			}
+			if tt.wantUpdate != nil && !reflect.DeepEqual(mq.Update, tt.wantUpdate) {
+				t.Errorf("Expected update %v, got %v", tt.wantUpdate, mq.Update)
 			}
 		})
 	}
 }
 
-func sliceEqual(expected, actual interface{}) bool {
-	switch e := expected.(type) {
-	case []string:
-		a, ok := actual.([]string)
-		if !ok {
-			return false
-		}
-		if len(e) != len(a) {
-			return false
-		}
-		for i, v := range e {
-			if v != a[i] {
-				return false
-			}
-		}
-		return true
-	case []int:
-		a, ok := actual.([]int)
-		if !ok {
-			return false
-		}
-		if len(e) != len(a) {
-			return false
-		}
-		for i, v := range e {
-			if v != a[i] {
-				return false
-			}
-		}
-		return true
+func TestBuildQuery_DeleteByID(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+	builder := NewQueryBuilder()
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpDelete, Model: "users", ID: "user123"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	mq := query.(*MongoQuery)
+	if !reflect.DeepEqual(mq.Filter, bson.M{"_id": "user123"}) {
+		t.Errorf("Expected filter on _id, got %v", mq.Filter)
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpDelete, Model: "users"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	if _, _, err := builder.BuildQuery(plan); err == nil {
+		t.Errorf("Expected error for delete without id or filters")
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpDelete, Model: "users", AllowFullTable: true})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err = builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	if mq := query.(*MongoQuery); len(mq.Filter.(bson.M)) != 0 {
+		t.Errorf("Expected empty filter for full-collection delete, got %v", mq.Filter)
 	}
-	return false
 }
 
-func TestBuildQuery_UnsupportedOperation(t *testing.T) {
-	builder := NewQueryBuilder()
+func TestBuildQuery_DeleteReturning(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
 
-	// Create a plan with unsupported operation
-	plan := &planner.QueryPlan{
-		Operation: "unsupported",
-		RootModel: &planner.ModelRef{
-			Table: "users",
-		},
+	tests := []struct {
+		name      string
+		returning []string
+		expected  interface{}
+	}{
+		{"default reads nothing back", nil, nil},
+		{"explicit fields", []string{"_id"}, bson.M{"_id": 1}},
 	}
 
-	_, _, err := builder.BuildQuery(plan)
-	if err == nil {
-		t.Error("Expected error for unsupported operation")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpDelete, Model: "users", ID: "user123", Returning: tt.returning})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+
+			mongoQuery := query.(*MongoQuery)
+			if !reflect.DeepEqual(mongoQuery.Projection, tt.expected) {
+				t.Errorf("Projection = %#v, want %#v", mongoQuery.Projection, tt.expected)
+			}
+		})
 	}
 }
 
-func TestMongoQuery_TypeAssertion(t *testing.T) {
-	query := &MongoQuery{
-		Collection: "users",
-		Operation:  "find",
-		Filter:     bson.M{"name": "John"},
-		Options:    nil,
+func TestBuildQuery_NestedLogicalFilter(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+	builder := NewQueryBuilder()
+
+	email := &dsl.ComparisonFilter{Field: "email", Op: dsl.OpEqual, Value: "a@b.c"}
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "users",
+		Filters: &dsl.LogicalFilter{Or: []dsl.FilterExpr{email, &dsl.LogicalFilter{Not: email}}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
 	}
 
-	if query.Collection != "users" {
-		t.Errorf("Collection mismatch: expected 'users', got %q", query.Collection)
+	want := bson.M{"$or": []bson.M{
+		{"email": "a@b.c"},
+		{"$nor": []bson.M{{"email": "a@b.c"}}},
+	}}
+	if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected filter %v, got %v", want, got)
 	}
+}
 
-	if query.Operation != "find" {
-		t.Errorf("Operation mismatch: expected 'find', got %q", query.Operation)
+func TestBuildQuery_TextSearch(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "articles",
+				Table:      "articles",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid", Nullable: false},
+					{Name: "title", Type: "string", Nullable: false},
+					{Name: "body", Type: "string", Nullable: false},
+				},
+				SearchFields: []string{"title", "body"},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+	builder := NewQueryBuilder()
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "articles",
+		Filters: &dsl.ComparisonFilter{Op: dsl.OpSearch, Value: "full text"},
+		Sort:    []dsl.Sort{{Field: dsl.SearchScoreField, Direction: dsl.SortDesc}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	mq := query.(*MongoQuery)
+
+	wantFilter := bson.M{"$text": bson.M{"$search": "full text"}}
+	if !reflect.DeepEqual(mq.Filter, wantFilter) {
+		t.Errorf("Expected filter %v, got %v", wantFilter, mq.Filter)
+	}
+
+	opts := mq.Options.(*options.FindOptions)
+	score := bson.M{"$meta": "textScore"}
+	if !reflect.DeepEqual(opts.Sort, bson.D{{Key: "_score", Value: score}}) {
+		t.Errorf("Expected sort by text score, got %v", opts.Sort)
+	}
+	if !reflect.DeepEqual(opts.Projection, bson.M{"_score": score}) {
+		t.Errorf("Expected text score projection, got %v", opts.Projection)
+	}
+}
+
+func TestBuildQuery_UpdateOperators(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+	builder := NewQueryBuilder()
+
+	tests := []struct {
+		name  string
+		query *dsl.Query
+		want  bson.M
+	}{
+		{
+			name:  "null stored by default",
+			query: &dsl.Query{Operation: dsl.OpUpdate, Model: "users", ID: "user123", Data: map[string]interface{}{"name": nil}},
+			want:  bson.M{"$set": bson.M{"name": nil}},
+		},
+		{
+			name:  "null unset",
+			query: &dsl.Query{Operation: dsl.OpUpdate, Model: "users", ID: "user123", Data: map[string]interface{}{"name": nil, "email": "a@b.c"}, UnsetNulls: true},
+			want:  bson.M{"$set": bson.M{"email": "a@b.c"}, "$unset": bson.M{"name": ""}},
+		},
+		{
+			name:  "increment and push",
+			query: &dsl.Query{Operation: dsl.OpUpdate, Model: "users", ID: "user123", Increment: map[string]interface{}{"age": 1.0}, Push: map[string]interface{}{"tags": "vip"}},
+			want:  bson.M{"$inc": bson.M{"age": 1.0}, "$push": bson.M{"tags": "vip"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := builder.BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Update; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected update %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_JSONUpdate(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "accounts",
+				Table:      "accounts",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid", Nullable: false},
+					{Name: "settings", Type: "json", Nullable: true},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+
+	plan, err := planner.NewPlanner(reg).PlanQuery(&dsl.Query{
+		Operation:  dsl.OpUpdate,
+		Model:      "accounts",
+		ID:         "acc1",
+		JSONSet:    map[string]interface{}{"settings.theme": "dark"},
+		JSONRemove: []string{"settings.legacy.flag"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	want := bson.M{"$set": bson.M{"settings.theme": "dark"}, "$unset": bson.M{"settings.legacy.flag": ""}}
+	if got := query.(*MongoQuery).Update; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected update %v, got %v", want, got)
+	}
+}
+
+func TestBuildQuery_AutoTimestamps(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid", Nullable: false},
+					{Name: "email", Type: "string", Nullable: false},
+					{Name: "created_at", Type: "timestamp", Nullable: false},
+					{Name: "updated_at", Type: "timestamp", Nullable: false},
+				},
+				CreatedAtField: "created_at",
+				UpdatedAtField: "updated_at",
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+	builder := NewQueryBuilder()
+
+	// insert
+	data := map[string]interface{}{"email": "a@b.c"}
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpCreate, Model: "users", Data: data})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	doc := query.(*MongoQuery).Document.(bson.M)
+	for _, col := range []string{"created_at", "updated_at"} {
+		if _, ok := doc[col].(time.Time); !ok {
+			t.Errorf("Expected %s to be set to a time, got %v", col, doc[col])
+		}
+	}
+	if _, ok := data["created_at"]; ok {
+		t.Errorf("Insert should not modify the request data")
+	}
+
+	// update
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpUpdate, Model: "users", ID: "user123", Data: map[string]interface{}{"email": "x@y.z"}})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err = builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	update := query.(*MongoQuery).Update.(bson.M)
+	if !reflect.DeepEqual(update["$currentDate"], bson.M{"updated_at": true}) {
+		t.Errorf("Expected $currentDate on updated_at, got %v", update["$currentDate"])
+	}
+}
+
+func TestBuildQuery_HiddenFieldsExcluded(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid", Nullable: false},
+					{Name: "email", Type: "string", Nullable: false},
+					{Name: "password_hash", Type: "string", Nullable: false},
+				},
+				HiddenFields: []string{"password_hash"},
+			},
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid", Nullable: false},
+					{Name: "user_id", Type: "uuid", Nullable: false},
+				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "_id"},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+	builder := NewQueryBuilder()
+	excluded := bson.M{"password_hash": 0}
+
+	// find
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "users"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	opts := query.(*MongoQuery).Options.(*options.FindOptions)
+	if !reflect.DeepEqual(opts.Projection, excluded) {
+		t.Errorf("Expected projection %v, got %v", excluded, opts.Projection)
+	}
+
+	// $lookup into a model with hidden fields
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "orders",
+		Include: []dsl.Include{{Relation: "user", Unwind: true}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err = builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	pipeline := query.(*MongoQuery).Pipeline.([]bson.M)
+	var lookup bson.M
+	for _, stage := range pipeline {
+		if l, ok := stage["$lookup"].(bson.M); ok {
+			lookup = l
+		}
+	}
+	if lookup == nil {
+		t.Fatalf("Expected $lookup stage, got %v", pipeline)
+	}
+	nested, _ := lookup["pipeline"].([]bson.M)
+	if len(nested) == 0 || !reflect.DeepEqual(nested[0], bson.M{"$project": excluded}) {
+		t.Errorf("Expected nested $project excluding hidden fields, got %v", lookup["pipeline"])
+	}
+}
+
+func TestBuildQuery_Insert(t *testing.T) {
+	reg := setupMongoDBTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	dslQuery := &dsl.Query{
+		Operation: dsl.OpCreate,
+		Model:     "users",
+		Data: map[string]interface{}{
+			"name":  "John",
+			"email": "john@example.com",
+		},
+	}
+
+	plan, err := queryPlanner.PlanQuery(dslQuery)
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Errorf("BuildQuery error: %v", err)
+	}
+
+	mongoQuery, ok := query.(*MongoQuery)
+	if !ok {
+		t.Fatalf("Expected MongoQuery, got %T", query)
+	}
+
+	if mongoQuery.Operation != "insert" {
+		t.Errorf("Expected operation 'insert', got '%s'", mongoQuery.Operation)
+	}
+
+	doc, ok := mongoQuery.Document.(bson.M)
+	if !ok {
+		t.Errorf("Expected bson.M document, got %T", mongoQuery.Document)
+	}
+
+	if len(doc) == 0 {
+		t.Error("Document should not be empty")
+	}
+}
+
+func TestBuildQuery_CoercesTypedStrings(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+	data := map[string]interface{}{"name": "John", "age": "10", "active": "true", "created_at": "2024-01-02"}
+
+	insertPlan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpCreate, Model: "users", Data: data})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(insertPlan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	doc := query.(*MongoQuery).Document.(bson.M)
+	if doc["age"] != int64(10) || doc["active"] != true || doc["name"] != "John" {
+		t.Errorf("Expected typed strings stored as their field types, got %#v", doc)
+	}
+	if created, ok := doc["created_at"].(time.Time); !ok || !created.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected created_at stored as a time, got %#v", doc["created_at"])
+	}
+
+	updatePlan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpUpdate, Model: "users", ID: "u1", Data: map[string]interface{}{"age": "11"}})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err = NewQueryBuilder().BuildQuery(updatePlan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	set := query.(*MongoQuery).Update.(bson.M)["$set"].(bson.M)
+	if set["age"] != int64(11) {
+		t.Errorf("Expected $set age int64(11), got %#v", set["age"])
+	}
+}
+
+func TestBuildQuery_KeysetCursorOnDate(t *testing.T) {
+	plan, err := planner.NewPlanner(setupMongoDBTestRegistry()).PlanQuery(&dsl.Query{
+		Model: "users",
+		Sort:  []dsl.Sort{{Field: "created_at", Direction: dsl.SortDesc}, {Field: "_id", Direction: dsl.SortDesc}},
+		Pagination: &dsl.Pagination{Limit: 10, Cursor: "token", After: &dsl.Keyset{
+			Field: "created_at", Direction: dsl.SortDesc, Value: "2024-01-02T03:04:05Z", Key: "u9",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	// The cursor's string must compare as a BSON date, or the next page matches nothing
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	filter := fmt.Sprintf("%#v", query.(*MongoQuery).Filter)
+	if strings.Contains(filter, `"2024-01-02T03:04:05Z"`) || !strings.Contains(filter, fmt.Sprintf("%#v", want)) {
+		t.Errorf("expected the keyset value as a time, got %s", filter)
+	}
+}
+
+func TestBuildQuery_NegativePagination(t *testing.T) {
+	plan, err := planner.NewPlanner(setupMongoDBTestRegistry()).PlanQuery(&dsl.Query{Model: "users"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	plan.Pagination.Offset = -5
+
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil {
+		t.Errorf("BuildQuery() with a negative offset should error")
+	}
+}
+
+func TestBuildQuery_InsertReturning(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+	data := map[string]interface{}{"name": "John", "email": "john@example.com"}
+
+	tests := []struct {
+		name      string
+		returning []string
+		expected  interface{}
+	}{
+		{"default returns whole document", nil, nil},
+		{"explicit fields", []string{"name"}, bson.M{"_id": 0, "name": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpCreate, Model: "users", Data: data, Returning: tt.returning})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+
+			mongoQuery := query.(*MongoQuery)
+			if !reflect.DeepEqual(mongoQuery.Projection, tt.expected) {
+				t.Errorf("Projection = %#v, want %#v", mongoQuery.Projection, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_Update(t *testing.T) {
+	reg := setupMongoDBTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	dslQuery := &dsl.Query{
+		Operation: dsl.OpUpdate,
+		Model:     "users",
+		Filters: &dsl.ComparisonFilter{
+			Field: "_id",
+			Op:    dsl.OpEqual,
+			Value: "user123",
+		},
+		Data: map[string]interface{}{
+			"email": "newemail@example.com",
+		},
+	}
+
+	plan, err := queryPlanner.PlanQuery(dslQuery)
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Errorf("BuildQuery error: %v", err)
+	}
+
+	mongoQuery, ok := query.(*MongoQuery)
+	if !ok {
+		t.Fatalf("Expected MongoQuery, got %T", query)
+	}
+
+	if mongoQuery.Operation != "update" {
+		t.Errorf("Expected operation 'update', got '%s'", mongoQuery.Operation)
+	}
+
+	updateDoc, ok := mongoQuery.Update.(bson.M)
+	if !ok {
+		t.Errorf("Expected bson.M update, got %T", mongoQuery.Update)
+	}
+
+	if updateDoc["$set"] == nil {
+		t.Error("Update should contain $set operator")
+	}
+}
+
+func TestBuildQuery_ValueExprs(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpCreate,
+		Model:     "users",
+		Data: map[string]interface{}{
+			"_id":        map[string]interface{}{"$expr": "gen_random_uuid()"},
+			"name":       "Ann",
+			"email":      map[string]interface{}{"$expr": "DEFAULT"},
+			"created_at": map[string]interface{}{"$expr": "current_date"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	doc := query.(*MongoQuery).Document.(bson.M)
+	if id, _ := doc["_id"].(string); len(id) != 36 {
+		t.Errorf("Expected a generated uuid, got %v", doc["_id"])
+	}
+	if _, ok := doc["email"]; ok {
+		t.Errorf("DEFAULT should leave the field out, got %v", doc["email"])
+	}
+	if day, ok := doc["created_at"].(time.Time); !ok || !day.Equal(day.Truncate(24*time.Hour)) {
+		t.Errorf("Expected midnight today, got %v", doc["created_at"])
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpUpdate,
+		Model:     "users",
+		ID:        "user123",
+		Data:      map[string]interface{}{"created_at": map[string]interface{}{"$expr": "now()"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err = NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	update := query.(*MongoQuery).Update.(bson.M)
+	if !reflect.DeepEqual(update["$currentDate"], bson.M{"created_at": true}) {
+		t.Errorf("Expected now() as $currentDate, got %v", update)
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpUpdate,
+		Model:     "users",
+		ID:        "user123",
+		Data:      map[string]interface{}{"email": map[string]interface{}{"$expr": "DEFAULT"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil {
+		t.Error("Expected error for DEFAULT in a MongoDB update")
+	}
+}
+
+func TestBuildQuery_Delete(t *testing.T) {
+	reg := setupMongoDBTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	dslQuery := &dsl.Query{
+		Operation: dsl.OpDelete,
+		Model:     "users",
+		Filters: &dsl.ComparisonFilter{
+			Field: "_id",
+			Op:    dsl.OpEqual,
+			Value: "user123",
+		},
+	}
+
+	plan, err := queryPlanner.PlanQuery(dslQuery)
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Errorf("BuildQuery error: %v", err)
+	}
+
+	mongoQuery, ok := query.(*MongoQuery)
+	if !ok {
+		t.Fatalf("Expected MongoQuery, got %T", query)
+	}
+
+	if mongoQuery.Operation != "delete" {
+		t.Errorf("Expected operation 'delete', got '%s'", mongoQuery.Operation)
+	}
+
+	if mongoQuery.Filter == nil {
+		t.Error("Filter should not be nil for delete operation")
+	}
+}
+
+func TestConvertOperator(t *testing.T) {
+	builder := NewQueryBuilder()
+
+	tests := []struct {
+		op          string
+		value       interface{}
+		expectedOp  string
+		expectedVal interface{}
+		shouldError bool
+		checkFn     func(expected, actual interface{}) bool
+	}{
+		{"=", "test", "$eq", "test", false, func(e, a interface{}) bool { return e == a }},
+		{"!=", 42, "$ne", 42, false, func(e, a interface{}) bool { return e == a }},
+		{">", 100, "$gt", 100, false, func(e, a interface{}) bool { return e == a }},
+		{">=", 50, "$gte", 50, false, func(e, a interface{}) bool { return e == a }},
+		{"<", 10, "$lt", 10, false, func(e, a interface{}) bool { return e == a }},
+		{"<=", 5, "$lte", 5, false, func(e, a interface{}) bool { return e == a }},
+		{"before", "2024-01-01", "$lt", "2024-01-01", false, func(e, a interface{}) bool { return e == a }},
+		{"after", "2024-01-01", "$gt", "2024-01-01", false, func(e, a interface{}) bool { return e == a }},
+		{"in", []string{"a", "b"}, "$in", []string{"a", "b"}, false, sliceEqual},
+		{"not_in", []int{1, 2}, "$nin", []int{1, 2}, false, sliceEqual},
+		{"like", "pattern", "$regex", "pattern", false, func(e, a interface{}) bool { return e == a }},
+		{"is_null", nil, "$exists", false, false, func(e, a interface{}) bool { return e == a }},
+		{"not_null", nil, "$exists", true, false, func(e, a interface{}) bool { return e == a }},
+		{"unknown_op", "val", "", nil, true, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.op, func(t *testing.T) {
+			mongoOp, mongoVal, err := builder.convertOperator(test.op, test.value)
+
+			if test.shouldError && err == nil {
+				t.Errorf("Expected error for operator %q", test.op)
+			}
+
+			if !test.shouldError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if !test.shouldError {
+				if mongoOp != test.expectedOp {
+					t.Errorf("Expected operator %q, got %q", test.expectedOp, mongoOp)
+				}
+				if !test.checkFn(test.expectedVal, mongoVal) {
+					t.Errorf("Expected value %v, got %v", test.expectedVal, mongoVal)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertOperator_InRequiresSlice(t *testing.T) {
+	builder := NewQueryBuilder()
+
+	for _, op := range []string{"in", "not_in"} {
+		if _, _, err := builder.convertOperator(op, "a"); err == nil {
+			t.Errorf("Expected error for %s with scalar value", op)
+		}
+		if _, _, err := builder.convertOperator(op, nil); err == nil {
+			t.Errorf("Expected error for %s with nil value", op)
+		}
+		if _, _, err := builder.convertOperator(op, []interface{}{"a", 1}); err != nil {
+			t.Errorf("Unexpected error for %s with slice value: %v", op, err)
+		}
+	}
+}
+
+func TestBuildQuery_Between(t *testing.T) {
+	reg := setupMongoDBTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	dslQuery := &dsl.Query{
+		Model: "orders",
+		Filters: &dsl.ComparisonFilter{
+			Field: "amount",
+			Op:    dsl.OpBetween,
+			Value: []interface{}{10, 100},
+		},
+	}
+
+	plan, err := queryPlanner.PlanQuery(dslQuery)
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	filter, ok := query.(*MongoQuery).Filter.(bson.M)
+	if !ok {
+		t.Fatalf("Expected bson.M filter, got %T", query.(*MongoQuery).Filter)
+	}
+
+	rangeFilter, ok := filter["amount"].(bson.M)
+	if !ok {
+		t.Fatalf("Expected range filter on amount, got %v", filter)
+	}
+	if rangeFilter["$gte"] != 10 || rangeFilter["$lte"] != 100 {
+		t.Errorf("Expected {$gte: 10, $lte: 100}, got %v", rangeFilter)
+	}
+}
+
+func TestBuildQuery_BetweenInvalidValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"scalar", 10},
+		{"one element", []interface{}{10}},
+		{"three elements", []interface{}{10, 20, 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := setupMongoDBTestRegistry()
+			queryPlanner := planner.NewPlanner(reg)
+
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{
+				Model: "orders",
+				Filters: &dsl.ComparisonFilter{
+					Field: "amount",
+					Op:    dsl.OpBetween,
+					Value: tt.value,
+				},
+			})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+
+			builder := NewQueryBuilder()
+			if _, _, err := builder.BuildQuery(plan); err == nil {
+				t.Error("Expected error for invalid between value")
+			}
+		})
+	}
+}
+
+func sliceEqual(expected, actual interface{}) bool {
+	switch e := expected.(type) {
+	case []string:
+		a, ok := actual.([]string)
+		if !ok {
+			return false
+		}
+		if len(e) != len(a) {
+			return false
+		}
+		for i, v := range e {
+			if v != a[i] {
+				return false
+			}
+		}
+		return true
+	case []int:
+		a, ok := actual.([]int)
+		if !ok {
+			return false
+		}
+		if len(e) != len(a) {
+			return false
+		}
+		for i, v := range e {
+			if v != a[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func TestBuildQuery_UnsupportedOperation(t *testing.T) {
+	builder := NewQueryBuilder()
+
+	// Create a plan with unsupported operation
+	plan := &planner.QueryPlan{
+		Operation: "unsupported",
+		RootModel: &planner.ModelRef{
+			Table: "users",
+		},
+	}
+
+	_, _, err := builder.BuildQuery(plan)
+	if err == nil {
+		t.Error("Expected error for unsupported operation")
+	}
+}
+
+func TestMongoQuery_TypeAssertion(t *testing.T) {
+	query := &MongoQuery{
+		Collection: "users",
+		Operation:  "find",
+		Filter:     bson.M{"name": "John"},
+		Options:    nil,
+	}
+
+	if query.Collection != "users" {
+		t.Errorf("Collection mismatch: expected 'users', got %q", query.Collection)
+	}
+
+	if query.Operation != "find" {
+		t.Errorf("Operation mismatch: expected 'find', got %q", query.Operation)
+	}
+}
+
+func TestBuildQuery_LockUnsupported(t *testing.T) {
+	reg := setupMongoDBTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQueryInTransaction(&dsl.Query{
+		Model: "users",
+		Lock:  &dsl.Lock{Mode: dsl.LockForUpdate},
+	})
+	if err != nil {
+		t.Fatalf("PlanQueryInTransaction error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	if _, _, err := builder.BuildQuery(plan); err == nil {
+		t.Error("Expected error for row locking on MongoDB")
+	}
+}
+
+func TestBuildQuery_Count(t *testing.T) {
+	reg := setupMongoDBTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpCount,
+		Model:     "users",
+		Filters: &dsl.ComparisonFilter{
+			Field: "name",
+			Op:    dsl.OpEqual,
+			Value: "John",
+		},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	mongoQuery, ok := query.(*MongoQuery)
+	if !ok {
+		t.Fatalf("Expected MongoQuery, got %T", query)
+	}
+	if mongoQuery.Operation != "count" {
+		t.Errorf("Expected operation 'count', got '%s'", mongoQuery.Operation)
+	}
+	filter, ok := mongoQuery.Filter.(bson.M)
+	if !ok || filter["name"] != "John" {
+		t.Errorf("Expected filter on name, got %v", mongoQuery.Filter)
+	}
+}
+
+func TestBuildQuery_IncludeLookup(t *testing.T) {
+	reg := setupMongoDBTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model: "orders",
+		Filters: &dsl.ComparisonFilter{
+			Field: "status",
+			Op:    dsl.OpEqual,
+			Value: "PAID",
+		},
+		Include: []dsl.Include{{
+			Relation: "user",
+			Unwind:   true,
+			Include:  []dsl.Include{{Relation: "orders"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	mongoQuery := query.(*MongoQuery)
+	if mongoQuery.Operation != "aggregate" {
+		t.Fatalf("Expected operation 'aggregate', got '%s'", mongoQuery.Operation)
+	}
+
+	pipeline, ok := mongoQuery.Pipeline.([]bson.M)
+	if !ok {
+		t.Fatalf("Expected []bson.M pipeline, got %T", mongoQuery.Pipeline)
+	}
+
+	// $match, $limit, $lookup, $unwind
+	if len(pipeline) != 4 {
+		t.Fatalf("Expected 4 stages, got %d: %v", len(pipeline), pipeline)
+	}
+	if _, ok := pipeline[0]["$match"]; !ok {
+		t.Errorf("First stage should be $match, got %v", pipeline[0])
+	}
+
+	lookup, ok := pipeline[2]["$lookup"].(bson.M)
+	if !ok {
+		t.Fatalf("Third stage should be $lookup, got %v", pipeline[2])
+	}
+	if lookup["from"] != "users" || lookup["localField"] != "user_id" || lookup["foreignField"] != "_id" || lookup["as"] != "user" {
+		t.Errorf("Unexpected $lookup: %v", lookup)
+	}
+
+	nested, ok := lookup["pipeline"].([]bson.M)
+	if !ok || len(nested) != 1 {
+		t.Fatalf("Expected nested $lookup pipeline, got %v", lookup["pipeline"])
+	}
+	if nestedLookup, ok := nested[0]["$lookup"].(bson.M); !ok || nestedLookup["as"] != "orders" {
+		t.Errorf("Unexpected nested $lookup: %v", nested[0])
+	}
+
+	unwind, ok := pipeline[3]["$unwind"].(bson.M)
+	if !ok || unwind["path"] != "$user" {
+		t.Errorf("Fourth stage should unwind $user, got %v", pipeline[3])
+	}
+}
+
+// matchesFilter evaluates the subset of MongoDB filter syntax the builder emits for
+// logical and ordered comparisons against an in-memory document
+func matchesFilter(t *testing.T, doc bson.M, filter bson.M) bool {
+	t.Helper()
+	for key, cond := range filter {
+		switch key {
+		case "$and", "$or", "$nor":
+			clauses, ok := cond.([]bson.M)
+			if !ok {
+				t.Fatalf("unexpected %s clause type %T", key, cond)
+			}
+			matched := 0
+			for _, clause := range clauses {
+				if matchesFilter(t, doc, clause) {
+					matched++
+				}
+			}
+			if (key == "$and" && matched != len(clauses)) || (key == "$or" && matched == 0) || (key == "$nor" && matched != 0) {
+				return false
+			}
+		default:
+			ops, ok := cond.(bson.M)
+			if !ok {
+				ops = bson.M{"$eq": cond}
+			}
+			for op, want := range ops {
+				if !compareValues(t, doc[key], op, want) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func compareValues(t *testing.T, got interface{}, op string, want interface{}) bool {
+	t.Helper()
+	switch op {
+	case "$eq":
+		return reflect.DeepEqual(got, want)
+	case "$ne":
+		return !reflect.DeepEqual(got, want)
+	}
+	g, gok := got.(int)
+	w, wok := want.(int)
+	if !gok || !wok {
+		t.Fatalf("ordered comparison %s needs int operands, got %T and %T", op, got, want)
+	}
+	switch op {
+	case "$gt":
+		return g > w
+	case "$gte":
+		return g >= w
+	case "$lt":
+		return g < w
+	case "$lte":
+		return g <= w
+	}
+	t.Fatalf("unsupported operator %s", op)
+	return false
+}
+
+func TestBuildQuery_NotFilterSemantics(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	adult := &dsl.ComparisonFilter{Field: "age", Op: dsl.OpGTE, Value: 18}
+	named := &dsl.ComparisonFilter{Field: "name", Op: dsl.OpEqual, Value: "ann"}
+	docs := []bson.M{
+		{"name": "ann", "age": 30},
+		{"name": "ann", "age": 10},
+		{"name": "bob", "age": 30},
+		{"name": "bob", "age": 10},
+	}
+
+	tests := []struct {
+		name   string
+		filter dsl.FilterExpr
+		want   func(doc bson.M) bool
+	}{
+		{
+			name:   "not comparison",
+			filter: &dsl.LogicalFilter{Not: adult},
+			want:   func(doc bson.M) bool { return doc["age"].(int) < 18 },
+		},
+		{
+			name:   "not and",
+			filter: &dsl.LogicalFilter{Not: &dsl.LogicalFilter{And: []dsl.FilterExpr{adult, named}}},
+			want:   func(doc bson.M) bool { return !(doc["age"].(int) >= 18 && doc["name"] == "ann") },
+		},
+		{
+			name:   "not or",
+			filter: &dsl.LogicalFilter{Not: &dsl.LogicalFilter{Or: []dsl.FilterExpr{adult, named}}},
+			want:   func(doc bson.M) bool { return !(doc["age"].(int) >= 18 || doc["name"] == "ann") },
+		},
+		{
+			name:   "double not",
+			filter: &dsl.LogicalFilter{Not: &dsl.LogicalFilter{Not: named}},
+			want:   func(doc bson.M) bool { return doc["name"] == "ann" },
+		},
+		{
+			name:   "and with not",
+			filter: &dsl.LogicalFilter{And: []dsl.FilterExpr{adult, &dsl.LogicalFilter{Not: named}}},
+			want:   func(doc bson.M) bool { return doc["age"].(int) >= 18 && doc["name"] != "ann" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "users", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			filter := query.(*MongoQuery).Filter.(bson.M)
+
+			for _, doc := range docs {
+				if got, want := matchesFilter(t, doc, filter), tt.want(doc); got != want {
+					t.Errorf("filter %v on %v matched = %v, want %v", filter, doc, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildQuery_NotSearchRejected(t *testing.T) {
+	plan := &planner.QueryPlan{
+		RootModel: &planner.ModelRef{Name: "users", Table: "users", Alias: "t0"},
+		Operation: "select",
+		Filters: &planner.LogicalFilterIR{
+			Op:    "NOT",
+			Nodes: []planner.FilterExpr{&planner.SearchFilterIR{Query: "ann"}},
+		},
+	}
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil {
+		t.Error("Expected error for negated search filter")
+	}
+}
+
+func TestBuildQuery_ElemMatch(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "carts",
+				Table:      "carts",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid", Nullable: false},
+					{Name: "items", Type: "json", Nullable: true},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	sku := &dsl.ComparisonFilter{Field: "sku", Op: dsl.OpEqual, Value: "A1"}
+	tests := []struct {
+		name       string
+		conditions []*dsl.ComparisonFilter
+		want       bson.M
+	}{
+		{
+			name:       "merged conditions",
+			conditions: []*dsl.ComparisonFilter{sku, {Field: "qty", Op: dsl.OpGTE, Value: 2}},
+			want:       bson.M{"items": bson.M{"$elemMatch": bson.M{"sku": "A1", "qty": bson.M{"$gte": 2}}}},
+		},
+		{
+			name:       "same key twice",
+			conditions: []*dsl.ComparisonFilter{{Field: "qty", Op: dsl.OpGT, Value: 1}, {Field: "qty", Op: dsl.OpLT, Value: 5}},
+			want: bson.M{"items": bson.M{"$elemMatch": bson.M{"$and": []bson.M{
+				{"qty": bson.M{"$gt": 1}},
+				{"qty": bson.M{"$lt": 5}},
+			}}}},
+		},
+		{
+			name:       "between",
+			conditions: []*dsl.ComparisonFilter{{Field: "qty", Op: dsl.OpBetween, Value: []interface{}{1, 3}}},
+			want:       bson.M{"items": bson.M{"$elemMatch": bson.M{"qty": bson.M{"$gte": 1, "$lte": 3}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{
+				Model:   "carts",
+				Filters: &dsl.ElemMatchFilter{Field: "items", ElemMatch: tt.conditions},
+			})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected filter %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	_, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "carts",
+		Filters: &dsl.ElemMatchFilter{Field: "items", ElemMatch: []*dsl.ComparisonFilter{{Field: "sku", Op: "~="}}},
+	})
+	if err == nil {
+		t.Error("Expected error for unknown operator inside elem_match")
+	}
+}
+
+func TestBuildQuery_Exists(t *testing.T) {
+	reg := setupMongoDBTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpExists,
+		Model:     "users",
+		Filters:   &dsl.ComparisonFilter{Field: "name", Op: dsl.OpEqual, Value: "John"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	mongoQuery, ok := query.(*MongoQuery)
+	if !ok {
+		t.Fatalf("Expected MongoQuery, got %T", query)
+	}
+	if mongoQuery.Operation != "exists" || mongoQuery.Collection != "users" {
+		t.Errorf("Expected exists on users, got %s on %s", mongoQuery.Operation, mongoQuery.Collection)
+	}
+	filter, ok := mongoQuery.Filter.(bson.M)
+	if !ok || filter["name"] != "John" {
+		t.Errorf("Expected filter on name, got %v", mongoQuery.Filter)
+	}
+}
+
+func TestBuildQuery_Distinct(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpDistinct,
+		Model:     "orders",
+		Fields:    []string{"status"},
+		Filters:   &dsl.ComparisonFilter{Field: "amount", Op: dsl.OpGT, Value: 100},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	mq := query.(*MongoQuery)
+	if mq.Operation != "distinct" || mq.Collection != "orders" || mq.Field != "status" {
+		t.Errorf("unexpected distinct query: %+v", mq)
+	}
+	if want := (bson.M{"amount": bson.M{"$gt": 100}}); !reflect.DeepEqual(mq.Filter, want) {
+		t.Errorf("Expected filter %v, got %v", want, mq.Filter)
+	}
+}
+
+func TestBuildQuery_CaseInsensitive(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid"},
+					{Name: "email", Type: "string", CaseInsensitive: true},
+				},
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	exact := primitive.Regex{Pattern: `^a\.b@x\.io$`, Options: "i"}
+	tests := []struct {
+		name   string
+		filter *dsl.ComparisonFilter
+		want   bson.M
+	}{
+		{"equal", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpEqual, Value: "a.b@x.io"}, bson.M{"email": exact}},
+		{"not equal", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpNotEqual, Value: "a.b@x.io"}, bson.M{"email": bson.M{"$not": exact}}},
+		{"in", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpIn, Value: []interface{}{"a.b@x.io"}}, bson.M{"email": bson.M{"$in": []interface{}{exact}}}},
+		{"not in", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpNotIn, Value: []interface{}{"a.b@x.io"}}, bson.M{"email": bson.M{"$nin": []interface{}{exact}}}},
+		{"starts with", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpStartsWith, Value: "a.b"}, bson.M{"email": primitive.Regex{Pattern: `^a\.b`, Options: "i"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpSelect, Model: "users", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_ColumnComparison(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "tasks",
+				Table:      "tasks",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid"},
+					{Name: "created_at", Type: "timestamp"},
+					{Name: "updated_at", Type: "timestamp"},
+					{Name: "owner", Type: "string", CaseInsensitive: true},
+					{Name: "reviewer", Type: "string"},
+				},
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name   string
+		filter *dsl.ComparisonFilter
+		want   bson.M
+	}{
+		{"greater than field", &dsl.ComparisonFilter{Field: "updated_at", Op: dsl.OpGT, ValueField: "created_at"}, bson.M{"$expr": bson.M{"$gt": bson.A{"$updated_at", "$created_at"}}}},
+		{"before field", &dsl.ComparisonFilter{Field: "created_at", Op: dsl.OpBefore, ValueField: "updated_at"}, bson.M{"$expr": bson.M{"$lt": bson.A{"$created_at", "$updated_at"}}}},
+		{"case-insensitive equality", &dsl.ComparisonFilter{Field: "owner", Op: dsl.OpEqual, ValueField: "reviewer"}, bson.M{"$expr": bson.M{"$eq": bson.A{bson.M{"$toLower": "$owner"}, bson.M{"$toLower": "$reviewer"}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpSelect, Model: "tasks", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_NullSafeEqual(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  bson.M
+	}{
+		{"value", 30, bson.M{"age": 30}},
+		{"null matches null and missing", nil, bson.M{"age": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &dsl.ComparisonFilter{Field: "age", Op: dsl.OpNullSafeEqual, Value: tt.value}
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "users", Filters: filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_Regex(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid"},
+					{Name: "sku", Type: "string"},
+					{Name: "email", Type: "string", CaseInsensitive: true},
+				},
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name   string
+		filter *dsl.ComparisonFilter
+		want   bson.M
+	}{
+		{"anchored regex", &dsl.ComparisonFilter{Field: "sku", Op: dsl.OpRegex, Value: `^AB-[0-9]{4}$`}, bson.M{"sku": bson.M{"$regex": primitive.Regex{Pattern: `^AB-[0-9]{4}$`}}}},
+		{"iregex", &dsl.ComparisonFilter{Field: "sku", Op: dsl.OpIRegex, Value: `^ab-`}, bson.M{"sku": bson.M{"$regex": primitive.Regex{Pattern: `^ab-`, Options: "i"}}}},
+		{"regex on case-insensitive field", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpRegex, Value: `@x\.io$`}, bson.M{"email": bson.M{"$regex": primitive.Regex{Pattern: `@x\.io$`, Options: "i"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpSelect, Model: "users", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_Subquery(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	tests := []struct {
+		name string
+		op   dsl.FilterOperator
+		want string
+	}{
+		{"in", dsl.OpIn, "$in"},
+		{"not in", dsl.OpNotIn, "$nin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &dsl.ComparisonFilter{Field: "user_id", Op: tt.op, Subquery: &dsl.Subquery{
+				Model:   "users",
+				Field:   "_id",
+				Filters: &dsl.ComparisonFilter{Field: "active", Op: dsl.OpEqual, Value: true},
+			}}
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "orders", Filters: filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+
+			want := bson.M{"user_id": bson.M{tt.want: &Subquery{Collection: "users", Field: "_id", Filter: bson.M{"active": true}}}}
+			if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, want) {
+				t.Errorf("filter = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_VersionedUpdate(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "string"},
+					{Name: "status", Type: "string"},
+					{Name: "version", Type: "integer"},
+				},
+				VersionField: "version",
+			},
+		},
+	})
+
+	plan, err := planner.NewPlanner(reg).PlanQuery(&dsl.Query{
+		Operation:       dsl.OpUpdate,
+		Model:           "orders",
+		ID:              "a1",
+		Data:            map[string]interface{}{"status": "PAID"},
+		ExpectedVersion: 3,
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	mq := query.(*MongoQuery)
+
+	wantFilter := bson.M{"$and": bson.A{bson.M{"_id": "a1"}, bson.M{"version": 3}}}
+	if !reflect.DeepEqual(mq.Filter, wantFilter) {
+		t.Errorf("filter = %v, want %v", mq.Filter, wantFilter)
+	}
+	update := mq.Update.(bson.M)
+	if !reflect.DeepEqual(update["$inc"], bson.M{"version": 1}) {
+		t.Errorf("$inc = %v, want version increment", update["$inc"])
+	}
+}
+
+func TestBuildQuery_Collation(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+	collation := &config.Collation{Locale: "de", Strength: 2}
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:     "users",
+		Sort:      []dsl.Sort{{Field: "name"}},
+		Collation: collation,
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	opts := query.(*MongoQuery).Options.(*options.FindOptions)
+	if opts.Collation == nil || opts.Collation.Locale != "de" || opts.Collation.Strength != 2 {
+		t.Errorf("Expected collation de/2, got %+v", opts.Collation)
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Model:      "orders",
+		GroupBy:    []string{"status"},
+		Aggregates: []dsl.Aggregate{{Function: dsl.AggCount, Alias: "n"}},
+		Sort:       []dsl.Sort{{Field: "status"}},
+		Collation:  collation,
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err = NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	aggOpts, ok := query.(*MongoQuery).Options.(*options.AggregateOptions)
+	if !ok || aggOpts.Collation == nil || aggOpts.Collation.Locale != "de" {
+		t.Errorf("Expected aggregate collation de, got %+v", query.(*MongoQuery).Options)
+	}
+}
+
+func TestBuildQuery_AggregateOperation(t *testing.T) {
+	plan, err := planner.NewPlanner(setupMongoDBTestRegistry()).PlanQuery(&dsl.Query{
+		Operation:  dsl.OpAggregate,
+		Model:      "orders",
+		GroupBy:    []string{"status"},
+		Aggregates: []dsl.Aggregate{{Function: dsl.AggSum, Field: "amount", Alias: "total"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	mq := query.(*MongoQuery)
+	if mq.Operation != "aggregate" {
+		t.Fatalf("Expected an aggregate pipeline, got %s", mq.Operation)
+	}
+	if _, ok := mq.Pipeline.([]bson.M)[0]["$group"]; !ok {
+		t.Errorf("Expected a $group stage, got %v", mq.Pipeline)
+	}
+}
+
+func TestBuildQuery_DefaultSort(t *testing.T) {
+	plan, err := planner.NewPlanner(setupMongoDBTestRegistry()).PlanQuery(&dsl.Query{
+		Model:      "users",
+		Pagination: &dsl.Pagination{Limit: 10, Offset: 10},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	opts := query.(*MongoQuery).Options.(*options.FindOptions)
+	if expected := (bson.D{{Key: "_id", Value: 1}}); !reflect.DeepEqual(opts.Sort, expected) {
+		t.Errorf("Expected sort %v, got %v", expected, opts.Sort)
+	}
+}
+
+func TestBuildQuery_CollectionDiffersFromModelName(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "customers",
+				Table:      "tbl_customers_v2",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid"},
+					{Name: "email", Type: "string"},
+				},
+				Relations: []config.Relation{
+					{Name: "orders", Type: "one_to_many", TargetModel: "orders", ForeignKey: "_id", ReferenceKey: "customer_id"},
+				},
+			},
+			{
+				Name:       "orders",
+				Table:      "tbl_orders",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "uuid"},
+					{Name: "customer_id", Type: "uuid"},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	for _, q := range []*dsl.Query{
+		{Model: "customers"},
+		{Operation: dsl.OpCount, Model: "customers"},
+		{Operation: dsl.OpCreate, Model: "customers", Data: map[string]interface{}{"email": "a@b.c"}},
+		{Operation: dsl.OpDelete, Model: "customers", ID: "c1"},
+	} {
+		plan, err := queryPlanner.PlanQuery(q)
+		if err != nil {
+			t.Fatalf("PlanQuery(%s) error: %v", q.Operation, err)
+		}
+		query, _, err := NewQueryBuilder().BuildQuery(plan)
+		if err != nil {
+			t.Fatalf("BuildQuery(%s) error: %v", q.Operation, err)
+		}
+		if coll := query.(*MongoQuery).Collection; coll != "tbl_customers_v2" {
+			t.Errorf("%s: expected collection tbl_customers_v2, got %s", q.Operation, coll)
+		}
+	}
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "customers", Include: []dsl.Include{{Relation: "orders"}}})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	var from interface{}
+	for _, stage := range query.(*MongoQuery).Pipeline.([]bson.M) {
+		if l, ok := stage["$lookup"].(bson.M); ok {
+			from = l["from"]
+		}
+	}
+	if from != "tbl_orders" {
+		t.Errorf("expected $lookup from tbl_orders, got %v", from)
+	}
+}
+
+func TestBuildQuery_FirstLast(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	for op, direction := range map[dsl.Operation]int{dsl.OpFirst: 1, dsl.OpLast: -1} {
+		plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: op, Model: "users"})
+		if err != nil {
+			t.Fatalf("PlanQuery(%s) error: %v", op, err)
+		}
+		query, _, err := NewQueryBuilder().BuildQuery(plan)
+		if err != nil {
+			t.Fatalf("BuildQuery(%s) error: %v", op, err)
+		}
+		opts := query.(*MongoQuery).Options.(*options.FindOptions)
+		if expected := (bson.D{{Key: "_id", Value: direction}}); !reflect.DeepEqual(opts.Sort, expected) {
+			t.Errorf("%s: expected sort %v, got %v", op, expected, opts.Sort)
+		}
+		if opts.Limit == nil || *opts.Limit != 1 {
+			t.Errorf("%s: expected limit 1, got %v", op, opts.Limit)
+		}
+	}
+}
+
+func TestBuildQuery_VirtualField(t *testing.T) {
+	concat := map[string]interface{}{"$concat": []interface{}{"$first_name", " ", "$last_name"}}
+	cfg := &config.Config{
+		Models: []config.Model{{
+			Name:         "people",
+			Table:        "people",
+			PrimaryKey:   "_id",
+			HiddenFields: []string{"last_name"},
+			Fields: []config.Field{
+				{Name: "_id", Type: "uuid"},
+				{Name: "first_name", Type: "string"},
+				{Name: "last_name", Type: "string"},
+			},
+			VirtualFields: []config.VirtualField{{Name: "full_name", Type: "string", Mongo: concat}},
+		}},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+
+	plan, err := planner.NewPlanner(reg).PlanQuery(&dsl.Query{Model: "people"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	mq := query.(*MongoQuery)
+	if mq.Operation != "aggregate" {
+		t.Fatalf("Expected an aggregation pipeline, got %s", mq.Operation)
+	}
+	// $limit, $addFields, then the hidden field is dropped
+	pipeline := mq.Pipeline.([]bson.M)
+	expected := []bson.M{
+		{"$limit": int64(100)},
+		{"$addFields": bson.M{"full_name": concat}},
+		{"$project": bson.M{"last_name": 0}},
+	}
+	if !reflect.DeepEqual(pipeline, expected) {
+		t.Errorf("Expected pipeline %v, got %v", expected, pipeline)
+	}
+}
+
+func TestBuildQuery_ObjectIDPrimaryKey(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+	first, _ := primitive.ObjectIDFromHex("65a1f0c2e4b0a1b2c3d4e5f6")
+	second, _ := primitive.ObjectIDFromHex("65a1f0c2e4b0a1b2c3d4e5f7")
+	hexIDs := []interface{}{"65a1f0c2e4b0a1b2c3d4e5f6", "65a1f0c2e4b0a1b2c3d4e5f7"}
+
+	tests := []struct {
+		name   string
+		filter *dsl.ComparisonFilter
+		want   bson.M
+	}{
+		{"in", &dsl.ComparisonFilter{Field: "_id", Op: dsl.OpIn, Value: hexIDs}, bson.M{"_id": bson.M{"$in": []interface{}{first, second}}}},
+		{"not in", &dsl.ComparisonFilter{Field: "_id", Op: dsl.OpNotIn, Value: hexIDs}, bson.M{"_id": bson.M{"$nin": []interface{}{first, second}}}},
+		{"equal", &dsl.ComparisonFilter{Field: "_id", Op: dsl.OpEqual, Value: "65a1f0c2e4b0a1b2c3d4e5f6"}, bson.M{"_id": first}},
+		{"greater than", &dsl.ComparisonFilter{Field: "_id", Op: dsl.OpGT, Value: "65a1f0c2e4b0a1b2c3d4e5f6"}, bson.M{"_id": bson.M{"$gt": first}}},
+		{"other ids", &dsl.ComparisonFilter{Field: "_id", Op: dsl.OpIn, Value: []interface{}{"a", "b"}}, bson.M{"_id": bson.M{"$in": []interface{}{"a", "b"}}}},
+		{"not primary key", &dsl.ComparisonFilter{Field: "user_id", Op: dsl.OpIn, Value: hexIDs}, bson.M{"user_id": bson.M{"$in": hexIDs}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "orders", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected filter %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "orders", Filters: &dsl.ComparisonFilter{
+		Field: "_id", Op: dsl.OpIn, Value: []interface{}{"65a1f0c2e4b0a1b2c3d4e5f6", "legacy-7"},
+	}})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil || !strings.Contains(err.Error(), "legacy-7") {
+		t.Errorf("Expected an error naming the non-ObjectID value, got %v", err)
+	}
+}
+
+func TestBuildQuery_Size(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{{
+			Name:       "posts",
+			Table:      "posts",
+			PrimaryKey: "_id",
+			Fields: []config.Field{
+				{Name: "_id", Type: "uuid"},
+				{Name: "tags", Type: "json"},
+			},
+		}},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+	size := bson.M{"$size": bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  bson.M
+	}{
+		{"exact", float64(3), bson.M{"$expr": bson.M{"$eq": bson.A{size, int64(3)}}}},
+		{"comparison", map[string]interface{}{">": float64(3)}, bson.M{"$expr": bson.M{"$gt": bson.A{size, int64(3)}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "posts", Filters: &dsl.ComparisonFilter{Field: "tags", Op: dsl.OpSize, Value: tt.value}})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Filter; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected filter %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_UpdateUpsert(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	build := func(upsert bool) *MongoQuery {
+		plan, err := queryPlanner.PlanQuery(&dsl.Query{
+			Operation: dsl.OpUpdate,
+			Model:     "users",
+			ID:        "user123",
+			Data:      map[string]interface{}{"email": "new@example.com"},
+			Upsert:    upsert,
+		})
+		if err != nil {
+			t.Fatalf("PlanQuery error: %v", err)
+		}
+		query, _, err := NewQueryBuilder().BuildQuery(plan)
+		if err != nil {
+			t.Fatalf("BuildQuery error: %v", err)
+		}
+		return query.(*MongoQuery)
+	}
+
+	opts, ok := build(true).Options.(*options.UpdateOptions)
+	if !ok || opts.Upsert == nil || !*opts.Upsert {
+		t.Errorf("Expected upsert update options, got %#v", build(true).Options)
+	}
+	if build(false).Options != nil {
+		t.Errorf("Expected no update options without upsert")
+	}
+}
+
+func TestBuildQuery_BetweenBounds(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupMongoDBTestRegistry())
+
+	tests := []struct {
+		bounds string
+		want   bson.M
+	}{
+		{"[]", bson.M{"$gte": 10, "$lte": 100}},
+		{"[)", bson.M{"$gte": 10, "$lt": 100}},
+		{"(]", bson.M{"$gt": 10, "$lte": 100}},
+		{"()", bson.M{"$gt": 10, "$lt": 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bounds, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{
+				Model:   "orders",
+				Filters: &dsl.ComparisonFilter{Field: "amount", Op: dsl.OpBetween, Value: []interface{}{10, 100}, Bounds: tt.bounds},
+			})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if got := query.(*MongoQuery).Filter.(bson.M)["amount"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
 	}
 }
@@ -2,17 +2,31 @@ package mongodb
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
+	"strconv"
+	"time"
 
 	"udv/internal/adapter"
+	"udv/internal/metrics"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
+// disconnectTimeout bounds how long Close waits for the client to disconnect.
+const disconnectTimeout = 10 * time.Second
+
 // Database represents a MongoDB database connection with its context.
+// Operations run in child contexts of ctx, so Close interrupts any still in flight.
 type Database struct {
 	ctx      context.Context
+	cancel   context.CancelFunc
 	database *mongo.Database
 	client   *mongo.Client
 }
@@ -20,38 +34,145 @@ type Database struct {
 // Compile-time assertion that Database implements adapter.Database interface
 var _ adapter.Database = (*Database)(nil)
 
+var _ adapter.Truncater = (*Database)(nil)
+
+var _ adapter.Estimator = (*Database)(nil)
+
+var _ adapter.UpsertReporter = (*MongoQuery)(nil)
+
+// ConnectOptions holds client settings that cannot always be expressed in the URI.
+// Zero values leave the URI (or driver default) in effect.
+type ConnectOptions struct {
+	AuthSource             string
+	ReplicaSet             string
+	TLS                    bool
+	TLSCAFile              string // PEM bundle used to verify the server; implies TLS
+	TLSInsecureSkipVerify  bool
+	ServerSelectionTimeout time.Duration
+	ReadPreference         string // primary, primaryPreferred, secondary, secondaryPreferred or nearest
+	WriteConcern           string // "majority", a tag set name or a node count
+	Journal                bool   // Writes wait for the on-disk journal
+}
+
 // Connect creates a new MongoDB client and connects to the given URI and database name.
 func Connect(uri string, databaseName string) (*Database, error) {
-	ctx := context.Background()
-	clientOptions := options.Client().ApplyURI(uri)
-	client, err := mongo.Connect(ctx, clientOptions)
+	return ConnectWithOptions(uri, databaseName, ConnectOptions{})
+}
+
+// ConnectWithOptions connects like Connect, applying opts on top of the URI.
+func ConnectWithOptions(uri string, databaseName string, opts ConnectOptions) (*Database, error) {
+	clientOptions, err := opts.clientOptions(uri)
 	if err != nil {
-		return nil, err
+		return nil, adapter.RedactError(err, uri)
 	}
 
-	// Ping to verify connection
-	err = client.Ping(ctx, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		return nil, err
+		cancel()
+		return nil, fmt.Errorf("failed to connect to %s: %w", adapter.RedactDSN(uri), adapter.RedactError(err, uri))
 	}
 
-	db := client.Database(databaseName)
-
-	return &Database{
+	d := &Database{
 		ctx:      ctx,
-		database: db,
+		cancel:   cancel,
+		database: client.Database(databaseName),
 		client:   client,
-	}, nil
+	}
+
+	// Ping to verify connection
+	if err := d.Ping(); err != nil {
+		_ = d.Close()
+		return nil, fmt.Errorf("failed to ping %s: %w", adapter.RedactDSN(uri), adapter.RedactError(err, uri))
+	}
+
+	return d, nil
+}
+
+// clientOptions builds driver options from the URI and the non-zero fields of o.
+func (o ConnectOptions) clientOptions(uri string) (*options.ClientOptions, error) {
+	clientOptions := options.Client().ApplyURI(uri)
+
+	if o.AuthSource != "" {
+		if clientOptions.Auth == nil {
+			return nil, fmt.Errorf("auth source requires credentials in the URI")
+		}
+		clientOptions.Auth.AuthSource = o.AuthSource
+	}
+	if o.ReplicaSet != "" {
+		clientOptions.SetReplicaSet(o.ReplicaSet)
+	}
+	if o.ServerSelectionTimeout > 0 {
+		clientOptions.SetServerSelectionTimeout(o.ServerSelectionTimeout)
+	}
+	if o.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(o.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetReadPreference(rp)
+	}
+	if o.WriteConcern != "" || o.Journal {
+		clientOptions.SetWriteConcern(o.writeConcern(clientOptions.WriteConcern))
+	}
+
+	if o.TLS || o.TLSCAFile != "" || o.TLSInsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: o.TLSInsecureSkipVerify}
+		if o.TLSCAFile != "" {
+			pem, err := os.ReadFile(o.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in TLS CA file %s", o.TLSCAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+
+	return clientOptions, clientOptions.Validate()
+}
+
+// writeConcern applies the configured w and journal settings on top of the URI's write concern
+func (o ConnectOptions) writeConcern(base *writeconcern.WriteConcern) *writeconcern.WriteConcern {
+	wc := &writeconcern.WriteConcern{}
+	if base != nil {
+		*wc = *base
+	}
+	if o.WriteConcern != "" {
+		if n, err := strconv.Atoi(o.WriteConcern); err == nil {
+			wc.W = n
+		} else {
+			wc.W = o.WriteConcern
+		}
+	}
+	if o.Journal {
+		wc.Journal = &o.Journal
+	}
+	return wc
 }
 
 // Close disconnects the MongoDB client.
+// In-flight operations are cancelled first, then the disconnect is bounded by disconnectTimeout.
 func (d *Database) Close() error {
-	return d.client.Disconnect(d.ctx)
+	d.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+	defer cancel()
+	return d.client.Disconnect(ctx)
 }
 
 // Ping checks the connection to the MongoDB server.
 func (d *Database) Ping() error {
-	return d.client.Ping(d.ctx, nil)
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+	return d.client.Ping(ctx, nil)
 }
 
 // ExecResult is an interface representing the result of an exec operation
@@ -71,15 +192,30 @@ func (r *ExecInsertResult) RowsAffected() (int64, error) {
 }
 
 // ExecUpdateResult holds the result of an update or delete operation.
+// UpsertedID is set when an upsert inserted a document because none matched.
 type ExecUpdateResult struct {
 	ModifiedCount int64
+	UpsertedID    interface{}
 }
 
-// RowsAffected for ExecUpdateResult returns the number of documents modified.
+// newExecUpdateResult converts a driver update result
+func newExecUpdateResult(res *mongo.UpdateResult) *ExecUpdateResult {
+	return &ExecUpdateResult{ModifiedCount: res.ModifiedCount, UpsertedID: res.UpsertedID}
+}
+
+// RowsAffected for ExecUpdateResult returns the number of documents modified or upserted.
 func (r *ExecUpdateResult) RowsAffected() (int64, error) {
+	if r.Upserted() {
+		return r.ModifiedCount + 1, nil
+	}
 	return r.ModifiedCount, nil
 }
 
+// Upserted reports whether the update inserted a document instead of modifying one.
+func (r *ExecUpdateResult) Upserted() bool {
+	return r.UpsertedID != nil
+}
+
 // Ensure our types implement adapter.ExecResult
 var (
 	_ adapter.ExecResult = (*ExecInsertResult)(nil)
@@ -87,74 +223,261 @@ var (
 )
 
 // ExecuteQuery executes a read operation like find or aggregate and returns the results.
-func (d *Database) ExecuteQuery(query interface{}, args ...interface{}) ([]map[string]interface{}, error) {
+func (d *Database) ExecuteQuery(query interface{}, args ...interface{}) (_ []map[string]interface{}, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBCall("mongodb", "query", start, err) }()
+
 	mq, ok := query.(*MongoQuery)
 	if !ok {
 		return nil, fmt.Errorf("ExecuteQuery: invalid query type %T", query)
 	}
 
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+
+	// Subqueries run first, since their values are part of the filter
+	if mq, err = d.resolveSubqueries(ctx, mq); err != nil {
+		return nil, err
+	}
+
 	coll := d.database.Collection(mq.Collection)
+	switch mq.Operation {
+	case "insert", "update", "delete":
+		// Writes read their documents back, which a secondary may not have replicated yet
+		coll = d.database.Collection(mq.Collection, options.Collection().SetReadPreference(readpref.Primary()))
+	}
 
 	switch mq.Operation {
 	case "find":
-		cursor, err := coll.Find(d.ctx, mq.Filter, mq.Options.(*options.FindOptions))
+		cursor, err := coll.Find(ctx, mq.Filter, mq.Options.(*options.FindOptions))
 		if err != nil {
 			return nil, err
 		}
-		defer cursor.Close(d.ctx)
+		defer cursor.Close(ctx)
 
 		var results []map[string]interface{}
-		err = cursor.All(d.ctx, &results)
+		err = cursor.All(ctx, &results)
 		if err != nil {
 			return nil, err
 		}
-		return results, nil
+		return normalizeDocuments(results), nil
 
 	case "aggregate":
-		cursor, err := coll.Aggregate(d.ctx, mq.Pipeline)
+		opts, _ := mq.Options.(*options.AggregateOptions)
+		cursor, err := coll.Aggregate(ctx, mq.Pipeline, opts)
 		if err != nil {
 			return nil, err
 		}
-		defer cursor.Close(d.ctx)
+		defer cursor.Close(ctx)
 
 		var results []map[string]interface{}
-		err = cursor.All(d.ctx, &results)
+		err = cursor.All(ctx, &results)
+		if err != nil {
+			return nil, err
+		}
+		return normalizeDocuments(results), nil
+
+	case "count":
+		count, err := coll.CountDocuments(ctx, mq.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]interface{}{{"count": count}}, nil
+
+	case "exists":
+		// The limit lets the server stop at the first match
+		count, err := coll.CountDocuments(ctx, mq.Filter, options.Count().SetLimit(1))
 		if err != nil {
 			return nil, err
 		}
-		return results, nil
+		return []map[string]interface{}{{"exists": count > 0}}, nil
+
+	case "distinct":
+		values, err := coll.Distinct(ctx, mq.Field, mq.Filter)
+		if err != nil {
+			return nil, err
+		}
+		// One row per value keeps the result shape shared with PostgreSQL's SELECT DISTINCT
+		rows := make([]map[string]interface{}, 0, len(values))
+		for _, value := range values {
+			rows = append(rows, map[string]interface{}{mq.Field: value})
+		}
+		return normalizeDocuments(rows), nil
+
+	case "insert":
+		insertResult, err := coll.InsertOne(ctx, mq.Document)
+		if err != nil {
+			return nil, err
+		}
+		return findWritten(ctx, coll, bson.M{"_id": insertResult.InsertedID}, mq.Projection)
+
+	case "update":
+		// Capture the matched ids first so documents the update moves out of the filter are still returned
+		ids, err := matchingIDs(ctx, coll, mq.Filter)
+		if err != nil {
+			return nil, err
+		}
+		opts, _ := mq.Options.(*options.UpdateOptions)
+		if len(ids) == 0 {
+			if opts == nil || opts.Upsert == nil || !*opts.Upsert {
+				return []map[string]interface{}{}, nil
+			}
+			// Nothing matched, so the upsert inserts a document, unless one was written meanwhile
+			res, err := coll.UpdateMany(ctx, mq.Filter, mq.Update, opts)
+			if err != nil {
+				return nil, err
+			}
+			result := newExecUpdateResult(res)
+			result.UpsertedID = objectIDToHex(result.UpsertedID)
+			query.(*MongoQuery).Result = result
+			if res.UpsertedID != nil {
+				return findWritten(ctx, coll, bson.M{"_id": res.UpsertedID}, mq.Projection)
+			}
+			return findWritten(ctx, coll, mq.Filter, mq.Projection)
+		}
+		byID := bson.M{"_id": bson.M{"$in": ids}}
+		res, err := coll.UpdateMany(ctx, bson.M{"$and": []interface{}{mq.Filter, byID}}, mq.Update)
+		if err != nil {
+			return nil, err
+		}
+		// Documents changed concurrently, e.g. to a newer version, may no longer match
+		if res.MatchedCount == 0 {
+			return []map[string]interface{}{}, nil
+		}
+		return findWritten(ctx, coll, byID, mq.Projection)
+
+	case "delete":
+		// DeleteMany reports no ids, so the matching documents are read before they are removed.
+		// A document deleted concurrently between the two steps may still be returned.
+		ids, err := matchingIDs(ctx, coll, mq.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return []map[string]interface{}{}, nil
+		}
+		byID := bson.M{"_id": bson.M{"$in": ids}}
+		deleted, err := findWritten(ctx, coll, byID, mq.Projection)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := coll.DeleteMany(ctx, bson.M{"$and": []interface{}{mq.Filter, byID}}); err != nil {
+			return nil, err
+		}
+		return deleted, nil
 
 	default:
 		return nil, fmt.Errorf("ExecuteQuery: unsupported operation %s", mq.Operation)
 	}
 }
 
+// matchingIDs returns the _id of every document matching filter
+func matchingIDs(ctx context.Context, coll *mongo.Collection, filter interface{}) ([]interface{}, error) {
+	cursor, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	ids := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc["_id"])
+	}
+	return ids, nil
+}
+
+// findWritten reads back documents after a write, applying the returning projection
+func findWritten(ctx context.Context, coll *mongo.Collection, filter interface{}, projection interface{}) ([]map[string]interface{}, error) {
+	opts := options.Find()
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []map[string]interface{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return normalizeDocuments(results), nil
+}
+
+// Truncate deletes every document of a collection and returns how many were removed.
+// Unlike dropping the collection, its indexes and validation rules are kept.
+func (d *Database) Truncate(collection string) (_ int64, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBCall("mongodb", "truncate", start, err) }()
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+
+	res, err := d.database.Collection(collection).DeleteMany(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// EstimateCount returns the document count of a collection from its metadata, without scanning it
+func (d *Database) EstimateCount(collection string) (_ int64, _ bool, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBCall("mongodb", "estimate", start, err) }()
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+
+	count, err := d.database.Collection(collection).EstimatedDocumentCount(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
+}
+
 // Exec executes insert, update or delete operations and returns the result.
-func (d *Database) Exec(query interface{}, args ...interface{}) (adapter.ExecResult, error) {
+func (d *Database) Exec(query interface{}, args ...interface{}) (_ adapter.ExecResult, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBCall("mongodb", "exec", start, err) }()
+
 	mq, ok := query.(*MongoQuery)
 	if !ok {
 		return nil, fmt.Errorf("Exec: invalid query type %T", query)
 	}
 
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+
+	// Subqueries run first, since their values are part of the filter
+	if mq, err = d.resolveSubqueries(ctx, mq); err != nil {
+		return nil, err
+	}
+
 	coll := d.database.Collection(mq.Collection)
 
 	switch mq.Operation {
 	case "insert":
-		insertResult, err := coll.InsertOne(d.ctx, mq.Document)
+		insertResult, err := coll.InsertOne(ctx, mq.Document)
 		if err != nil {
 			return nil, err
 		}
 		return &ExecInsertResult{InsertedID: insertResult.InsertedID}, nil
 
 	case "update":
-		res, err := coll.UpdateMany(d.ctx, mq.Filter, mq.Update)
+		opts, _ := mq.Options.(*options.UpdateOptions)
+		res, err := coll.UpdateMany(ctx, mq.Filter, mq.Update, opts)
 		if err != nil {
 			return nil, err
 		}
-		return &ExecUpdateResult{ModifiedCount: res.ModifiedCount}, nil
+		return newExecUpdateResult(res), nil
 
 	case "delete":
-		res, err := coll.DeleteMany(d.ctx, mq.Filter)
+		res, err := coll.DeleteMany(ctx, mq.Filter)
 		if err != nil {
 			return nil, err
 		}
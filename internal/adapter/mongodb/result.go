@@ -0,0 +1,36 @@
+package mongodb
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"udv/internal/adapter"
+)
+
+// normalizeDocuments normalizes decoded documents like every other backend's rows, in place,
+// and renders ObjectIDs as 24-char hex strings
+func normalizeDocuments(docs []map[string]interface{}) []map[string]interface{} {
+	for _, doc := range adapter.NormalizeRows(docs) {
+		for key, value := range doc {
+			doc[key] = objectIDToHex(value)
+		}
+	}
+	return docs
+}
+
+// objectIDToHex converts ObjectIDs to hex strings, recursing into the plain documents and
+// arrays adapter.NormalizeRows leaves
+func objectIDToHex(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = objectIDToHex(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = objectIDToHex(item)
+		}
+	}
+	return value
+}
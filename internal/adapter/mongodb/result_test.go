@@ -0,0 +1,46 @@
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizeDocuments(t *testing.T) {
+	id := primitive.NewObjectID()
+	ref := primitive.NewObjectID()
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Round-trip through BSON so values have the types the driver decodes into
+	raw, err := bson.Marshal(bson.M{
+		"_id":        id,
+		"created_at": created,
+		"owner":      bson.M{"user_id": ref, "name": "Ann"},
+		"refs":       bson.A{ref, "plain"},
+	})
+	if err != nil {
+		t.Fatalf("bson.Marshal error: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("bson.Unmarshal error: %v", err)
+	}
+
+	got := normalizeDocuments([]map[string]interface{}{doc})[0]
+	expected := map[string]interface{}{
+		"_id":        id.Hex(),
+		"created_at": "2024-01-02T03:04:05Z",
+		"owner":      map[string]interface{}{"user_id": ref.Hex(), "name": "Ann"},
+		"refs":       []interface{}{ref.Hex(), "plain"},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("normalizeDocuments() = %#v, want %#v", got, expected)
+	}
+	if len(got["_id"].(string)) != 24 {
+		t.Errorf("_id = %v, want 24-char hex string", got["_id"])
+	}
+}
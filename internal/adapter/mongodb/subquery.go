@@ -0,0 +1,161 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// resolveSubqueries runs the subqueries in the filter or pipeline of mq and returns a copy of
+// mq with their values in place, or mq itself when it has none
+func (d *Database) resolveSubqueries(ctx context.Context, mq *MongoQuery) (*MongoQuery, error) {
+	resolve := func(sub *Subquery) (interface{}, error) {
+		values, err := d.database.Collection(sub.Collection).Distinct(ctx, sub.Field, sub.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("subquery on %s failed: %w", sub.Collection, err)
+		}
+		return subqueryValues(values), nil
+	}
+
+	filter, filterChanged, err := replaceSubqueries(mq.Filter, resolve)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, pipelineChanged, err := replaceSubqueries(mq.Pipeline, resolve)
+	if err != nil {
+		return nil, err
+	}
+	if !filterChanged && !pipelineChanged {
+		return mq, nil
+	}
+
+	resolved := *mq
+	resolved.Filter, resolved.Pipeline = filter, pipeline
+	return &resolved, nil
+}
+
+// subqueryValues drops nulls from a subquery's values. SQL IN never matches NULL, and dropping
+// them keeps $in from matching documents that lack the field.
+func subqueryValues(values []interface{}) bson.A {
+	out := make(bson.A, 0, len(values))
+	for _, value := range values {
+		if value != nil {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// replaceSubqueries returns value with every *Subquery replaced by what resolve returns for it.
+// Documents and arrays are copied only along the path to a subquery; changed reports whether
+// any was found.
+func replaceSubqueries(value interface{}, resolve func(*Subquery) (interface{}, error)) (_ interface{}, changed bool, err error) {
+	switch v := value.(type) {
+	case *Subquery:
+		resolved, err := resolve(v)
+		if err != nil {
+			return nil, false, err
+		}
+		return resolved, true, nil
+
+	case bson.M:
+		var out bson.M
+		for key, elem := range v {
+			r, ok, err := replaceSubqueries(elem, resolve)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = make(bson.M, len(v))
+				for k, e := range v {
+					out[k] = e
+				}
+			}
+			out[key] = r
+		}
+		if out == nil {
+			return v, false, nil
+		}
+		return out, true, nil
+
+	case bson.D:
+		var out bson.D
+		for i, elem := range v {
+			r, ok, err := replaceSubqueries(elem.Value, resolve)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = append(bson.D{}, v...)
+			}
+			out[i].Value = r
+		}
+		if out == nil {
+			return v, false, nil
+		}
+		return out, true, nil
+
+	case bson.A:
+		out, ok, err := replaceInSlice([]interface{}(v), resolve)
+		if err != nil || !ok {
+			return v, false, err
+		}
+		return bson.A(out), true, nil
+
+	case []interface{}:
+		out, ok, err := replaceInSlice(v, resolve)
+		if err != nil || !ok {
+			return v, false, err
+		}
+		return out, true, nil
+
+	case []bson.M:
+		var out []bson.M
+		for i, elem := range v {
+			r, ok, err := replaceSubqueries(elem, resolve)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = append([]bson.M{}, v...)
+			}
+			out[i] = r.(bson.M)
+		}
+		if out == nil {
+			return v, false, nil
+		}
+		return out, true, nil
+
+	default:
+		return value, false, nil
+	}
+}
+
+// replaceInSlice replaces subqueries in the elements of an array, copying it only when one is found
+func replaceInSlice(v []interface{}, resolve func(*Subquery) (interface{}, error)) ([]interface{}, bool, error) {
+	var out []interface{}
+	for i, elem := range v {
+		r, ok, err := replaceSubqueries(elem, resolve)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}{}, v...)
+		}
+		out[i] = r
+	}
+	return out, out != nil, nil
+}
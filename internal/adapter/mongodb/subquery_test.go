@@ -0,0 +1,76 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReplaceSubqueries(t *testing.T) {
+	sub := &Subquery{Collection: "users", Field: "_id", Filter: bson.M{"active": true}}
+	resolve := func(s *Subquery) (interface{}, error) {
+		if s != sub {
+			t.Errorf("unexpected subquery %v", s)
+		}
+		return bson.A{1, 2}, nil
+	}
+
+	original := bson.M{"$and": []bson.M{
+		{"status": "PAID"},
+		{"user_id": bson.M{"$in": sub}},
+	}}
+	got, changed, err := replaceSubqueries(original, resolve)
+	if err != nil {
+		t.Fatalf("replaceSubqueries error: %v", err)
+	}
+	want := bson.M{"$and": []bson.M{
+		{"status": "PAID"},
+		{"user_id": bson.M{"$in": bson.A{1, 2}}},
+	}}
+	if !changed || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v (changed %v), want %v", got, changed, want)
+	}
+	if original["$and"].([]bson.M)[1]["user_id"].(bson.M)["$in"] != sub {
+		t.Error("expected the original filter to be left unchanged")
+	}
+
+	pipeline := []bson.M{{"$match": bson.M{"user_id": bson.M{"$nin": sub}}}, {"$limit": 10}}
+	gotPipeline, changed, err := replaceSubqueries(pipeline, resolve)
+	if err != nil {
+		t.Fatalf("replaceSubqueries error: %v", err)
+	}
+	wantPipeline := []bson.M{{"$match": bson.M{"user_id": bson.M{"$nin": bson.A{1, 2}}}}, {"$limit": 10}}
+	if !changed || !reflect.DeepEqual(gotPipeline, wantPipeline) {
+		t.Errorf("got %v (changed %v), want %v", gotPipeline, changed, wantPipeline)
+	}
+}
+
+func TestReplaceSubqueries_NoSubquery(t *testing.T) {
+	filter := bson.M{"status": bson.M{"$in": bson.A{"PAID", "NEW"}}, "tags": bson.D{{Key: "$size", Value: 2}}}
+	got, changed, err := replaceSubqueries(filter, func(*Subquery) (interface{}, error) {
+		t.Error("resolve should not be called")
+		return nil, nil
+	})
+	if err != nil || changed || !reflect.DeepEqual(got, filter) {
+		t.Errorf("got %v (changed %v, err %v), want the filter unchanged", got, changed, err)
+	}
+}
+
+func TestReplaceSubqueries_Error(t *testing.T) {
+	failure := errors.New("boom")
+	_, _, err := replaceSubqueries(bson.M{"user_id": bson.M{"$in": &Subquery{}}}, func(*Subquery) (interface{}, error) {
+		return nil, failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected resolve error, got %v", err)
+	}
+}
+
+func TestSubqueryValues_DropsNulls(t *testing.T) {
+	got := subqueryValues([]interface{}{1, nil, 2})
+	if !reflect.DeepEqual(got, bson.A{1, 2}) {
+		t.Errorf("got %v, want [1 2]", got)
+	}
+}
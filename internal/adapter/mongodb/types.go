@@ -1,12 +1,13 @@
 package mongodb
 
 // MongoQuery represents a MongoDB operation to be executed
-// Operation can be one of: find, aggregate, insert, update, delete
+// Operation can be one of: find, aggregate, count, insert, update, delete
 // Filter is a bson.M representing query filter
 // Update is a bson.M representing update document
 // Document is a bson.M for insert operations
 // Pipeline is a mongo.Pipeline for aggregations
 // Options are find options
+// Projection shapes the documents read back after an insert or update
 
 type MongoQuery struct {
 	Collection string
@@ -16,4 +17,27 @@ type MongoQuery struct {
 	Update     interface{}
 	Document   interface{}
 	Options    interface{}
+	Projection interface{}
+	Field      string // Field whose values a distinct query returns
+
+	// Result is set by ExecuteQuery for an upserting update that matched nothing, with the
+	// hex _id of the inserted document unless one was written meanwhile
+	Result *ExecUpdateResult
+}
+
+// UpsertedID returns the _id of the document an executed upsert inserted, or nil
+func (q *MongoQuery) UpsertedID() interface{} {
+	if q.Result == nil {
+		return nil
+	}
+	return q.Result.UpsertedID
+}
+
+// Subquery stands in for the value list of an $in or $nin filter. MongoDB has no subqueries,
+// so the database first reads the distinct values of Field in the documents of Collection
+// matching Filter, then runs the query with them in its place.
+type Subquery struct {
+	Collection string
+	Field      string
+	Filter     interface{}
 }
@@ -0,0 +1,74 @@
+package adapter
+
+import (
+	"reflect"
+	"time"
+)
+
+// timeValue is implemented by driver date types such as the MongoDB primitive.DateTime
+type timeValue interface {
+	Time() time.Time
+}
+
+// NormalizeRows rewrites result values into one canonical JSON form regardless of backend:
+// timestamps become RFC3339 strings in UTC and booleans stay JSON booleans. Nested documents
+// and arrays are normalized too. Rows are modified in place and returned.
+func NormalizeRows(rows []map[string]interface{}) []map[string]interface{} {
+	for _, row := range rows {
+		for key, value := range row {
+			row[key] = normalizeValue(value)
+		}
+	}
+	return rows
+}
+
+// normalizeValue normalizes a single result value
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, string:
+		return v
+	case time.Time:
+		return formatTime(v)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return formatTime(*v)
+	case timeValue:
+		return formatTime(v.Time())
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+		return v
+	}
+
+	// Named document and array types (bson.M, primitive.A) share the underlying kinds
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String && rv.Type().Elem().Kind() == reflect.Interface:
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = normalizeValue(iter.Value().Interface())
+		}
+		return out
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Interface:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = normalizeValue(rv.Index(i).Interface())
+		}
+		return out
+	}
+	return value
+}
+
+// formatTime renders a timestamp as RFC3339 in UTC
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
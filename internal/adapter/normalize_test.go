@@ -0,0 +1,43 @@
+package adapter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizeRows(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	rows := []map[string]interface{}{
+		{
+			"created_at": ts,
+			"updated_at": primitive.NewDateTimeFromTime(ts),
+			"active":     true,
+			"amount":     int64(5),
+			"name":       "widget",
+			"deleted_at": nil,
+			"meta":       bson.M{"seen_at": ts, "tags": primitive.A{"a", ts}},
+		},
+	}
+
+	got := NormalizeRows(rows)[0]
+	expected := map[string]interface{}{
+		"created_at": "2024-01-02T02:04:05Z",
+		"updated_at": "2024-01-02T02:04:05Z",
+		"active":     true,
+		"amount":     int64(5),
+		"name":       "widget",
+		"deleted_at": nil,
+		"meta": map[string]interface{}{
+			"seen_at": "2024-01-02T02:04:05Z",
+			"tags":    []interface{}{"a", "2024-01-02T02:04:05Z"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("NormalizeRows() = %#v, want %#v", got, expected)
+	}
+}
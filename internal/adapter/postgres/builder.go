@@ -3,7 +3,11 @@ package postgres
 // Package postgres implements PostgreSQL-specific query generation
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"udv/internal/dsl"
@@ -21,6 +25,16 @@ func getPostgreSQLType(fieldType planner.FieldType) string {
 		return "bytea"
 	case planner.TypeTimestamp, planner.TypeDate, planner.TypeDateTime:
 		return "timestamp"
+	case planner.TypeInterval:
+		return "interval"
+	case planner.TypeInet:
+		return "inet"
+	case planner.TypeCIDR:
+		return "cidr"
+	case planner.TypeMacAddr:
+		return "macaddr"
+	case planner.TypeXML:
+		return "xml"
 	// For other types, PostgreSQL can usually infer from context
 	default:
 		return ""
@@ -30,7 +44,8 @@ func getPostgreSQLType(fieldType planner.FieldType) string {
 // needsTypeCasting returns true if the field type needs explicit type casting in SQL
 func needsTypeCasting(fieldType planner.FieldType) bool {
 	switch fieldType {
-	case planner.TypeUUID, planner.TypeJSON, planner.TypeBinary, planner.TypeTimestamp:
+	case planner.TypeUUID, planner.TypeJSON, planner.TypeBinary, planner.TypeTimestamp,
+		planner.TypeInterval, planner.TypeInet, planner.TypeCIDR, planner.TypeMacAddr, planner.TypeXML:
 		return true
 	default:
 		return false
@@ -62,6 +77,14 @@ func (qb *QueryBuilder) BuildQuery(plan *planner.QueryPlan) (interface{}, []inte
 		return nil, nil, fmt.Errorf("root model is nil")
 	}
 
+	if err := plan.Pagination.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if plan.RootModel.ReadOnly && plan.Operation.Writes() {
+		return nil, nil, fmt.Errorf("model %s is read-only and does not support %s", plan.RootModel.Name, plan.Operation)
+	}
+
 	qb.params = []interface{}{}
 	qb.paramCount = 0
 
@@ -71,6 +94,15 @@ func (qb *QueryBuilder) BuildQuery(plan *planner.QueryPlan) (interface{}, []inte
 		operation = "select" // Default to select
 	}
 
+	// Operations returning rows render the model's computed fields
+	if operation != "count" && operation != "exists" && operation != "distinct" {
+		for _, col := range plan.RootModel.Columns {
+			if col.Virtual != nil && col.Virtual.SQL == "" {
+				return nil, nil, fmt.Errorf("virtual field %s has no sql expression", col.ColumnName)
+			}
+		}
+	}
+
 	switch operation {
 	case "create":
 		sql, args, err := qb.buildInsert(plan)
@@ -84,6 +116,23 @@ func (qb *QueryBuilder) BuildQuery(plan *planner.QueryPlan) (interface{}, []inte
 	case "select", "":
 		sql, args, err := qb.buildSelect(plan)
 		return sql, args, err
+	case "aggregate":
+		// The validator guarantees grouping or aggregates, which buildSelect renders as GROUP BY
+		sql, args, err := qb.buildSelect(plan)
+		return sql, args, err
+	case "first", "last":
+		// The planner orders by primary key and limits the select to one row
+		sql, args, err := qb.buildSelect(plan)
+		return sql, args, err
+	case "count":
+		sql, args, err := qb.buildCount(plan)
+		return sql, args, err
+	case "distinct":
+		sql, args, err := qb.buildDistinct(plan)
+		return sql, args, err
+	case "exists":
+		sql, args, err := qb.buildExists(plan)
+		return sql, args, err
 	default:
 		return nil, nil, fmt.Errorf("unsupported operation: %s", operation)
 	}
@@ -91,6 +140,10 @@ func (qb *QueryBuilder) BuildQuery(plan *planner.QueryPlan) (interface{}, []inte
 
 // buildSelect builds a SELECT query (existing logic)
 func (qb *QueryBuilder) buildSelect(plan *planner.QueryPlan) (string, []interface{}, error) {
+	if len(plan.Joins) > 0 {
+		return "", nil, fmt.Errorf("relation includes are not supported by the PostgreSQL builder")
+	}
+
 	var parts []string
 
 	// 1. SELECT clause
@@ -101,12 +154,12 @@ func (qb *QueryBuilder) buildSelect(plan *planner.QueryPlan) (string, []interfac
 	fromPart := qb.buildFromClause(plan)
 	parts = append(parts, fromPart)
 
-	// 3. WHERE clause (if filters exist)
-	if plan.Filters != nil {
-		wherePart, err := qb.buildWhereClause(plan.Filters)
-		if err != nil {
-			return "", nil, err
-		}
+	// 3. WHERE clause (if filters exist or soft-deleted rows are excluded)
+	wherePart, err := qb.buildPlanWhereClause(plan)
+	if err != nil {
+		return "", nil, err
+	}
+	if wherePart != "" {
 		parts = append(parts, wherePart)
 	}
 
@@ -126,12 +179,78 @@ func (qb *QueryBuilder) buildSelect(plan *planner.QueryPlan) (string, []interfac
 	paginationPart := qb.buildPaginationClause(plan)
 	parts = append(parts, paginationPart)
 
+	// 7. Row locking clause (if locking)
+	if plan.Lock != nil {
+		parts = append(parts, qb.buildLockClause(plan.Lock))
+	}
+
 	// Join all parts
 	sql := strings.Join(parts, " ") + ";"
 
 	return sql, qb.params, nil
 }
 
+// buildDistinct builds a SELECT DISTINCT query over the plan's single column, ordered by its values
+func (qb *QueryBuilder) buildDistinct(plan *planner.QueryPlan) (string, []interface{}, error) {
+	if len(plan.Select) != 1 {
+		return "", nil, fmt.Errorf("distinct requires exactly one field")
+	}
+	col := plan.Select[0].Column
+	colName := fmt.Sprintf("%s.%s", col.TableAlias, col.ColumnName)
+
+	parts := []string{"SELECT DISTINCT " + colName, qb.buildFromClause(plan)}
+
+	wherePart, err := qb.buildPlanWhereClause(plan)
+	if err != nil {
+		return "", nil, err
+	}
+	if wherePart != "" {
+		parts = append(parts, wherePart)
+	}
+	parts = append(parts, "ORDER BY "+colName)
+
+	return strings.Join(parts, " ") + ";", qb.params, nil
+}
+
+// buildCount builds a SELECT COUNT(*) query with the plan's filters
+func (qb *QueryBuilder) buildCount(plan *planner.QueryPlan) (string, []interface{}, error) {
+	countExpr := qb.buildAggregateExpression(planner.AggregateExpr{
+		Function: planner.AggCountFn,
+		Alias:    "count",
+	})
+
+	parts := []string{"SELECT " + countExpr, qb.buildFromClause(plan)}
+
+	wherePart, err := qb.buildPlanWhereClause(plan)
+	if err != nil {
+		return "", nil, err
+	}
+	if wherePart != "" {
+		parts = append(parts, wherePart)
+	}
+
+	sql := strings.Join(parts, " ") + ";"
+
+	return sql, qb.params, nil
+}
+
+// buildExists builds a SELECT EXISTS(...) query, which stops at the first matching row
+func (qb *QueryBuilder) buildExists(plan *planner.QueryPlan) (string, []interface{}, error) {
+	parts := []string{"SELECT 1", qb.buildFromClause(plan)}
+
+	wherePart, err := qb.buildPlanWhereClause(plan)
+	if err != nil {
+		return "", nil, err
+	}
+	if wherePart != "" {
+		parts = append(parts, wherePart)
+	}
+
+	sql := "SELECT EXISTS(" + strings.Join(parts, " ") + ") AS exists;"
+
+	return sql, qb.params, nil
+}
+
 // buildInsert builds an INSERT query
 func (qb *QueryBuilder) buildInsert(plan *planner.QueryPlan) (string, []interface{}, error) {
 	if plan.Data == nil || len(plan.Data) == 0 {
@@ -144,16 +263,27 @@ func (qb *QueryBuilder) buildInsert(plan *planner.QueryPlan) (string, []interfac
 
 	for field, value := range plan.Data {
 		fields = append(fields, field)
+		// Allowlisted expressions such as now() and DEFAULT are written as SQL, not bound
+		if expr, ok := value.(dsl.ValueExpr); ok {
+			placeholders = append(placeholders, string(expr))
+			continue
+		}
 		qb.paramCount++
 		placeholders = append(placeholders, fmt.Sprintf("$%d", qb.paramCount))
 		qb.params = append(qb.params, value)
 	}
 
+	for _, col := range plan.AutoTimestamps() {
+		fields = append(fields, col)
+		placeholders = append(placeholders, "now()")
+	}
+
 	sql := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s) RETURNING *;",
+		"INSERT INTO %s (%s) VALUES (%s) %s;",
 		table,
 		strings.Join(fields, ", "),
 		strings.Join(placeholders, ", "),
+		qb.buildReturningClause(plan),
 	)
 
 	return sql, qb.params, nil
@@ -161,68 +291,137 @@ func (qb *QueryBuilder) buildInsert(plan *planner.QueryPlan) (string, []interfac
 
 // buildUpdate builds an UPDATE query
 func (qb *QueryBuilder) buildUpdate(plan *planner.QueryPlan) (string, []interface{}, error) {
-	if plan.Data == nil || len(plan.Data) == 0 {
-		return "", nil, fmt.Errorf("data is required for update operation")
+	if len(plan.Push) > 0 {
+		return "", nil, fmt.Errorf("push updates are not supported by PostgreSQL")
+	}
+	if plan.Upsert {
+		return "", nil, fmt.Errorf("upsert is not supported by PostgreSQL")
+	}
+	if len(plan.Data) == 0 && len(plan.Increment) == 0 && len(plan.JSONUpdates) == 0 {
+		return "", nil, fmt.Errorf("data, increment or json updates are required for update operation")
 	}
 
 	table := plan.RootModel.Table
 	sets := []string{}
 
 	for field, value := range plan.Data {
+		if expr, ok := value.(dsl.ValueExpr); ok {
+			sets = append(sets, fmt.Sprintf("%s = %s", field, expr))
+			continue
+		}
 		qb.paramCount++
 		sets = append(sets, fmt.Sprintf("%s = $%d", field, qb.paramCount))
 		qb.params = append(qb.params, value)
 	}
 
-	where := ""
-	if plan.ID != nil {
-		// Use ID for WHERE clause
+	incremented := make([]string, 0, len(plan.Increment))
+	for field := range plan.Increment {
+		incremented = append(incremented, field)
+	}
+	sort.Strings(incremented)
+	for _, field := range incremented {
 		qb.paramCount++
-		where = fmt.Sprintf("WHERE %s = $%d", plan.RootModel.PrimaryKey.ColumnName, qb.paramCount)
-		qb.params = append(qb.params, plan.ID)
-	} else if plan.Filters != nil {
-		// Use filters for WHERE clause
-		wherePart, err := qb.buildWhereClause(plan.Filters)
-		if err != nil {
-			return "", nil, err
-		}
-		where = wherePart
-	} else {
+		sets = append(sets, fmt.Sprintf("%s = %s + $%d", field, field, qb.paramCount))
+		qb.params = append(qb.params, plan.Increment[field])
+	}
+
+	jsonSets, err := qb.buildJSONUpdates(plan.JSONUpdates)
+	if err != nil {
+		return "", nil, err
+	}
+	sets = append(sets, jsonSets...)
+
+	for _, col := range plan.AutoTimestamps() {
+		sets = append(sets, col+" = now()")
+	}
+	if plan.Version != nil {
+		sets = append(sets, fmt.Sprintf("%s = %s + 1", plan.Version.ColumnName, plan.Version.ColumnName))
+	}
+
+	if plan.ID == nil && plan.Filters == nil && !plan.AllowFullTable {
 		return "", nil, fmt.Errorf("id or filters required for update operation")
 	}
+	where, err := qb.buildPlanWhereClause(plan)
+	if err != nil {
+		return "", nil, err
+	}
 
 	sql := fmt.Sprintf(
-		"UPDATE %s SET %s %s RETURNING *;",
+		"UPDATE %s %s SET %s %s %s;",
 		table,
+		plan.RootModel.Alias,
 		strings.Join(sets, ", "),
 		where,
+		qb.buildReturningClause(plan),
 	)
 
 	return sql, qb.params, nil
 }
 
+// buildJSONUpdates folds the key updates of each jsonb column into one assignment, e.g.
+// settings = jsonb_set(COALESCE(settings, '{}'), '{theme}', $1::jsonb) #- '{legacy}'.
+// A NULL column is treated as an empty object when a key is set in it.
+func (qb *QueryBuilder) buildJSONUpdates(updates []planner.JSONUpdate) ([]string, error) {
+	exprs := make(map[string]string)
+	var columns []string
+	for _, u := range updates {
+		expr, ok := exprs[u.Column]
+		if !ok {
+			expr = u.Column
+			if !u.Remove {
+				expr = fmt.Sprintf("COALESCE(%s, '{}')", u.Column)
+			}
+			columns = append(columns, u.Column)
+		}
+
+		// Path keys are restricted to [A-Za-z0-9_-] by the planner, so the literal is safe
+		path := "'{" + strings.Join(u.Path, ",") + "}'"
+		if u.Remove {
+			exprs[u.Column] = fmt.Sprintf("%s #- %s", expr, path)
+			continue
+		}
+
+		value, err := json.Marshal(u.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json value for %s: %w", u.Column, err)
+		}
+		qb.paramCount++
+		qb.params = append(qb.params, string(value))
+		exprs[u.Column] = fmt.Sprintf("jsonb_set(%s, %s, $%d::jsonb)", expr, path, qb.paramCount)
+	}
+
+	sets := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = %s", column, exprs[column]))
+	}
+	return sets, nil
+}
+
 // buildDelete builds a DELETE query
 func (qb *QueryBuilder) buildDelete(plan *planner.QueryPlan) (string, []interface{}, error) {
 	table := plan.RootModel.Table
 
-	where := ""
-	if plan.ID != nil {
-		// Use ID for WHERE clause
-		qb.paramCount++
-		where = fmt.Sprintf("WHERE %s = $%d", plan.RootModel.PrimaryKey.ColumnName, qb.paramCount)
-		qb.params = append(qb.params, plan.ID)
-	} else if plan.Filters != nil {
-		// Use filters for WHERE clause
-		wherePart, err := qb.buildWhereClause(plan.Filters)
-		if err != nil {
-			return "", nil, err
-		}
-		where = wherePart
-	} else {
+	if plan.ID == nil && plan.Filters == nil && !plan.AllowFullTable {
 		return "", nil, fmt.Errorf("id or filters required for delete operation")
 	}
+	where, err := qb.buildPlanWhereClause(plan)
+	if err != nil {
+		return "", nil, err
+	}
 
-	sql := fmt.Sprintf("DELETE FROM %s %s;", table, where)
+	// Deleted rows are only sent back when the client asked for specific columns
+	returning := ""
+	if len(plan.Returning) > 0 {
+		returning = " " + qb.buildReturningClause(plan)
+	}
+
+	// Soft deletes mark the row instead of removing it
+	if plan.SoftDelete != nil {
+		sql := fmt.Sprintf("UPDATE %s %s SET %s = now() %s%s;", table, plan.RootModel.Alias, plan.SoftDelete.ColumnName, where, returning)
+		return sql, qb.params, nil
+	}
+
+	sql := fmt.Sprintf("DELETE FROM %s %s %s%s;", table, plan.RootModel.Alias, where, returning)
 
 	return sql, qb.params, nil
 }
@@ -235,19 +434,29 @@ func (qb *QueryBuilder) buildSelectClause(plan *planner.QueryPlan) string {
 	if len(plan.Select) > 0 {
 		for _, expr := range plan.Select {
 			colName := fmt.Sprintf("%s.%s", expr.Column.TableAlias, expr.Column.ColumnName)
-			if expr.Alias != expr.Column.ColumnName {
+			if expr.Column.Virtual != nil {
+				colName = fmt.Sprintf("%s AS %s", virtualExpression(expr.Column, expr.Column.TableAlias+"."), expr.Alias)
+			} else if expr.Alias != expr.Column.ColumnName {
 				colName = fmt.Sprintf("%s AS %s", colName, expr.Alias)
 			}
 			columns = append(columns, colName)
 		}
 	}
 
-	// Add group by columns if grouping
-	if len(plan.GroupBy) > 0 && len(plan.Select) == 0 {
-		for _, groupExpr := range plan.GroupBy {
-			colName := fmt.Sprintf("%s.%s", groupExpr.Column.TableAlias, groupExpr.Column.ColumnName)
-			columns = append(columns, colName)
+	// Add group by columns that were not explicitly selected, so every group key is returned
+	selected := make(map[string]bool, len(plan.Select))
+	for _, expr := range plan.Select {
+		selected[expr.Column.ColumnName] = true
+	}
+	for _, groupExpr := range plan.GroupBy {
+		if groupExpr.Granularity != "" {
+			columns = append(columns, fmt.Sprintf("%s AS %s", groupExpression(groupExpr), groupExpr.Alias))
+			continue
+		}
+		if selected[groupExpr.Column.ColumnName] {
+			continue
 		}
+		columns = append(columns, groupExpression(groupExpr))
 	}
 
 	// Add aggregates
@@ -256,26 +465,92 @@ func (qb *QueryBuilder) buildSelectClause(plan *planner.QueryPlan) string {
 		columns = append(columns, aggStr)
 	}
 
-	// If no columns selected, use *
+	// If no columns selected, list the model's declared fields so results have a stable
+	// column order and leave out hidden fields; * remains only for models without fields
 	if len(columns) == 0 {
-		return "SELECT *"
+		if len(plan.RootModel.Columns) == 0 && len(plan.RootModel.HiddenFields) == 0 {
+			return "SELECT *"
+		}
+		for _, col := range plan.RootModel.Columns {
+			if col.Virtual != nil {
+				columns = append(columns, fmt.Sprintf("%s AS %s", virtualExpression(col, col.TableAlias+"."), col.ColumnName))
+				continue
+			}
+			columns = append(columns, fmt.Sprintf("%s.%s", col.TableAlias, col.ColumnName))
+		}
 	}
 
 	return "SELECT " + strings.Join(columns, ", ")
 }
 
+// buildReturningClause generates the RETURNING part of a write: the requested
+// columns, or every column except hidden fields followed by the computed fields
+func (qb *QueryBuilder) buildReturningClause(plan *planner.QueryPlan) string {
+	if len(plan.Returning) > 0 {
+		columns := make([]string, 0, len(plan.Returning))
+		for _, col := range plan.Returning {
+			columns = append(columns, col.ColumnName)
+		}
+		return "RETURNING " + strings.Join(columns, ", ")
+	}
+
+	var columns, virtual []string
+	for _, col := range plan.RootModel.Columns {
+		if col.Virtual != nil {
+			virtual = append(virtual, fmt.Sprintf("%s AS %s", virtualExpression(col, ""), col.ColumnName))
+			continue
+		}
+		columns = append(columns, col.ColumnName)
+	}
+	if len(plan.RootModel.HiddenFields) == 0 {
+		columns = []string{"*"}
+	}
+	return "RETURNING " + strings.Join(append(columns, virtual...), ", ")
+}
+
+// virtualExpression renders a computed field's SQL expression, parenthesized, with its
+// columns prefixed by qualifier
+func virtualExpression(col planner.ColumnRef, qualifier string) string {
+	return "(" + col.Virtual.ExpandSQL(func(column string) string { return qualifier + column }) + ")"
+}
+
 // buildFromClause generates the FROM part of the query
 func (qb *QueryBuilder) buildFromClause(plan *planner.QueryPlan) string {
 	return fmt.Sprintf("FROM %s %s", plan.RootModel.Table, plan.RootModel.Alias)
 }
 
-// buildWhereClause generates the WHERE part of the query
-func (qb *QueryBuilder) buildWhereClause(filterExpr planner.FilterExpr) (string, error) {
-	filterSQL, err := qb.buildFilterExpression(filterExpr)
-	if err != nil {
-		return "", err
+// buildPlanWhereClause builds the WHERE clause from the plan's id (update/delete only) or filters,
+// excluding soft-deleted rows. It returns an empty string when there are no conditions.
+func (qb *QueryBuilder) buildPlanWhereClause(plan *planner.QueryPlan) (string, error) {
+	var conditions []string
+
+	if plan.ID != nil && (plan.Operation == dsl.OpUpdate || plan.Operation == dsl.OpDelete) {
+		qb.paramCount++
+		conditions = append(conditions, fmt.Sprintf("%s.%s = $%d", plan.RootModel.Alias, plan.RootModel.PrimaryKey.ColumnName, qb.paramCount))
+		qb.params = append(qb.params, plan.ID)
+	} else if plan.Filters != nil {
+		filterSQL, err := qb.buildFilterExpression(plan.Filters)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, filterSQL)
+	}
+
+	if plan.SoftDelete != nil {
+		conditions = append(conditions, fmt.Sprintf("%s.%s IS NULL", plan.SoftDelete.TableAlias, plan.SoftDelete.ColumnName))
+	}
+
+	// A stale expected version matches no row, which the caller reports as a conflict
+	if plan.Version != nil && plan.ExpectedVersion != nil {
+		qb.paramCount++
+		conditions = append(conditions, fmt.Sprintf("%s.%s = $%d", plan.Version.TableAlias, plan.Version.ColumnName, qb.paramCount))
+		qb.params = append(qb.params, plan.ExpectedVersion)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
 	}
-	return "WHERE " + filterSQL, nil
+	return "WHERE " + strings.Join(conditions, " AND "), nil
 }
 
 // buildFilterExpression recursively builds filter expressions
@@ -287,6 +562,12 @@ func (qb *QueryBuilder) buildFilterExpression(expr planner.FilterExpr) (string,
 	case *planner.LogicalFilterIR:
 		return qb.buildLogicalFilter(e)
 
+	case *planner.SearchFilterIR:
+		return qb.buildSearchFilter(e), nil
+
+	case *planner.ElemMatchFilterIR:
+		return "", fmt.Errorf("elem_match filters are not supported by PostgreSQL")
+
 	default:
 		return "", fmt.Errorf("unknown filter expression type")
 	}
@@ -296,16 +577,27 @@ func (qb *QueryBuilder) buildFilterExpression(expr planner.FilterExpr) (string,
 func (qb *QueryBuilder) buildComparisonFilter(f *planner.ComparisonFilterIR) (string, error) {
 	colName := fmt.Sprintf("%s.%s", f.Left.TableAlias, f.Left.ColumnName)
 
+	if f.Right != nil {
+		return buildColumnComparison(colName, f)
+	}
+	if f.Subquery != nil {
+		return qb.buildSubqueryFilter(colName, f)
+	}
+
+	if f.Left.CaseInsensitive {
+		if sql, ok, err := qb.buildCaseInsensitiveFilter(colName, f); ok {
+			return sql, err
+		}
+	}
+
 	switch f.Operator {
 	case dsl.OpEqual:
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for = operator")
 		}
-		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		paramPlaceholder := fmt.Sprintf("$%d", qb.paramCount)
-		if needsTypeCasting(f.Left.DataType) {
-			paramPlaceholder = addTypeCast(paramPlaceholder, f.Left.DataType)
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
 		}
 		return fmt.Sprintf("%s = %s", colName, paramPlaceholder), nil
 
@@ -313,11 +605,9 @@ func (qb *QueryBuilder) buildComparisonFilter(f *planner.ComparisonFilterIR) (st
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for != operator")
 		}
-		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		paramPlaceholder := fmt.Sprintf("$%d", qb.paramCount)
-		if needsTypeCasting(f.Left.DataType) {
-			paramPlaceholder = addTypeCast(paramPlaceholder, f.Left.DataType)
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
 		}
 		return fmt.Sprintf("%s != %s", colName, paramPlaceholder), nil
 
@@ -325,38 +615,49 @@ func (qb *QueryBuilder) buildComparisonFilter(f *planner.ComparisonFilterIR) (st
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for > operator")
 		}
-		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		return fmt.Sprintf("%s > $%d", colName, qb.paramCount), nil
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s > %s", colName, paramPlaceholder), nil
 
 	case dsl.OpGTE:
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for >= operator")
 		}
-		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		return fmt.Sprintf("%s >= $%d", colName, qb.paramCount), nil
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s >= %s", colName, paramPlaceholder), nil
 
 	case dsl.OpLT:
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for < operator")
 		}
-		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		return fmt.Sprintf("%s < $%d", colName, qb.paramCount), nil
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s < %s", colName, paramPlaceholder), nil
 
 	case dsl.OpLTE:
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for <= operator")
 		}
-		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		return fmt.Sprintf("%s <= $%d", colName, qb.paramCount), nil
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s <= %s", colName, paramPlaceholder), nil
 
 	case dsl.OpIn:
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for in operator")
 		}
+		if elems, ok := sliceValues(f.Value.Value); ok {
+			return qb.buildInList(colName, "IN", elems, f.Left)
+		}
 		qb.paramCount++
 		qb.params = append(qb.params, f.Value.Value)
 		paramPlaceholder := fmt.Sprintf("$%d", qb.paramCount)
@@ -369,6 +670,9 @@ func (qb *QueryBuilder) buildComparisonFilter(f *planner.ComparisonFilterIR) (st
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for not_in operator")
 		}
+		if elems, ok := sliceValues(f.Value.Value); ok {
+			return qb.buildInList(colName, "NOT IN", elems, f.Left)
+		}
 		qb.paramCount++
 		qb.params = append(qb.params, f.Value.Value)
 		paramPlaceholder := fmt.Sprintf("$%d", qb.paramCount)
@@ -377,6 +681,16 @@ func (qb *QueryBuilder) buildComparisonFilter(f *planner.ComparisonFilterIR) (st
 		}
 		return fmt.Sprintf("%s != ALL(%s)", colName, paramPlaceholder), nil
 
+	case dsl.OpNullSafeEqual:
+		if f.Value == nil || f.Value.Value == nil {
+			return fmt.Sprintf("%s IS NULL", colName), nil
+		}
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s IS NOT DISTINCT FROM %s", colName, paramPlaceholder), nil
+
 	case dsl.OpIsNull:
 		return fmt.Sprintf("%s IS NULL", colName), nil
 
@@ -423,35 +737,263 @@ func (qb *QueryBuilder) buildComparisonFilter(f *planner.ComparisonFilterIR) (st
 		qb.params = append(qb.params, "%"+f.Value.Value.(string)+"%")
 		return fmt.Sprintf("%s LIKE $%d", colName, qb.paramCount), nil
 
+	case dsl.OpRegex, dsl.OpIRegex:
+		if f.Value == nil {
+			return "", fmt.Errorf("value required for %s operator", f.Operator)
+		}
+		// Case-insensitive fields match regexes ignoring case as well
+		sqlOp := "~"
+		if f.Operator == dsl.OpIRegex || f.Left.CaseInsensitive {
+			sqlOp = "~*"
+		}
+		qb.paramCount++
+		qb.params = append(qb.params, f.Value.Value)
+		return fmt.Sprintf("%s %s $%d", colName, sqlOp, qb.paramCount), nil
+
 	case dsl.OpBetween:
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for between operator")
 		}
+		low, high, err := planner.BetweenBounds(f.Value.Value)
+		if err != nil {
+			return "", err
+		}
+		lowPlaceholder, err := qb.bindValue(low, f.Left)
+		if err != nil {
+			return "", err
+		}
+		highPlaceholder, err := qb.bindValue(high, f.Left)
+		if err != nil {
+			return "", err
+		}
+		if !f.ExcludeLow && !f.ExcludeHigh {
+			return fmt.Sprintf("%s BETWEEN %s AND %s", colName, lowPlaceholder, highPlaceholder), nil
+		}
+		lowOp, highOp := ">=", "<="
+		if f.ExcludeLow {
+			lowOp = ">"
+		}
+		if f.ExcludeHigh {
+			highOp = "<"
+		}
+		return fmt.Sprintf("(%s %s %s AND %s %s %s)", colName, lowOp, lowPlaceholder, colName, highOp, highPlaceholder), nil
+
+	case dsl.OpSize:
+		if f.Value == nil {
+			return "", fmt.Errorf("value required for size operator")
+		}
+		op, length, err := dsl.SizeCondition(f.Value.Value)
+		if err != nil {
+			return "", err
+		}
+		// Null arrays count as empty, as does an empty PostgreSQL array, whose array_length is NULL
+		lengthExpr := fmt.Sprintf("COALESCE(array_length(%s, 1), 0)", colName)
+		if f.Left.DataType == planner.TypeJSON {
+			lengthExpr = fmt.Sprintf("COALESCE(jsonb_array_length(%s), 0)", colName)
+		}
 		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		return fmt.Sprintf("%s BETWEEN $%d AND $%d", colName, qb.paramCount, qb.paramCount+1), nil
+		qb.params = append(qb.params, length)
+		return fmt.Sprintf("%s %s $%d", lengthExpr, op, qb.paramCount), nil
 
 	case dsl.OpBefore:
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for before operator")
 		}
-		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		return fmt.Sprintf("%s < $%d", colName, qb.paramCount), nil
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s < %s", colName, paramPlaceholder), nil
 
 	case dsl.OpAfter:
 		if f.Value == nil {
 			return "", fmt.Errorf("value required for after operator")
 		}
-		qb.paramCount++
-		qb.params = append(qb.params, f.Value.Value)
-		return fmt.Sprintf("%s > $%d", colName, qb.paramCount), nil
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s > %s", colName, paramPlaceholder), nil
 
 	default:
 		return "", fmt.Errorf("unknown operator: %s", f.Operator)
 	}
 }
 
+// columnComparisonOperators maps the operators allowed between two columns to SQL
+var columnComparisonOperators = map[dsl.FilterOperator]string{
+	dsl.OpEqual:    "=",
+	dsl.OpNotEqual: "!=",
+	dsl.OpGT:       ">",
+	dsl.OpGTE:      ">=",
+	dsl.OpLT:       "<",
+	dsl.OpLTE:      "<=",
+	dsl.OpBefore:   "<",
+	dsl.OpAfter:    ">",
+}
+
+// buildColumnComparison compares two columns, e.g. t0.updated_at > t0.created_at. Equality
+// involving a case-insensitive column compares LOWER() of both sides.
+func buildColumnComparison(colName string, f *planner.ComparisonFilterIR) (string, error) {
+	sqlOp, ok := columnComparisonOperators[f.Operator]
+	if !ok {
+		return "", fmt.Errorf("operator %s cannot compare two columns", f.Operator)
+	}
+	rightName := fmt.Sprintf("%s.%s", f.Right.TableAlias, f.Right.ColumnName)
+
+	if (f.Left.CaseInsensitive || f.Right.CaseInsensitive) && (f.Operator == dsl.OpEqual || f.Operator == dsl.OpNotEqual) {
+		return fmt.Sprintf("LOWER(%s) %s LOWER(%s)", colName, sqlOp, rightName), nil
+	}
+	return fmt.Sprintf("%s %s %s", colName, sqlOp, rightName), nil
+}
+
+// buildSubqueryFilter renders an in/not_in filter against another model's rows, e.g.
+// t0.user_id IN (SELECT s0.id FROM users s0 WHERE s0.active = $1). The subquery's parameters
+// continue the outer numbering. NULLs are left out of NOT IN, where one would match no rows.
+func (qb *QueryBuilder) buildSubqueryFilter(colName string, f *planner.ComparisonFilterIR) (string, error) {
+	keyword := "IN"
+	switch f.Operator {
+	case dsl.OpIn:
+	case dsl.OpNotIn:
+		keyword = "NOT IN"
+	default:
+		return "", fmt.Errorf("operator %s cannot be used with a subquery", f.Operator)
+	}
+
+	sub := f.Subquery
+	if len(sub.Select) != 1 {
+		return "", fmt.Errorf("subquery requires exactly one field")
+	}
+	col := sub.Select[0].Column
+	subCol := fmt.Sprintf("%s.%s", col.TableAlias, col.ColumnName)
+
+	parts := []string{"SELECT " + subCol, qb.buildFromClause(sub)}
+	wherePart, err := qb.buildPlanWhereClause(sub)
+	if err != nil {
+		return "", err
+	}
+	if f.Operator == dsl.OpNotIn {
+		if wherePart == "" {
+			wherePart = "WHERE " + subCol + " IS NOT NULL"
+		} else {
+			wherePart += " AND " + subCol + " IS NOT NULL"
+		}
+	}
+	if wherePart != "" {
+		parts = append(parts, wherePart)
+	}
+
+	return fmt.Sprintf("%s %s (%s)", colName, keyword, strings.Join(parts, " ")), nil
+}
+
+// buildCaseInsensitiveFilter builds the case-insensitive form of =, !=, in, not_in and
+// starts_with by comparing LOWER() of both sides. ok is false for other operators.
+func (qb *QueryBuilder) buildCaseInsensitiveFilter(colName string, f *planner.ComparisonFilterIR) (sql string, ok bool, err error) {
+	switch f.Operator {
+	case dsl.OpEqual, dsl.OpNotEqual, dsl.OpIn, dsl.OpNotIn, dsl.OpStartsWith:
+	default:
+		return "", false, nil
+	}
+	if f.Value == nil {
+		return "", true, fmt.Errorf("value required for %s operator", f.Operator)
+	}
+
+	lowerCol := "LOWER(" + colName + ")"
+	switch f.Operator {
+	case dsl.OpEqual, dsl.OpNotEqual:
+		paramPlaceholder, err := qb.bindValue(f.Value.Value, f.Left)
+		if err != nil {
+			return "", true, err
+		}
+		return fmt.Sprintf("%s %s LOWER(%s)", lowerCol, f.Operator, paramPlaceholder), true, nil
+
+	case dsl.OpIn, dsl.OpNotIn:
+		elems, isList := sliceValues(f.Value.Value)
+		if !isList || len(elems) == 0 {
+			return "", true, fmt.Errorf("%s operator requires a non-empty list", f.Operator)
+		}
+		placeholders := make([]string, 0, len(elems))
+		for _, elem := range elems {
+			paramPlaceholder, err := qb.bindValue(elem, f.Left)
+			if err != nil {
+				return "", true, err
+			}
+			placeholders = append(placeholders, "LOWER("+paramPlaceholder+")")
+		}
+		keyword := "IN"
+		if f.Operator == dsl.OpNotIn {
+			keyword = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", lowerCol, keyword, strings.Join(placeholders, ", ")), true, nil
+
+	default: // dsl.OpStartsWith
+		prefix, isString := f.Value.Value.(string)
+		if !isString {
+			return "", true, fmt.Errorf("starts_with operator requires a string value")
+		}
+		qb.paramCount++
+		qb.params = append(qb.params, prefix+"%")
+		return fmt.Sprintf("%s ILIKE $%d", colName, qb.paramCount), true, nil
+	}
+}
+
+// buildInList expands a slice value into an IN (...) list with one parameter per element
+func (qb *QueryBuilder) buildInList(colName, keyword string, elems []interface{}, col planner.ColumnRef) (string, error) {
+	if len(elems) == 0 {
+		return "", fmt.Errorf("%s operator requires a non-empty list", strings.ToLower(keyword))
+	}
+
+	placeholders := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		paramPlaceholder, err := qb.bindValue(elem, col)
+		if err != nil {
+			return "", err
+		}
+		placeholders = append(placeholders, paramPlaceholder)
+	}
+
+	return fmt.Sprintf("%s %s (%s)", colName, keyword, strings.Join(placeholders, ", ")), nil
+}
+
+// bindValue coerces a comparison value to the column's declared type and binds
+// it as the next parameter, returning the (type-cast) placeholder
+func (qb *QueryBuilder) bindValue(value interface{}, col planner.ColumnRef) (string, error) {
+	coerced, err := dsl.CoerceValue(value, string(col.DataType))
+	if err != nil {
+		return "", fmt.Errorf("invalid value for field %s: %w", col.ColumnName, err)
+	}
+
+	qb.paramCount++
+	qb.params = append(qb.params, coerced)
+	paramPlaceholder := fmt.Sprintf("$%d", qb.paramCount)
+	if needsTypeCasting(col.DataType) {
+		paramPlaceholder = addTypeCast(paramPlaceholder, col.DataType)
+	}
+	return paramPlaceholder, nil
+}
+
+// sliceValues returns the elements of a slice or array value.
+// []byte is treated as a scalar since it maps to bytea rather than a list.
+func sliceValues(value interface{}) ([]interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if _, isBytes := value.([]byte); isBytes {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	elems := make([]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		elems[i] = rv.Index(i).Interface()
+	}
+	return elems, true
+}
+
 // buildLogicalFilter builds logical filter expressions (AND/OR/NOT)
 func (qb *QueryBuilder) buildLogicalFilter(f *planner.LogicalFilterIR) (string, error) {
 	if len(f.Nodes) == 0 {
@@ -489,21 +1031,57 @@ func (qb *QueryBuilder) buildLogicalFilter(f *planner.LogicalFilterIR) (string,
 func (qb *QueryBuilder) buildGroupByClause(plan *planner.QueryPlan) string {
 	var groupCols []string
 	for _, groupExpr := range plan.GroupBy {
-		colName := fmt.Sprintf("%s.%s", groupExpr.Column.TableAlias, groupExpr.Column.ColumnName)
-		groupCols = append(groupCols, colName)
+		groupCols = append(groupCols, groupExpression(groupExpr))
 	}
 	return "GROUP BY " + strings.Join(groupCols, ", ")
 }
 
+// groupExpression renders a group key: the column, or date_trunc of it for a time bucket.
+// Granularities come from a fixed list checked by the validator, so they are inlined.
+func groupExpression(g planner.GroupExpr) string {
+	col := fmt.Sprintf("%s.%s", g.Column.TableAlias, g.Column.ColumnName)
+	if g.Granularity == "" {
+		return col
+	}
+	return fmt.Sprintf("date_trunc('%s', %s)", g.Granularity, col)
+}
+
+// buildSearchFilter matches the concatenated search columns against the search text
+func (qb *QueryBuilder) buildSearchFilter(f *planner.SearchFilterIR) string {
+	qb.paramCount++
+	qb.params = append(qb.params, f.Query)
+	return fmt.Sprintf("%s @@ plainto_tsquery($%d)", searchVector(f), qb.paramCount)
+}
+
+// searchVector builds the tsvector expression over a search filter's columns
+func searchVector(f *planner.SearchFilterIR) string {
+	cols := make([]string, len(f.Columns))
+	for i, col := range f.Columns {
+		cols[i] = fmt.Sprintf("%s.%s", col.TableAlias, col.ColumnName)
+	}
+	return fmt.Sprintf("to_tsvector(concat_ws(' ', %s))", strings.Join(cols, ", "))
+}
+
 // buildOrderByClause generates the ORDER BY part of the query
 func (qb *QueryBuilder) buildOrderByClause(plan *planner.QueryPlan) string {
 	var sortCols []string
 	for _, sortExpr := range plan.Sort {
 		var colRef string
-		if sortExpr.Column != nil {
+		if plan.Collation != nil && sortExpr.Column != nil && sortExpr.Column.DataType == planner.TypeString {
+			// An output alias cannot take COLLATE, so renamed fields sort by their column
+			colRef = fmt.Sprintf("%s.%s COLLATE %q", sortExpr.Column.TableAlias, sortExpr.Column.ColumnName, plan.Collation.Locale)
+		} else if sortExpr.Select != nil {
+			colRef = sortExpr.Select.Alias
+		} else if sortExpr.Column != nil {
 			colRef = fmt.Sprintf("%s.%s", sortExpr.Column.TableAlias, sortExpr.Column.ColumnName)
 		} else if sortExpr.Aggregate != nil {
 			colRef = sortExpr.Aggregate.Alias
+		} else if sortExpr.Bucket != nil {
+			colRef = sortExpr.Bucket.Alias
+		} else if sortExpr.Search != nil {
+			qb.paramCount++
+			qb.params = append(qb.params, sortExpr.Search.Query)
+			colRef = fmt.Sprintf("ts_rank(%s, plainto_tsquery($%d))", searchVector(sortExpr.Search), qb.paramCount)
 		}
 
 		direction := "ASC"
@@ -529,8 +1107,28 @@ func (qb *QueryBuilder) buildPaginationClause(plan *planner.QueryPlan) string {
 	return fmt.Sprintf("LIMIT $%d OFFSET $%d", limitParam, offsetParam)
 }
 
+// buildLockClause generates the FOR UPDATE / FOR SHARE part of the query
+func (qb *QueryBuilder) buildLockClause(lock *planner.LockClause) string {
+	clause := "FOR " + lock.Strength
+	if lock.SkipLocked {
+		clause += " SKIP LOCKED"
+	} else if lock.NoWait {
+		clause += " NOWAIT"
+	}
+	return clause
+}
+
 // buildAggregateExpression builds an aggregate function expression
 func (qb *QueryBuilder) buildAggregateExpression(agg planner.AggregateExpr) string {
+	return fmt.Sprintf("%s AS %s", aggregateSQL(agg), agg.Alias)
+}
+
+// aggregateSQL renders an aggregate without its alias
+func aggregateSQL(agg planner.AggregateExpr) string {
+	if agg.Expr != nil {
+		return arithmeticSQL(agg.Expr)
+	}
+
 	var aggSQL string
 
 	switch agg.Function {
@@ -553,11 +1151,33 @@ func (qb *QueryBuilder) buildAggregateExpression(agg planner.AggregateExpr) stri
 	case planner.AggMaxFn:
 		aggSQL = fmt.Sprintf("MAX(%s.%s)", agg.Column.TableAlias, agg.Column.ColumnName)
 
+	case planner.AggCountDistinctFn:
+		aggSQL = fmt.Sprintf("COUNT(DISTINCT %s.%s)", agg.Column.TableAlias, agg.Column.ColumnName)
+
 	default:
 		aggSQL = "COUNT(*)"
 	}
 
-	return fmt.Sprintf("%s AS %s", aggSQL, agg.Alias)
+	return aggSQL
+}
+
+// arithmeticSQL renders an aggregate expression with each referenced aggregate inlined, since
+// SELECT aliases cannot be referenced within the same SELECT. Dividends are cast to numeric so
+// integer sums do not truncate, and NULLIF turns a zero divisor into NULL instead of an error.
+func arithmeticSQL(expr *planner.ArithExpr) string {
+	switch {
+	case expr.Aggregate != nil:
+		return aggregateSQL(*expr.Aggregate)
+	case expr.Op == "":
+		if expr.Value < 0 {
+			return "(" + strconv.FormatFloat(expr.Value, 'f', -1, 64) + ")"
+		}
+		return strconv.FormatFloat(expr.Value, 'f', -1, 64)
+	case expr.Op == "/":
+		return fmt.Sprintf("(%s::numeric / NULLIF(%s, 0))", arithmeticSQL(expr.Left), arithmeticSQL(expr.Right))
+	default:
+		return fmt.Sprintf("(%s %s %s)", arithmeticSQL(expr.Left), expr.Op, arithmeticSQL(expr.Right))
+	}
 }
 
 // NewQueryBuilder creates a new query builder
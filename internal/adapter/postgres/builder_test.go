@@ -1,6 +1,8 @@
 package postgres
 
 import (
+	"encoding/json"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -48,7 +50,8 @@ func TestBuildQuery_SimpleSelect(t *testing.T) {
 	}
 
 	builder := NewQueryBuilder()
-	sql, _, err := builder.BuildQuery(plan)
+	query, _, err := builder.BuildQuery(plan)
+	sql, _ := query.(string)
 
 	if err != nil {
 		t.Errorf("BuildQuery error: %v", err)
@@ -87,7 +90,8 @@ func TestBuildQuery_WithFilter(t *testing.T) {
 	}
 
 	builder := NewQueryBuilder()
-	sql, params, err := builder.BuildQuery(plan)
+	query, params, err := builder.BuildQuery(plan)
+	sql, _ := query.(string)
 
 	if err != nil {
 		t.Errorf("BuildQuery error: %v", err)
@@ -115,9 +119,9 @@ func TestBuildQuery_WithLogicalFilter(t *testing.T) {
 	dslQuery := &dsl.Query{
 		Model: "orders",
 		Filters: &dsl.LogicalFilter{
-			And: []*dsl.ComparisonFilter{
-				{Field: "status", Op: dsl.OpEqual, Value: "PAID"},
-				{Field: "amount", Op: dsl.OpGT, Value: 1000},
+			And: []dsl.FilterExpr{
+				&dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"},
+				&dsl.ComparisonFilter{Field: "amount", Op: dsl.OpGT, Value: 1000},
 			},
 		},
 	}
@@ -128,7 +132,8 @@ func TestBuildQuery_WithLogicalFilter(t *testing.T) {
 	}
 
 	builder := NewQueryBuilder()
-	sql, params, err := builder.BuildQuery(plan)
+	query, params, err := builder.BuildQuery(plan)
+	sql, _ := query.(string)
 
 	if err != nil {
 		t.Errorf("BuildQuery error: %v", err)
@@ -162,7 +167,8 @@ func TestBuildQuery_WithGroupByAndAggregate(t *testing.T) {
 	}
 
 	builder := NewQueryBuilder()
-	sql, params, err := builder.BuildQuery(plan)
+	query, params, err := builder.BuildQuery(plan)
+	sql, _ := query.(string)
 
 	if err != nil {
 		t.Errorf("BuildQuery error: %v", err)
@@ -186,6 +192,233 @@ func TestBuildQuery_WithGroupByAndAggregate(t *testing.T) {
 	}
 }
 
+func TestBuildQuery_CountGroupByStatus(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:      "orders",
+		GroupBy:    []string{"status"},
+		Aggregates: []dsl.Aggregate{{Function: dsl.AggCount, Alias: "order_count"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	query, params, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	want := "SELECT t0.status, COUNT(*) AS order_count FROM orders t0 GROUP BY t0.status LIMIT $1 OFFSET $2;"
+	if query != want {
+		t.Errorf("Expected SQL:\n%s\ngot:\n%s", want, query)
+	}
+	if len(params) != 2 || params[0] != 100 || params[1] != 0 {
+		t.Errorf("Expected default limit/offset params, got %v", params)
+	}
+}
+
+func TestBuildQuery_CountDistinct(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:      "orders",
+		GroupBy:    []string{"status"},
+		Aggregates: []dsl.Aggregate{{Function: dsl.AggCountDistinct, Field: "user_id", Alias: "buyers"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	want := "SELECT t0.status, COUNT(DISTINCT t0.user_id) AS buyers FROM orders t0 GROUP BY t0.status"
+	if !strings.Contains(sql, want) {
+		t.Errorf("Expected SQL containing %q, got %s", want, sql)
+	}
+}
+
+func TestBuildQuery_AggregateRatio(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "orders",
+		GroupBy: []string{"status"},
+		Aggregates: []dsl.Aggregate{
+			{Alias: "avg_pct", Expr: "revenue / order_count * 100"},
+			{Function: dsl.AggSum, Field: "amount", Alias: "revenue"},
+			{Function: dsl.AggCount, Alias: "order_count"},
+			{Alias: "adjusted", Expr: "(revenue - -5) / (order_count + 1)"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	want := "SELECT t0.status, ((SUM(t0.amount)::numeric / NULLIF(COUNT(*), 0)) * 100) AS avg_pct, SUM(t0.amount) AS revenue, COUNT(*) AS order_count, " +
+		"((SUM(t0.amount) - (-5))::numeric / NULLIF((COUNT(*) + 1), 0)) AS adjusted FROM orders t0 GROUP BY t0.status"
+	if !strings.Contains(sql, want) {
+		t.Errorf("Expected SQL containing %q, got %s", want, sql)
+	}
+}
+
+func TestBuildQuery_GroupByTime(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+	count := []dsl.Aggregate{{Function: dsl.AggCount, Alias: "order_count"}}
+
+	tests := []struct {
+		name   string
+		bucket dsl.TimeBucket
+		want   string
+	}{
+		{
+			"daily",
+			dsl.TimeBucket{Field: "created_at", Granularity: "day", Alias: "day"},
+			"SELECT date_trunc('day', t0.created_at) AS day, COUNT(*) AS order_count FROM orders t0 GROUP BY date_trunc('day', t0.created_at) ORDER BY day ASC LIMIT",
+		},
+		{
+			"monthly",
+			dsl.TimeBucket{Field: "created_at", Granularity: "month"},
+			"SELECT date_trunc('month', t0.created_at) AS created_at, COUNT(*) AS order_count FROM orders t0 GROUP BY date_trunc('month', t0.created_at) ORDER BY created_at ASC LIMIT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{
+				Model:       "orders",
+				GroupByTime: []dsl.TimeBucket{tt.bucket},
+				Aggregates:  count,
+				Sort:        []dsl.Sort{{Field: tt.bucket.Name()}},
+			})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if sql, _ := query.(string); !strings.Contains(sql, tt.want) {
+				t.Errorf("Expected SQL containing %q, got %s", tt.want, sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_SelectWithGroupBy(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+	count := []dsl.Aggregate{{Function: dsl.AggCount, Alias: "order_count"}}
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "orders", Fields: []string{"status"}, GroupBy: []string{"status", "user_id"}, Aggregates: count})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	want := "SELECT t0.status, t0.user_id, COUNT(*) AS order_count FROM orders t0 GROUP BY t0.status, t0.user_id"
+	if !strings.Contains(sql, want) {
+		t.Errorf("Expected SQL containing %q, got %s", want, sql)
+	}
+
+	if _, err := queryPlanner.PlanQuery(&dsl.Query{Model: "orders", Fields: []string{"status", "amount"}, GroupBy: []string{"status"}, Aggregates: count}); err == nil {
+		t.Errorf("Expected error for selected column that is neither grouped nor aggregated")
+	}
+}
+
+func TestBuildQuery_SortByAggregateAlias(t *testing.T) {
+	reg := setupTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	dslQuery := &dsl.Query{
+		Model:   "orders",
+		GroupBy: []string{"status"},
+		Aggregates: []dsl.Aggregate{
+			{Function: dsl.AggSum, Field: "amount", Alias: "total_amount"},
+		},
+		Sort: []dsl.Sort{
+			{Field: "total_amount", Direction: dsl.SortDesc},
+			{Field: "status", Direction: dsl.SortAsc},
+		},
+	}
+
+	plan, err := queryPlanner.PlanQuery(dslQuery)
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	if !strings.Contains(sql, "ORDER BY total_amount DESC, t0.status ASC") {
+		t.Errorf("SQL missing ORDER BY on alias: %s", sql)
+	}
+}
+
+func TestBuildQuery_SortByFieldAlias(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	tests := []struct {
+		name  string
+		query *dsl.Query
+		want  string
+	}{
+		{
+			"select alias",
+			&dsl.Query{
+				Model:        "orders",
+				Fields:       []string{"id", "amount"},
+				FieldAliases: map[string]string{"amount": "total"},
+				Sort:         []dsl.Sort{{Field: "total", Direction: dsl.SortDesc}, {Field: "id"}},
+			},
+			"SELECT t0.id, t0.amount AS total FROM orders t0 ORDER BY total DESC, t0.id ASC",
+		},
+		{
+			"select and aggregate aliases",
+			&dsl.Query{
+				Model:        "orders",
+				Fields:       []string{"status"},
+				FieldAliases: map[string]string{"status": "state"},
+				GroupBy:      []string{"status"},
+				Aggregates:   []dsl.Aggregate{{Function: dsl.AggSum, Field: "amount", Alias: "total_amount"}},
+				Sort:         []dsl.Sort{{Field: "total_amount", Direction: dsl.SortDesc}, {Field: "state"}},
+			},
+			"SELECT t0.status AS state, SUM(t0.amount) AS total_amount FROM orders t0 GROUP BY t0.status ORDER BY total_amount DESC, state ASC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if sql, _ := query.(string); !strings.Contains(sql, tt.want) {
+				t.Errorf("Expected SQL containing %q, got %s", tt.want, sql)
+			}
+		})
+	}
+}
+
 func TestBuildQuery_WithSort(t *testing.T) {
 	reg := setupTestRegistry()
 	queryPlanner := planner.NewPlanner(reg)
@@ -204,7 +437,8 @@ func TestBuildQuery_WithSort(t *testing.T) {
 	}
 
 	builder := NewQueryBuilder()
-	sql, _, err := builder.BuildQuery(plan)
+	query, _, err := builder.BuildQuery(plan)
+	sql, _ := query.(string)
 
 	if err != nil {
 		t.Errorf("BuildQuery error: %v", err)
@@ -239,7 +473,8 @@ func TestBuildQuery_WithPagination(t *testing.T) {
 	}
 
 	builder := NewQueryBuilder()
-	sql, params, err := builder.BuildQuery(plan)
+	query, params, err := builder.BuildQuery(plan)
+	sql, _ := query.(string)
 
 	if err != nil {
 		t.Errorf("BuildQuery error: %v", err)
@@ -305,7 +540,8 @@ func TestBuildQuery_FilterOperators(t *testing.T) {
 			}
 
 			builder := NewQueryBuilder()
-			sql, _, err := builder.BuildQuery(plan)
+			query, _, err := builder.BuildQuery(plan)
+			sql, _ := query.(string)
 
 			if err != nil {
 				t.Errorf("BuildQuery error: %v", err)
@@ -327,9 +563,9 @@ func TestBuildQuery_ComplexQuery(t *testing.T) {
 		Fields:  []string{"status"},
 		GroupBy: []string{"status"},
 		Filters: &dsl.LogicalFilter{
-			And: []*dsl.ComparisonFilter{
-				{Field: "created_at", Op: dsl.OpAfter, Value: "2024-01-01"},
-				{Field: "amount", Op: dsl.OpGTE, Value: 100},
+			And: []dsl.FilterExpr{
+				&dsl.ComparisonFilter{Field: "created_at", Op: dsl.OpAfter, Value: "2024-01-01"},
+				&dsl.ComparisonFilter{Field: "amount", Op: dsl.OpGTE, Value: 100},
 			},
 		},
 		Aggregates: []dsl.Aggregate{
@@ -351,7 +587,8 @@ func TestBuildQuery_ComplexQuery(t *testing.T) {
 	}
 
 	builder := NewQueryBuilder()
-	sql, params, err := builder.BuildQuery(plan)
+	query, params, err := builder.BuildQuery(plan)
+	sql, _ := query.(string)
 
 	if err != nil {
 		t.Errorf("BuildQuery error: %v", err)
@@ -411,7 +648,8 @@ func TestBuildQuery_StringOperators(t *testing.T) {
 			}
 
 			builder := NewQueryBuilder()
-			sql, params, err := builder.BuildQuery(plan)
+			query, params, err := builder.BuildQuery(plan)
+			sql, _ := query.(string)
 
 			if err != nil {
 				t.Errorf("BuildQuery error: %v", err)
@@ -448,3 +686,1740 @@ func TestNewQueryBuilder(t *testing.T) {
 		t.Errorf("Initial paramCount should be 0")
 	}
 }
+
+func TestBuildQuery_InWithSlices(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     string
+		op        dsl.FilterOperator
+		value     interface{}
+		expected  string
+		numParams int
+	}{
+		{"integer in", "user_id", dsl.OpIn, []interface{}{1, 2, 3}, "t0.user_id IN ($1, $2, $3)", 5},
+		{"string in", "status", dsl.OpIn, []string{"PAID", "PENDING"}, "t0.status IN ($1, $2)", 4},
+		{"integer not_in", "user_id", dsl.OpNotIn, []int{7, 8}, "t0.user_id NOT IN ($1, $2)", 4},
+		{"string not_in", "status", dsl.OpNotIn, []interface{}{"CANCELLED"}, "t0.status NOT IN ($1)", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := setupTestRegistry()
+			queryPlanner := planner.NewPlanner(reg)
+
+			dslQuery := &dsl.Query{
+				Model: "orders",
+				Filters: &dsl.ComparisonFilter{
+					Field: tt.field,
+					Op:    tt.op,
+					Value: tt.value,
+				},
+			}
+
+			plan, err := queryPlanner.PlanQuery(dslQuery)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+
+			builder := NewQueryBuilder()
+			query, params, err := builder.BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			sql, _ := query.(string)
+
+			if !strings.Contains(sql, tt.expected) {
+				t.Errorf("SQL missing %q: %s", tt.expected, sql)
+			}
+			if len(params) != tt.numParams { // one per element, plus limit, offset
+				t.Errorf("Expected %d params, got %d", tt.numParams, len(params))
+			}
+		})
+	}
+}
+
+func TestBuildQuery_InWithEmptySlice(t *testing.T) {
+	reg := setupTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	dslQuery := &dsl.Query{
+		Model: "orders",
+		Filters: &dsl.ComparisonFilter{
+			Field: "status",
+			Op:    dsl.OpIn,
+			Value: []interface{}{},
+		},
+	}
+
+	plan, err := queryPlanner.PlanQuery(dslQuery)
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	if _, _, err := builder.BuildQuery(plan); err == nil {
+		t.Error("Expected error for empty in list")
+	}
+}
+
+func TestBuildQuery_Between(t *testing.T) {
+	reg := setupTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	dslQuery := &dsl.Query{
+		Model: "orders",
+		Filters: &dsl.ComparisonFilter{
+			Field: "amount",
+			Op:    dsl.OpBetween,
+			Value: []interface{}{"10", "100.5"},
+		},
+	}
+
+	plan, err := queryPlanner.PlanQuery(dslQuery)
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, params, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	if !strings.Contains(sql, "t0.amount BETWEEN $1 AND $2") {
+		t.Errorf("SQL missing BETWEEN clause: %s", sql)
+	}
+	if len(params) != 4 { // low, high, limit, offset
+		t.Errorf("Expected 4 params, got %d", len(params))
+	}
+	if params[0] != json.Number("10") || params[1] != json.Number("100.5") { // decimal bounds are validated but kept as written
+		t.Errorf("Expected bounds [10 100.5], got %#v", params[:2])
+	}
+}
+
+func TestBuildQuery_BetweenInvalidValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"scalar", 10},
+		{"one element", []interface{}{10}},
+		{"three elements", []interface{}{10, 20, 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := setupTestRegistry()
+			queryPlanner := planner.NewPlanner(reg)
+
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{
+				Model: "orders",
+				Filters: &dsl.ComparisonFilter{
+					Field: "amount",
+					Op:    dsl.OpBetween,
+					Value: tt.value,
+				},
+			})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+
+			builder := NewQueryBuilder()
+			if _, _, err := builder.BuildQuery(plan); err == nil {
+				t.Error("Expected error for invalid between value")
+			}
+		})
+	}
+}
+
+func TestBuildQuery_SchemaQualifiedTable(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "tenant_a_orders",
+				Table:      "tenant_a.orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "tenant_a_orders"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, _, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	if !strings.Contains(sql, "FROM tenant_a.orders t0") {
+		t.Errorf("SQL missing schema-qualified table: %s", sql)
+	}
+}
+
+func TestBuildQuery_HiddenFields(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "email", Type: "string", Nullable: false},
+					{Name: "password_hash", Type: "string", Nullable: false},
+				},
+				HiddenFields: []string{"password_hash"},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name     string
+		query    *dsl.Query
+		expected string
+	}{
+		{"select expands star", &dsl.Query{Model: "users"}, "SELECT t0.id, t0.email FROM users t0"},
+		{"insert returning", &dsl.Query{Operation: dsl.OpCreate, Model: "users", Data: map[string]interface{}{"email": "a@b.c"}}, "RETURNING id, email;"},
+		{"update returning", &dsl.Query{Operation: dsl.OpUpdate, Model: "users", ID: 1, Data: map[string]interface{}{"email": "a@b.c"}}, "RETURNING id, email;"},
+		{"insert explicit returning", &dsl.Query{Operation: dsl.OpCreate, Model: "users", Data: map[string]interface{}{"email": "a@b.c"}, Returning: []string{"email"}}, "RETURNING email;"},
+		{"update explicit returning", &dsl.Query{Operation: dsl.OpUpdate, Model: "users", ID: 1, Data: map[string]interface{}{"email": "a@b.c"}, Returning: []string{"id"}}, "RETURNING id;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+
+			builder := NewQueryBuilder()
+			query, _, err := builder.BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			sql, _ := query.(string)
+
+			if !strings.Contains(sql, tt.expected) {
+				t.Errorf("SQL missing %q: %s", tt.expected, sql)
+			}
+			if strings.Contains(sql, "password_hash") || strings.Contains(sql, "*") {
+				t.Errorf("SQL should not expose hidden fields: %s", sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_SoftDelete(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "status", Type: "string", Nullable: false},
+					{Name: "deleted_at", Type: "timestamp", Nullable: true},
+				},
+				SoftDeleteField: "deleted_at",
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name     string
+		query    *dsl.Query
+		expected string
+	}{
+		{"select excludes deleted", &dsl.Query{Model: "orders"}, "SELECT t0.id, t0.status, t0.deleted_at FROM orders t0 WHERE t0.deleted_at IS NULL"},
+		{"select with filter", &dsl.Query{Model: "orders", Filters: &dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"}}, "WHERE t0.status = $1 AND t0.deleted_at IS NULL"},
+		{"select including deleted", &dsl.Query{Model: "orders", IncludeDeleted: true}, "SELECT t0.id, t0.status, t0.deleted_at FROM orders t0 LIMIT"},
+		{"count excludes deleted", &dsl.Query{Operation: dsl.OpCount, Model: "orders"}, "FROM orders t0 WHERE t0.deleted_at IS NULL;"},
+		{"exists excludes deleted", &dsl.Query{Operation: dsl.OpExists, Model: "orders"}, "FROM orders t0 WHERE t0.deleted_at IS NULL) AS exists;"},
+		{"delete becomes update", &dsl.Query{Operation: dsl.OpDelete, Model: "orders", ID: 1}, "UPDATE orders t0 SET deleted_at = now() WHERE t0.id = $1 AND t0.deleted_at IS NULL;"},
+		{"update by id", &dsl.Query{Operation: dsl.OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}}, "UPDATE orders t0 SET status = $1 WHERE t0.id = $2 AND t0.deleted_at IS NULL"},
+		{"full-table delete", &dsl.Query{Operation: dsl.OpDelete, Model: "orders", AllowFullTable: true}, "UPDATE orders t0 SET deleted_at = now() WHERE t0.deleted_at IS NULL;"},
+		{"delete returning", &dsl.Query{Operation: dsl.OpDelete, Model: "orders", ID: 1, Returning: []string{"id"}}, "WHERE t0.id = $1 AND t0.deleted_at IS NULL RETURNING id;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+
+			builder := NewQueryBuilder()
+			query, _, err := builder.BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			sql, _ := query.(string)
+
+			if !strings.Contains(sql, tt.expected) {
+				t.Errorf("SQL missing %q: %s", tt.expected, sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_AutoTimestamps(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "status", Type: "string", Nullable: false},
+					{Name: "created_at", Type: "timestamp", Nullable: false},
+					{Name: "updated_at", Type: "timestamp", Nullable: false},
+				},
+				CreatedAtField: "created_at",
+				UpdatedAtField: "updated_at",
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name        string
+		query       *dsl.Query
+		contains    []string
+		notContains string
+		wantParams  int
+	}{
+		{
+			name:       "insert sets both",
+			query:      &dsl.Query{Operation: dsl.OpCreate, Model: "orders", Data: map[string]interface{}{"status": "NEW"}},
+			contains:   []string{"INSERT INTO orders (status, created_at, updated_at) VALUES ($1, now(), now())"},
+			wantParams: 1,
+		},
+		{
+			name:        "update sets updated_at only",
+			query:       &dsl.Query{Operation: dsl.OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}},
+			contains:    []string{"SET status = $1, updated_at = now() WHERE t0.id = $2"},
+			notContains: "created_at",
+			wantParams:  2,
+		},
+		{
+			name:       "client value wins",
+			query:      &dsl.Query{Operation: dsl.OpCreate, Model: "orders", Data: map[string]interface{}{"created_at": "2024-01-01T00:00:00Z"}},
+			contains:   []string{"(created_at, updated_at) VALUES ($1, now())"},
+			wantParams: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+
+			builder := NewQueryBuilder()
+			query, params, err := builder.BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			sql, _ := query.(string)
+
+			for _, want := range tt.contains {
+				if !strings.Contains(sql, want) {
+					t.Errorf("SQL missing %q: %s", want, sql)
+				}
+			}
+			if tt.notContains != "" && strings.Contains(sql, tt.notContains) {
+				t.Errorf("SQL should not contain %q: %s", tt.notContains, sql)
+			}
+			if len(params) != tt.wantParams {
+				t.Errorf("Expected %d params, got %d", tt.wantParams, len(params))
+			}
+		})
+	}
+}
+
+func TestBuildQuery_Search(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "articles",
+				Table:      "articles",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "title", Type: "string", Nullable: false},
+					{Name: "body", Type: "string", Nullable: false},
+				},
+				SearchFields: []string{"title", "body"},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "articles",
+		Filters: &dsl.ComparisonFilter{Op: dsl.OpSearch, Value: "full text"},
+		Sort:    []dsl.Sort{{Field: dsl.SearchScoreField, Direction: dsl.SortDesc}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, params, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	vector := "to_tsvector(concat_ws(' ', t0.title, t0.body))"
+	if !strings.Contains(sql, "WHERE "+vector+" @@ plainto_tsquery($1)") {
+		t.Errorf("SQL missing search condition: %s", sql)
+	}
+	if !strings.Contains(sql, "ORDER BY ts_rank("+vector+", plainto_tsquery($2)) DESC") {
+		t.Errorf("SQL missing rank ordering: %s", sql)
+	}
+	if params[0] != "full text" || params[1] != "full text" {
+		t.Errorf("Expected search text bound for filter and rank, got %v", params)
+	}
+}
+
+func TestBuildQuery_UpdateIncrement(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+	builder := NewQueryBuilder()
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpUpdate,
+		Model:     "orders",
+		ID:        7,
+		Data:      map[string]interface{}{"status": "PAID"},
+		Increment: map[string]interface{}{"amount": 2.5},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, params, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	if !strings.Contains(sql, "SET status = $1, amount = amount + $2 WHERE t0.id = $3") {
+		t.Errorf("SQL missing increment: %s", sql)
+	}
+	if len(params) != 3 || params[1] != 2.5 {
+		t.Errorf("Expected increment amount as second param, got %v", params)
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpUpdate,
+		Model:     "orders",
+		ID:        7,
+		Push:      map[string]interface{}{"status": "PAID"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil {
+		t.Errorf("Expected error for push on PostgreSQL")
+	}
+}
+
+func TestBuildQuery_StripNulls(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+	strip := true
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation:  dsl.OpCreate,
+		Model:      "orders",
+		Data:       map[string]interface{}{"status": "NEW", "amount": nil},
+		StripNulls: &strip,
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, params, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	if !strings.Contains(sql, "INSERT INTO orders (status) VALUES ($1)") {
+		t.Errorf("SQL should omit null columns: %s", sql)
+	}
+	if len(params) != 1 {
+		t.Errorf("Expected 1 param, got %v", params)
+	}
+}
+
+func TestBuildQuery_ValueExprs(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpCreate,
+		Model:     "orders",
+		Data: map[string]interface{}{
+			"status":     map[string]interface{}{"$expr": "default"},
+			"created_at": map[string]interface{}{"$expr": "CURRENT_TIMESTAMP"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, params, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+	if !strings.Contains(sql, "(status, created_at) VALUES (DEFAULT, now())") && !strings.Contains(sql, "(created_at, status) VALUES (now(), DEFAULT)") {
+		t.Errorf("SQL should write expressions unbound: %s", sql)
+	}
+	if len(params) != 0 {
+		t.Errorf("Expected no params, got %v", params)
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpUpdate,
+		Model:     "orders",
+		ID:        7,
+		Data:      map[string]interface{}{"created_at": map[string]interface{}{"$expr": "now()"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, params, _ = NewQueryBuilder().BuildQuery(plan)
+	if sql, _ := query.(string); !strings.Contains(sql, "SET created_at = now() WHERE t0.id = $1") {
+		t.Errorf("SQL should set the expression: %s", sql)
+	}
+	if len(params) != 1 {
+		t.Errorf("Expected only the id param, got %v", params)
+	}
+
+	if _, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpCreate,
+		Model:     "orders",
+		Data:      map[string]interface{}{"status": map[string]interface{}{"$expr": "pg_sleep(10)"}},
+	}); err == nil {
+		t.Error("Expected error for an expression outside the allowlist")
+	}
+}
+
+func TestBuildQuery_ReadOnlyModel(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "daily_sales",
+				Table:      "daily_sales",
+				PrimaryKey: "day",
+				Fields: []config.Field{
+					{Name: "day", Type: "date", Nullable: false},
+					{Name: "total", Type: "decimal", Nullable: true},
+				},
+				ReadOnly: true,
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	for _, q := range []*dsl.Query{
+		{Operation: dsl.OpCreate, Model: "daily_sales", Data: map[string]interface{}{"total": 1.0}},
+		{Operation: dsl.OpUpdate, Model: "daily_sales", ID: "2024-01-01", Data: map[string]interface{}{"total": 1.0}},
+		{Operation: dsl.OpDelete, Model: "daily_sales", ID: "2024-01-01"},
+	} {
+		plan, err := queryPlanner.PlanQuery(q)
+		if err != nil {
+			t.Fatalf("PlanQuery error: %v", err)
+		}
+		if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil || !strings.Contains(err.Error(), "read-only") {
+			t.Errorf("Expected %s on a read-only model to be rejected, got %v", q.Operation, err)
+		}
+	}
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "daily_sales"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err != nil {
+		t.Errorf("Expected selects on a read-only model to build, got %v", err)
+	}
+}
+
+func TestBuildQuery_ForUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		lock     *dsl.Lock
+		expected string
+	}{
+		{"for update", &dsl.Lock{Mode: dsl.LockForUpdate}, "LIMIT $1 OFFSET $2 FOR UPDATE;"},
+		{"for share", &dsl.Lock{Mode: dsl.LockForShare}, "LIMIT $1 OFFSET $2 FOR SHARE;"},
+		{"skip locked", &dsl.Lock{Mode: dsl.LockForUpdate, SkipLocked: true}, "FOR UPDATE SKIP LOCKED;"},
+		{"nowait", &dsl.Lock{Mode: dsl.LockForUpdate, NoWait: true}, "FOR UPDATE NOWAIT;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := setupTestRegistry()
+			queryPlanner := planner.NewPlanner(reg)
+
+			plan, err := queryPlanner.PlanQueryInTransaction(&dsl.Query{Model: "orders", Lock: tt.lock})
+			if err != nil {
+				t.Fatalf("PlanQueryInTransaction error: %v", err)
+			}
+
+			builder := NewQueryBuilder()
+			query, _, err := builder.BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			sql, _ := query.(string)
+
+			if !strings.HasSuffix(sql, tt.expected) {
+				t.Errorf("SQL should end with %q: %s", tt.expected, sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_Count(t *testing.T) {
+	reg := setupTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	dslQuery := &dsl.Query{
+		Operation: dsl.OpCount,
+		Model:     "orders",
+		Filters: &dsl.ComparisonFilter{
+			Field: "status",
+			Op:    dsl.OpEqual,
+			Value: "PAID",
+		},
+	}
+
+	plan, err := queryPlanner.PlanQuery(dslQuery)
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, params, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	expected := "SELECT COUNT(*) AS count FROM orders t0 WHERE t0.status = $1;"
+	if sql != expected {
+		t.Errorf("SQL = %s, want %s", sql, expected)
+	}
+	if len(params) != 1 { // status only, no pagination
+		t.Errorf("Expected 1 param, got %d", len(params))
+	}
+}
+
+func TestBuildQuery_Exists(t *testing.T) {
+	reg := setupTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpExists,
+		Model:     "orders",
+		Filters:   &dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	query, params, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	expected := "SELECT EXISTS(SELECT 1 FROM orders t0 WHERE t0.status = $1) AS exists;"
+	if sql != expected {
+		t.Errorf("SQL = %s, want %s", sql, expected)
+	}
+	if len(params) != 1 || params[0] != "PAID" { // no pagination
+		t.Errorf("Expected params [PAID], got %v", params)
+	}
+}
+
+func TestAddTypeCast_PostgresSpecificTypes(t *testing.T) {
+	tests := []struct {
+		fieldType planner.FieldType
+		want      string
+	}{
+		{planner.TypeInterval, "$1::interval"},
+		{planner.TypeInet, "$1::inet"},
+		{planner.TypeCIDR, "$1::cidr"},
+		{planner.TypeMacAddr, "$1::macaddr"},
+		{planner.TypeXML, "$1::xml"},
+		{planner.TypeTimestamp, "$1::timestamp"},
+		{planner.TypeString, "$1"},
+	}
+
+	for _, tt := range tests {
+		if got := addTypeCast("$1", tt.fieldType); got != tt.want {
+			t.Errorf("addTypeCast($1, %s) = %s, want %s", tt.fieldType, got, tt.want)
+		}
+		if needsTypeCasting(tt.fieldType) != (tt.want != "$1") {
+			t.Errorf("needsTypeCasting(%s) disagrees with addTypeCast", tt.fieldType)
+		}
+	}
+}
+
+func TestBuildQuery_ElemMatchUnsupported(t *testing.T) {
+	plan := &planner.QueryPlan{
+		RootModel: &planner.ModelRef{Name: "orders", Table: "orders", Alias: "t0"},
+		Operation: "select",
+		Filters: &planner.ElemMatchFilterIR{
+			Column:     planner.ColumnRef{TableAlias: "t0", ColumnName: "items"},
+			Conditions: []*planner.ComparisonFilterIR{{Left: planner.ColumnRef{ColumnName: "sku"}, Operator: dsl.OpEqual, Value: &planner.ValueExpr{Value: "A1"}}},
+		},
+	}
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil {
+		t.Error("Expected error for elem_match on PostgreSQL")
+	} else if !strings.Contains(err.Error(), "elem_match") {
+		t.Errorf("Expected elem_match error, got %v", err)
+	}
+}
+
+func TestBuildQuery_Distinct(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpDistinct,
+		Model:     "orders",
+		Fields:    []string{"status"},
+		Filters:   &dsl.ComparisonFilter{Field: "amount", Op: dsl.OpGT, Value: 100},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	query, params, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+
+	want := "SELECT DISTINCT t0.status FROM orders t0 WHERE t0.amount > $1 ORDER BY t0.status;"
+	if query != want {
+		t.Errorf("SQL = %s, want %s", query, want)
+	}
+	if len(params) != 1 {
+		t.Errorf("Expected 1 param, got %v", params)
+	}
+}
+
+func TestBuildQuery_CaseInsensitive(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "email", Type: "string", CaseInsensitive: true},
+				},
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name   string
+		filter *dsl.ComparisonFilter
+		want   string
+		params []interface{}
+	}{
+		{"equal", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpEqual, Value: "Ann@Example.com"}, "WHERE LOWER(t0.email) = LOWER($1)", []interface{}{"Ann@Example.com"}},
+		{"not equal", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpNotEqual, Value: "a@x.io"}, "WHERE LOWER(t0.email) != LOWER($1)", []interface{}{"a@x.io"}},
+		{"in", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpIn, Value: []interface{}{"A@x.io", "b@x.io"}}, "WHERE LOWER(t0.email) IN (LOWER($1), LOWER($2))", []interface{}{"A@x.io", "b@x.io"}},
+		{"not in", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpNotIn, Value: []interface{}{"A@x.io"}}, "WHERE LOWER(t0.email) NOT IN (LOWER($1))", []interface{}{"A@x.io"}},
+		{"starts with", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpStartsWith, Value: "Ann"}, "WHERE t0.email ILIKE $1", []interface{}{"Ann%"}},
+		{"contains unaffected", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpContains, Value: "ann"}, "WHERE t0.email LIKE $1", []interface{}{"%ann%"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpSelect, Model: "users", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, params, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if !strings.Contains(query.(string), tt.want) {
+				t.Errorf("SQL = %s, want it to contain %s", query, tt.want)
+			}
+			// Default pagination appends LIMIT/OFFSET params after the filter's
+			if len(params) < len(tt.params) {
+				t.Fatalf("params = %v, want prefix %v", params, tt.params)
+			}
+			for i := range tt.params {
+				if params[i] != tt.params[i] {
+					t.Errorf("param %d = %v, want %v", i, params[i], tt.params[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildQuery_FullTableGuard(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	tests := []struct {
+		name    string
+		query   *dsl.Query
+		want    string
+		wantErr bool
+	}{
+		{"delete without filters", &dsl.Query{Operation: dsl.OpDelete, Model: "orders"}, "", true},
+		{"update without filters", &dsl.Query{Operation: dsl.OpUpdate, Model: "orders", Data: map[string]interface{}{"status": "PAID"}}, "", true},
+		{"delete confirmed", &dsl.Query{Operation: dsl.OpDelete, Model: "orders", AllowFullTable: true}, "DELETE FROM orders t0 ;", false},
+		{"delete returning ids", &dsl.Query{Operation: dsl.OpDelete, Model: "orders", Filters: &dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"}, Returning: []string{"id"}}, "DELETE FROM orders t0 WHERE t0.status = $1 RETURNING id;", false},
+		{"update confirmed", &dsl.Query{Operation: dsl.OpUpdate, Model: "orders", Data: map[string]interface{}{"status": "PAID"}, AllowFullTable: true}, "UPDATE orders t0 SET status = $1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildQuery error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sql, _ := query.(string); !strings.Contains(sql, tt.want) {
+				t.Errorf("SQL = %s, want it to contain %s", sql, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_ColumnComparison(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "tasks",
+				Table:      "tasks",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "created_at", Type: "timestamp"},
+					{Name: "updated_at", Type: "timestamp"},
+					{Name: "estimate", Type: "integer"},
+					{Name: "spent", Type: "decimal"},
+					{Name: "owner", Type: "string", CaseInsensitive: true},
+					{Name: "reviewer", Type: "string"},
+				},
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name   string
+		filter dsl.FilterExpr
+		want   string
+	}{
+		{"greater than field", &dsl.ComparisonFilter{Field: "updated_at", Op: dsl.OpGT, ValueField: "created_at"}, "WHERE t0.updated_at > t0.created_at LIMIT"},
+		{"after field", &dsl.ComparisonFilter{Field: "updated_at", Op: dsl.OpAfter, ValueField: "created_at"}, "WHERE t0.updated_at > t0.created_at LIMIT"},
+		{"numeric types", &dsl.ComparisonFilter{Field: "spent", Op: dsl.OpLTE, ValueField: "estimate"}, "WHERE t0.spent <= t0.estimate LIMIT"},
+		{"case-insensitive equality", &dsl.ComparisonFilter{Field: "owner", Op: dsl.OpNotEqual, ValueField: "reviewer"}, "WHERE LOWER(t0.owner) != LOWER(t0.reviewer) LIMIT"},
+		{"combined with a value filter", &dsl.LogicalFilter{And: []dsl.FilterExpr{
+			&dsl.ComparisonFilter{Field: "spent", Op: dsl.OpGT, ValueField: "estimate"},
+			&dsl.ComparisonFilter{Field: "estimate", Op: dsl.OpGT, Value: 0},
+		}}, "WHERE (t0.spent > t0.estimate AND t0.estimate > $1) LIMIT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpSelect, Model: "tasks", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if !strings.Contains(query.(string), tt.want) {
+				t.Errorf("SQL = %s, want it to contain %s", query, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_Regex(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "sku", Type: "string"},
+					{Name: "email", Type: "string", CaseInsensitive: true},
+				},
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name   string
+		filter *dsl.ComparisonFilter
+		want   string
+	}{
+		{"anchored regex", &dsl.ComparisonFilter{Field: "sku", Op: dsl.OpRegex, Value: `^AB-[0-9]{4}$`}, "WHERE t0.sku ~ $1 LIMIT"},
+		{"iregex", &dsl.ComparisonFilter{Field: "sku", Op: dsl.OpIRegex, Value: `^ab-`}, "WHERE t0.sku ~* $1 LIMIT"},
+		{"regex on case-insensitive field", &dsl.ComparisonFilter{Field: "email", Op: dsl.OpRegex, Value: `@example\.com$`}, "WHERE t0.email ~* $1 LIMIT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpSelect, Model: "users", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, params, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if !strings.Contains(query.(string), tt.want) {
+				t.Errorf("SQL = %s, want it to contain %s", query, tt.want)
+			}
+			if len(params) == 0 || params[0] != tt.filter.Value {
+				t.Errorf("expected the pattern to be bound unchanged, got %v", params)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_NullSafeEqual(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	tests := []struct {
+		name       string
+		value      interface{}
+		want       string
+		wantParams int
+	}{
+		{"value", "PAID", "WHERE t0.status IS NOT DISTINCT FROM $1 LIMIT $2", 3},
+		{"null", nil, "WHERE t0.status IS NULL LIMIT $1", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &dsl.ComparisonFilter{Field: "status", Op: dsl.OpNullSafeEqual, Value: tt.value}
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "orders", Filters: filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, params, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if !strings.Contains(query.(string), tt.want) {
+				t.Errorf("SQL = %s, want it to contain %s", query, tt.want)
+			}
+			if len(params) != tt.wantParams {
+				t.Errorf("expected %d params, got %v", tt.wantParams, params)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_JSONUpdate(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "accounts",
+				Table:      "accounts",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "settings", Type: "json", Nullable: true},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation:  dsl.OpUpdate,
+		Model:      "accounts",
+		ID:         7,
+		JSONSet:    map[string]interface{}{"settings.theme": "dark"},
+		JSONRemove: []string{"settings.legacy.flag"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, params, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	if !strings.Contains(sql, "SET settings = jsonb_set(COALESCE(settings, '{}'), '{theme}', $1::jsonb) #- '{legacy,flag}' WHERE t0.id = $2") {
+		t.Errorf("SQL missing json update: %s", sql)
+	}
+	if len(params) != 2 || params[0] != `"dark"` {
+		t.Errorf("Expected JSON-encoded value as first param, got %v", params)
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Operation:  dsl.OpUpdate,
+		Model:      "accounts",
+		ID:         7,
+		JSONRemove: []string{"settings.theme"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err = NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	if sql, _ := query.(string); !strings.Contains(sql, "SET settings = settings #- '{theme}' WHERE") {
+		t.Errorf("SQL missing json remove: %s", sql)
+	}
+}
+
+func TestBuildQuery_Subquery(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "user_id", Type: "integer"},
+					{Name: "status", Type: "string"},
+				},
+			},
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "active", Type: "boolean"},
+					{Name: "deleted_at", Type: "timestamp", Nullable: true},
+				},
+				SoftDeleteField: "deleted_at",
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	activeUsers := &dsl.Subquery{Model: "users", Field: "id", Filters: &dsl.ComparisonFilter{Field: "active", Op: dsl.OpEqual, Value: true}}
+	tests := []struct {
+		name       string
+		filter     dsl.FilterExpr
+		want       string
+		wantParams []interface{}
+	}{
+		{
+			"in",
+			&dsl.ComparisonFilter{Field: "user_id", Op: dsl.OpIn, Subquery: activeUsers},
+			"WHERE t0.user_id IN (SELECT s0.id FROM users s0 WHERE s0.active = $1 AND s0.deleted_at IS NULL) LIMIT",
+			[]interface{}{true},
+		},
+		{
+			"not in skips nulls",
+			&dsl.ComparisonFilter{Field: "user_id", Op: dsl.OpNotIn, Subquery: &dsl.Subquery{Model: "users", Field: "id"}},
+			"WHERE t0.user_id NOT IN (SELECT s0.id FROM users s0 WHERE s0.deleted_at IS NULL AND s0.id IS NOT NULL) LIMIT",
+			nil,
+		},
+		{
+			"parameters continue the outer numbering",
+			&dsl.LogicalFilter{And: []dsl.FilterExpr{
+				&dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"},
+				&dsl.ComparisonFilter{Field: "user_id", Op: dsl.OpIn, Subquery: activeUsers},
+				&dsl.ComparisonFilter{Field: "id", Op: dsl.OpGT, Value: 10},
+			}},
+			"WHERE (t0.status = $1 AND t0.user_id IN (SELECT s0.id FROM users s0 WHERE s0.active = $2 AND s0.deleted_at IS NULL) AND t0.id > $3) LIMIT",
+			[]interface{}{"PAID", true, 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpSelect, Model: "orders", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, params, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if !strings.Contains(query.(string), tt.want) {
+				t.Errorf("SQL = %s, want it to contain %s", query, tt.want)
+			}
+			// The outer query adds its LIMIT and OFFSET after the filter parameters
+			if len(params) < len(tt.wantParams) {
+				t.Fatalf("params = %v, want prefix %v", params, tt.wantParams)
+			}
+			for i, want := range tt.wantParams {
+				if params[i] != want {
+					t.Errorf("param %d = %v, want %v", i+1, params[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildQuery_VersionedUpdate(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "status", Type: "string"},
+					{Name: "version", Type: "integer"},
+				},
+				VersionField: "version",
+			},
+		},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Operation:       dsl.OpUpdate,
+		Model:           "orders",
+		ID:              7,
+		Data:            map[string]interface{}{"status": "PAID"},
+		ExpectedVersion: 3,
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, params, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	sql, _ := query.(string)
+
+	if !strings.Contains(sql, "SET status = $1, version = version + 1 WHERE t0.id = $2 AND t0.version = $3") {
+		t.Errorf("SQL missing version check: %s", sql)
+	}
+	if len(params) != 3 || params[2] != 3 {
+		t.Errorf("Expected expected_version as last param, got %v", params)
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Operation: dsl.OpUpdate,
+		Model:     "orders",
+		ID:        7,
+		Data:      map[string]interface{}{"status": "PAID"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	query, _, err = NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	if sql, _ := query.(string); !strings.Contains(sql, "version = version + 1") || strings.Contains(sql, "t0.version =") {
+		t.Errorf("Expected increment without version check: %s", sql)
+	}
+}
+
+func TestBuildQuery_ReturningWithoutHiddenFields(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	tests := []struct {
+		name     string
+		query    *dsl.Query
+		expected string
+	}{
+		{"insert", &dsl.Query{Operation: dsl.OpCreate, Model: "orders", Data: map[string]interface{}{"status": "NEW"}}, "RETURNING *;"},
+		{"update", &dsl.Query{Operation: dsl.OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}}, "RETURNING *;"},
+		{"insert explicit returning", &dsl.Query{Operation: dsl.OpCreate, Model: "orders", Data: map[string]interface{}{"status": "NEW"}, Returning: []string{"id", "status"}}, "RETURNING id, status;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if sql, _ := query.(string); !strings.HasSuffix(sql, tt.expected) {
+				t.Errorf("SQL should end with %q: %s", tt.expected, sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_Collation(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "people",
+				Table:      "people",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "name", Type: "string"},
+				},
+				Collation: &config.Collation{Locale: "de-DE-x-icu"},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name     string
+		query    *dsl.Query
+		expected string
+	}{
+		{"model default", &dsl.Query{Model: "people", Sort: []dsl.Sort{{Field: "name"}, {Field: "id", Direction: dsl.SortDesc}}}, `ORDER BY t0.name COLLATE "de-DE-x-icu" ASC, t0.id DESC`},
+		{"query override", &dsl.Query{Model: "people", Sort: []dsl.Sort{{Field: "name"}}, Collation: &config.Collation{Locale: "sv-SE-x-icu"}}, `ORDER BY t0.name COLLATE "sv-SE-x-icu" ASC`},
+		{"renamed field", &dsl.Query{Model: "people", Fields: []string{"name"}, FieldAliases: map[string]string{"name": "full_name"}, Sort: []dsl.Sort{{Field: "full_name"}}}, `ORDER BY t0.name COLLATE "de-DE-x-icu" ASC`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if sql, _ := query.(string); !strings.Contains(sql, tt.expected) {
+				t.Errorf("SQL missing %q: %s", tt.expected, sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_AggregateOperation(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+	query := func(op dsl.Operation) string {
+		plan, err := queryPlanner.PlanQuery(&dsl.Query{
+			Operation:  op,
+			Model:      "orders",
+			GroupBy:    []string{"status"},
+			Aggregates: []dsl.Aggregate{{Function: dsl.AggSum, Field: "amount", Alias: "total"}},
+		})
+		if err != nil {
+			t.Fatalf("PlanQuery error: %v", err)
+		}
+		q, _, err := NewQueryBuilder().BuildQuery(plan)
+		if err != nil {
+			t.Fatalf("BuildQuery error: %v", err)
+		}
+		sql, _ := q.(string)
+		return sql
+	}
+
+	aggregate := query(dsl.OpAggregate)
+	if !strings.Contains(aggregate, "GROUP BY t0.status") {
+		t.Errorf("SQL missing GROUP BY: %s", aggregate)
+	}
+	if grouped := query(dsl.OpSelect); aggregate != grouped {
+		t.Errorf("Expected aggregate to build the grouped select:\n%s\n%s", aggregate, grouped)
+	}
+}
+
+func TestBuildQuery_DefaultSort(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "status", Type: "string"},
+					{Name: "amount", Type: "decimal"},
+				},
+			},
+			{
+				Name:       "events",
+				Table:      "events",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+				},
+				DisableDefaultSort: true,
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+	page := &dsl.Pagination{Limit: 10, Offset: 20}
+
+	tests := []struct {
+		name      string
+		query     *dsl.Query
+		wantOrder string // Empty when no ORDER BY is expected
+	}{
+		{"paginated without sort", &dsl.Query{Model: "orders", Pagination: page}, "ORDER BY t0.id ASC LIMIT"},
+		{"explicit sort kept", &dsl.Query{Model: "orders", Pagination: page, Sort: []dsl.Sort{{Field: "status"}}}, "ORDER BY t0.status ASC LIMIT"},
+		{"not paginated", &dsl.Query{Model: "orders"}, ""},
+		{"grouped", &dsl.Query{Model: "orders", Pagination: page, GroupBy: []string{"status"}, Aggregates: []dsl.Aggregate{{Function: dsl.AggSum, Field: "amount", Alias: "total"}}}, ""},
+		{"disabled for model", &dsl.Query{Model: "events", Pagination: page}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			sql, _ := query.(string)
+			if tt.wantOrder == "" {
+				if strings.Contains(sql, "ORDER BY") {
+					t.Errorf("SQL should not be ordered: %s", sql)
+				}
+			} else if !strings.Contains(sql, tt.wantOrder) {
+				t.Errorf("SQL missing %q: %s", tt.wantOrder, sql)
+			}
+		})
+	}
+}
+
+// modelNameRef matches a bare model name where only table names belong
+var modelNameRef = regexp.MustCompile(`\b(customers|orders)\b`)
+
+func TestBuildQuery_TableDiffersFromModelName(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "customers",
+				Table:      "tbl_customers_v2",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "email", Type: "string"},
+				},
+			},
+			{
+				Name:       "orders",
+				Table:      "tbl_orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "customer_id", Type: "integer"},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name     string
+		query    *dsl.Query
+		expected string
+	}{
+		{"select", &dsl.Query{Model: "customers"}, "FROM tbl_customers_v2 t0"},
+		{"count", &dsl.Query{Operation: dsl.OpCount, Model: "customers"}, "FROM tbl_customers_v2 t0"},
+		{"exists", &dsl.Query{Operation: dsl.OpExists, Model: "customers"}, "FROM tbl_customers_v2 t0"},
+		{"distinct", &dsl.Query{Operation: dsl.OpDistinct, Model: "customers", Fields: []string{"email"}}, "FROM tbl_customers_v2 t0"},
+		{"create", &dsl.Query{Operation: dsl.OpCreate, Model: "customers", Data: map[string]interface{}{"email": "a@b.c"}}, "INSERT INTO tbl_customers_v2 "},
+		{"update", &dsl.Query{Operation: dsl.OpUpdate, Model: "customers", ID: 1, Data: map[string]interface{}{"email": "a@b.c"}}, "UPDATE tbl_customers_v2 t0"},
+		{"delete", &dsl.Query{Operation: dsl.OpDelete, Model: "customers", ID: 1}, "DELETE FROM tbl_customers_v2 t0"},
+		{"subquery", &dsl.Query{Model: "orders", Filters: &dsl.ComparisonFilter{Field: "customer_id", Op: dsl.OpIn, Subquery: &dsl.Subquery{Model: "customers", Field: "id"}}}, "(SELECT s0.id FROM tbl_customers_v2 s0)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			sql, _ := query.(string)
+			if !strings.Contains(sql, tt.expected) {
+				t.Errorf("SQL missing %q: %s", tt.expected, sql)
+			}
+			if modelNameRef.MatchString(sql) {
+				t.Errorf("SQL references a model name instead of its table: %s", sql)
+			}
+		})
+	}
+}
+
+// camelCaseName matches a camelCase field name where only snake_case columns belong
+var camelCaseName = regexp.MustCompile(`[a-z][A-Z]`)
+
+func TestBuildQuery_CamelCaseFieldNaming(t *testing.T) {
+	cfg := &config.Config{
+		FieldNaming: config.FieldNamingCamel,
+		Models: []config.Model{{
+			Name:           "orders",
+			Table:          "orders",
+			PrimaryKey:     "id",
+			UpdatedAtField: "updated_at",
+			Fields: []config.Field{
+				{Name: "id", Type: "integer"},
+				{Name: "user_id", Type: "integer"},
+				{Name: "line_count", Type: "integer"},
+				{Name: "created_at", Type: "timestamp"},
+				{Name: "updated_at", Type: "timestamp"},
+			},
+		}},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name     string
+		query    *dsl.Query
+		expected string
+	}{
+		{
+			name: "select",
+			query: &dsl.Query{
+				Model:   "orders",
+				Fields:  []string{"id", "createdAt"},
+				Filters: &dsl.ComparisonFilter{Field: "userId", Op: dsl.OpEqual, Value: 7},
+				Sort:    []dsl.Sort{{Field: "createdAt", Direction: dsl.SortDesc}},
+			},
+			expected: "SELECT t0.id, t0.created_at FROM orders t0 WHERE t0.user_id = $1 ORDER BY t0.created_at DESC LIMIT $2 OFFSET $3;",
+		},
+		{
+			name:     "all fields",
+			query:    &dsl.Query{Model: "orders", Pagination: &dsl.Pagination{Limit: 5}},
+			expected: "SELECT t0.id, t0.user_id, t0.line_count, t0.created_at, t0.updated_at FROM orders t0 ORDER BY t0.id ASC",
+		},
+		{
+			name:     "create",
+			query:    &dsl.Query{Operation: dsl.OpCreate, Model: "orders", Data: map[string]interface{}{"userId": 7, "updatedAt": "2026-01-01T00:00:00Z"}},
+			expected: "user_id",
+		},
+		{
+			name:     "increment",
+			query:    &dsl.Query{Operation: dsl.OpUpdate, Model: "orders", ID: 1, Increment: map[string]interface{}{"lineCount": 1}},
+			expected: "SET line_count = line_count + $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			sql, _ := query.(string)
+			if !strings.Contains(sql, tt.expected) {
+				t.Errorf("expected SQL containing %q, got %s", tt.expected, sql)
+			}
+			if camelCaseName.MatchString(sql) {
+				t.Errorf("SQL references a field name instead of its column: %s", sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_FirstLast(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	tests := []struct {
+		op       dsl.Operation
+		expected string
+	}{
+		{dsl.OpFirst, "SELECT t0.id, t0.user_id, t0.status, t0.amount, t0.created_at FROM orders t0 WHERE t0.status = $1 ORDER BY t0.id ASC LIMIT $2 OFFSET $3;"},
+		{dsl.OpLast, "SELECT t0.id, t0.user_id, t0.status, t0.amount, t0.created_at FROM orders t0 WHERE t0.status = $1 ORDER BY t0.id DESC LIMIT $2 OFFSET $3;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.op), func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{
+				Operation: tt.op,
+				Model:     "orders",
+				Filters:   &dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"},
+			})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, params, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if query != tt.expected {
+				t.Errorf("Expected SQL:\n%s\nGot:\n%s", tt.expected, query)
+			}
+			if len(params) != 3 || params[1] != 1 {
+				t.Errorf("Expected a limit of 1, got params %v", params)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_VirtualField(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{{
+			Name:         "people",
+			Table:        "people",
+			PrimaryKey:   "id",
+			HiddenFields: []string{"last_name"},
+			Fields: []config.Field{
+				{Name: "id", Type: "integer"},
+				{Name: "first_name", Type: "string"},
+				{Name: "last_name", Type: "string"},
+			},
+			VirtualFields: []config.VirtualField{
+				{Name: "full_name", Type: "string", SQL: "{first_name} || ' ' || {last_name}"},
+			},
+		}},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name     string
+		query    *dsl.Query
+		expected string
+	}{
+		{
+			name:     "all fields",
+			query:    &dsl.Query{Model: "people"},
+			expected: "SELECT t0.id, t0.first_name, (t0.first_name || ' ' || t0.last_name) AS full_name FROM people t0",
+		},
+		{
+			name:     "selected",
+			query:    &dsl.Query{Model: "people", Fields: []string{"id", "full_name"}},
+			expected: "SELECT t0.id, (t0.first_name || ' ' || t0.last_name) AS full_name FROM people t0",
+		},
+		{
+			name:     "returning",
+			query:    &dsl.Query{Operation: dsl.OpCreate, Model: "people", Data: map[string]interface{}{"first_name": "Ada", "last_name": "Lovelace"}},
+			expected: "RETURNING id, first_name, (first_name || ' ' || last_name) AS full_name;",
+		},
+		{
+			name:     "count",
+			query:    &dsl.Query{Operation: dsl.OpCount, Model: "people"},
+			expected: "SELECT COUNT(*) AS count FROM people t0;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if sql, _ := query.(string); !strings.Contains(sql, tt.expected) {
+				t.Errorf("expected SQL containing %q, got %s", tt.expected, sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_Size(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{{
+			Name:       "posts",
+			Table:      "posts",
+			PrimaryKey: "id",
+			Fields: []config.Field{
+				{Name: "id", Type: "integer"},
+				{Name: "tags", Type: "string", Array: true},
+				{Name: "links", Type: "json"},
+			},
+		}},
+	})
+	queryPlanner := planner.NewPlanner(reg)
+
+	tests := []struct {
+		name     string
+		filter   *dsl.ComparisonFilter
+		expected string
+	}{
+		{"array exact", &dsl.ComparisonFilter{Field: "tags", Op: dsl.OpSize, Value: float64(3)}, "WHERE COALESCE(array_length(t0.tags, 1), 0) = $1"},
+		{"array comparison", &dsl.ComparisonFilter{Field: "tags", Op: dsl.OpSize, Value: map[string]interface{}{">": float64(3)}}, "WHERE COALESCE(array_length(t0.tags, 1), 0) > $1"},
+		{"json comparison", &dsl.ComparisonFilter{Field: "links", Op: dsl.OpSize, Value: map[string]interface{}{"<=": float64(3)}}, "WHERE COALESCE(jsonb_array_length(t0.links), 0) <= $1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{Model: "posts", Filters: tt.filter})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, params, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if sql, _ := query.(string); !strings.Contains(sql, tt.expected) {
+				t.Errorf("expected SQL containing %q, got %s", tt.expected, sql)
+			}
+			if len(params) == 0 || params[0] != int64(3) {
+				t.Errorf("expected the length as first param, got %v", params)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_BetweenBounds(t *testing.T) {
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	tests := []struct {
+		bounds string
+		want   string
+	}{
+		{"[]", "t0.created_at BETWEEN $1::timestamp AND $2::timestamp"},
+		{"[)", "(t0.created_at >= $1::timestamp AND t0.created_at < $2::timestamp)"},
+		{"(]", "(t0.created_at > $1::timestamp AND t0.created_at <= $2::timestamp)"},
+		{"()", "(t0.created_at > $1::timestamp AND t0.created_at < $2::timestamp)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bounds, func(t *testing.T) {
+			plan, err := queryPlanner.PlanQuery(&dsl.Query{
+				Model: "orders",
+				Filters: &dsl.ComparisonFilter{
+					Field:  "created_at",
+					Op:     dsl.OpBetween,
+					Value:  []interface{}{"2024-01-01T00:00:00Z", "2024-02-01T00:00:00Z"},
+					Bounds: tt.bounds,
+				},
+			})
+			if err != nil {
+				t.Fatalf("PlanQuery error: %v", err)
+			}
+			query, _, err := NewQueryBuilder().BuildQuery(plan)
+			if err != nil {
+				t.Fatalf("BuildQuery error: %v", err)
+			}
+			if sql := query.(string); !strings.Contains(sql, tt.want) {
+				t.Errorf("Expected %q in SQL, got %s", tt.want, sql)
+			}
+		})
+	}
+}
+
+func TestBuildQuery_CoercesComparisonValues(t *testing.T) {
+	reg := setupTestRegistry()
+	queryPlanner := planner.NewPlanner(reg)
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "orders",
+		Filters: &dsl.ComparisonFilter{Field: "user_id", Op: dsl.OpEqual, Value: "5"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+
+	builder := NewQueryBuilder()
+	_, params, err := builder.BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	if params[0] != int64(5) {
+		t.Errorf("First param should be int64(5), got %v (%T)", params[0], params[0])
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{
+		Model:   "orders",
+		Filters: &dsl.ComparisonFilter{Field: "user_id", Op: dsl.OpGT, Value: "five"},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	if _, _, err := NewQueryBuilder().BuildQuery(plan); err == nil {
+		t.Errorf("BuildQuery should reject a non-integer value for an integer column")
+	}
+}
+
+func TestBuildQuery_DecimalKeepsPrecision(t *testing.T) {
+	// float64 would round this to 12345678901234568
+	const exact = "12345678901234567.89"
+	queryPlanner := planner.NewPlanner(setupTestRegistry())
+
+	plan, err := queryPlanner.PlanQuery(&dsl.Query{Operation: dsl.OpCreate, Model: "orders", Data: map[string]interface{}{"amount": exact}})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	_, params, err := NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	if len(params) != 1 || params[0] != json.Number(exact) {
+		t.Errorf("Expected the decimal value as written, got %#v", params)
+	}
+
+	plan, err = queryPlanner.PlanQuery(&dsl.Query{Model: "orders", Filters: &dsl.ComparisonFilter{Field: "amount", Op: dsl.OpEqual, Value: exact}})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	_, params, err = NewQueryBuilder().BuildQuery(plan)
+	if err != nil {
+		t.Fatalf("BuildQuery error: %v", err)
+	}
+	if params[0] != json.Number(exact) {
+		t.Errorf("Expected the decimal filter value as written, got %#v", params[0])
+	}
+}
@@ -2,9 +2,16 @@ package postgres
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"udv/internal/adapter"
+	"udv/internal/metrics"
 
 	_ "github.com/lib/pq"
 )
@@ -12,31 +19,122 @@ import (
 // Database wraps a PostgreSQL connection pool
 type Database struct {
 	db *sql.DB
+
+	// statementTimeout bounds each statement server-side; zero leaves the server default
+	statementTimeout time.Duration
+}
+
+// execer is the subset of *sql.DB and *sql.Tx used to run statements
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
 }
 
 // Compile-time assertion that Database implements adapter.Database interface
 var _ adapter.Database = (*Database)(nil)
 
+var _ adapter.Explainer = (*Database)(nil)
+
+var _ adapter.Truncater = (*Database)(nil)
+
+var _ adapter.Estimator = (*Database)(nil)
+
+var _ adapter.Refresher = (*Database)(nil)
+
 // Connect opens a connection to a PostgreSQL database using a DSN
 func Connect(dsn string) (*Database, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to open database %s: %w", adapter.RedactDSN(dsn), adapter.RedactError(err, dsn))
 	}
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to ping database %s: %w", adapter.RedactDSN(dsn), adapter.RedactError(err, dsn))
 	}
 
 	return &Database{db: db}, nil
 }
 
+// DefaultApplicationName identifies the server's connections in pg_stat_activity
+const DefaultApplicationName = "udv-server"
+
+// applicationNameKey matches an application_name setting in a key=value DSN
+var applicationNameKey = regexp.MustCompile(`(^|\s)application_name\s*=`)
+
+// WithApplicationName returns dsn with application_name set to name, so the connections show
+// up under it in pg_stat_activity. Both URL and key=value DSNs are supported; a DSN that
+// already sets application_name, or an empty name, leaves dsn unchanged.
+func WithApplicationName(dsn, name string) (string, error) {
+	if name == "" {
+		return dsn, nil
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid database URL %s: %w", adapter.RedactDSN(dsn), adapter.RedactError(err, dsn))
+		}
+		query := u.Query()
+		if query.Get("application_name") != "" {
+			return dsn, nil
+		}
+		query.Set("application_name", name)
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	if applicationNameKey.MatchString(dsn) {
+		return dsn, nil
+	}
+	quoted := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name)
+	return strings.TrimSpace(dsn + " application_name='" + quoted + "'"), nil
+}
+
+// SetStatementTimeout makes every statement run in a transaction with SET LOCAL statement_timeout,
+// so the server cancels runaway queries even when the client does not. Zero disables it.
+func (d *Database) SetStatementTimeout(timeout time.Duration) {
+	d.statementTimeout = timeout
+}
+
+// statementTimeoutSQL returns the SET LOCAL statement for a timeout, rounded up to whole milliseconds
+func statementTimeoutSQL(timeout time.Duration) string {
+	ms := (timeout + time.Millisecond - 1) / time.Millisecond
+	return fmt.Sprintf("SET LOCAL statement_timeout = %d", ms)
+}
+
+// run calls fn with the pool, or inside a transaction scoped by the statement timeout when one is set
+func (d *Database) run(fn func(execer) error) error {
+	if d.statementTimeout <= 0 {
+		return fn(d.db)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if _, err := tx.Exec(statementTimeoutSQL(d.statementTimeout)); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 // Close closes the database connection
 func (d *Database) Close() error {
 	return d.db.Close()
 }
 
+// DB returns the underlying connection pool, for schema introspection
+func (d *Database) DB() *sql.DB {
+	return d.db
+}
+
 // Ping checks the connection to the database
 func (d *Database) Ping() error {
 	return d.db.Ping()
@@ -59,12 +157,157 @@ func (d *Database) Exec(query interface{}, args ...interface{}) (adapter.ExecRes
 		return nil, fmt.Errorf("expected query to be string, got %T", query)
 	}
 
-	result, err := d.db.Exec(sql, args...)
+	var result PostgresExecResult
+	start := time.Now()
+	err := d.run(func(e execer) error {
+		var execErr error
+		result.result, execErr = e.Exec(sql, args...)
+		return execErr
+	})
+	metrics.ObserveDBCall("postgres", "exec", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("exec failed: %w", err)
 	}
 
-	return &PostgresExecResult{result: result}, nil
+	return &result, nil
+}
+
+// Explain runs query under EXPLAIN (ANALYZE, FORMAT JSON) in a transaction that is always
+// rolled back, so explaining a write measures it without applying it
+func (d *Database) Explain(query interface{}, args ...interface{}) (json.RawMessage, error) {
+	sql, ok := query.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected query to be string, got %T", query)
+	}
+
+	start := time.Now()
+	plan, err := d.explain(sql, args)
+	metrics.ObserveDBCall("postgres", "explain", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("explain failed: %w", err)
+	}
+	return plan, nil
+}
+
+func (d *Database) explain(sql string, args []interface{}) (json.RawMessage, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if d.statementTimeout > 0 {
+		if _, err := tx.Exec(statementTimeoutSQL(d.statementTimeout)); err != nil {
+			return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+
+	var plan []byte
+	if err := tx.QueryRow(explainSQL(sql), args...).Scan(&plan); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(plan), nil
+}
+
+// explainSQL prefixes a built statement with EXPLAIN (ANALYZE, FORMAT JSON)
+func explainSQL(sql string) string {
+	return "EXPLAIN (ANALYZE, FORMAT JSON) " + strings.TrimSuffix(sql, ";")
+}
+
+// Truncate empties table with TRUNCATE ... RESTART IDENTITY and returns how many rows it held.
+// The table is locked before counting, so no row can be written between the count and the truncate.
+func (d *Database) Truncate(table string) (_ int64, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBCall("postgres", "truncate", start, err) }()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if d.statementTimeout > 0 {
+		if _, err := tx.Exec(statementTimeoutSQL(d.statementTimeout)); err != nil {
+			return 0, fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE", table)); err != nil {
+		return 0, fmt.Errorf("failed to lock %s: %w", table, err)
+	}
+	var count int64
+	if err := tx.QueryRow(fmt.Sprintf("SELECT count(*) FROM %s", table)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", table, err)
+	}
+	if _, err := tx.Exec(truncateSQL(table)); err != nil {
+		return 0, fmt.Errorf("failed to truncate %s: %w", table, err)
+	}
+	return count, tx.Commit()
+}
+
+// Refresh recomputes a materialized view. CONCURRENTLY keeps the view readable meanwhile but
+// requires a unique index on it. Tables and plain views are rejected with
+// adapter.ErrNotMaterializedView.
+func (d *Database) Refresh(table string, concurrently bool) (err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBCall("postgres", "refresh", start, err) }()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if d.statementTimeout > 0 {
+		if _, err := tx.Exec(statementTimeoutSQL(d.statementTimeout)); err != nil {
+			return fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+	var kind string
+	err = tx.QueryRow("SELECT relkind FROM pg_class WHERE oid = to_regclass($1)", table).Scan(&kind)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up %s: %w", table, err)
+	}
+	if kind != "m" {
+		return fmt.Errorf("%s: %w", table, adapter.ErrNotMaterializedView)
+	}
+	if _, err := tx.Exec(refreshSQL(table, concurrently)); err != nil {
+		return fmt.Errorf("failed to refresh %s: %w", table, err)
+	}
+	return tx.Commit()
+}
+
+// refreshSQL returns the statement refreshing a materialized view
+func refreshSQL(table string, concurrently bool) string {
+	if concurrently {
+		return fmt.Sprintf("REFRESH MATERIALIZED VIEW CONCURRENTLY %s", table)
+	}
+	return fmt.Sprintf("REFRESH MATERIALIZED VIEW %s", table)
+}
+
+// EstimateCount returns the planner's row estimate for table from pg_class.reltuples. Tables
+// that were never vacuumed or analyzed have no estimate, and neither do missing tables.
+func (d *Database) EstimateCount(table string) (_ int64, _ bool, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBCall("postgres", "estimate", start, err) }()
+
+	var estimate sql.NullFloat64
+	err = d.db.QueryRow("SELECT reltuples FROM pg_class WHERE oid = to_regclass($1)", table).Scan(&estimate)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to estimate rows of %s: %w", table, err)
+	}
+	// reltuples is -1 before the first VACUUM or ANALYZE, or 0 before PostgreSQL 14
+	if !estimate.Valid || estimate.Float64 <= 0 {
+		return 0, false, nil
+	}
+	return int64(estimate.Float64), true, nil
+}
+
+// truncateSQL empties a table and resets the sequences of its identity and serial columns
+func truncateSQL(table string) string {
+	return fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY", table)
 }
 
 // ExecuteQuery executes a query and returns results as []map[string]interface{}
@@ -74,20 +317,41 @@ func (d *Database) ExecuteQuery(query interface{}, args ...interface{}) ([]map[s
 		return nil, fmt.Errorf("expected query to be string, got %T", query)
 	}
 
-	rows, err := d.db.Query(sql, args...)
+	var results []map[string]interface{}
+	start := time.Now()
+	err := d.run(func(e execer) error {
+		rows, queryErr := e.Query(sql, args...)
+		if queryErr != nil {
+			return fmt.Errorf("query execution failed: %w", queryErr)
+		}
+		defer rows.Close()
+
+		var scanErr error
+		results, scanErr = scanRows(rows)
+		return scanErr
+	})
+	metrics.ObserveDBCall("postgres", "query", start, err)
 	if err != nil {
-		return nil, fmt.Errorf("query execution failed: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	// Get column names
+	return results, nil
+}
+
+// scanRows reads all rows into maps keyed by column name
+func scanRows(rows *sql.Rows) ([]map[string]interface{}, error) {
+	// Get column names and database types
 	columns, err := rows.Columns()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column types: %w", err)
+	}
 
-	// Fetch all rows
-	var results []map[string]interface{}
+	// Fetch all rows; an empty result is an empty slice so it encodes as [] rather than null
+	results := []map[string]interface{}{}
 	for rows.Next() {
 		// Create a slice of interface{} to hold the values
 		values := make([]interface{}, len(columns))
@@ -104,13 +368,7 @@ func (d *Database) ExecuteQuery(query interface{}, args ...interface{}) ([]map[s
 		// Convert to map
 		entry := make(map[string]interface{})
 		for i, col := range columns {
-			val := values[i]
-			// Convert []byte to string for better JSON serialization
-			if b, ok := val.([]byte); ok {
-				entry[col] = string(b)
-			} else {
-				entry[col] = val
-			}
+			entry[col] = convertColumnValue(columnTypes[i].DatabaseTypeName(), values[i])
 		}
 		results = append(results, entry)
 	}
@@ -122,6 +380,33 @@ func (d *Database) ExecuteQuery(query interface{}, args ...interface{}) ([]map[s
 	return results, nil
 }
 
+// convertColumnValue gives each column a type-stable JSON representation: numerics become
+// json.Number so no precision is lost, timestamps RFC3339 strings in UTC, dates YYYY-MM-DD, and
+// other driver []byte values strings
+func convertColumnValue(dbType string, val interface{}) interface{} {
+	switch v := val.(type) {
+	case []byte:
+		switch dbType {
+		case "NUMERIC":
+			// NaN and Infinity are not JSON numbers, so they stay strings
+			if json.Valid(v) {
+				return json.Number(v)
+			}
+		case "BOOL":
+			if b, err := strconv.ParseBool(string(v)); err == nil {
+				return b
+			}
+		}
+		return string(v)
+	case time.Time:
+		if dbType == "DATE" {
+			return v.Format("2006-01-02")
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return val
+}
+
 // ExecuteAndFetchRows is kept for backward compatibility
 func (d *Database) ExecuteAndFetchRows(sql string, args ...interface{}) ([]map[string]interface{}, error) {
 	return d.ExecuteQuery(sql, args...)
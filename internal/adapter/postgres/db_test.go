@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStatementTimeoutSQL(t *testing.T) {
+	tests := []struct {
+		timeout  time.Duration
+		expected string
+	}{
+		{30 * time.Second, "SET LOCAL statement_timeout = 30000"},
+		{1500 * time.Microsecond, "SET LOCAL statement_timeout = 2"},
+		{time.Nanosecond, "SET LOCAL statement_timeout = 1"},
+	}
+
+	for _, tt := range tests {
+		if got := statementTimeoutSQL(tt.timeout); got != tt.expected {
+			t.Errorf("statementTimeoutSQL(%s) = %q, want %q", tt.timeout, got, tt.expected)
+		}
+	}
+}
+
+func TestConvertColumnValue(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	tests := []struct {
+		name     string
+		dbType   string
+		value    interface{}
+		expected interface{}
+	}{
+		{"numeric keeps precision", "NUMERIC", []byte("12345678901234567890.123"), json.Number("12345678901234567890.123")},
+		{"numeric NaN stays string", "NUMERIC", []byte("NaN"), "NaN"},
+		{"integer unchanged", "INT8", int64(42), int64(42)},
+		{"boolean unchanged", "BOOL", true, true},
+		{"boolean from text", "BOOL", []byte("t"), true},
+		{"timestamp as RFC3339", "TIMESTAMPTZ", ts, "2024-01-02T03:04:05.6Z"},
+		{"date without time", "DATE", ts, "2024-01-02"},
+		{"text from bytes", "TEXT", []byte("hello"), "hello"},
+		{"null unchanged", "NUMERIC", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertColumnValue(tt.dbType, tt.value)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("convertColumnValue(%s, %v) = %#v, want %#v", tt.dbType, tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExplainSQL(t *testing.T) {
+	got := explainSQL("SELECT t0.id FROM orders t0 WHERE t0.status = $1 LIMIT $2;")
+	want := "EXPLAIN (ANALYZE, FORMAT JSON) SELECT t0.id FROM orders t0 WHERE t0.status = $1 LIMIT $2"
+	if got != want {
+		t.Errorf("explainSQL() = %q, want %q", got, want)
+	}
+}
+
+func TestTruncateSQL(t *testing.T) {
+	if got := truncateSQL("sales.orders"); got != "TRUNCATE TABLE sales.orders RESTART IDENTITY" {
+		t.Errorf("truncateSQL() = %q", got)
+	}
+}
+
+func TestRefreshSQL(t *testing.T) {
+	if got := refreshSQL("reports.daily_sales", false); got != "REFRESH MATERIALIZED VIEW reports.daily_sales" {
+		t.Errorf("refreshSQL() = %q", got)
+	}
+	if got := refreshSQL("daily_sales", true); got != "REFRESH MATERIALIZED VIEW CONCURRENTLY daily_sales" {
+		t.Errorf("refreshSQL(concurrently) = %q", got)
+	}
+}
+
+func TestWithApplicationName(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		app  string
+		want string
+	}{
+		{"url", "postgres://u:p@db:5432/app?sslmode=disable", "udv-server", "postgres://u:p@db:5432/app?application_name=udv-server&sslmode=disable"},
+		{"url without query", "postgresql://db/app", "udv server", "postgresql://db/app?application_name=udv+server"},
+		{"url keeps its own", "postgres://db/app?application_name=reports", "udv-server", "postgres://db/app?application_name=reports"},
+		{"key value", "host=db dbname=app", "udv-server", "host=db dbname=app application_name='udv-server'"},
+		{"key value quoting", "host=db", `it's`, `host=db application_name='it\'s'`},
+		{"key value keeps its own", "host=db application_name = reports", "udv-server", "host=db application_name = reports"},
+		{"disabled", "host=db", "", "host=db"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WithApplicationName(tt.dsn, tt.app)
+			if err != nil {
+				t.Fatalf("WithApplicationName() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("WithApplicationName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -71,7 +72,8 @@ func TestE2EQueryExecution(t *testing.T) {
 		}
 
 		builder := NewQueryBuilder()
-		sql, params, err := builder.BuildQuery(plan)
+		query, params, err := builder.BuildQuery(plan)
+		sql, _ := query.(string)
 		if err != nil {
 			t.Fatalf("SQL build error: %v", err)
 		}
@@ -117,7 +119,8 @@ func TestE2EQueryExecution(t *testing.T) {
 		}
 
 		builder := NewQueryBuilder()
-		sql, params, err := builder.BuildQuery(plan)
+		query, params, err := builder.BuildQuery(plan)
+		sql, _ := query.(string)
 		if err != nil {
 			t.Fatalf("SQL build error: %v", err)
 		}
@@ -167,7 +170,8 @@ func TestE2EQueryExecution(t *testing.T) {
 		}
 
 		builder := NewQueryBuilder()
-		sql, params, err := builder.BuildQuery(plan)
+		query, params, err := builder.BuildQuery(plan)
+		sql, _ := query.(string)
 		if err != nil {
 			t.Fatalf("SQL build error: %v", err)
 		}
@@ -212,7 +216,8 @@ func TestE2EQueryExecution(t *testing.T) {
 		}
 
 		builder := NewQueryBuilder()
-		sql, params, err := builder.BuildQuery(plan)
+		query, params, err := builder.BuildQuery(plan)
+		sql, _ := query.(string)
 		if err != nil {
 			t.Fatalf("SQL build error: %v", err)
 		}
@@ -264,7 +269,8 @@ func TestE2EQueryExecution(t *testing.T) {
 		}
 
 		builder := NewQueryBuilder()
-		sql, params, err := builder.BuildQuery(plan)
+		query, params, err := builder.BuildQuery(plan)
+		sql, _ := query.(string)
 		if err != nil {
 			t.Fatalf("SQL build error: %v", err)
 		}
@@ -288,6 +294,38 @@ func TestE2EQueryExecution(t *testing.T) {
 			t.Fatalf("Row iteration error: %v", err)
 		}
 	})
+
+	// Test: a query matching no rows returns an empty slice, which encodes as [] rather than null
+	t.Run("SELECT matching no rows", func(t *testing.T) {
+		q := &dsl.Query{
+			Model: "orders",
+			Filters: &dsl.ComparisonFilter{
+				Field: "id",
+				Op:    dsl.OpLT,
+				Value: -1,
+			},
+		}
+
+		plan, err := planner.NewPlanner(registry).PlanQuery(q)
+		if err != nil {
+			t.Fatalf("Planning error: %v", err)
+		}
+		query, params, err := NewQueryBuilder().BuildQuery(plan)
+		if err != nil {
+			t.Fatalf("SQL build error: %v", err)
+		}
+
+		results, err := db.ExecuteQuery(query, params...)
+		if err != nil {
+			t.Fatalf("Query execution error: %v", err)
+		}
+		if results == nil || len(results) != 0 {
+			t.Fatalf("Expected an empty, non-nil slice, got %#v", results)
+		}
+		if encoded, _ := json.Marshal(results); string(encoded) != "[]" {
+			t.Errorf("Expected [] when encoded, got %s", encoded)
+		}
+	})
 }
 
 // TestE2EQueryWithDifferentOperators tests various filter operators against real data
@@ -386,7 +424,8 @@ func TestE2EQueryWithDifferentOperators(t *testing.T) {
 			}
 
 			builder := NewQueryBuilder()
-			sql, params, err := builder.BuildQuery(plan)
+			query, params, err := builder.BuildQuery(plan)
+			sql, _ := query.(string)
 			if err != nil {
 				t.Fatalf("SQL build error: %v", err)
 			}
@@ -403,3 +442,48 @@ func TestE2EQueryWithDifferentOperators(t *testing.T) {
 		})
 	}
 }
+
+// TestE2EColumnTypes checks that numeric, timestamp and boolean columns scan to stable JSON types
+func TestE2EColumnTypes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL environment variable not set; skipping E2E test")
+	}
+
+	db, err := Connect(dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.ExecuteQuery(`SELECT * FROM (VALUES (
+		12345678901234567890.123::numeric,
+		'2024-01-02 03:04:05+00'::timestamptz,
+		true,
+		7::integer
+	)) AS t (amount, created_at, active, quantity)`)
+	if err != nil {
+		t.Fatalf("ExecuteQuery error: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(rows))
+	}
+
+	row := rows[0]
+	if row["amount"] != json.Number("12345678901234567890.123") {
+		t.Errorf("amount = %#v, want json.Number with full precision", row["amount"])
+	}
+	if row["created_at"] != "2024-01-02T03:04:05Z" {
+		t.Errorf("created_at = %#v, want RFC3339 string", row["created_at"])
+	}
+	if row["active"] != true {
+		t.Errorf("active = %#v, want true", row["active"])
+	}
+	if row["quantity"] != int64(7) {
+		t.Errorf("quantity = %#v, want int64(7)", row["quantity"])
+	}
+}
@@ -0,0 +1,73 @@
+package adapter
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redactedPassword replaces passwords in connection strings, matching url.URL.Redacted
+const redactedPassword = "xxxxx"
+
+// keywordPassword matches password settings in key=value DSNs such as "host=db password=secret"
+var keywordPassword = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// RedactDSN masks the password in a PostgreSQL or MongoDB connection string, in URL or
+// key=value form, keeping the host and database visible for debugging
+func RedactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return keywordPassword.ReplaceAllString(dsn, "${1}"+redactedPassword)
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.EqualFold(key, "password") {
+			query.Set(key, redactedPassword)
+			u.RawQuery = query.Encode()
+		}
+	}
+	return u.Redacted()
+}
+
+// dsnPassword extracts the password from a connection string, or "" when it has none
+func dsnPassword(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.Host != "" {
+		if password, ok := u.User.Password(); ok {
+			return password
+		}
+		for key, values := range u.Query() {
+			if strings.EqualFold(key, "password") && len(values) > 0 {
+				return values[0]
+			}
+		}
+		return ""
+	}
+	if m := keywordPassword.FindStringSubmatch(dsn); m != nil {
+		return strings.Trim(m[2], "'")
+	}
+	return ""
+}
+
+// redactedError carries a scrubbed message while still unwrapping to the original error
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// RedactError masks the password of dsn wherever it appears in err's message. Drivers
+// sometimes echo the connection string they failed to parse or reach.
+func RedactError(err error, dsn string) error {
+	if err == nil {
+		return nil
+	}
+	password := dsnPassword(dsn)
+	msg := err.Error()
+	if password == "" || !strings.Contains(msg, password) {
+		return err
+	}
+	return &redactedError{msg: strings.ReplaceAll(msg, password, redactedPassword), err: err}
+}
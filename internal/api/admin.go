@@ -0,0 +1,175 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"udv/internal/adapter"
+	"udv/internal/limits"
+	"udv/internal/schema"
+)
+
+// EnableAdmin serves the /admin endpoints to requests bearing token as
+// "Authorization: Bearer <token>". Admin operations are destructive, so they stay disabled
+// until a token is set.
+func (a *API) EnableAdmin(token string) {
+	a.adminToken = token
+}
+
+// authorizeAdmin writes an error and returns false unless admin operations are enabled and
+// the request carries the admin token
+func (a *API) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if a.adminToken == "" {
+		writeError(w, http.StatusForbidden, CodeAdminDisabled, "admin operations are disabled", "start the server with ADMIN_TOKEN set to allow them")
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "invalid or missing admin token")
+		return false
+	}
+	return true
+}
+
+// adminTarget resolves the model an admin request names and the database holding it. It
+// writes an error and returns false when either is unavailable.
+func (a *API) adminTarget(w http.ResponseWriter, r *http.Request, modelName, operation string) (*schema.Model, adapter.Database, bool) {
+	if logEntry := requestLogFrom(r.Context()); logEntry != nil {
+		logEntry.Model = modelName
+		logEntry.Operation = operation
+	}
+
+	model := a.registry.GetModel(modelName)
+	if model == nil {
+		writeError(w, http.StatusNotFound, CodeModelNotFound, "model not found", modelName)
+		return nil, nil, false
+	}
+	_, db, err := a.backendFor(modelName)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeExecutionFailed, "execution error", err.Error())
+		return nil, nil, false
+	}
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeExecutionFailed, "no database connection", operation+" needs a connected database")
+		return nil, nil, false
+	}
+	return model, db, true
+}
+
+// decodeAdminRequest decodes an admin request body into v, writing an error and returning
+// false when it is invalid
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxRequestBodyBytes)
+	if err := decodeStrict(r.Body, v); err != nil {
+		status, detail := decodeErrorResponse(err)
+		code := CodeInvalidRequest
+		if status == http.StatusRequestEntityTooLarge {
+			code = CodeRequestTooLarge
+		}
+		writeError(w, status, code, "invalid request body", detail)
+		return false
+	}
+	return true
+}
+
+// handleTruncate removes every row of a model's table, or every document of its collection,
+// and reports how many were removed
+func (a *API) handleTruncate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+	if !a.authorizeAdmin(w, r) {
+		return
+	}
+
+	var req struct {
+		Model string `json:"model"`
+	}
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	model, db, ok := a.adminTarget(w, r, req.Model, "truncate")
+	if !ok {
+		return
+	}
+	if model.ReadOnly {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "model is read-only", req.Model)
+		return
+	}
+	truncater, ok := db.(adapter.Truncater)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "truncate is not supported by this database")
+		return
+	}
+
+	removed, err := truncater.Truncate(model.Table)
+	// Evict even on failure, since rows may be gone already
+	a.cache.invalidate(model.Table)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeExecutionFailed, "truncate error", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"model":   req.Model,
+		"removed": removed,
+	})
+}
+
+// handleRefresh recomputes the materialized view behind a model and reports how long it took
+func (a *API) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+	if !a.authorizeAdmin(w, r) {
+		return
+	}
+
+	var req struct {
+		Model        string `json:"model"`
+		Concurrently bool   `json:"concurrently"`
+	}
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	model, db, ok := a.adminTarget(w, r, req.Model, "refresh")
+	if !ok {
+		return
+	}
+	refresher, ok := db.(adapter.Refresher)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "refresh is not supported by this database")
+		return
+	}
+
+	start := time.Now()
+	err := refresher.Refresh(model.Table, req.Concurrently)
+	elapsed := time.Since(start)
+	if errors.Is(err, adapter.ErrNotMaterializedView) {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "model is not a materialized view", req.Model)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeExecutionFailed, "refresh error", fmt.Sprintf("%v (after %s)", err, elapsed.Round(time.Millisecond)))
+		return
+	}
+	// Cached results were computed from the old contents
+	a.cache.invalidate(model.Table)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"model":        req.Model,
+		"refreshed":    true,
+		"concurrently": req.Concurrently,
+		"duration_ms":  float64(elapsed) / float64(time.Millisecond),
+	})
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter"
+	"udv/internal/adapter/postgres"
+)
+
+// truncateDB is a fakeDB that can also be truncated
+type truncateDB struct {
+	*fakeDB
+	truncated string
+}
+
+func (t *truncateDB) Truncate(table string) (int64, error) {
+	t.truncated = table
+	return int64(len(t.rows)), nil
+}
+
+func TestTruncateEndpoint(t *testing.T) {
+	rows := []map[string]interface{}{{"id": 1}, {"id": 2}, {"id": 3}}
+	tests := []struct {
+		name       string
+		db         adapter.Database
+		token      string
+		auth       string
+		body       string
+		wantStatus int
+		wantCode   ErrorCode
+	}{
+		{"disabled", &truncateDB{fakeDB: &fakeDB{rows: rows}}, "", "Bearer secret", `{"model":"orders"}`, http.StatusForbidden, CodeAdminDisabled},
+		{"missing token", &truncateDB{fakeDB: &fakeDB{rows: rows}}, "secret", "", `{"model":"orders"}`, http.StatusUnauthorized, CodeUnauthorized},
+		{"wrong token", &truncateDB{fakeDB: &fakeDB{rows: rows}}, "secret", "Bearer guess", `{"model":"orders"}`, http.StatusUnauthorized, CodeUnauthorized},
+		{"unknown model", &truncateDB{fakeDB: &fakeDB{rows: rows}}, "secret", "Bearer secret", `{"model":"ghosts"}`, http.StatusNotFound, CodeModelNotFound},
+		{"database without truncate", &fakeDB{rows: rows}, "secret", "Bearer secret", `{"model":"orders"}`, http.StatusBadRequest, CodeInvalidRequest},
+		{"truncated", &truncateDB{fakeDB: &fakeDB{rows: rows}}, "secret", "Bearer secret", `{"model":"orders"}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(setupRegistryForTest(), tt.db, postgres.NewQueryBuilder())
+			if tt.token != "" {
+				a.EnableAdmin(tt.token)
+			}
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/admin/truncate", strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST /admin/truncate failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				var errResp ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+					t.Fatalf("failed to decode error: %v", err)
+				}
+				if errResp.Error.Code != tt.wantCode {
+					t.Errorf("code = %s, want %s", errResp.Error.Code, tt.wantCode)
+				}
+				if db, ok := tt.db.(*truncateDB); ok && db.truncated != "" {
+					t.Errorf("rejected request truncated %s", db.truncated)
+				}
+				return
+			}
+
+			var out map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if out["model"] != "orders" || out["removed"] != float64(3) {
+				t.Errorf("response = %v, want 3 orders removed", out)
+			}
+			if got := tt.db.(*truncateDB).truncated; got != "orders" {
+				t.Errorf("truncated table = %q, want orders", got)
+			}
+		})
+	}
+}
+
+// refreshDB is a fakeDB whose tables can be refreshed, failing with err
+type refreshDB struct {
+	*fakeDB
+	err          error
+	refreshed    string
+	concurrently bool
+}
+
+func (d *refreshDB) Refresh(table string, concurrently bool) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.refreshed, d.concurrently = table, concurrently
+	return nil
+}
+
+func TestRefreshEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		db         adapter.Database
+		auth       string
+		body       string
+		wantStatus int
+		wantCode   ErrorCode
+	}{
+		{"missing token", &refreshDB{fakeDB: &fakeDB{}}, "", `{"model":"orders"}`, http.StatusUnauthorized, CodeUnauthorized},
+		{"unknown model", &refreshDB{fakeDB: &fakeDB{}}, "Bearer secret", `{"model":"ghosts"}`, http.StatusNotFound, CodeModelNotFound},
+		{"unknown option", &refreshDB{fakeDB: &fakeDB{}}, "Bearer secret", `{"model":"orders","wait":true}`, http.StatusBadRequest, CodeInvalidRequest},
+		{"database without refresh", &fakeDB{}, "Bearer secret", `{"model":"orders"}`, http.StatusBadRequest, CodeInvalidRequest},
+		{"not a materialized view", &refreshDB{fakeDB: &fakeDB{}, err: fmt.Errorf("orders: %w", adapter.ErrNotMaterializedView)}, "Bearer secret", `{"model":"orders"}`, http.StatusBadRequest, CodeInvalidRequest},
+		{"refresh fails", &refreshDB{fakeDB: &fakeDB{}, err: errors.New("could not create unique index")}, "Bearer secret", `{"model":"orders","concurrently":true}`, http.StatusInternalServerError, CodeExecutionFailed},
+		{"refreshed", &refreshDB{fakeDB: &fakeDB{}}, "Bearer secret", `{"model":"orders","concurrently":true}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(setupRegistryForTest(), tt.db, postgres.NewQueryBuilder())
+			a.EnableAdmin("secret")
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/admin/refresh", strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST /admin/refresh failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				var errResp ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+					t.Fatalf("failed to decode error: %v", err)
+				}
+				if errResp.Error.Code != tt.wantCode {
+					t.Errorf("code = %s, want %s", errResp.Error.Code, tt.wantCode)
+				}
+				return
+			}
+
+			var out map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if out["model"] != "orders" || out["refreshed"] != true || out["concurrently"] != true {
+				t.Errorf("response = %v, want orders refreshed concurrently", out)
+			}
+			if _, ok := out["duration_ms"].(float64); !ok {
+				t.Errorf("expected a duration, got %v", out["duration_ms"])
+			}
+			if db := tt.db.(*refreshDB); db.refreshed != "orders" || !db.concurrently {
+				t.Errorf("refreshed %q concurrently=%v, want orders concurrently", db.refreshed, db.concurrently)
+			}
+		})
+	}
+}
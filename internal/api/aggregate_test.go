@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter"
+	"udv/internal/adapter/mongodb"
+	"udv/internal/adapter/postgres"
+)
+
+func TestQueryEndpoint_CountGroupByStatus(t *testing.T) {
+	body := `{"model":"orders","group_by":["status"],"aggregates":[{"fn":"count","alias":"order_count"}]}`
+
+	tests := []struct {
+		name     string
+		builder  adapter.QueryBuilder
+		dbType   string
+		contains []string
+	}{
+		{"postgres", postgres.NewQueryBuilder(), "postgres", []string{"SELECT t0.status, COUNT(*) AS order_count FROM orders t0 GROUP BY t0.status"}},
+		{"mongodb", mongodb.NewQueryBuilder(), "mongodb", []string{`"$group"`, `"$_id.status"`, `"order_count"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewWithType(setupRegistryForTest(), nil, tt.builder, tt.dbType)
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body)))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("Response missing %s: %s", want, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestQueryEndpoint_UngroupedSelectedField(t *testing.T) {
+	a := New(setupRegistryForTest(), nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	body := `{"model":"orders","fields":["status","amount"],"group_by":["status"],"aggregates":[{"fn":"count","alias":"order_count"}]}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "amount must appear in group_by") {
+		t.Errorf("Expected error naming the ungrouped field, got %s", rec.Body.String())
+	}
+}
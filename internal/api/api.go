@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"udv/internal/adapter"
+	"udv/internal/config"
 	"udv/internal/dsl"
+	"udv/internal/limits"
+	"udv/internal/metrics"
 	"udv/internal/planner"
 	"udv/internal/schema"
 )
@@ -19,6 +23,19 @@ type API struct {
 	builder      adapter.QueryBuilder
 	db           adapter.Database
 	databaseType string
+	health       *healthChecker
+	cache        *resultCache
+	datasources  map[string]*datasource // Named databases added with AddDatasource
+
+	explainEnabled bool // Allows ?explain=true, which runs queries under EXPLAIN ANALYZE
+
+	failedModels []config.ModelError // Models left out of a tolerant config load
+
+	cursors *cursorSigner // Signs next_cursor tokens; nil when cursor pagination is disabled
+
+	adminToken string // Bearer token required by /admin endpoints; empty disables them
+
+	countCacheTTL time.Duration // How long exact pagination totals are reused; zero counts every time
 }
 
 // New creates a new API instance with optional database connection
@@ -30,6 +47,10 @@ func New(reg *schema.Registry, db adapter.Database, builder adapter.QueryBuilder
 		builder:      builder,
 		db:           db,
 		databaseType: "postgres", // default
+		health:       newHealthChecker(db, healthCacheTTL),
+		cache:        newResultCache(),
+
+		countCacheTTL: DefaultCountCacheTTL,
 	}
 }
 
@@ -42,31 +63,45 @@ func NewWithType(reg *schema.Registry, db adapter.Database, builder adapter.Quer
 		builder:      builder,
 		db:           db,
 		databaseType: dbType,
+		health:       newHealthChecker(db, healthCacheTTL),
+		cache:        newResultCache(),
+
+		countCacheTTL: DefaultCountCacheTTL,
 	}
 }
 
 // RegisterRoutes registers HTTP handlers onto the provided mux
 func (a *API) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/health", a.handleHealth)
+	mux.HandleFunc("/livez", a.handleLivez)
+	mux.HandleFunc("/readyz", a.handleReadyz)
 	mux.HandleFunc("/info", a.handleInfo)
 	mux.HandleFunc("/models", a.handleModels)
+	mux.HandleFunc("/schema", a.handleSchema)
+	mux.HandleFunc("/schema/", a.handleSchema)
 	mux.HandleFunc("/query", a.handleQuery)
+	mux.HandleFunc("/batch", a.handleBatch)
+	mux.HandleFunc("/admin/truncate", a.handleTruncate)
+	mux.HandleFunc("/admin/refresh", a.handleRefresh)
 }
 
 // handleInfo returns information about the API and database
 func (a *API) handleInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
 	type infoResp struct {
-		DatabaseType string `json:"database_type"`
-		Status       string `json:"status"`
+		DatabaseType string            `json:"database_type"`
+		Datasources  map[string]string `json:"datasources,omitempty"` // Named datasource to database type
+		Status       string            `json:"status"`
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(infoResp{
 		DatabaseType: a.databaseType,
+		Datasources:  a.datasourceTypes(),
 		Status:       "ok",
 	})
 }
@@ -74,7 +109,7 @@ func (a *API) handleInfo(w http.ResponseWriter, r *http.Request) {
 // handleModels returns a JSON list of models and their fields
 func (a *API) handleModels(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
@@ -107,6 +142,10 @@ func (a *API) handleModels(w http.ResponseWriter, r *http.Request) {
 			fr.PrimaryKey = md.PrimaryKey
 		}
 		for _, f := range fields {
+			// Hidden fields cannot be selected, so they are not advertised
+			if !f.Selectable {
+				continue
+			}
 			fr.Fields = append(fr.Fields, fieldResp{Name: f.Name, Type: f.Type})
 		}
 		out = append(out, fr)
@@ -119,7 +158,7 @@ func (a *API) handleModels(w http.ResponseWriter, r *http.Request) {
 // handleQuery accepts a DSL query JSON, validates, plans, and returns SQL+params
 func (a *API) handleQuery(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 	// Decode into a raw structure so we can handle the FilterExpr interface
@@ -134,11 +173,51 @@ func (a *API) handleQuery(w http.ResponseWriter, r *http.Request) {
 		Pagination *dsl.Pagination        `json:"pagination,omitempty"`
 		Data       map[string]interface{} `json:"data,omitempty"` // NEW
 		ID         interface{}            `json:"id,omitempty"`   // NEW
+		Lock       *dsl.Lock              `json:"lock,omitempty"`
+		Include    []dsl.Include          `json:"include,omitempty"`
+
+		IncludeDeleted bool `json:"include_deleted,omitempty"`
+
+		Increment  map[string]interface{} `json:"increment,omitempty"`
+		Push       map[string]interface{} `json:"push,omitempty"`
+		UnsetNulls bool                   `json:"unset_nulls,omitempty"`
+		StripNulls *bool                  `json:"strip_nulls,omitempty"`
+		Returning  []string               `json:"returning,omitempty"`
+
+		AllowFullTable bool `json:"allow_full_table,omitempty"`
+
+		JSONSet    map[string]interface{} `json:"json_set,omitempty"`
+		JSONRemove []string               `json:"json_remove,omitempty"`
+
+		GroupByTime  []dsl.TimeBucket  `json:"group_by_time,omitempty"`
+		FieldAliases map[string]string `json:"field_aliases,omitempty"`
+
+		ExpectedVersion interface{} `json:"expected_version,omitempty"`
+		Upsert          bool        `json:"upsert,omitempty"`
+
+		Collation *config.Collation `json:"collation,omitempty"`
 	}
 
+	start := time.Now()
+
+	// Resolve the response shape first, so an unsupported one is rejected before any write runs
+	envelope, err := responseEnvelope(r)
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, CodeUnsupportedEnvelope, "unsupported response envelope", err.Error())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxRequestBodyBytes)
+
 	var rq rawQuery
-	if err := json.NewDecoder(r.Body).Decode(&rq); err != nil {
-		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+	if err := decodeStrict(r.Body, &rq); err != nil {
+		metrics.RecordQueryError("", "", metrics.ErrDecode)
+		status, detail := decodeErrorResponse(err)
+		code := CodeInvalidRequest
+		if status == http.StatusRequestEntityTooLarge {
+			code = CodeRequestTooLarge
+		}
+		writeError(w, status, code, "invalid request body", detail)
 		return
 	}
 
@@ -147,6 +226,15 @@ func (a *API) handleQuery(w http.ResponseWriter, r *http.Request) {
 	if rq.Operation != "" {
 		operation = dsl.Operation(rq.Operation)
 	}
+	// Labels come from the request, so unknown values share one series instead of adding new ones
+	metricModel, metricOperation := a.metricLabels(rq.Model, operation)
+	defer metrics.ObserveQuery(metricModel, metricOperation, start)
+
+	logEntry := requestLogFrom(r.Context())
+	if logEntry != nil {
+		logEntry.Model = rq.Model
+		logEntry.Operation = string(operation)
+	}
 
 	q := dsl.Query{
 		Operation:  operation, // NEW
@@ -158,39 +246,78 @@ func (a *API) handleQuery(w http.ResponseWriter, r *http.Request) {
 		Pagination: rq.Pagination,
 		Data:       rq.Data, // NEW
 		ID:         rq.ID,   // NEW
+		Lock:       rq.Lock,
+		Include:    rq.Include,
+
+		IncludeDeleted: rq.IncludeDeleted,
+		Increment:      rq.Increment,
+		Push:           rq.Push,
+		UnsetNulls:     rq.UnsetNulls,
+		StripNulls:     rq.StripNulls,
+		Returning:      rq.Returning,
+		AllowFullTable: rq.AllowFullTable,
+		JSONSet:        rq.JSONSet,
+		JSONRemove:     rq.JSONRemove,
+		GroupByTime:    rq.GroupByTime,
+		FieldAliases:   rq.FieldAliases,
+
+		ExpectedVersion: rq.ExpectedVersion,
+		Upsert:          rq.Upsert,
+
+		Collation: rq.Collation,
 	}
 
 	// Parse filters if provided
 	if len(rq.Filters) > 0 {
-		// try ComparisonFilter first
-		var cf dsl.ComparisonFilter
-		if err := json.Unmarshal(rq.Filters, &cf); err == nil && cf.Field != "" {
-			q.Filters = &cf
-		} else {
-			var lf dsl.LogicalFilter
-			if err := json.Unmarshal(rq.Filters, &lf); err == nil {
-				q.Filters = &lf
-			} else {
-				http.Error(w, "invalid filters format", http.StatusBadRequest)
-				return
-			}
+		filters, err := decodeFilters(rq.Filters)
+		if err != nil {
+			metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrDecode)
+			_, detail := decodeErrorResponse(err)
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid filters format", detail)
+			return
 		}
+		q.Filters = filters
 	}
 
 	if err := a.validator.ValidateQuery(&q); err != nil {
-		http.Error(w, fmt.Sprintf("validation error: %v", err), http.StatusBadRequest)
+		metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrValidation)
+		writeQueryError(w, http.StatusBadRequest, CodeValidationFailed, "validation error", err)
 		return
 	}
+	if q.Pagination != nil && q.Pagination.Cursor != "" {
+		if err := a.applyCursor(&q); err != nil {
+			metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrValidation)
+			writeError(w, http.StatusBadRequest, CodeInvalidCursor, "invalid cursor", err.Error())
+			return
+		}
+	}
 
 	plan, err := a.planner.PlanQuery(&q)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("planning error: %v", err), http.StatusInternalServerError)
+		metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrPlanning)
+		writeQueryError(w, http.StatusBadRequest, CodePlanningFailed, "planning error", err)
+		return
+	}
+
+	builder, db, err := a.backendFor(plan.RootModel.Name)
+	if err != nil {
+		metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrBuild)
+		writeError(w, http.StatusInternalServerError, CodeBuildFailed, "datasource error", err.Error())
 		return
 	}
 
-	sql, params, err := a.builder.BuildQuery(plan)
+	// Selects fetch one row past the page, so the response can tell whether another page exists
+	page := plan.Pagination
+	peek := operation.Selects() && !operation.SingleRow() && page.Limit > 0
+	if peek {
+		plan.Pagination.Limit++
+	}
+
+	sql, params, err := builder.BuildQuery(plan)
+	plan.Pagination = page
 	if err != nil {
-		http.Error(w, fmt.Sprintf("sql build error: %v", err), http.StatusInternalServerError)
+		metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrBuild)
+		writeQueryError(w, http.StatusBadRequest, CodeBuildFailed, "query build error", err)
 		return
 	}
 
@@ -198,29 +325,234 @@ func (a *API) handleQuery(w http.ResponseWriter, r *http.Request) {
 		"sql":    sql,
 		"params": params,
 	}
+	if logEntry != nil {
+		logEntry.Params = params
+	}
+
+	// With ?explain=true the response carries the execution plan instead of the results
+	if r.URL.Query().Get("explain") == "true" {
+		a.writeExplain(w, db, sql, params, metricModel, metricOperation)
+		return
+	}
 
 	// Execute query if database is available
-	if a.db != nil {
-		if operation == dsl.OpDelete {
+	if db != nil {
+		// With ?debug=true the response reports the time spent in the database
+		var timer *execTimer
+		if r.URL.Query().Get("debug") == "true" {
+			timer = &execTimer{}
+		}
+
+		if operation == dsl.OpDelete && len(q.Returning) == 0 {
 			// DELETE returns affected rows count
-			result, err := a.db.Exec(sql, params...)
+			start := time.Now()
+			result, err := db.Exec(sql, params...)
+			timer.since(start)
+			// Evict even on failure, since a multi-row write may have partially applied
+			a.cache.invalidate(plan.RootModel.Table)
 			if err != nil {
-				http.Error(w, fmt.Sprintf("execution error: %v", err), http.StatusInternalServerError)
+				metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrExecution)
+				writeError(w, http.StatusInternalServerError, CodeExecutionFailed, "execution error", err.Error())
 				return
 			}
 			affectedRows, _ := result.RowsAffected()
 			resp["affected_rows"] = affectedRows
+			if logEntry != nil {
+				logEntry.Rows, logEntry.HasRows = affectedRows, true
+			}
 		} else {
-			// CREATE, UPDATE, SELECT return data
-			rows, err := a.db.ExecuteQuery(sql, params...)
+			// CREATE, UPDATE, SELECT and DELETE with returning return data; COUNT and EXISTS return a single row
+			rows, err := a.readRows(db, plan, sql, params, timer)
+			if operation == dsl.OpCreate || operation == dsl.OpUpdate || operation == dsl.OpDelete {
+				a.cache.invalidate(plan.RootModel.Table)
+			}
 			if err != nil {
-				http.Error(w, fmt.Sprintf("execution error: %v", err), http.StatusInternalServerError)
+				metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrExecution)
+				writeError(w, http.StatusInternalServerError, CodeExecutionFailed, "execution error", err.Error())
+				return
+			}
+			if operation == dsl.OpUpdate && q.ExpectedVersion != nil && len(rows) == 0 {
+				metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrConflict)
+				writeError(w, http.StatusConflict, CodeVersionConflict, "version conflict",
+					fmt.Sprintf("no row matched expected_version %v; it was changed or deleted since it was read", q.ExpectedVersion))
 				return
 			}
-			resp["data"] = rows
+			if operation == dsl.OpCount {
+				// COUNT returns a single scalar instead of rows
+				resp["count"] = countFromRows(rows)
+			} else if operation == dsl.OpExists {
+				resp["exists"] = existsFromRows(rows)
+			} else if operation == dsl.OpDistinct {
+				// DISTINCT returns a flat array of the field's values
+				resp["values"] = valuesFromRows(rows, q.Fields[0])
+			} else if operation.SingleRow() {
+				// FIRST and LAST return the row itself, or 404 when nothing matched
+				if len(rows) == 0 {
+					writeError(w, http.StatusNotFound, CodeNotFound, "no matching row", fmt.Sprintf("no %s row matched the filters", rq.Model))
+					return
+				}
+				resp["data"] = rows[0]
+			} else if peek {
+				hasMore := len(rows) > page.Limit
+				if hasMore {
+					rows = rows[:page.Limit]
+				}
+				info := newPageInfo(q.Pagination, page, hasMore)
+				if hasMore {
+					if info.NextCursor, err = a.nextCursor(&q, rows); err != nil {
+						writeError(w, http.StatusInternalServerError, CodeExecutionFailed, "cursor error", err.Error())
+						return
+					}
+				}
+				if q.Pagination != nil && q.Pagination.WithTotal {
+					total, estimated, err := a.pageTotal(q, builder, db, timer)
+					if err != nil {
+						metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrExecution)
+						writeError(w, http.StatusInternalServerError, CodeExecutionFailed, "count error", err.Error())
+						return
+					}
+					info.Total, info.TotalEstimated = &total, &estimated
+				}
+				resp["data"] = rows
+				resp["pagination"] = info
+			} else {
+				resp["data"] = rows
+			}
+			if operation == dsl.OpDelete {
+				resp["affected_rows"] = int64(len(rows))
+			}
+			// Upserts report whether they inserted, where the database can tell
+			if reporter, ok := sql.(adapter.UpsertReporter); ok && plan.Upsert {
+				upsertedID := reporter.UpsertedID()
+				resp["upserted"] = upsertedID != nil
+				if upsertedID != nil {
+					resp["upserted_id"] = upsertedID
+				}
+			}
+			if logEntry != nil {
+				logEntry.Rows, logEntry.HasRows = int64(len(rows)), true
+			}
+		}
+
+		if timer != nil {
+			resp["execution_ms"] = timer.milliseconds()
 		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(resp)
+	w.Header().Set("Vary", "Accept")
+	_ = json.NewEncoder(w).Encode(envelopes[envelope](resp))
+}
+
+// readRows executes a query returning rows, normalizes them, renames columns to field names
+// and applies read transforms. Select, count, distinct and exists results of models with a
+// cacheTTL are served from, and stored in, the result cache.
+func (a *API) readRows(db adapter.Database, plan *planner.QueryPlan, query interface{}, params []interface{}, timer *execTimer) ([]map[string]interface{}, error) {
+	var ttl time.Duration
+	var datasource string
+	if model := a.registry.GetModel(plan.RootModel.Name); model != nil {
+		ttl, datasource = model.CacheTTL, model.Datasource
+	}
+
+	key, cacheable := "", ttl > 0 && (plan.Operation.Selects() || plan.Operation == dsl.OpCount || plan.Operation == dsl.OpDistinct || plan.Operation == dsl.OpExists)
+	if cacheable {
+		key, cacheable = cacheKey(query, params)
+		// The same query against two datasources must not share an entry
+		key = datasource + "|" + key
+	}
+	if cacheable {
+		if rows, ok := a.cache.get(key); ok {
+			metrics.RecordCacheLookup(plan.RootModel.Name, true)
+			return rows, nil
+		}
+		metrics.RecordCacheLookup(plan.RootModel.Name, false)
+	}
+
+	start := time.Now()
+	rows, err := db.ExecuteQuery(query, params...)
+	timer.since(start)
+	if err != nil {
+		return nil, err
+	}
+	rows = adapter.NormalizeRows(rows)
+	a.renameColumns(plan, rows)
+	// Transformed before caching, so cache hits never expose the stored values
+	a.transformRows(plan, rows)
+
+	if cacheable {
+		tables := []string{plan.RootModel.Table}
+		for _, join := range plan.Joins {
+			tables = append(tables, join.ToTable)
+		}
+		tables = append(tables, plan.SubqueryTables()...)
+		a.cache.set(key, rows, tables, ttl)
+	}
+	return rows, nil
+}
+
+// valuesFromRows flattens single-column DISTINCT rows into their values
+func valuesFromRows(rows []map[string]interface{}, field string) []interface{} {
+	values := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		values = append(values, row[field])
+	}
+	return values
+}
+
+// countFromRows extracts the scalar from a single-row COUNT result
+func countFromRows(rows []map[string]interface{}) interface{} {
+	if len(rows) == 0 {
+		return 0
+	}
+	return rows[0]["count"]
+}
+
+// pageInfo describes the page of rows returned by a select
+type pageInfo struct {
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasMore bool `json:"has_more"` // Whether rows exist past this page
+
+	// Set when the request paged by page number
+	Page     int `json:"page,omitempty"`
+	PageSize int `json:"page_size,omitempty"`
+
+	// NextCursor continues after this page's last row; set when cursors are enabled and the
+	// query's order is unique
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Total counts the matching rows across all pages; set when the request asked with_total
+	Total          *int64 `json:"total,omitempty"`
+	TotalEstimated *bool  `json:"total_estimated,omitempty"`
+}
+
+// newPageInfo describes the planned page, echoing page and page_size when the request used them
+func newPageInfo(requested *dsl.Pagination, page planner.Pagination, hasMore bool) pageInfo {
+	info := pageInfo{Limit: page.Limit, Offset: page.Offset, HasMore: hasMore}
+	if requested != nil && requested.ByPage() {
+		info.Page, info.PageSize = requested.Page, page.Limit
+	}
+	return info
+}
+
+// existsFromRows extracts the boolean of an exists query result
+func existsFromRows(rows []map[string]interface{}) bool {
+	if len(rows) == 0 {
+		return false
+	}
+	exists, _ := rows[0]["exists"].(bool)
+	return exists
+}
+
+// metricLabels returns the model and operation labels for a query's metrics, replacing names
+// that are not in the registry or not a known operation with metrics.Unknown
+func (a *API) metricLabels(model string, operation dsl.Operation) (string, string) {
+	modelLabel, operationLabel := metrics.Unknown, metrics.Unknown
+	if a.registry.GetModel(model) != nil {
+		modelLabel = model
+	}
+	if operation.Known() {
+		operationLabel = string(operation)
+	}
+	return modelLabel, operationLabel
 }
@@ -8,6 +8,7 @@ import (
     "net/http/httptest"
     "testing"
 
+    "udv/internal/adapter/postgres"
     "udv/internal/config"
     "udv/internal/dsl"
     "udv/internal/schema"
@@ -36,7 +37,7 @@ func setupRegistryForTest() *schema.Registry {
 
 func TestModelsEndpoint(t *testing.T) {
     reg := setupRegistryForTest()
-    a := New(reg, nil)  // ← Pass nil for database since we're testing without DB
+    a := New(reg, nil, postgres.NewQueryBuilder()) // ← Pass nil for database since we're testing without DB
     mux := http.NewServeMux()
     a.RegisterRoutes(mux)
 
@@ -66,7 +67,7 @@ func TestModelsEndpoint(t *testing.T) {
 
 func TestQueryEndpoint_Simple(t *testing.T) {
     reg := setupRegistryForTest()
-    a := New(reg, nil)  // ← Pass nil for database since we're testing without DB
+    a := New(reg, nil, postgres.NewQueryBuilder()) // ← Pass nil for database since we're testing without DB
     mux := http.NewServeMux()
     a.RegisterRoutes(mux)
 
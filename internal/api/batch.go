@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"udv/internal/dsl"
+	"udv/internal/limits"
+)
+
+// batchOperations are the read operations a batch may contain
+var batchOperations = map[dsl.Operation]bool{
+	dsl.OpSelect:    true,
+	dsl.OpAggregate: true,
+	dsl.OpCount:     true,
+	dsl.OpExists:    true,
+	dsl.OpDistinct:  true,
+	dsl.OpFirst:     true,
+	dsl.OpLast:      true,
+}
+
+// batchOperationList names the operations allowed in a batch, sorted, for error messages
+func batchOperationList() string {
+	names := make([]string, 0, len(batchOperations))
+	for op := range batchOperations {
+		names = append(names, string(op))
+	}
+	sort.Strings(names)
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// batchResult is the outcome of one query in a batch: the /query response body on
+// success, or its error body otherwise
+type batchResult struct {
+	Success bool            `json:"success"`
+	Status  int             `json:"status"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ErrorBody      `json:"error,omitempty"`
+}
+
+// batchResponse buffers the response of one query in a batch
+type batchResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (br *batchResponse) Header() http.Header {
+	if br.header == nil {
+		br.header = make(http.Header)
+	}
+	return br.header
+}
+
+func (br *batchResponse) WriteHeader(status int) {
+	if br.status == 0 {
+		br.status = status
+	}
+}
+
+func (br *batchResponse) Write(b []byte) (int, error) {
+	br.WriteHeader(http.StatusOK)
+	return br.body.Write(b)
+}
+
+// handleBatch runs independent read queries concurrently and returns their results in request order.
+// Each query goes through the same pipeline as /query, and a failing query does not affect the others.
+func (a *API) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxRequestBodyBytes)
+
+	var req struct {
+		Queries []json.RawMessage `json:"queries"`
+	}
+	if err := decodeStrict(r.Body, &req); err != nil {
+		status, detail := decodeErrorResponse(err)
+		code := CodeInvalidRequest
+		if status == http.StatusRequestEntityTooLarge {
+			code = CodeRequestTooLarge
+		}
+		writeError(w, status, code, "invalid request body", detail)
+		return
+	}
+	if len(req.Queries) == 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid batch", "queries must contain at least one query")
+		return
+	}
+	if len(req.Queries) > limits.MaxBatchSize {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid batch",
+			fmt.Sprintf("batch has %d queries; the limit is %d", len(req.Queries), limits.MaxBatchSize))
+		return
+	}
+
+	if logEntry := requestLogFrom(r.Context()); logEntry != nil {
+		logEntry.Operation = "batch"
+	}
+
+	results := make([]batchResult, len(req.Queries))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < limits.BatchConcurrency && worker < len(req.Queries); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = a.runBatchQuery(r, req.Queries[i])
+			}
+		}()
+	}
+	for i := range req.Queries {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
+
+// runBatchQuery runs one query of a batch through handleQuery and captures its response
+func (a *API) runBatchQuery(r *http.Request, raw json.RawMessage) batchResult {
+	var head struct {
+		Operation string `json:"operation"`
+	}
+	_ = json.Unmarshal(raw, &head)
+	if head.Operation != "" && !batchOperations[dsl.Operation(head.Operation)] {
+		return batchResult{Status: http.StatusBadRequest, Error: &ErrorBody{
+			Code:    CodeValidationFailed,
+			Message: "validation error",
+			Details: []string{fmt.Sprintf("operation %s is not allowed in a batch; only %s are", head.Operation, batchOperationList())},
+		}}
+	}
+
+	// Queries share the batch's request log entry otherwise, and would write to it concurrently
+	ctx := context.WithValue(r.Context(), requestLogKey{}, (*requestLog)(nil))
+	sub, err := http.NewRequestWithContext(ctx, http.MethodPost, "/query?"+r.URL.RawQuery, bytes.NewReader(raw))
+	if err != nil {
+		return batchResult{Status: http.StatusBadRequest, Error: &ErrorBody{Code: CodeInvalidRequest, Message: "invalid query", Details: []string{err.Error()}}}
+	}
+
+	var rec batchResponse
+	a.handleQuery(&rec, sub)
+
+	if rec.status == http.StatusOK {
+		return batchResult{Success: true, Status: rec.status, Result: bytes.TrimSpace(rec.body.Bytes())}
+	}
+	var envelope ErrorResponse
+	if err := json.Unmarshal(rec.body.Bytes(), &envelope); err != nil {
+		envelope.Error = ErrorBody{Code: CodeExecutionFailed, Message: "invalid query response"}
+	}
+	return batchResult{Status: rec.status, Error: &envelope.Error}
+}
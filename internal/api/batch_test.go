@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/limits"
+)
+
+func TestBatchEndpoint(t *testing.T) {
+	a := New(setupRegistryForTest(), nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	body := `{"queries": [
+		{"operation": "count", "model": "orders"},
+		{"model": "missing"},
+		{"operation": "delete", "model": "orders", "id": 1},
+		{"model": "orders", "fields": ["status"]}
+	]}`
+	resp, err := http.Post(ts.URL+"/batch", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /batch failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var out struct {
+		Results []batchResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if len(out.Results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(out.Results))
+	}
+
+	if r := out.Results[0]; !r.Success || !strings.Contains(string(r.Result), "COUNT(*)") {
+		t.Errorf("unexpected count result: %+v", r)
+	}
+	if r := out.Results[1]; r.Success || r.Status != http.StatusNotFound || r.Error.Code != CodeModelNotFound {
+		t.Errorf("unexpected unknown model result: %+v", r)
+	}
+	if r := out.Results[2]; r.Success || r.Status != http.StatusBadRequest || r.Error.Code != CodeValidationFailed {
+		t.Errorf("unexpected write result: %+v", r)
+	}
+	if r := out.Results[2]; r.Error == nil || len(r.Error.Details) == 0 ||
+		!strings.Contains(r.Error.Details[0], "only aggregate, count, distinct, exists, first, last and select are") {
+		t.Errorf("write result should list every batch operation: %+v", r.Error)
+	}
+	if r := out.Results[3]; !r.Success || !strings.Contains(string(r.Result), "t0.status") {
+		t.Errorf("unexpected select result: %+v", r)
+	}
+}
+
+func TestBatchEndpoint_Size(t *testing.T) {
+	defer func(max int) { limits.MaxBatchSize = max }(limits.MaxBatchSize)
+	limits.MaxBatchSize = 2
+
+	a := New(setupRegistryForTest(), nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for _, body := range []string{
+		`{"queries": []}`,
+		`{"queries": [{"model": "orders"}, {"model": "orders"}, {"model": "orders"}]}`,
+	} {
+		resp, err := http.Post(ts.URL+"/batch", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /batch failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the result cache; new results are not cached once it is full
+const maxCacheEntries = 1000
+
+// resultCache holds read results for models with a cacheTTL, keyed by the built query
+type resultCache struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// cacheEntry is a cached result and the tables it was read from
+type cacheEntry struct {
+	rows      []map[string]interface{}
+	tables    []string
+	expiresAt time.Time
+}
+
+// newResultCache creates an empty result cache
+func newResultCache() *resultCache {
+	return &resultCache{now: time.Now, entries: make(map[string]cacheEntry)}
+}
+
+// cacheKey identifies a built query and its parameters. It reports false when the
+// query cannot be serialized, in which case the result is not cached.
+func cacheKey(query interface{}, params []interface{}) (string, bool) {
+	key, err := json.Marshal(struct {
+		Query  interface{}   `json:"query"`
+		Params []interface{} `json:"params"`
+	}{query, params})
+	if err != nil {
+		return "", false
+	}
+	return string(key), true
+}
+
+// get returns the unexpired rows cached under key
+func (c *resultCache) get(key string) ([]map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.rows, true
+}
+
+// set caches rows read from tables for ttl. Cached rows are shared between
+// requests and must not be modified afterwards.
+func (c *resultCache) set(key string, rows []map[string]interface{}, tables []string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= maxCacheEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = cacheEntry{rows: rows, tables: tables, expiresAt: now.Add(ttl)}
+}
+
+// invalidate drops every cached result read from table
+func (c *resultCache) invalidate(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		for _, t := range entry.tables {
+			if t == table {
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+}
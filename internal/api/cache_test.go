@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/config"
+	"udv/internal/schema"
+)
+
+func TestResultCache_ExpiryAndInvalidation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newResultCache()
+	c.now = func() time.Time { return now }
+
+	rows := []map[string]interface{}{{"id": 1}}
+	c.set("orders-with-users", rows, []string{"orders", "users"}, time.Minute)
+	c.set("products", rows, []string{"products"}, time.Minute)
+
+	if _, ok := c.get("orders-with-users"); !ok {
+		t.Fatalf("get() missed a fresh entry")
+	}
+
+	c.invalidate("users")
+	if _, ok := c.get("orders-with-users"); ok {
+		t.Errorf("invalidate() kept an entry read from the written table")
+	}
+	if _, ok := c.get("products"); !ok {
+		t.Errorf("invalidate() dropped an unrelated entry")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get("products"); ok {
+		t.Errorf("get() returned an expired entry")
+	}
+}
+
+func TestQueryEndpoint_CachedReads(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "status", Type: "string"},
+				},
+				CacheTTL: "1m",
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	if err := reg.LoadFromConfig(cfg); err != nil {
+		t.Fatalf("LoadFromConfig error: %v", err)
+	}
+
+	db := &fakeDB{rows: []map[string]interface{}{{"id": int64(1), "status": "PAID"}}}
+	mux := http.NewServeMux()
+	New(reg, db, postgres.NewQueryBuilder()).RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	post := func(body string) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status: %d", resp.StatusCode)
+		}
+	}
+
+	selectBody := `{"model":"orders","filters":{"field":"status","op":"=","value":"PAID"}}`
+	post(selectBody)
+	post(selectBody)
+	if db.queries != 1 {
+		t.Errorf("repeated select ran %d queries, want 1", db.queries)
+	}
+
+	post(`{"operation":"update","model":"orders","id":1,"data":{"status":"SHIPPED"}}`)
+	post(selectBody)
+	if db.queries != 3 {
+		t.Errorf("select after update ran %d queries in total, want 3", db.queries)
+	}
+}
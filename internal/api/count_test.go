@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"udv/internal/adapter"
+	"udv/internal/adapter/postgres"
+)
+
+// fakeDB is an in-memory adapter.Database returning canned rows
+type fakeDB struct {
+	rows      []map[string]interface{}
+	lastQuery interface{}
+	lastArgs  []interface{}
+	pingErr   error
+	pings     int
+	queries   int
+}
+
+func (f *fakeDB) Close() error { return nil }
+
+func (f *fakeDB) Ping() error {
+	f.pings++
+	return f.pingErr
+}
+
+func (f *fakeDB) ExecuteQuery(query interface{}, args ...interface{}) ([]map[string]interface{}, error) {
+	f.lastQuery, f.lastArgs = query, args
+	f.queries++
+	return f.rows, nil
+}
+
+func (f *fakeDB) Exec(query interface{}, args ...interface{}) (adapter.ExecResult, error) {
+	f.lastQuery, f.lastArgs = query, args
+	return fakeResult(len(f.rows)), nil
+}
+
+type fakeResult int64
+
+func (r fakeResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestQueryEndpoint_Count(t *testing.T) {
+	reg := setupRegistryForTest()
+	db := &fakeDB{rows: []map[string]interface{}{{"count": int64(42)}}}
+	a := New(reg, db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	body := `{"operation":"count","model":"orders","filters":{"field":"status","op":"=","value":"PAID"}}`
+	resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatalf("POST /query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var out map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+
+	if count, _ := out["count"].(float64); count != 42 {
+		t.Errorf("expected count 42, got %v", out["count"])
+	}
+	if _, ok := out["data"]; ok {
+		t.Errorf("count response should not include data")
+	}
+}
+
+func TestQueryEndpoint_Distinct(t *testing.T) {
+	reg := setupRegistryForTest()
+	db := &fakeDB{rows: []map[string]interface{}{{"status": "PAID"}, {"status": "PENDING"}}}
+	a := New(reg, db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	body := `{"operation":"distinct","model":"orders","fields":["status"]}`
+	resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatalf("POST /query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var out map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+
+	values, _ := out["values"].([]interface{})
+	if len(values) != 2 || values[0] != "PAID" || values[1] != "PENDING" {
+		t.Errorf("expected values [PAID PENDING], got %v", out["values"])
+	}
+	if _, ok := out["data"]; ok {
+		t.Errorf("distinct response should not include data")
+	}
+}
+
+func TestQueryEndpoint_PaginationMetadata(t *testing.T) {
+	threeRows := []map[string]interface{}{{"id": int64(1)}, {"id": int64(2)}, {"id": int64(3)}}
+	for _, tt := range []struct {
+		name     string
+		rows     []map[string]interface{}
+		wantRows int
+		wantMore bool
+	}{
+		{"more rows", threeRows, 2, true},
+		{"last page", threeRows[:2], 2, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{rows: tt.rows}
+			a := New(setupRegistryForTest(), db, postgres.NewQueryBuilder())
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			body := `{"model":"orders","pagination":{"limit":2,"offset":4}}`
+			resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+			if err != nil {
+				t.Fatalf("POST /query failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			var out struct {
+				Data       []map[string]interface{} `json:"data"`
+				Pagination pageInfo                 `json:"pagination"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("invalid json response: %v", err)
+			}
+
+			if len(db.lastArgs) != 2 || db.lastArgs[0] != 3 || db.lastArgs[1] != 4 {
+				t.Errorf("expected one extra row to be fetched (LIMIT 3 OFFSET 4), got args %v", db.lastArgs)
+			}
+			if len(out.Data) != tt.wantRows {
+				t.Errorf("expected %d rows, got %d", tt.wantRows, len(out.Data))
+			}
+			want := pageInfo{Limit: 2, Offset: 4, HasMore: tt.wantMore}
+			if out.Pagination != want {
+				t.Errorf("pagination = %+v, want %+v", out.Pagination, want)
+			}
+		})
+	}
+}
+
+func TestQueryEndpoint_PageNumber(t *testing.T) {
+	db := &fakeDB{rows: []map[string]interface{}{{"id": int64(21)}}}
+	a := New(setupRegistryForTest(), db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	body := `{"model":"orders","pagination":{"page":3,"page_size":10}}`
+	resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatalf("POST /query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Pagination pageInfo `json:"pagination"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+
+	want := pageInfo{Limit: 10, Offset: 20, Page: 3, PageSize: 10}
+	if out.Pagination != want {
+		t.Errorf("pagination = %+v, want %+v", out.Pagination, want)
+	}
+	if len(db.lastArgs) != 2 || db.lastArgs[1] != 20 {
+		t.Errorf("expected OFFSET 20, got args %v", db.lastArgs)
+	}
+}
+
+func TestQueryEndpoint_Exists(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		rows []map[string]interface{}
+		want bool
+	}{
+		{"match", []map[string]interface{}{{"exists": true}}, true},
+		{"no match", []map[string]interface{}{{"exists": false}}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(setupRegistryForTest(), &fakeDB{rows: tt.rows}, postgres.NewQueryBuilder())
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			body := `{"operation":"exists","model":"orders","filters":{"field":"status","op":"=","value":"PAID"}}`
+			resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+			if err != nil {
+				t.Fatalf("POST /query failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("unexpected status: %d", resp.StatusCode)
+			}
+
+			var out map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("invalid json response: %v", err)
+			}
+			if out["exists"] != tt.want {
+				t.Errorf("expected exists %v, got %v", tt.want, out["exists"])
+			}
+			if _, ok := out["data"]; ok {
+				t.Errorf("exists response should not include data")
+			}
+		})
+	}
+}
+
+func TestQueryEndpoint_DeleteReturning(t *testing.T) {
+	reg := setupRegistryForTest()
+	db := &fakeDB{rows: []map[string]interface{}{{"id": int64(1)}, {"id": int64(2)}}}
+	a := New(reg, db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	body := `{"operation":"delete","model":"orders","filters":{"field":"status","op":"=","value":"VOID"},"returning":["id"]}`
+	resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatalf("POST /query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	var out map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+
+	if db.queries != 1 {
+		t.Errorf("expected delete with returning to read rows, got %d queries", db.queries)
+	}
+	if affected, _ := out["affected_rows"].(float64); affected != 2 {
+		t.Errorf("expected affected_rows 2, got %v", out["affected_rows"])
+	}
+	if data, _ := out["data"].([]interface{}); len(data) != 2 {
+		t.Errorf("expected 2 deleted ids, got %v", out["data"])
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"udv/internal/adapter/postgres"
 	"udv/internal/config"
 	"udv/internal/dsl"
 	"udv/internal/schema"
@@ -33,7 +34,7 @@ func TestCreateEndpoint_Simple(t *testing.T) {
 	reg := schema.NewRegistry()
 	reg.LoadFromConfig(cfg)
 
-	a := New(reg, nil)
+	a := New(reg, nil, postgres.NewQueryBuilder())
 	mux := http.NewServeMux()
 	a.RegisterRoutes(mux)
 
@@ -114,7 +115,7 @@ func TestCreateEndpoint_WithAllFields(t *testing.T) {
 	reg := schema.NewRegistry()
 	reg.LoadFromConfig(cfg)
 
-	a := New(reg, nil)
+	a := New(reg, nil, postgres.NewQueryBuilder())
 	mux := http.NewServeMux()
 	a.RegisterRoutes(mux)
 
@@ -175,3 +176,29 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestCreateEndpoint_InvalidData(t *testing.T) {
+	db := &fakeDB{}
+	a := New(setupRegistryForTest(), db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	body := `{"operation":"create","model":"orders","data":{"status":7,"amount":"ten"}}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", bytes.NewReader([]byte(body))))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var out ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	want := []string{`amount: expected decimal, got string "ten"`, "status: expected string, got number 7"}
+	if out.Error.Code != CodeInvalidData || len(out.Error.Details) != 2 || out.Error.Details[0] != want[0] || out.Error.Details[1] != want[1] {
+		t.Errorf("expected %s listing %q, got %+v", CodeInvalidData, want, out.Error)
+	}
+	if db.lastQuery != nil {
+		t.Errorf("expected the database not to be called, got %v", db.lastQuery)
+	}
+}
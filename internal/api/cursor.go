@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"udv/internal/dsl"
+)
+
+// DefaultCursorTTL is how long a next_cursor token stays valid unless configured otherwise
+const DefaultCursorTTL = time.Hour
+
+// errInvalidCursor hides why a token was rejected, so clients cannot probe the signature
+var errInvalidCursor = errors.New("cursor is malformed, tampered with or expired")
+
+// cursorSigner issues and verifies HMAC-SHA256 signed pagination cursors
+type cursorSigner struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// cursorPayload is the signed content of a cursor: the model, its keyset order and the
+// position of the last row of the page
+type cursorPayload struct {
+	Model     string            `json:"m"`
+	Field     string            `json:"f,omitempty"`
+	Direction dsl.SortDirection `json:"d"`
+	Value     interface{}       `json:"v,omitempty"`
+	Key       interface{}       `json:"k"`
+	Expires   int64             `json:"e"`
+}
+
+// EnableCursors turns on next_cursor tokens for keyset pagination, signed with secret. Tokens
+// expire after ttl, or DefaultCursorTTL when ttl is zero.
+func (a *API) EnableCursors(secret []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultCursorTTL
+	}
+	a.cursors = &cursorSigner{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// sign encodes payload as base64url(JSON) "." base64url(HMAC), stamping its expiry
+func (c *cursorSigner) sign(payload cursorPayload) (string, error) {
+	payload.Expires = c.now().Add(c.ttl).Unix()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.mac(body)), nil
+}
+
+// verify checks a token's signature and expiry and returns its payload
+func (c *cursorSigner) verify(token string) (cursorPayload, error) {
+	var payload cursorPayload
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return payload, errInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, c.mac(body)) {
+		return payload, errInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return payload, errInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if dec.Decode(&payload) != nil || c.now().Unix() > payload.Expires {
+		return payload, errInvalidCursor
+	}
+	payload.Value, payload.Key = cursorNumber(payload.Value), cursorNumber(payload.Key)
+	return payload, nil
+}
+
+// cursorNumber turns a decoded json.Number back into an int64 where it fits, so large integer
+// keys keep their precision
+func cursorNumber(v interface{}) interface{} {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+func (c *cursorSigner) mac(body string) []byte {
+	h := hmac.New(sha256.New, c.secret)
+	h.Write([]byte(body))
+	return h.Sum(nil)
+}
+
+// applyCursor verifies the query's cursor and sets the keyset position it carries. The
+// cursor must come from the same model and order as the query.
+func (a *API) applyCursor(q *dsl.Query) error {
+	if a.cursors == nil {
+		return fmt.Errorf("cursor pagination is not enabled on this server")
+	}
+	payload, err := a.cursors.verify(q.Pagination.Cursor)
+	if err != nil {
+		return err
+	}
+
+	field, direction, _ := a.validator.KeysetOrder(q)
+	if payload.Model != q.Model || payload.Field != field || payload.Direction != direction {
+		return fmt.Errorf("cursor was issued for a different model or sort")
+	}
+
+	q.Pagination.After = &dsl.Keyset{
+		Field:     payload.Field,
+		Direction: payload.Direction,
+		Value:     payload.Value,
+		Key:       payload.Key,
+	}
+	return nil
+}
+
+// nextCursor returns a token continuing after the last row, or "" when cursors are disabled,
+// the query's order is not unique or the row lacks the values to continue from
+func (a *API) nextCursor(q *dsl.Query, rows []map[string]interface{}) (string, error) {
+	if a.cursors == nil || len(rows) == 0 {
+		return "", nil
+	}
+	field, direction, ok := a.validator.KeysetOrder(q)
+	if !ok {
+		return "", nil
+	}
+	model := a.registry.GetModel(q.Model)
+
+	// Values must come back as stored: not renamed by field_aliases or rewritten by a transform
+	last := rows[len(rows)-1]
+	position := func(name string) (interface{}, bool) {
+		if _, aliased := q.FieldAliases[name]; aliased {
+			return nil, false
+		}
+		if f := model.Fields[name]; f == nil || f.Transform != nil {
+			return nil, false
+		}
+		value, ok := last[name]
+		return value, ok && value != nil
+	}
+
+	payload := cursorPayload{Model: q.Model, Field: field, Direction: direction}
+	if payload.Key, ok = position(model.PrimaryKey); !ok {
+		return "", nil
+	}
+	if field != "" {
+		if payload.Value, ok = position(field); !ok {
+			return "", nil
+		}
+	}
+	return a.cursors.sign(payload)
+}
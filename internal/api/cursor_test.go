@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"udv/internal/adapter/postgres"
+)
+
+func TestQueryEndpoint_Cursor(t *testing.T) {
+	db := &fakeDB{rows: []map[string]interface{}{
+		{"id": int64(1), "status": "PAID"},
+		{"id": int64(2), "status": "PAID"},
+		{"id": int64(3), "status": "NEW"},
+	}}
+	a := New(setupRegistryForTest(), db, postgres.NewQueryBuilder())
+	a.EnableCursors([]byte("test-secret"), time.Minute)
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	send := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var out map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("invalid json response: %v (%s)", err, rec.Body.String())
+		}
+		return rec.Code, out
+	}
+	errorCode := func(out map[string]interface{}) interface{} {
+		body, _ := out["error"].(map[string]interface{})
+		return body["code"]
+	}
+
+	status, out := send(`{"model":"orders","sort":[{"field":"id"}],"pagination":{"limit":2}}`)
+	info, _ := out["pagination"].(map[string]interface{})
+	cursor, _ := info["next_cursor"].(string)
+	if status != http.StatusOK || cursor == "" {
+		t.Fatalf("expected a next_cursor, got %d %v", status, out)
+	}
+
+	status, out = send(`{"model":"orders","sort":[{"field":"id"}],"pagination":{"limit":2,"cursor":"` + cursor + `"}}`)
+	if status != http.StatusOK {
+		t.Fatalf("expected the cursor to be accepted, got %d %v", status, out)
+	}
+	if sql, _ := db.lastQuery.(string); !strings.Contains(sql, "t0.id > $1") || len(db.lastArgs) == 0 || db.lastArgs[0] != int64(2) {
+		t.Errorf("expected the page to start after id 2, got %v %v", db.lastQuery, db.lastArgs)
+	}
+
+	tampered := strings.Replace(cursor, cursor[:4], "AAAA", 1)
+	rejected := map[string]string{
+		"tampered":        `{"model":"orders","sort":[{"field":"id"}],"pagination":{"limit":2,"cursor":"` + tampered + `"}}`,
+		"malformed":       `{"model":"orders","sort":[{"field":"id"}],"pagination":{"limit":2,"cursor":"not-a-cursor"}}`,
+		"different order": `{"model":"orders","sort":[{"field":"id","direction":"desc"}],"pagination":{"limit":2,"cursor":"` + cursor + `"}}`,
+	}
+	for name, body := range rejected {
+		if status, out := send(body); status != http.StatusBadRequest || errorCode(out) != string(CodeInvalidCursor) {
+			t.Errorf("%s: expected 400 %s, got %d %v", name, CodeInvalidCursor, status, out)
+		}
+	}
+
+	a.cursors.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if status, out := send(`{"model":"orders","sort":[{"field":"id"}],"pagination":{"limit":2,"cursor":"` + cursor + `"}}`); status != http.StatusBadRequest {
+		t.Errorf("expected an expired cursor to be rejected, got %d %v", status, out)
+	}
+
+	a.cursors = nil
+	if status, out := send(`{"model":"orders","sort":[{"field":"id"}],"pagination":{"limit":2,"cursor":"` + cursor + `"}}`); status != http.StatusBadRequest || errorCode(out) != string(CodeInvalidCursor) {
+		t.Errorf("expected cursors to be rejected when disabled, got %d %v", status, out)
+	}
+	if _, out := send(`{"model":"orders","sort":[{"field":"id"}],"pagination":{"limit":2}}`); out["pagination"].(map[string]interface{})["next_cursor"] != nil {
+		t.Errorf("expected no next_cursor when disabled, got %v", out["pagination"])
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+
+	"udv/internal/adapter"
+	"udv/internal/config"
+)
+
+// datasource is a named database and the query builder for its dialect
+type datasource struct {
+	db      adapter.Database
+	builder adapter.QueryBuilder
+	dbType  string
+	health  *healthChecker
+}
+
+// AddDatasource registers a named database for models whose config sets "datasource".
+// Models without one use the database passed to New.
+func (a *API) AddDatasource(name string, db adapter.Database, builder adapter.QueryBuilder, dbType string) {
+	if a.datasources == nil {
+		a.datasources = make(map[string]*datasource)
+	}
+	a.datasources[name] = &datasource{
+		db:      db,
+		builder: builder,
+		dbType:  dbType,
+		health:  newHealthChecker(db, healthCacheTTL),
+	}
+}
+
+// backendFor returns the query builder and (possibly nil) database serving a model
+func (a *API) backendFor(modelName string) (adapter.QueryBuilder, adapter.Database, error) {
+	model := a.registry.GetModel(modelName)
+	if model == nil || model.Datasource == "" || model.Datasource == config.DefaultDatasource {
+		return a.builder, a.db, nil
+	}
+	ds, ok := a.datasources[model.Datasource]
+	if !ok {
+		return nil, nil, fmt.Errorf("datasource %s of model %s is not configured", model.Datasource, modelName)
+	}
+	return ds.builder, ds.db, nil
+}
+
+// checkDatasources returns the state of every named datasource and the first failure, if any
+func (a *API) checkDatasources() (map[string]string, error) {
+	if len(a.datasources) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(a.datasources))
+	for name := range a.datasources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	states := make(map[string]string, len(names))
+	var firstErr error
+	for _, name := range names {
+		state, err := a.datasources[name].health.check()
+		states[name] = state
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("datasource %s: %w", name, err)
+		}
+	}
+	return states, firstErr
+}
+
+// datasourceTypes maps each named datasource to its database type
+func (a *API) datasourceTypes() map[string]string {
+	if len(a.datasources) == 0 {
+		return nil
+	}
+	types := make(map[string]string, len(a.datasources))
+	for name, ds := range a.datasources {
+		types[name] = ds.dbType
+	}
+	return types
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"udv/internal/adapter/mongodb"
+	"udv/internal/adapter/postgres"
+	"udv/internal/config"
+	"udv/internal/schema"
+)
+
+// setupDatasourceRegistry returns orders on the default database and events on "analytics"
+func setupDatasourceRegistry(t *testing.T) *schema.Registry {
+	t.Helper()
+	cfg := &config.Config{
+		Datasources: []config.Datasource{{Name: "analytics", Type: "mongodb", URL: "mongodb://x", Database: "analytics"}},
+		Models: []config.Model{
+			{Name: "orders", Table: "orders", PrimaryKey: "id", Fields: []config.Field{{Name: "id", Type: "integer"}}},
+			{Name: "events", Table: "events", PrimaryKey: "id", Datasource: "analytics", Fields: []config.Field{{Name: "id", Type: "integer"}}},
+		},
+	}
+	reg := schema.NewRegistry()
+	if err := reg.LoadFromConfig(cfg); err != nil {
+		t.Fatalf("LoadFromConfig failed: %v", err)
+	}
+	return reg
+}
+
+func TestQueryEndpoint_RoutesToDatasource(t *testing.T) {
+	defaultDB := &fakeDB{rows: []map[string]interface{}{{"id": int64(1)}}}
+	analyticsDB := &fakeDB{rows: []map[string]interface{}{{"id": int64(2)}}}
+	a := New(setupDatasourceRegistry(t), defaultDB, postgres.NewQueryBuilder())
+	a.AddDatasource("analytics", analyticsDB, mongodb.NewQueryBuilder(), "mongodb")
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for _, model := range []string{"orders", "events"} {
+		resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(`{"operation":"select","model":"`+model+`"}`)))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: unexpected status: %d", model, resp.StatusCode)
+		}
+	}
+
+	if defaultDB.queries != 1 || analyticsDB.queries != 1 {
+		t.Fatalf("expected one query per database, got default=%d analytics=%d", defaultDB.queries, analyticsDB.queries)
+	}
+	if _, ok := defaultDB.lastQuery.(string); !ok {
+		t.Errorf("expected SQL for the default database, got %T", defaultDB.lastQuery)
+	}
+	if _, ok := analyticsDB.lastQuery.(*mongodb.MongoQuery); !ok {
+		t.Errorf("expected a Mongo query for the analytics datasource, got %T", analyticsDB.lastQuery)
+	}
+}
+
+func TestQueryEndpoint_MissingDatasource(t *testing.T) {
+	a := New(setupDatasourceRegistry(t), &fakeDB{}, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(`{"operation":"select","model":"events"}`)))
+	if err != nil {
+		t.Fatalf("POST /query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected 500 for an unregistered datasource, got %d", resp.StatusCode)
+	}
+}
+
+func TestHealthEndpoint_Datasources(t *testing.T) {
+	a := New(setupDatasourceRegistry(t), &fakeDB{}, postgres.NewQueryBuilder())
+	a.AddDatasource("analytics", &fakeDB{pingErr: errors.New("connection refused")}, mongodb.NewQueryBuilder(), "mongodb")
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when a datasource is down, got %d", rec.Code)
+	}
+	var resp healthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if resp.Database != dbStateUp || resp.Datasources["analytics"] != dbStateDown {
+		t.Errorf("unexpected health response: %+v", resp)
+	}
+}
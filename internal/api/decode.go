@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"udv/internal/dsl"
+)
+
+// decodeStrict decodes a single JSON value into v, rejecting unknown fields and trailing data
+func decodeStrict(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errors.New("request body must contain a single JSON object")
+	}
+	return nil
+}
+
+// rawLogicalFilter mirrors dsl.LogicalFilter with its children left undecoded
+type rawLogicalFilter struct {
+	And []json.RawMessage `json:"and"`
+	Or  []json.RawMessage `json:"or"`
+	Not json.RawMessage   `json:"not"`
+}
+
+// rawSubqueryFilter mirrors dsl.ComparisonFilter with the subquery's filters left undecoded
+type rawSubqueryFilter struct {
+	Field      string             `json:"field"`
+	Op         dsl.FilterOperator `json:"op"`
+	Value      interface{}        `json:"value"`
+	ValueField string             `json:"value_field"`
+	Subquery   struct {
+		Model   string          `json:"model"`
+		Field   string          `json:"field"`
+		Filters json.RawMessage `json:"filters"`
+	} `json:"subquery"`
+}
+
+// decodeFilters decodes a filter expression, choosing the logical, element-match or comparison form by its keys.
+// Logical filters are decoded recursively so they may nest.
+func decodeFilters(raw json.RawMessage) (dsl.FilterExpr, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		return nil, errors.New("filter must be a JSON object")
+	}
+
+	_, hasAnd := keys["and"]
+	_, hasOr := keys["or"]
+	_, hasNot := keys["not"]
+	if hasAnd || hasOr || hasNot {
+		var rlf rawLogicalFilter
+		if err := decodeStrict(bytes.NewReader(raw), &rlf); err != nil {
+			return nil, err
+		}
+
+		var lf dsl.LogicalFilter
+		var err error
+		if hasAnd {
+			if lf.And, err = decodeFilterList(rlf.And); err != nil {
+				return nil, err
+			}
+		}
+		if hasOr {
+			if lf.Or, err = decodeFilterList(rlf.Or); err != nil {
+				return nil, err
+			}
+		}
+		if hasNot {
+			if lf.Not, err = decodeFilters(rlf.Not); err != nil {
+				return nil, err
+			}
+		}
+		return &lf, nil
+	}
+
+	if _, ok := keys["elem_match"]; ok {
+		var ef dsl.ElemMatchFilter
+		if err := decodeStrict(bytes.NewReader(raw), &ef); err != nil {
+			return nil, err
+		}
+		return &ef, nil
+	}
+
+	if _, ok := keys["subquery"]; ok {
+		return decodeSubqueryFilter(raw)
+	}
+
+	var cf dsl.ComparisonFilter
+	if err := decodeStrict(bytes.NewReader(raw), &cf); err != nil {
+		return nil, err
+	}
+	return &cf, nil
+}
+
+// decodeSubqueryFilter decodes a comparison filter with a subquery, whose own filters may nest
+func decodeSubqueryFilter(raw json.RawMessage) (dsl.FilterExpr, error) {
+	var rsf rawSubqueryFilter
+	if err := decodeStrict(bytes.NewReader(raw), &rsf); err != nil {
+		return nil, err
+	}
+
+	sub := &dsl.Subquery{Model: rsf.Subquery.Model, Field: rsf.Subquery.Field}
+	if len(rsf.Subquery.Filters) > 0 && string(rsf.Subquery.Filters) != "null" {
+		filters, err := decodeFilters(rsf.Subquery.Filters)
+		if err != nil {
+			return nil, err
+		}
+		sub.Filters = filters
+	}
+	return &dsl.ComparisonFilter{
+		Field:      rsf.Field,
+		Op:         rsf.Op,
+		Value:      rsf.Value,
+		ValueField: rsf.ValueField,
+		Subquery:   sub,
+	}, nil
+}
+
+// decodeFilterList decodes the children of an and/or filter, keeping an empty list non-nil
+func decodeFilterList(raws []json.RawMessage) ([]dsl.FilterExpr, error) {
+	filters := make([]dsl.FilterExpr, 0, len(raws))
+	for _, raw := range raws {
+		f, err := decodeFilters(raw)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// decodeErrorResponse maps a JSON decode error to a status and a message naming the offending field
+func decodeErrorResponse(err error) (int, string) {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit)
+	case errors.As(err, &syntaxErr):
+		return http.StatusBadRequest, fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return http.StatusBadRequest, "malformed JSON: unexpected end of input"
+	case errors.Is(err, io.EOF):
+		return http.StatusBadRequest, "request body is empty"
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return http.StatusBadRequest, fmt.Sprintf("expected %s, got JSON %s", typeErr.Type, typeErr.Value)
+		}
+		return http.StatusBadRequest, fmt.Sprintf("field %q must be %s, got JSON %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		return http.StatusBadRequest, "unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	default:
+		return http.StatusBadRequest, err.Error()
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/dsl"
+	"udv/internal/limits"
+)
+
+func TestQueryEndpoint_DecodeHardening(t *testing.T) {
+	reg := setupRegistryForTest()
+	a := New(reg, nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	tests := []struct {
+		name       string
+		body       string
+		status     int
+		code       ErrorCode
+		wantDetail string
+	}{
+		{"unknown top-level field", `{"model":"orders","feilds":["id"]}`, http.StatusBadRequest, CodeInvalidRequest, `unknown field "feilds"`},
+		{"wrong field type", `{"model":"orders","fields":"id"}`, http.StatusBadRequest, CodeInvalidRequest, `field "fields"`},
+		{"malformed json", `{"model":"orders",}`, http.StatusBadRequest, CodeInvalidRequest, "malformed JSON at offset"},
+		{"truncated json", `{"model":`, http.StatusBadRequest, CodeInvalidRequest, "unexpected end of input"},
+		{"empty body", ``, http.StatusBadRequest, CodeInvalidRequest, "request body is empty"},
+		{"trailing data", `{"model":"orders"} {}`, http.StatusBadRequest, CodeInvalidRequest, "single JSON object"},
+		{"unknown comparison filter field", `{"model":"orders","filters":{"field":"status","op":"=","valeu":"PAID"}}`, http.StatusBadRequest, CodeInvalidRequest, `unknown field "valeu"`},
+		{"unknown nested filter field", `{"model":"orders","filters":{"and":[{"feild":"status","op":"=","value":"PAID"}]}}`, http.StatusBadRequest, CodeInvalidRequest, `unknown field "feild"`},
+		{"unknown deeply nested filter field", `{"model":"orders","filters":{"or":[{"not":{"feild":"status","op":"=","value":"PAID"}}]}}`, http.StatusBadRequest, CodeInvalidRequest, `unknown field "feild"`},
+		{"unknown elem_match condition field", `{"model":"orders","filters":{"field":"status","elem_match":[{"field":"sku","op":"=","valeu":"A"}]}}`, http.StatusBadRequest, CodeInvalidRequest, `unknown field "valeu"`},
+		{"null filter", `{"model":"orders","filters":{"not":null}}`, http.StatusBadRequest, CodeInvalidRequest, "must be a JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+
+			var out ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+				t.Fatalf("invalid error envelope: %v", err)
+			}
+			if out.Error.Code != tt.code {
+				t.Errorf("expected code %s, got %s", tt.code, out.Error.Code)
+			}
+			if len(out.Error.Details) == 0 || !strings.Contains(out.Error.Details[0], tt.wantDetail) {
+				t.Errorf("expected detail containing %q, got %v", tt.wantDetail, out.Error.Details)
+			}
+		})
+	}
+}
+
+func TestQueryEndpoint_BodyTooLarge(t *testing.T) {
+	original := limits.MaxRequestBodyBytes
+	limits.MaxRequestBodyBytes = 64
+	defer func() { limits.MaxRequestBodyBytes = original }()
+
+	reg := setupRegistryForTest()
+	a := New(reg, nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	body := `{"model":"orders","fields":["` + strings.Repeat("x", 128) + `"]}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body)))
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", rec.Code)
+	}
+
+	var out ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatalf("invalid error envelope: %v", err)
+	}
+	if out.Error.Code != CodeRequestTooLarge {
+		t.Errorf("expected code %s, got %s", CodeRequestTooLarge, out.Error.Code)
+	}
+}
+
+func TestQueryEndpoint_FilterDepthLimit(t *testing.T) {
+	reg := setupRegistryForTest()
+	a := New(reg, nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	nested := func(depth int) string {
+		f := `{"field":"status","op":"=","value":"PAID"}`
+		for i := 0; i < depth; i++ {
+			f = `{"and":[` + f + `]}`
+		}
+		return `{"model":"orders","filters":` + f + `}`
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"at max depth", nested(limits.MaxFilterDepth), http.StatusOK},
+		{"beyond max depth", nested(limits.MaxFilterDepth + 1), http.StatusBadRequest},
+		{"empty and", `{"model":"orders","filters":{"and":[]}}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDecodeFilters_ElemMatch(t *testing.T) {
+	raw := `{"and":[{"field":"items","elem_match":[{"field":"sku","op":"=","value":"A1"},{"field":"qty","op":">=","value":2}]}]}`
+	expr, err := decodeFilters(json.RawMessage(raw))
+	if err != nil {
+		t.Fatalf("decodeFilters failed: %v", err)
+	}
+
+	lf, ok := expr.(*dsl.LogicalFilter)
+	if !ok || len(lf.And) != 1 {
+		t.Fatalf("expected and filter with one child, got %#v", expr)
+	}
+	ef, ok := lf.And[0].(*dsl.ElemMatchFilter)
+	if !ok {
+		t.Fatalf("expected elem_match filter, got %T", lf.And[0])
+	}
+	if ef.Field != "items" || len(ef.ElemMatch) != 2 || ef.ElemMatch[1].Field != "qty" || ef.ElemMatch[1].Op != dsl.OpGTE {
+		t.Errorf("unexpected elem_match filter: %+v", ef)
+	}
+}
+
+func TestDecodeFilters_Subquery(t *testing.T) {
+	raw := `{"field":"user_id","op":"in","subquery":{"model":"users","field":"id","filters":{"or":[{"field":"active","op":"=","value":true}]}}}`
+	expr, err := decodeFilters(json.RawMessage(raw))
+	if err != nil {
+		t.Fatalf("decodeFilters failed: %v", err)
+	}
+
+	cf, ok := expr.(*dsl.ComparisonFilter)
+	if !ok || cf.Subquery == nil {
+		t.Fatalf("expected comparison filter with a subquery, got %#v", expr)
+	}
+	if cf.Field != "user_id" || cf.Op != dsl.OpIn || cf.Subquery.Model != "users" || cf.Subquery.Field != "id" {
+		t.Errorf("unexpected subquery filter: %+v", cf)
+	}
+	if lf, ok := cf.Subquery.Filters.(*dsl.LogicalFilter); !ok || len(lf.Or) != 1 {
+		t.Errorf("expected nested or filter in subquery, got %#v", cf.Subquery.Filters)
+	}
+
+	if _, err := decodeFilters(json.RawMessage(`{"field":"user_id","op":"in","subquery":{"model":"users","field":"id","extra":1}}`)); err == nil {
+		t.Error("expected unknown subquery key to be rejected")
+	}
+}
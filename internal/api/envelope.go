@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DefaultEnvelope is the /query response shape used when a request does not ask for one
+const DefaultEnvelope = "v1"
+
+// envelopeMediaPrefix and envelopeMediaSuffix wrap a version token in an Accept media type,
+// e.g. application/vnd.udv.bare+json
+const (
+	envelopeMediaPrefix = "application/vnd.udv."
+	envelopeMediaSuffix = "+json"
+)
+
+// envelopes shapes a /query response for each version token. New shapes are added here so
+// existing clients keep the one they were written against.
+var envelopes = map[string]func(resp map[string]interface{}) interface{}{
+	// v1 is the full object: sql, params, and data, pagination, count, values, exists or affected_rows
+	"v1": func(resp map[string]interface{}) interface{} {
+		return resp
+	},
+	// bare is the result alone: the rows (or row for first/last), count, values or exists,
+	// falling back to affected_rows for deletes without returning
+	"bare": func(resp map[string]interface{}) interface{} {
+		for _, key := range []string{"data", "values", "count", "exists", "affected_rows"} {
+			if value, ok := resp[key]; ok {
+				return value
+			}
+		}
+		return nil
+	},
+}
+
+// responseEnvelope returns the version token requested with ?envelope= or, failing that,
+// an Accept media type of the form application/vnd.udv.<token>+json
+func responseEnvelope(r *http.Request) (string, error) {
+	if token := r.URL.Query().Get("envelope"); token != "" {
+		return knownEnvelope(token)
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || !strings.HasPrefix(mediaType, envelopeMediaPrefix) || !strings.HasSuffix(mediaType, envelopeMediaSuffix) {
+			continue
+		}
+		return knownEnvelope(strings.TrimSuffix(strings.TrimPrefix(mediaType, envelopeMediaPrefix), envelopeMediaSuffix))
+	}
+	return DefaultEnvelope, nil
+}
+
+// knownEnvelope checks that token names a response envelope
+func knownEnvelope(token string) (string, error) {
+	if _, ok := envelopes[token]; ok {
+		return token, nil
+	}
+	names := make([]string, 0, len(envelopes))
+	for name := range envelopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("unknown response envelope %q; supported envelopes are %s", token, strings.Join(names, ", "))
+}
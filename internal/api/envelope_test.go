@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+)
+
+func TestQueryEndpoint_ResponseEnvelope(t *testing.T) {
+	db := &fakeDB{rows: []map[string]interface{}{{"id": int64(1), "status": "PAID"}}}
+	a := New(setupRegistryForTest(), db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	send := func(path, accept, body string) (*httptest.ResponseRecorder, interface{}) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var out interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("invalid json response: %v (%s)", err, rec.Body.String())
+		}
+		return rec, out
+	}
+	selectBody := `{"model":"orders"}`
+	bareRows := []interface{}{map[string]interface{}{"id": float64(1), "status": "PAID"}}
+
+	_, out := send("/query", "", selectBody)
+	if obj, ok := out.(map[string]interface{}); !ok || obj["data"] == nil || obj["sql"] == nil {
+		t.Errorf("expected the v1 object by default, got %v", out)
+	}
+
+	_, out = send("/query?envelope=bare", "", selectBody)
+	if !reflect.DeepEqual(out, bareRows) {
+		t.Errorf("expected a bare array, got %v", out)
+	}
+
+	_, out = send("/query", "application/vnd.udv.bare+json", selectBody)
+	if !reflect.DeepEqual(out, bareRows) {
+		t.Errorf("expected Accept to select the bare envelope, got %v", out)
+	}
+
+	_, out = send("/query?envelope=v1", "application/vnd.udv.bare+json", selectBody)
+	if _, ok := out.(map[string]interface{}); !ok {
+		t.Errorf("expected the query parameter to take precedence, got %v", out)
+	}
+
+	db.rows = []map[string]interface{}{{"count": int64(7)}}
+	_, out = send("/query?envelope=bare", "", `{"operation":"count","model":"orders"}`)
+	if out != float64(7) {
+		t.Errorf("expected a bare count, got %v", out)
+	}
+
+	db.lastQuery = nil
+	rec, out := send("/query?envelope=v9", "", `{"operation":"delete","model":"orders","id":1}`)
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected 406 for an unknown envelope, got %d", rec.Code)
+	}
+	if body, _ := out.(map[string]interface{})["error"].(map[string]interface{}); body["code"] != string(CodeUnsupportedEnvelope) {
+		t.Errorf("expected code %s, got %v", CodeUnsupportedEnvelope, out)
+	}
+	if db.lastQuery != nil {
+		t.Errorf("expected the write not to run, got %v", db.lastQuery)
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"udv/internal/dsl"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an API error
+type ErrorCode string
+
+const (
+	CodeMethodNotAllowed ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeInvalidRequest   ErrorCode = "INVALID_REQUEST"
+	CodeRequestTooLarge  ErrorCode = "REQUEST_TOO_LARGE"
+	CodeValidationFailed ErrorCode = "VALIDATION_FAILED"
+	CodeModelNotFound    ErrorCode = "MODEL_NOT_FOUND"
+	CodePlanningFailed   ErrorCode = "PLANNING_FAILED"
+	CodeBuildFailed      ErrorCode = "BUILD_FAILED"
+	CodeExecutionFailed  ErrorCode = "EXECUTION_FAILED"
+
+	// CodeInvalidData reports create or update data that does not fit the fields' types or
+	// nullability, with one detail per offending field
+	CodeInvalidData ErrorCode = "INVALID_DATA"
+
+	// CodeVersionConflict reports an update whose expected_version no longer matches the row
+	CodeVersionConflict ErrorCode = "VERSION_CONFLICT"
+
+	// CodeNotFound reports a first or last query that matched no row
+	CodeNotFound ErrorCode = "NOT_FOUND"
+
+	// CodeExplainDisabled rejects ?explain=true on a server that has not enabled it
+	CodeExplainDisabled ErrorCode = "EXPLAIN_DISABLED"
+
+	// CodeModelFailed reports a model that was left out at startup because its config is invalid
+	CodeModelFailed ErrorCode = "MODEL_FAILED"
+
+	// CodeInvalidCursor rejects a pagination cursor that is forged, expired or from another query
+	CodeInvalidCursor ErrorCode = "INVALID_CURSOR"
+
+	// CodeAdminDisabled rejects an /admin request on a server that has not set an admin token
+	CodeAdminDisabled ErrorCode = "ADMIN_DISABLED"
+
+	// CodeUnauthorized rejects an /admin request without the admin token
+	CodeUnauthorized ErrorCode = "UNAUTHORIZED"
+
+	// CodeUnsupportedEnvelope rejects a request for a response envelope the server does not know
+	CodeUnsupportedEnvelope ErrorCode = "UNSUPPORTED_ENVELOPE"
+)
+
+// ErrorBody is the payload of an error response
+type ErrorBody struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Details []string  `json:"details,omitempty"`
+}
+
+// ErrorResponse is the envelope returned for every failed request
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// writeError writes a JSON error envelope with the given status and code
+func writeError(w http.ResponseWriter, status int, code ErrorCode, message string, details ...string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: ErrorBody{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	})
+}
+
+// writeQueryError maps an error from a query pipeline stage to a status and code.
+// Unknown models are reported as 404 regardless of the stage that detected them, and
+// invalid data as 400 with a detail per field.
+func writeQueryError(w http.ResponseWriter, status int, code ErrorCode, message string, err error) {
+	if errors.Is(err, dsl.ErrModelNotFound) {
+		writeError(w, http.StatusNotFound, CodeModelNotFound, message, err.Error())
+		return
+	}
+	var dataErr *dsl.DataError
+	if errors.As(err, &dataErr) {
+		writeError(w, http.StatusBadRequest, CodeInvalidData, "invalid data", dataErr.Details()...)
+		return
+	}
+	writeError(w, status, code, message, err.Error())
+}
+
+// writeMethodNotAllowed writes the standard 405 error envelope
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+)
+
+func TestQueryEndpoint_ErrorEnvelope(t *testing.T) {
+	reg := setupRegistryForTest()
+	a := New(reg, nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   ErrorCode
+	}{
+		{"malformed body", `{"model":`, http.StatusBadRequest, CodeInvalidRequest},
+		{"unknown model", `{"model":"missing"}`, http.StatusNotFound, CodeModelNotFound},
+		{"unknown field", `{"model":"orders","fields":["nope"]}`, http.StatusBadRequest, CodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(tt.body)))
+			if err != nil {
+				t.Fatalf("POST /query failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+
+			var out ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("invalid error envelope: %v", err)
+			}
+			if out.Error.Code != tt.code {
+				t.Errorf("expected code %s, got %s", tt.code, out.Error.Code)
+			}
+			if out.Error.Message == "" {
+				t.Errorf("expected non-empty error message")
+			}
+		})
+	}
+}
+
+func TestModelsEndpoint_MethodNotAllowed(t *testing.T) {
+	reg := setupRegistryForTest()
+	a := New(reg, nil, postgres.NewQueryBuilder())
+
+	rec := httptest.NewRecorder()
+	a.handleModels(rec, httptest.NewRequest(http.MethodPost, "/models", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", rec.Code)
+	}
+
+	var out ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatalf("invalid error envelope: %v", err)
+	}
+	if out.Error.Code != CodeMethodNotAllowed {
+		t.Errorf("expected code %s, got %s", CodeMethodNotAllowed, out.Error.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"udv/internal/adapter"
+	"udv/internal/metrics"
+)
+
+// EnableExplain allows clients to request execution plans with ?explain=true. EXPLAIN ANALYZE
+// runs the query, so this is meant for debugging deployments rather than public ones.
+func (a *API) EnableExplain() {
+	a.explainEnabled = true
+}
+
+// writeExplain runs a built query under EXPLAIN ANALYZE and writes its plan next to the query.
+// Writes are measured but rolled back by the database.
+func (a *API) writeExplain(w http.ResponseWriter, db adapter.Database, query interface{}, params []interface{}, metricModel, metricOperation string) {
+	if !a.explainEnabled {
+		writeError(w, http.StatusForbidden, CodeExplainDisabled, "explain is disabled", "start the server with ENABLE_EXPLAIN=true to allow it")
+		return
+	}
+	explainer, ok := db.(adapter.Explainer)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "explain is not supported", "explain requires a PostgreSQL database connection")
+		return
+	}
+
+	plan, err := explainer.Explain(query, params...)
+	if err != nil {
+		metrics.RecordQueryError(metricModel, metricOperation, metrics.ErrExecution)
+		writeError(w, http.StatusInternalServerError, CodeExecutionFailed, "explain error", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"sql":    query,
+		"params": params,
+		"plan":   plan,
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter"
+	"udv/internal/adapter/postgres"
+)
+
+// explainDB is a fakeDB that also reports execution plans
+type explainDB struct {
+	*fakeDB
+	explained interface{}
+}
+
+func (e *explainDB) Explain(query interface{}, args ...interface{}) (json.RawMessage, error) {
+	e.explained = query
+	return json.RawMessage(`[{"Plan":{"Node Type":"Seq Scan"}}]`), nil
+}
+
+func TestQueryEndpoint_Explain(t *testing.T) {
+	tests := []struct {
+		name       string
+		db         adapter.Database
+		enabled    bool
+		wantStatus int
+	}{
+		{"disabled", &explainDB{fakeDB: &fakeDB{}}, false, http.StatusForbidden},
+		{"enabled", &explainDB{fakeDB: &fakeDB{}}, true, http.StatusOK},
+		{"database without explain", &fakeDB{}, true, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(setupRegistryForTest(), tt.db, postgres.NewQueryBuilder())
+			if tt.enabled {
+				a.EnableExplain()
+			}
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Post(ts.URL+"/query?explain=true", "application/json", strings.NewReader(`{"model":"orders"}`))
+			if err != nil {
+				t.Fatalf("POST /query failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var out map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatalf("invalid json response: %v", err)
+			}
+			if _, ok := out["plan"].([]interface{}); !ok {
+				t.Errorf("expected plan in response, got %v", out)
+			}
+			if _, ok := out["data"]; ok {
+				t.Errorf("explain should not return results, got %v", out)
+			}
+			db := tt.db.(*explainDB)
+			if db.queries != 0 || db.explained != out["sql"] {
+				t.Errorf("expected only the explained query to run, got %d queries and explained %v", db.queries, db.explained)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+)
+
+func TestQueryEndpoint_FirstLast(t *testing.T) {
+	db := &fakeDB{}
+	a := New(setupRegistryForTest(), db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	post := func(body string) (*http.Response, map[string]interface{}) {
+		resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+		return resp, out
+	}
+
+	db.rows = []map[string]interface{}{{"id": int64(9), "status": "PAID"}}
+	resp, out := post(`{"operation":"last","model":"orders","filters":{"field":"status","op":"=","value":"PAID"}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+	row, ok := out["data"].(map[string]interface{})
+	if !ok || row["id"] != float64(9) {
+		t.Errorf("expected a single row object, got %v", out["data"])
+	}
+	if _, ok := out["pagination"]; ok {
+		t.Errorf("last response should not include pagination")
+	}
+	if sql, _ := db.lastQuery.(string); !strings.Contains(sql, "ORDER BY t0.id DESC") {
+		t.Errorf("expected a primary key sort, got %s", sql)
+	}
+
+	db.rows = nil
+	resp, out = post(`{"operation":"first","model":"orders"}`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 when nothing matches, got %d", resp.StatusCode)
+	}
+	if body, _ := out["error"].(map[string]interface{}); body["code"] != string(CodeNotFound) {
+		t.Errorf("expected code %s, got %v", CodeNotFound, out["error"])
+	}
+}
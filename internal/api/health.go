@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"udv/internal/adapter"
+)
+
+// healthCacheTTL is how long a database ping result is reused before pinging again
+const healthCacheTTL = 5 * time.Second
+
+// Database states reported by the health endpoint
+const (
+	dbStateUp            = "up"
+	dbStateDown          = "down"
+	dbStateNotConfigured = "not_configured"
+)
+
+// healthChecker pings the database and caches the result for a short TTL
+type healthChecker struct {
+	db  adapter.Database
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	lastErr   error
+}
+
+// newHealthChecker creates a health checker for the given (possibly nil) database
+func newHealthChecker(db adapter.Database, ttl time.Duration) *healthChecker {
+	return &healthChecker{db: db, ttl: ttl, now: time.Now}
+}
+
+// check returns the database state, pinging only when the cached result has expired
+func (h *healthChecker) check() (string, error) {
+	if h.db == nil {
+		return dbStateNotConfigured, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	if h.checkedAt.IsZero() || now.Sub(h.checkedAt) >= h.ttl {
+		h.lastErr = h.db.Ping()
+		h.checkedAt = now
+	}
+
+	if h.lastErr != nil {
+		return dbStateDown, h.lastErr
+	}
+	return dbStateUp, nil
+}
+
+// healthResponse is the body returned by the health endpoint
+type healthResponse struct {
+	Status      string            `json:"status"`
+	Database    string            `json:"database"`
+	Datasources map[string]string `json:"datasources,omitempty"`
+}
+
+// handleHealth reports whether the service and its database are healthy
+func (a *API) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	state, err := a.health.check()
+	resp := healthResponse{Status: "ok", Database: state}
+	var dsErr error
+	resp.Datasources, dsErr = a.checkDatasources()
+	status := http.StatusOK
+	if err != nil || dsErr != nil {
+		resp.Status = "degraded"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// readinessResponse is the body returned by the readiness endpoint
+type readinessResponse struct {
+	Status   string `json:"status"`
+	Registry string `json:"registry"`
+	Database string `json:"database"`
+}
+
+// handleLivez reports that the process is up and serving HTTP
+func (a *API) handleLivez(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether the registry is loaded and every database is reachable
+func (a *API) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	resp := readinessResponse{Status: "ready", Registry: "loaded"}
+	if a.registry == nil || len(a.registry.ListModels()) == 0 {
+		resp.Registry = "empty"
+	}
+	state, err := a.health.check()
+	resp.Database = state
+	// Named datasources are reported by /health; any of them being down makes the service not ready
+	_, dsErr := a.checkDatasources()
+
+	status := http.StatusOK
+	if err != nil || dsErr != nil || resp.Registry != "loaded" {
+		resp.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
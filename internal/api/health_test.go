@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/schema"
+)
+
+func TestHealthEndpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		db           *fakeDB
+		wantStatus   int
+		wantBody     string
+		wantDatabase string
+	}{
+		{"no database configured", nil, http.StatusOK, "ok", dbStateNotConfigured},
+		{"database up", &fakeDB{}, http.StatusOK, "ok", dbStateUp},
+		{"database down", &fakeDB{pingErr: errors.New("connection refused")}, http.StatusServiceUnavailable, "degraded", dbStateDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := setupRegistryForTest()
+			var a *API
+			if tt.db == nil {
+				a = New(reg, nil, postgres.NewQueryBuilder())
+			} else {
+				a = New(reg, tt.db, postgres.NewQueryBuilder())
+			}
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			var resp healthResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Status != tt.wantBody {
+				t.Errorf("Expected status %q, got %q", tt.wantBody, resp.Status)
+			}
+			if resp.Database != tt.wantDatabase {
+				t.Errorf("Expected database %q, got %q", tt.wantDatabase, resp.Database)
+			}
+		})
+	}
+}
+
+func TestHealthChecker_CachesPing(t *testing.T) {
+	db := &fakeDB{}
+	now := time.Unix(0, 0)
+	h := newHealthChecker(db, time.Second)
+	h.now = func() time.Time { return now }
+
+	h.check()
+	h.check()
+	if db.pings != 1 {
+		t.Errorf("Expected 1 ping within TTL, got %d", db.pings)
+	}
+
+	now = now.Add(time.Second)
+	db.pingErr = errors.New("connection refused")
+	if state, err := h.check(); err == nil || state != dbStateDown {
+		t.Errorf("Expected down after TTL expiry, got %q (err %v)", state, err)
+	}
+	if db.pings != 2 {
+		t.Errorf("Expected 2 pings after TTL expiry, got %d", db.pings)
+	}
+}
+
+func TestLivezEndpoint(t *testing.T) {
+	a := New(schema.NewRegistry(), nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestReadyzEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		reg        *schema.Registry
+		db         *fakeDB
+		wantStatus int
+		wantBody   readinessResponse
+	}{
+		{"empty registry", schema.NewRegistry(), &fakeDB{}, http.StatusServiceUnavailable, readinessResponse{"not_ready", "empty", dbStateUp}},
+		{"database down", setupRegistryForTest(), &fakeDB{pingErr: errors.New("connection refused")}, http.StatusServiceUnavailable, readinessResponse{"not_ready", "loaded", dbStateDown}},
+		{"database up", setupRegistryForTest(), &fakeDB{}, http.StatusOK, readinessResponse{"ready", "loaded", dbStateUp}},
+		{"no database configured", setupRegistryForTest(), nil, http.StatusOK, readinessResponse{"ready", "loaded", dbStateNotConfigured}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a *API
+			if tt.db == nil {
+				a = New(tt.reg, nil, postgres.NewQueryBuilder())
+			} else {
+				a = New(tt.reg, tt.db, postgres.NewQueryBuilder())
+			}
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			var resp readinessResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp != tt.wantBody {
+				t.Errorf("Expected %+v, got %+v", tt.wantBody, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// redactedValue replaces parameter values in logs when redaction is enabled
+const redactedValue = "[REDACTED]"
+
+// requestLogKey is the context key for the per-request log entry
+type requestLogKey struct{}
+
+// requestLog collects query details from handlers for the request log line
+type requestLog struct {
+	Model     string
+	Operation string
+	Rows      int64
+	HasRows   bool
+	Params    []interface{}
+}
+
+// requestLogFrom returns the request log entry, or nil when logging is not enabled
+func requestLogFrom(ctx context.Context) *requestLog {
+	entry, _ := ctx.Value(requestLogKey{}).(*requestLog)
+	return entry
+}
+
+// statusRecorder captures the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// LoggingMiddleware emits one structured log line per request, tagged with the
+// request ID when RequestIDMiddleware runs first.
+// Bound parameter values are only logged at debug level, and are replaced
+// with a placeholder when redactParams is set.
+func LoggingMiddleware(logger *slog.Logger, redactParams bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &requestLog{}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestLogKey{}, entry)))
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if id := RequestIDFrom(r.Context()); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if entry.Model != "" {
+			attrs = append(attrs, slog.String("model", entry.Model))
+		}
+		if entry.Operation != "" {
+			attrs = append(attrs, slog.String("operation", entry.Operation))
+		}
+		if entry.HasRows {
+			attrs = append(attrs, slog.Int64("rows", entry.Rows))
+		}
+		if len(entry.Params) > 0 && logger.Enabled(r.Context(), slog.LevelDebug) {
+			attrs = append(attrs, slog.Any("params", paramsForLog(entry.Params, redactParams)))
+		}
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if rec.status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// paramsForLog returns the parameters to log, masking every value if redact is set
+func paramsForLog(params []interface{}, redact bool) []interface{} {
+	if !redact {
+		return params
+	}
+	masked := make([]interface{}, len(params))
+	for i := range params {
+		masked[i] = redactedValue
+	}
+	return masked
+}
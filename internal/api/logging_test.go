@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/metrics"
+)
+
+func TestLoggingMiddleware_LogsQueryDetails(t *testing.T) {
+	reg := setupRegistryForTest()
+	a := New(reg, nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	handler := LoggingMiddleware(logger, true, mux)
+
+	body := `{"model":"orders","filters":{"field":"status","op":"=","value":"PAID"}}`
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not valid JSON: %v (%s)", err, buf.String())
+	}
+
+	if line["method"] != "POST" || line["path"] != "/query" {
+		t.Errorf("unexpected method/path: %v %v", line["method"], line["path"])
+	}
+	if line["model"] != "orders" || line["operation"] != "select" {
+		t.Errorf("unexpected model/operation: %v %v", line["model"], line["operation"])
+	}
+	if status, _ := line["status"].(float64); int(status) != http.StatusOK {
+		t.Errorf("expected status 200, got %v", line["status"])
+	}
+	if strings.Contains(buf.String(), "PAID") {
+		t.Errorf("parameter value should be redacted: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), redactedValue) {
+		t.Errorf("expected redacted params in log: %s", buf.String())
+	}
+}
+
+func TestLoggingMiddleware_OmitsParamsAboveDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entry := requestLogFrom(r.Context())
+		entry.Params = []interface{}{"secret"}
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	LoggingMiddleware(logger, false, inner).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("params should not be logged at info level: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"level":"WARN"`) {
+		t.Errorf("expected WARN level for 4xx response: %s", buf.String())
+	}
+}
+
+func TestQueryMetrics_UnknownLabels(t *testing.T) {
+	a := New(setupRegistryForTest(), &fakeDB{}, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for _, body := range []string{
+		`{"model":"no_such_model_7f3a"}`,
+		`{"model":"orders","operation":"no_such_operation_7f3a"}`,
+	} {
+		resp, err := http.Post(ts.URL+"/query", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			t.Fatalf("status = %d, want an error for %s", resp.StatusCode, body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := rec.Body.String()
+	if strings.Contains(out, "7f3a") {
+		t.Errorf("client-supplied names became metric labels:\n%s", out)
+	}
+	for _, series := range []string{
+		`udv_query_errors_total{model="unknown",operation="select",type="validation"}`,
+		`udv_query_errors_total{model="orders",operation="unknown",type="validation"}`,
+	} {
+		if !strings.Contains(out, series) {
+			t.Errorf("metrics output missing %s", series)
+		}
+	}
+}
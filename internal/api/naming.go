@@ -0,0 +1,54 @@
+package api
+
+import (
+	"udv/internal/planner"
+)
+
+// renameColumns rekeys result rows in place from database columns to the field names of
+// models with a field naming style. Aggregate, time bucket and field alias keys are kept.
+func (a *API) renameColumns(plan *planner.QueryPlan, rows []map[string]interface{}) {
+	kept := make(map[string]bool)
+	for _, agg := range plan.Aggregates {
+		kept[agg.Alias] = true
+	}
+	for _, group := range plan.GroupBy {
+		if group.Alias != "" {
+			kept[group.Alias] = true
+		}
+	}
+	for _, alias := range selectAliases(plan) {
+		kept[alias] = true
+	}
+
+	a.renameModelColumns(plan.RootModel.Name, kept, rows)
+	a.eachIncluded(plan, rows, func(modelName string, nested []map[string]interface{}) {
+		a.renameModelColumns(modelName, nil, nested)
+	})
+}
+
+// renameModelColumns rekeys rows of a model from its columns to its field names, except for kept keys
+func (a *API) renameModelColumns(modelName string, kept map[string]bool, rows []map[string]interface{}) {
+	model := a.registry.GetModel(modelName)
+	if model == nil || len(rows) == 0 {
+		return
+	}
+
+	names := make(map[string]string)
+	for _, name := range model.FieldOrder {
+		if column := model.Fields[name].Column; column != name && !kept[column] {
+			names[column] = name
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	for _, row := range rows {
+		for column, name := range names {
+			if value, ok := row[column]; ok {
+				delete(row, column)
+				row[name] = value
+			}
+		}
+	}
+}
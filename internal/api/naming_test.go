@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/config"
+	"udv/internal/schema"
+)
+
+func TestQueryEndpoint_CamelCaseFieldNaming(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		FieldNaming: config.FieldNamingCamel,
+		Models: []config.Model{{
+			Name:       "orders",
+			Table:      "orders",
+			PrimaryKey: "id",
+			Fields: []config.Field{
+				{Name: "id", Type: "integer"},
+				{Name: "user_id", Type: "integer"},
+				{Name: "line_count", Type: "integer"},
+			},
+		}},
+	})
+	db := &fakeDB{}
+	a := New(reg, db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	query := func(body string) []map[string]interface{} {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status: %d", resp.StatusCode)
+		}
+		var out struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+		return out.Data
+	}
+
+	// Written camelCase fields reach the database as snake_case columns and come back renamed
+	db.rows = []map[string]interface{}{{"id": int64(1), "user_id": int64(7), "line_count": int64(2)}}
+	data := query(`{"operation":"create","model":"orders","data":{"userId":7,"lineCount":2}}`)
+	if sql, _ := db.lastQuery.(string); !strings.Contains(sql, "user_id") || strings.Contains(sql, "userId") {
+		t.Errorf("expected SQL against columns, got %s", sql)
+	}
+	want := []map[string]interface{}{{"id": float64(1), "userId": float64(7), "lineCount": float64(2)}}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("expected %v, got %v", want, data)
+	}
+
+	// Aggregate aliases are returned as written, even when they match a column
+	db.rows = []map[string]interface{}{{"user_id": int64(7), "line_count": int64(3)}}
+	data = query(`{"model":"orders","group_by":["userId"],"aggregates":[{"fn":"sum","field":"lineCount","alias":"line_count"}]}`)
+	want = []map[string]interface{}{{"userId": float64(7), "line_count": float64(3)}}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("expected %v, got %v", want, data)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"udv/internal/planner"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs; longer ones are replaced
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key for the request ID
+type requestIDKey struct{}
+
+// RequestIDFrom returns the request ID attached by RequestIDMiddleware, or ""
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDMiddleware attaches a request ID to the request context and echoes it in the
+// X-Request-ID response header. The incoming header is reused when it is a short printable
+// value, otherwise a random UUID is generated. Wrap LoggingMiddleware with it so log lines
+// carry the ID.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			generated, err := planner.NewUUID()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id = generated
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// validRequestID reports whether a client-supplied ID is safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"from header", "req-123", true},
+		{"missing", "", false},
+		{"with spaces", "req 123", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIDFrom(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			RequestIDMiddleware(inner).ServeHTTP(rec, req)
+
+			echoed := rec.Header().Get(RequestIDHeader)
+			if echoed != seen {
+				t.Errorf("response header %q differs from context ID %q", echoed, seen)
+			}
+			if tt.keep && seen != tt.incoming {
+				t.Errorf("expected incoming ID %q to be kept, got %q", tt.incoming, seen)
+			}
+			if !tt.keep && !uuidPattern.MatchString(seen) {
+				t.Errorf("expected a generated UUID, got %q", seen)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddleware_Logged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := RequestIDMiddleware(LoggingMiddleware(logger, false, inner))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(RequestIDHeader, "trace-abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not valid JSON: %v (%s)", err, buf.String())
+	}
+	if line["request_id"] != "trace-abc" {
+		t.Errorf("expected request_id in log line, got %v", line["request_id"])
+	}
+}
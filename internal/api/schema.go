@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"udv/internal/config"
+)
+
+// SetFailedModels records the models a tolerant config load left out, for GET /schema?failed=true
+func (a *API) SetFailedModels(failed []config.ModelError) {
+	a.failedModels = failed
+}
+
+// handleSchema serves the live registry as config models: GET /schema lists every model
+// and GET /schema/{model} returns a single one. GET /schema?failed=true lists the models
+// that were left out at startup because of config problems.
+func (a *API) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/schema"), "/")
+	if name == "" && r.URL.Query().Get("failed") == "true" {
+		failed := a.failedModels
+		if failed == nil {
+			failed = []config.ModelError{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(failed)
+		return
+	}
+
+	if name != "" {
+		model, err := a.registry.ExportModel(name)
+		if err != nil {
+			for _, failed := range a.failedModels {
+				if failed.Name == name {
+					writeError(w, http.StatusNotFound, CodeModelFailed, "model failed to load", failed.Problems...)
+					return
+				}
+			}
+			writeError(w, http.StatusNotFound, CodeModelNotFound, "model not found", err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(publicModel(model))
+		return
+	}
+
+	names := a.registry.ListModels()
+	sort.Strings(names)
+
+	out := make([]*config.Model, 0, len(names))
+	for _, n := range names {
+		model, err := a.registry.ExportModel(n)
+		if err != nil {
+			continue
+		}
+		out = append(out, publicModel(model))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// publicModel strips hidden fields so the schema never advertises them
+func publicModel(model *config.Model) *config.Model {
+	if len(model.HiddenFields) == 0 {
+		return model
+	}
+
+	hidden := make(map[string]bool, len(model.HiddenFields))
+	for _, name := range model.HiddenFields {
+		hidden[name] = true
+	}
+
+	fields := make([]config.Field, 0, len(model.Fields))
+	for _, f := range model.Fields {
+		if !hidden[f.Name] {
+			fields = append(fields, f)
+		}
+	}
+	model.Fields = fields
+	model.HiddenFields = nil
+	return model
+}
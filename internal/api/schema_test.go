@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/config"
+	"udv/internal/schema"
+)
+
+func setupSchemaRegistry() *schema.Registry {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "email", Type: "string"},
+					{Name: "password_hash", Type: "string"},
+				},
+				HiddenFields: []string{"password_hash"},
+			},
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "user_id", Type: "integer"},
+					{Name: "note", Type: "string", Nullable: true},
+				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "id"},
+				},
+			},
+		},
+	}
+
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	return reg
+}
+
+func TestSchemaEndpoint_List(t *testing.T) {
+	a := New(setupSchemaRegistry(), nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var models []config.Model
+	if err := json.NewDecoder(rec.Body).Decode(&models); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(models) != 2 || models[0].Name != "orders" || models[1].Name != "users" {
+		t.Fatalf("Expected orders and users sorted by name, got %+v", models)
+	}
+
+	orders := models[0]
+	if orders.PrimaryKey != "id" || len(orders.Fields) != 3 || !orders.Fields[2].Nullable {
+		t.Errorf("Unexpected orders model: %+v", orders)
+	}
+	if len(orders.Relations) != 1 || orders.Relations[0].TargetModel != "users" {
+		t.Errorf("Expected user relation, got %+v", orders.Relations)
+	}
+
+	users := models[1]
+	if len(users.Fields) != 2 || len(users.HiddenFields) != 0 {
+		t.Errorf("Expected hidden fields to be stripped, got %+v", users)
+	}
+}
+
+func TestSchemaEndpoint_Model(t *testing.T) {
+	a := New(setupSchemaRegistry(), nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema/orders", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var model config.Model
+	if err := json.NewDecoder(rec.Body).Decode(&model); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if model.Name != "orders" || model.Table != "orders" {
+		t.Errorf("Unexpected model: %+v", model)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown model, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/schema", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestSchemaEndpoint_FailedModels(t *testing.T) {
+	a := New(setupSchemaRegistry(), nil, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema?failed=true", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+		t.Errorf("Expected an empty list without failures, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	a.SetFailedModels([]config.ModelError{{Name: "invoices", Index: 2, Problems: []string{"table is required"}}})
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema?failed=true", nil))
+	var failed []config.ModelError
+	if err := json.Unmarshal(rec.Body.Bytes(), &failed); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Name != "invoices" || failed[0].Index != 2 || failed[0].Problems[0] != "table is required" {
+		t.Errorf("Unexpected failed models: %+v", failed)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema/invoices", nil))
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if rec.Code != http.StatusNotFound || resp.Error.Code != CodeModelFailed {
+		t.Errorf("Expected 404 %s, got %d %+v", CodeModelFailed, rec.Code, resp)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/schema", nil))
+	var models []config.Model
+	if err := json.Unmarshal(rec.Body.Bytes(), &models); err != nil || len(models) != 2 {
+		t.Errorf("Expected the loaded models to be listed as before, got %s", rec.Body.String())
+	}
+}
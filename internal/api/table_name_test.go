@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/config"
+	"udv/internal/schema"
+)
+
+func TestQueryEndpoint_ModelNameDiffersFromTable(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{{
+			Name:       "customers",
+			Table:      "tbl_customers_v2",
+			PrimaryKey: "id",
+			Fields: []config.Field{
+				{Name: "id", Type: "integer"},
+				{Name: "email", Type: "string"},
+			},
+		}},
+	})
+	db := &fakeDB{rows: []map[string]interface{}{{"id": int64(1), "email": "a@b.c"}}}
+	a := New(reg, db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	post := func(body string) *http.Response {
+		resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post(`{"model":"customers"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for the logical model name, got %d", resp.StatusCode)
+	}
+	if sql, _ := db.lastQuery.(string); !strings.Contains(sql, "FROM tbl_customers_v2 t0") {
+		t.Errorf("expected SQL against the real table, got %s", sql)
+	}
+
+	if resp := post(`{"model":"tbl_customers_v2"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 when addressing the model by its table, got %d", resp.StatusCode)
+	}
+
+	for path, status := range map[string]int{"/schema/customers": http.StatusOK, "/schema/tbl_customers_v2": http.StatusNotFound} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("GET %s: expected %d, got %d", path, status, resp.StatusCode)
+		}
+	}
+}
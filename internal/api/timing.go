@@ -0,0 +1,21 @@
+package api
+
+import "time"
+
+// execTimer accumulates the time spent executing queries, so debug responses can report
+// database time apart from planning and building. A nil timer records nothing.
+type execTimer struct {
+	elapsed time.Duration
+}
+
+// since adds the time passed since start
+func (t *execTimer) since(start time.Time) {
+	if t != nil {
+		t.elapsed += time.Since(start)
+	}
+}
+
+// milliseconds reports the accumulated execution time in fractional milliseconds
+func (t *execTimer) milliseconds() float64 {
+	return float64(t.elapsed) / float64(time.Millisecond)
+}
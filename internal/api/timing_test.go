@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+)
+
+func TestQueryEndpoint_DebugExecutionTime(t *testing.T) {
+	reg := setupRegistryForTest()
+	db := &fakeDB{rows: []map[string]interface{}{{"count": int64(1)}}}
+	a := New(reg, db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	body := `{"operation":"count","model":"orders"}`
+	for _, tt := range []struct {
+		url  string
+		want bool
+	}{
+		{ts.URL + "/query", false},
+		{ts.URL + "/query?debug=false", false},
+		{ts.URL + "/query?debug=true", true},
+	} {
+		resp, err := http.Post(tt.url, "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatalf("POST %s failed: %v", tt.url, err)
+		}
+		var out map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("invalid json response: %v", err)
+		}
+
+		ms, ok := out["execution_ms"].(float64)
+		if ok != tt.want {
+			t.Errorf("%s: expected execution_ms present=%v, got %v", tt.url, tt.want, out["execution_ms"])
+		}
+		if ok && ms < 0 {
+			t.Errorf("%s: expected non-negative execution_ms, got %v", tt.url, ms)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"udv/internal/adapter"
+	"udv/internal/dsl"
+)
+
+// DefaultCountCacheTTL is how long an exact pagination total is reused before counting again
+const DefaultCountCacheTTL = time.Minute
+
+// SetCountCacheTTL sets how long exact pagination totals are cached. Writes through the API
+// evict them early; zero counts on every request.
+func (a *API) SetCountCacheTTL(ttl time.Duration) {
+	a.countCacheTTL = ttl
+}
+
+// pageTotal counts the rows a select matches across all pages. An unfiltered count uses the
+// database's row estimate when it has one, since an exact count scans the whole table; other
+// counts are exact and cached for countCacheTTL.
+func (a *API) pageTotal(q dsl.Query, builder adapter.QueryBuilder, db adapter.Database, timer *execTimer) (int64, bool, error) {
+	q.Operation = dsl.OpCount
+	q.Fields, q.FieldAliases, q.Sort, q.Pagination, q.Include, q.Lock = nil, nil, nil, nil, nil, nil
+
+	plan, err := a.planner.PlanQuery(&q)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if plan.Filters == nil && plan.SoftDelete == nil {
+		if estimator, ok := db.(adapter.Estimator); ok {
+			start := time.Now()
+			estimate, ok, err := estimator.EstimateCount(plan.RootModel.Table)
+			timer.since(start)
+			if err != nil {
+				return 0, false, err
+			}
+			if ok {
+				return estimate, true, nil
+			}
+		}
+	}
+
+	query, params, err := builder.BuildQuery(plan)
+	if err != nil {
+		return 0, false, err
+	}
+
+	key, cacheable := cacheKey(query, params)
+	cacheable = cacheable && a.countCacheTTL > 0
+	if model := a.registry.GetModel(plan.RootModel.Name); model != nil {
+		// Totals share the result cache, so their keys must not collide with cached counts
+		key = "total|" + model.Datasource + "|" + key
+	}
+	var rows []map[string]interface{}
+	cached := false
+	if cacheable {
+		rows, cached = a.cache.get(key)
+	}
+	if !cached {
+		start := time.Now()
+		rows, err = db.ExecuteQuery(query, params...)
+		timer.since(start)
+		if err != nil {
+			return 0, false, err
+		}
+		if cacheable {
+			a.cache.set(key, rows, append([]string{plan.RootModel.Table}, plan.SubqueryTables()...), a.countCacheTTL)
+		}
+	}
+
+	switch count := countFromRows(rows).(type) {
+	case int64:
+		return count, false, nil
+	case int:
+		return int64(count), false, nil
+	default:
+		return 0, false, fmt.Errorf("unexpected count type %T", count)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+)
+
+// totalDB is a fakeDB that answers COUNT queries with count and may provide a row estimate
+type totalDB struct {
+	*fakeDB
+	count    int64
+	counts   int
+	estimate int64 // Zero means no estimate is available
+}
+
+func (d *totalDB) ExecuteQuery(query interface{}, args ...interface{}) ([]map[string]interface{}, error) {
+	if sql, _ := query.(string); strings.HasPrefix(sql, "SELECT COUNT(*)") {
+		d.counts++
+		return []map[string]interface{}{{"count": d.count}}, nil
+	}
+	return d.fakeDB.ExecuteQuery(query, args...)
+}
+
+func (d *totalDB) EstimateCount(table string) (int64, bool, error) {
+	return d.estimate, d.estimate > 0, nil
+}
+
+func TestQueryEndpoint_PaginationTotal(t *testing.T) {
+	rows := []map[string]interface{}{{"id": 1}, {"id": 2}, {"id": 3}}
+	post := func(t *testing.T, ts *httptest.Server, body string) pageInfo {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+		var out struct {
+			Pagination pageInfo `json:"pagination"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return out.Pagination
+	}
+	check := func(t *testing.T, info pageInfo, total int64, estimated bool) {
+		t.Helper()
+		if info.Total == nil || *info.Total != total || info.TotalEstimated == nil || *info.TotalEstimated != estimated {
+			t.Errorf("total = %v estimated = %v, want %d %v", info.Total, info.TotalEstimated, total, estimated)
+		}
+	}
+	serve := func(db *totalDB) *httptest.Server {
+		a := New(setupRegistryForTest(), db, postgres.NewQueryBuilder())
+		mux := http.NewServeMux()
+		a.RegisterRoutes(mux)
+		return httptest.NewServer(mux)
+	}
+
+	t.Run("unfiltered uses the estimate", func(t *testing.T) {
+		db := &totalDB{fakeDB: &fakeDB{rows: rows}, count: 1200, estimate: 1187}
+		ts := serve(db)
+		defer ts.Close()
+
+		check(t, post(t, ts, `{"model":"orders","pagination":{"limit":2,"with_total":true}}`), 1187, true)
+		if db.counts != 0 {
+			t.Errorf("counted %d times, want the estimate only", db.counts)
+		}
+	})
+
+	t.Run("unfiltered without an estimate counts", func(t *testing.T) {
+		db := &totalDB{fakeDB: &fakeDB{rows: rows}, count: 3}
+		ts := serve(db)
+		defer ts.Close()
+
+		check(t, post(t, ts, `{"model":"orders","pagination":{"limit":2,"with_total":true}}`), 3, false)
+	})
+
+	t.Run("filtered counts are cached until a write", func(t *testing.T) {
+		db := &totalDB{fakeDB: &fakeDB{rows: rows}, count: 42, estimate: 1187}
+		ts := serve(db)
+		defer ts.Close()
+
+		query := `{"model":"orders","filters":{"field":"status","op":"=","value":"PAID"},"pagination":{"limit":2,"with_total":true}}`
+		check(t, post(t, ts, query), 42, false)
+		db.count = 43
+		check(t, post(t, ts, query), 42, false)
+		if db.counts != 1 {
+			t.Errorf("counted %d times, want 1", db.counts)
+		}
+
+		resp, err := http.Post(ts.URL+"/query", "application/json", strings.NewReader(`{"operation":"delete","model":"orders","id":1}`))
+		if err != nil {
+			t.Fatalf("POST /query failed: %v", err)
+		}
+		resp.Body.Close()
+		check(t, post(t, ts, query), 43, false)
+	})
+
+	t.Run("without with_total", func(t *testing.T) {
+		db := &totalDB{fakeDB: &fakeDB{rows: rows}, count: 42}
+		ts := serve(db)
+		defer ts.Close()
+
+		if info := post(t, ts, `{"model":"orders","pagination":{"limit":2}}`); info.Total != nil || db.counts != 0 {
+			t.Errorf("total = %v after %d counts, want none", info.Total, db.counts)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"strings"
+
+	"udv/internal/config"
+	"udv/internal/dsl"
+	"udv/internal/planner"
+)
+
+// transformRows applies the read transforms and read defaults declared on the plan's models to
+// result rows in place. Renamed fields are matched under their alias, and included rows under
+// the model of their relation.
+func (a *API) transformRows(plan *planner.QueryPlan, rows []map[string]interface{}) {
+	a.transformModelRows(plan.RootModel.Name, selectAliases(plan), returnedColumns(plan), rows)
+	a.eachIncluded(plan, rows, func(modelName string, nested []map[string]interface{}) {
+		a.transformModelRows(modelName, nil, nil, nested)
+	})
+}
+
+// returnedColumns lists the root model columns every result row carries, so one missing from
+// a row (an absent document field) can take its read default. Rows that are not model rows,
+// such as counts and aggregates, carry none.
+func returnedColumns(plan *planner.QueryPlan) map[string]bool {
+	if len(plan.Aggregates) > 0 || len(plan.GroupBy) > 0 {
+		return nil
+	}
+	var selected []planner.ColumnRef
+	switch {
+	case plan.Operation == dsl.OpSelect || plan.Operation.SingleRow():
+		for _, sel := range plan.Select {
+			selected = append(selected, sel.Column)
+		}
+	case plan.Operation == dsl.OpCreate || plan.Operation == dsl.OpUpdate || plan.Operation == dsl.OpDelete:
+		selected = plan.Returning
+	default:
+		return nil
+	}
+	if len(selected) == 0 {
+		selected = plan.RootModel.Columns
+	}
+	columns := make(map[string]bool, len(selected))
+	for _, col := range selected {
+		columns[col.ColumnName] = true
+	}
+	return columns
+}
+
+// selectAliases maps the columns of the plan's renamed selected fields to their aliases
+func selectAliases(plan *planner.QueryPlan) map[string]string {
+	renamed := make(map[string]string)
+	for _, sel := range plan.Select {
+		if !sel.IsAggregate && sel.Alias != "" && sel.Alias != sel.Column.ColumnName {
+			renamed[sel.Column.ColumnName] = sel.Alias
+		}
+	}
+	return renamed
+}
+
+// eachIncluded calls fn with the rows of every included relation and the model they belong to.
+// Included rows are nested under their relation name, one level below the rows they join from.
+func (a *API) eachIncluded(plan *planner.QueryPlan, rows []map[string]interface{}, fn func(modelName string, rows []map[string]interface{})) {
+	models := map[string]string{plan.RootModel.Alias: plan.RootModel.Name}
+	paths := map[string][]string{plan.RootModel.Alias: nil}
+	for _, join := range plan.Joins {
+		rel, err := a.registry.GetRelation(models[join.FromAlias], join.Relation)
+		if err != nil {
+			continue
+		}
+		models[join.ToAlias] = rel.TargetModel
+		paths[join.ToAlias] = append(append([]string{}, paths[join.FromAlias]...), join.Relation)
+		fn(rel.TargetModel, nestedRows(rows, paths[join.ToAlias]))
+	}
+}
+
+// transformModelRows applies a model's read transforms and read defaults to rows of that model,
+// which are keyed by field name except for the columns in renamed. Read defaults replace null
+// values, and missing ones for the columns in returned.
+func (a *API) transformModelRows(modelName string, renamed map[string]string, returned map[string]bool, rows []map[string]interface{}) {
+	model := a.registry.GetModel(modelName)
+	if model == nil || len(rows) == 0 {
+		return
+	}
+	for _, name := range model.FieldOrder {
+		field := model.Fields[name]
+		if field.Transform == nil && field.ReadDefault == nil {
+			continue
+		}
+		key := name
+		if alias, ok := renamed[field.Column]; ok {
+			key = alias
+		}
+		for _, row := range rows {
+			value, ok := row[key]
+			if field.ReadDefault != nil && value == nil && (ok || returned[field.Column]) {
+				row[key] = field.ReadDefault
+				continue
+			}
+			if s, isString := value.(string); isString && field.Transform != nil {
+				row[key] = applyTransform(field.Transform, s)
+			}
+		}
+	}
+}
+
+// nestedRows collects the rows found by following path through included relations
+func nestedRows(rows []map[string]interface{}, path []string) []map[string]interface{} {
+	for _, relation := range path {
+		var next []map[string]interface{}
+		for _, row := range rows {
+			switch nested := row[relation].(type) {
+			case map[string]interface{}:
+				next = append(next, nested)
+			case []interface{}:
+				for _, item := range nested {
+					if m, ok := item.(map[string]interface{}); ok {
+						next = append(next, m)
+					}
+				}
+			}
+		}
+		rows = next
+	}
+	return rows
+}
+
+// applyTransform returns value rewritten by a built-in read transform
+func applyTransform(t *config.FieldTransform, value string) string {
+	switch t.Type {
+	case config.TransformMaskEmail:
+		at := strings.LastIndex(value, "@")
+		if at < 0 {
+			return maskKeeping(value, 0)
+		}
+		local := []rune(value[:at])
+		if len(local) == 0 {
+			return value
+		}
+		return string(local[0]) + "***" + value[at:]
+	case config.TransformMask:
+		return maskKeeping(value, t.Length)
+	case config.TransformTruncate:
+		if runes := []rune(value); len(runes) > t.Length {
+			return string(runes[:t.Length])
+		}
+	}
+	return value
+}
+
+// maskKeeping replaces every character of value except the last keep with *
+func maskKeeping(value string, keep int) string {
+	runes := []rune(value)
+	for i := 0; i < len(runes)-keep; i++ {
+		runes[i] = '*'
+	}
+	return string(runes)
+}
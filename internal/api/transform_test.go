@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/config"
+	"udv/internal/dsl"
+	"udv/internal/schema"
+)
+
+func TestApplyTransform(t *testing.T) {
+	tests := []struct {
+		transform config.FieldTransform
+		value     string
+		want      string
+	}{
+		{config.FieldTransform{Type: config.TransformMaskEmail}, "jane.doe@example.com", "j***@example.com"},
+		{config.FieldTransform{Type: config.TransformMaskEmail}, "not-an-email", "************"},
+		{config.FieldTransform{Type: config.TransformMask, Length: 4}, "4111111111111111", "************1111"},
+		{config.FieldTransform{Type: config.TransformMask}, "secret", "******"},
+		{config.FieldTransform{Type: config.TransformMask, Length: 10}, "abc", "abc"},
+		{config.FieldTransform{Type: config.TransformTruncate, Length: 5}, "héllo world", "héllo"},
+		{config.FieldTransform{Type: config.TransformTruncate, Length: 20}, "short", "short"},
+	}
+
+	for _, tt := range tests {
+		if got := applyTransform(&tt.transform, tt.value); got != tt.want {
+			t.Errorf("applyTransform(%s, %q) = %q, want %q", tt.transform.Type, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestQueryEndpoint_Transforms(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "email", Type: "string", Transform: &config.FieldTransform{Type: config.TransformMaskEmail}},
+					{Name: "bio", Type: "string", Nullable: true, Transform: &config.FieldTransform{Type: config.TransformTruncate, Length: 3}},
+				},
+			},
+		},
+	})
+	db := &fakeDB{rows: []map[string]interface{}{
+		{"id": int64(1), "contact": "jane@example.com", "bio": nil},
+	}}
+	a := New(reg, db, postgres.NewQueryBuilder())
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	body := `{"model":"users","fields":["id","email","bio"],"field_aliases":{"email":"contact"}}`
+	resp, err := http.Post(ts.URL+"/query", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if len(out.Data) != 1 {
+		t.Fatalf("expected 1 row, got %v", out.Data)
+	}
+	if got := out.Data[0]["contact"]; got != "j***@example.com" {
+		t.Errorf("expected aliased email to be masked, got %v", got)
+	}
+	if got := out.Data[0]["bio"]; got != nil {
+		t.Errorf("expected null to be left alone, got %v", got)
+	}
+}
+
+func TestTransformRows_ReadDefaults(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "status", Type: "string", Nullable: true, ReadDefault: "unknown"},
+					{Name: "score", Type: "integer", Nullable: true, ReadDefault: 0.0},
+				},
+			},
+		},
+	})
+	a := New(reg, nil, nil)
+
+	plan, err := a.planner.PlanQuery(&dsl.Query{Model: "users"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	rows := []map[string]interface{}{
+		{"id": int64(1), "status": nil, "score": int64(7)},
+		{"id": int64(2), "status": "active", "score": nil},
+		{"id": int64(3)},
+	}
+	a.transformRows(plan, rows)
+
+	want := []map[string]interface{}{
+		{"id": int64(1), "status": "unknown", "score": int64(7)},
+		{"id": int64(2), "status": "active", "score": 0.0},
+		{"id": int64(3), "status": "unknown", "score": 0.0},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	// Fields that were not selected stay out of the rows
+	plan, err = a.planner.PlanQuery(&dsl.Query{Model: "users", Fields: []string{"id", "score"}})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	rows = []map[string]interface{}{{"id": int64(1)}}
+	a.transformRows(plan, rows)
+	if !reflect.DeepEqual(rows[0], map[string]interface{}{"id": int64(1), "score": 0.0}) {
+		t.Errorf("expected only the selected score to be filled, got %v", rows[0])
+	}
+
+	plan, err = a.planner.PlanQuery(&dsl.Query{Operation: dsl.OpCount, Model: "users"})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	rows = []map[string]interface{}{{"count": int64(3)}}
+	a.transformRows(plan, rows)
+	if len(rows[0]) != 1 {
+		t.Errorf("expected count rows to be left alone, got %v", rows[0])
+	}
+}
+
+func TestTransformRows_Includes(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "string"},
+					{Name: "user_id", Type: "string"},
+				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "_id"},
+				},
+			},
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "string"},
+					{Name: "email", Type: "string", Transform: &config.FieldTransform{Type: config.TransformMaskEmail}},
+				},
+			},
+		},
+	})
+	a := New(reg, nil, nil)
+
+	plan, err := a.planner.PlanQuery(&dsl.Query{Model: "orders", Include: []dsl.Include{{Relation: "user"}}})
+	if err != nil {
+		t.Fatalf("PlanQuery error: %v", err)
+	}
+	rows := []map[string]interface{}{
+		{"_id": "o1", "user": []interface{}{map[string]interface{}{"_id": "u1", "email": "sam@example.com"}}},
+		{"_id": "o2", "user": map[string]interface{}{"_id": "u2", "email": "kim@example.com"}},
+	}
+	a.transformRows(plan, rows)
+
+	if got := rows[0]["user"].([]interface{})[0].(map[string]interface{})["email"]; got != "s***@example.com" {
+		t.Errorf("expected included email to be masked, got %v", got)
+	}
+	if got := rows[1]["user"].(map[string]interface{})["email"]; got != "k***@example.com" {
+		t.Errorf("expected unwound email to be masked, got %v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"udv/internal/adapter/mongodb"
+)
+
+// upsertDB is a fakeDB whose updates record the id of the document an upsert inserted
+type upsertDB struct {
+	fakeDB
+	upsertedID interface{}
+	upserts    int
+}
+
+func (u *upsertDB) ExecuteQuery(query interface{}, args ...interface{}) ([]map[string]interface{}, error) {
+	if mq, ok := query.(*mongodb.MongoQuery); ok {
+		if opts, _ := mq.Options.(*options.UpdateOptions); opts != nil && opts.Upsert != nil {
+			u.upserts++
+			mq.Result = &mongodb.ExecUpdateResult{UpsertedID: u.upsertedID}
+		}
+	}
+	return u.fakeDB.ExecuteQuery(query, args...)
+}
+
+func TestQueryEndpoint_UpsertReportsInsert(t *testing.T) {
+	body := `{"operation":"update","model":"orders","filters":{"field":"status","op":"=","value":"PAID"},"data":{"amount":10},"upsert":true}`
+
+	tests := []struct {
+		name       string
+		upsertedID interface{}
+		path       string
+		wantID     bool
+	}{
+		{"inserted on miss", "65f1c0ffee0000000000abcd", "/query", true},
+		{"updated existing", nil, "/query", false},
+		{"inserted with debug timing", "65f1c0ffee0000000000abcd", "/query?debug=true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &upsertDB{
+				fakeDB:     fakeDB{rows: []map[string]interface{}{{"id": "65f1c0ffee0000000000abcd", "status": "PAID", "amount": 10}}},
+				upsertedID: tt.upsertedID,
+			}
+			a := NewWithType(setupRegistryForTest(), db, mongodb.NewQueryBuilder(), "mongodb")
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body)))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if db.upserts != 1 {
+				t.Fatalf("Expected the update to run as an upsert once, got %d", db.upserts)
+			}
+
+			var resp map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp["upserted"] != tt.wantID {
+				t.Errorf("Expected upserted %v, got %v", tt.wantID, resp["upserted"])
+			}
+			id, hasID := resp["upserted_id"]
+			if hasID != tt.wantID {
+				t.Fatalf("Expected upserted_id present %v, got %v", tt.wantID, resp)
+			}
+			if tt.wantID && id != tt.upsertedID {
+				t.Errorf("Expected upserted_id %v, got %v", tt.upsertedID, id)
+			}
+		})
+	}
+}
+
+func TestQueryEndpoint_UpdateWithoutUpsertOmitsUpserted(t *testing.T) {
+	db := &upsertDB{fakeDB: fakeDB{rows: []map[string]interface{}{{"id": "1", "status": "PAID"}}}}
+	a := NewWithType(setupRegistryForTest(), db, mongodb.NewQueryBuilder(), "mongodb")
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	body := `{"operation":"update","model":"orders","filters":{"field":"status","op":"=","value":"PAID"},"data":{"amount":10}}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if db.upserts != 0 {
+		t.Errorf("Expected a plain update, got %d upserts", db.upserts)
+	}
+	if strings.Contains(rec.Body.String(), "upserted") {
+		t.Errorf("Expected no upsert fields, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"udv/internal/adapter/postgres"
+	"udv/internal/config"
+	"udv/internal/schema"
+)
+
+func TestQueryEndpoint_VersionConflict(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "status", Type: "string"},
+					{Name: "version", Type: "integer"},
+				},
+				VersionField: "version",
+			},
+		},
+	})
+
+	tests := []struct {
+		name       string
+		rows       []map[string]interface{}
+		wantStatus int
+	}{
+		{"current version", []map[string]interface{}{{"id": int64(1), "status": "PAID", "version": int64(4)}}, http.StatusOK},
+		{"stale version", nil, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{rows: tt.rows}
+			a := New(reg, db, postgres.NewQueryBuilder())
+			mux := http.NewServeMux()
+			a.RegisterRoutes(mux)
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			body := `{"operation":"update","model":"orders","id":1,"data":{"status":"PAID"},"expected_version":3}`
+			resp, err := http.Post(ts.URL+"/query", "application/json", bytes.NewReader([]byte(body)))
+			if err != nil {
+				t.Fatalf("POST /query failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			sql, _ := db.lastQuery.(string)
+			if !strings.Contains(sql, "version = version + 1") || !strings.Contains(sql, "t0.version = $3") {
+				t.Errorf("unexpected SQL: %s", sql)
+			}
+			if tt.wantStatus == http.StatusConflict {
+				var out ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+					t.Fatalf("invalid json response: %v", err)
+				}
+				if out.Error.Code != CodeVersionConflict {
+					t.Errorf("code = %s, want %s", out.Error.Code, CodeVersionConflict)
+				}
+			}
+		})
+	}
+}
@@ -3,15 +3,106 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Model represents a data model configuration
 type Model struct {
-	Name       string  `json:"name"`
-	Table      string  `json:"table"`
-	PrimaryKey string  `json:"primaryKey"`
-	Fields     []Field `json:"fields"`
+	Name       string     `json:"name"`
+	Table      string     `json:"table"`
+	PrimaryKey string     `json:"primaryKey"`
+	Fields     []Field    `json:"fields"`
+	Relations  []Relation `json:"relations,omitempty"`
+	// HiddenFields are never returned by queries and cannot be explicitly selected
+	HiddenFields []string `json:"hiddenFields,omitempty"`
+	// SoftDeleteField names a timestamp column set on delete instead of removing the row
+	SoftDeleteField string `json:"softDeleteField,omitempty"`
+	// CreatedAtField and UpdatedAtField name timestamp columns filled automatically on write
+	CreatedAtField string `json:"createdAtField,omitempty"`
+	UpdatedAtField string `json:"updatedAtField,omitempty"`
+	// VersionField names an integer column incremented by every update, for optimistic concurrency
+	VersionField string `json:"versionField,omitempty"`
+	// SearchFields are the text fields matched by the search filter operator
+	SearchFields []string `json:"searchFields,omitempty"`
+	// StripNulls drops null values from inserts and updates so columns keep their defaults
+	StripNulls bool `json:"stripNulls,omitempty"`
+	// GenerateUUID fills a missing uuid primary key on insert; leave it off when the database has a default
+	GenerateUUID bool `json:"generateUUID,omitempty"`
+	// CacheTTL caches select and count results for this duration (e.g. "30s"); writes evict them
+	CacheTTL string `json:"cacheTTL,omitempty"`
+	// Indexes describes the table's indexes; informational only, served by /schema
+	Indexes []Index `json:"indexes,omitempty"`
+	// Datasource names the entry in Config.Datasources holding this model; empty means the default database
+	Datasource string `json:"datasource,omitempty"`
+	// Collation is the default locale for sorting select results; queries may override it
+	Collation *Collation `json:"collation,omitempty"`
+	// DisableDefaultSort stops paginated selects without a sort from being ordered by the primary key
+	DisableDefaultSort bool `json:"disableDefaultSort,omitempty"`
+	// FieldNaming maps column names to API field names; empty inherits Config.FieldNaming
+	FieldNaming string `json:"fieldNaming,omitempty"`
+	// VirtualFields are read-only fields computed from the model's columns when selected
+	VirtualFields []VirtualField `json:"virtualFields,omitempty"`
+	// ReadOnly rejects creates, updates and deletes, e.g. for models backed by a view
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// Removed marks a model whose table generate-models -merge no longer found; it is not loaded
+	Removed bool `json:"removed,omitempty"`
+}
+
+// Field naming styles. Fields and every other column reference in the config keep the
+// database column names; the style decides the names clients use for them.
+const (
+	FieldNamingColumn = "column"    // Fields are named after their columns
+	FieldNamingCamel  = "camelCase" // snake_case columns are exposed as camelCase fields
+)
+
+// FieldName returns the API name of column under a field naming style
+func FieldName(naming, column string) string {
+	if naming != FieldNamingCamel {
+		return column
+	}
+	// Leading underscores are kept, so MongoDB's _id stays _id
+	prefix := column[:len(column)-len(strings.TrimLeft(column, "_"))]
+	var b strings.Builder
+	b.WriteString(prefix)
+	for i, part := range strings.Split(column[len(prefix):], "_") {
+		if part == "" {
+			continue
+		}
+		if i == 0 {
+			b.WriteString(part)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// Collation selects locale-aware string ordering. Locale is a MongoDB locale or a PostgreSQL
+// collation name; Strength (1-5) is the MongoDB comparison level.
+type Collation struct {
+	Locale   string `json:"locale"`
+	Strength int    `json:"strength,omitempty"`
+}
+
+// Validate checks that the locale is a plain collation name and the strength is in range
+func (c *Collation) Validate() error {
+	if c.Locale == "" {
+		return fmt.Errorf("collation locale is required")
+	}
+	for _, r := range c.Locale {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.@=", r)) {
+			return fmt.Errorf("invalid collation locale %q", c.Locale)
+		}
+	}
+	if c.Strength < 0 || c.Strength > 5 {
+		return fmt.Errorf("collation strength must be between 1 and 5")
+	}
+	return nil
 }
 
 // Field represents a field within a model
@@ -19,75 +110,464 @@ type Field struct {
 	Name     string `json:"name"`
 	Type     string `json:"type"`
 	Nullable bool   `json:"nullable"`
+	// CaseInsensitive makes =, !=, in, not_in and starts_with ignore case (string fields only)
+	CaseInsensitive bool `json:"caseInsensitive,omitempty"`
+	// Array marks a PostgreSQL array column whose elements are of Type, e.g. text[] as a string array
+	Array bool `json:"array,omitempty"`
+	// HasDefault marks a column the database fills when an insert omits it, so creates need not set it
+	HasDefault bool `json:"hasDefault,omitempty"`
+	// Transform rewrites the field's value in query results, e.g. to mask PII (string fields only)
+	Transform *FieldTransform `json:"transform,omitempty"`
+	// ReadDefault replaces a null or missing value in query results; writes are unaffected
+	ReadDefault interface{} `json:"readDefault,omitempty"`
+	// Removed marks a column generate-models -merge no longer found; it is not loaded
+	Removed bool `json:"removed,omitempty"`
+}
+
+// Read transforms applied to string values in query results
+const (
+	TransformMaskEmail = "mask_email" // Keeps the first character and the domain: j***@example.com
+	TransformMask      = "mask"       // Replaces all but the last Length characters with *
+	TransformTruncate  = "truncate"   // Keeps the first Length characters
+)
+
+// FieldTransform is a read transform declared on a field
+type FieldTransform struct {
+	Type   string `json:"type"`
+	Length int    `json:"length,omitempty"`
+}
+
+// VirtualField is a read-only field computed from the columns of a row. SQL is a PostgreSQL
+// expression in which {column} stands for a column; Mongo is a MongoDB aggregation expression
+// referencing columns as "$column". Only the expression for the model's database is needed.
+// Expressions are not expanded by ExpandEnv, so "$$" and "$1" reach the database as written.
+type VirtualField struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	SQL   string      `json:"sql,omitempty" env:"-"`
+	Mongo interface{} `json:"mongo,omitempty" env:"-"`
+}
+
+// sqlColumnRef matches a {column} placeholder in a virtual field's SQL expression
+var sqlColumnRef = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// SQLColumns returns the columns referenced by the SQL expression
+func (v *VirtualField) SQLColumns() []string {
+	var columns []string
+	for _, m := range sqlColumnRef.FindAllStringSubmatch(v.SQL, -1) {
+		columns = append(columns, m[1])
+	}
+	return columns
+}
+
+// ExpandSQL returns the SQL expression with every {column} placeholder replaced by ref(column)
+func (v *VirtualField) ExpandSQL(ref func(column string) string) string {
+	return sqlColumnRef.ReplaceAllStringFunc(v.SQL, func(placeholder string) string {
+		return ref(placeholder[1 : len(placeholder)-1])
+	})
+}
+
+// MongoColumns returns the columns referenced as "$column" or "$column.path" by the MongoDB
+// expression; "$$" variables are not columns
+func (v *VirtualField) MongoColumns() []string {
+	var columns []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch e := value.(type) {
+		case string:
+			if strings.HasPrefix(e, "$") && !strings.HasPrefix(e, "$$") {
+				columns = append(columns, strings.SplitN(e[1:], ".", 2)[0])
+			}
+		case []interface{}:
+			for _, item := range e {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range e {
+				walk(item)
+			}
+		}
+	}
+	walk(v.Mongo)
+	return columns
+}
+
+// Index describes a database index on a model's table
+type Index struct {
+	Name      string   `json:"name"`
+	Columns   []string `json:"columns" env:"-"` // Key columns in order; expression keys hold the expression text
+	Unique    bool     `json:"unique,omitempty"`
+	Predicate string   `json:"predicate,omitempty" env:"-"` // WHERE clause of a partial index
+}
+
+// Relation represents a relationship from a model to another model
+type Relation struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	TargetModel  string `json:"targetModel"`
+	ForeignKey   string `json:"foreignKey"`   // Field on this model
+	ReferenceKey string `json:"referenceKey"` // Field on the target model
+}
+
+// DefaultDatasource names the database configured through DB_TYPE and its environment variables
+const DefaultDatasource = "default"
+
+// Datasource is an additional named database that models can be assigned to
+type Datasource struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`               // postgres or mongodb
+	URL      string `json:"url"`                // Connection string; use ${VAR} to keep secrets out of the file
+	Database string `json:"database,omitempty"` // Database name (MongoDB only)
+
+	// ReadPreference and WriteConcern tune MongoDB consistency; unset keeps the URL's settings
+	ReadPreference string        `json:"readPreference,omitempty"`
+	WriteConcern   *WriteConcern `json:"writeConcern,omitempty"`
+}
+
+// WriteConcern is the acknowledgement MongoDB waits for before a write returns
+type WriteConcern struct {
+	W       interface{} `json:"w,omitempty"` // "majority", a tag set name or a node count
+	Journal bool        `json:"j,omitempty"` // Wait for the write to reach the on-disk journal
+}
+
+// readPreferences are the MongoDB read preference modes
+var readPreferences = map[string]bool{
+	"primary":            true,
+	"primaryPreferred":   true,
+	"secondary":          true,
+	"secondaryPreferred": true,
+	"nearest":            true,
+}
+
+// WString returns w as a string, with node counts in decimal, or "" when unset
+func (wc *WriteConcern) WString() string {
+	switch w := wc.W.(type) {
+	case string:
+		return w
+	case float64:
+		return strconv.FormatFloat(w, 'f', -1, 64)
+	}
+	return ""
 }
 
 // Config represents the entire configuration
 type Config struct {
-	Models []Model `json:"models"`
+	Models      []Model      `json:"models"`
+	Datasources []Datasource `json:"datasources,omitempty"`
+
+	// FieldNaming is the default field naming style of models that do not set their own
+	FieldNaming string `json:"fieldNaming,omitempty"`
 }
 
-// LoadConfig loads and validates the configuration from a JSON file
+// FieldNamingOf returns the field naming style in effect for model
+func (c *Config) FieldNamingOf(model *Model) string {
+	if model.FieldNaming != "" {
+		return model.FieldNaming
+	}
+	return c.FieldNaming
+}
+
+// LoadConfig loads and validates the configuration from a JSON file, or a YAML file
+// when the path ends in .yaml or .yml
 func LoadConfig(filePath string) (*Config, error) {
+	cfg, err := readConfig(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// readConfig reads, decodes and expands a config file without validating it
+func readConfig(filePath string) (*Config, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// YAML files are converted to JSON so both formats decode through the same struct tags
+	format := "JSON"
+	if IsYAMLPath(filePath) {
+		format = "YAML"
+		if data, err = YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to parse config YAML: %w", err)
+		}
+	}
+
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config JSON: %w", err)
+		return nil, fmt.Errorf("failed to parse config %s: %w", format, err)
 	}
 
-	if err := ValidateConfig(&cfg); err != nil {
-		return nil, err
+	// ${VAR} placeholders in string values are filled from the environment
+	if err := ExpandEnv(&cfg, os.LookupEnv); err != nil {
+		return nil, fmt.Errorf("failed to expand config: %w", err)
 	}
 
+	cfg.DropRemoved()
 	return &cfg, nil
 }
 
-// ValidateConfig validates the configuration
+// DropRemoved removes the models and fields marked removed, which no longer exist in the
+// database. References to them elsewhere in the config then fail validation.
+func (c *Config) DropRemoved() {
+	models := c.Models[:0]
+	for _, model := range c.Models {
+		if model.Removed {
+			continue
+		}
+		fields := model.Fields[:0]
+		for _, field := range model.Fields {
+			if !field.Removed {
+				fields = append(fields, field)
+			}
+		}
+		model.Fields = fields
+		models = append(models, model)
+	}
+	c.Models = models
+}
+
+// ValidationError lists every problem found while validating a config, so all of them can
+// be fixed before the next restart
+type ValidationError struct {
+	Problems []string
+
+	byModel map[int][]string // Problem details without the model prefix, by model index
+	global  bool             // Whether a problem is not about a single model
+}
+
+// Error returns the single problem, or a count followed by one problem per line
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0]
+	}
+	return fmt.Sprintf("%d config problems:\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// problems collects validation messages, remembering the model each one is about
+type problems struct {
+	messages []string
+	byModel  map[int][]string
+	global   bool
+}
+
+// add records a problem that is not about a single model
+func (p *problems) add(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	p.record(-1, message, message)
+}
+
+// addModel records a problem about the model at index, prefixing the message with the model
+func (p *problems) addModel(index int, name, format string, args ...interface{}) {
+	detail := fmt.Sprintf(format, args...)
+	p.record(index, fmt.Sprintf("model[%d] %s: %s", index, name, detail), detail)
+}
+
+// record adds message, and blames detail on the model at index unless index is negative
+func (p *problems) record(index int, message, detail string) {
+	p.messages = append(p.messages, message)
+	if index < 0 {
+		p.global = true
+		return
+	}
+	if p.byModel == nil {
+		p.byModel = make(map[int][]string)
+	}
+	p.byModel[index] = append(p.byModel[index], detail)
+}
+
+// err returns a *ValidationError for the collected problems, or nil when there are none
+func (p *problems) err() error {
+	if len(p.messages) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p.messages, byModel: p.byModel, global: p.global}
+}
+
+// Validate checks the config and reports every problem at once as a *ValidationError
+func (c *Config) Validate() error {
+	return ValidateConfig(c)
+}
+
+// ValidateConfig validates the configuration, returning a *ValidationError that lists every problem
 func ValidateConfig(cfg *Config) error {
 	if cfg == nil {
 		return fmt.Errorf("config is nil")
 	}
 
+	var p problems
 	if len(cfg.Models) == 0 {
-		return fmt.Errorf("no models defined in config")
+		p.add("no models defined in config")
+		return p.err()
+	}
+
+	datasources := validateDatasources(cfg.Datasources, &p)
+
+	if !validFieldNaming(cfg.FieldNaming) {
+		p.add("invalid fieldNaming %s", cfg.FieldNaming)
 	}
 
 	modelNames := make(map[string]bool)
 
 	for i, model := range cfg.Models {
-		if err := ValidateModel(&model, i); err != nil {
-			return err
-		}
+		validateModel(&model, i, &p)
 
 		// Check for duplicate model names
-		if modelNames[model.Name] {
-			return fmt.Errorf("duplicate model name: %s", model.Name)
+		if model.Name != "" && modelNames[model.Name] {
+			message := fmt.Sprintf("duplicate model name: %s", model.Name)
+			p.record(i, message, message)
 		}
 		modelNames[model.Name] = true
+
+		// Two columns must not map to the same field name
+		naming := cfg.FieldNamingOf(&model)
+		apiNames := make(map[string]string, len(model.Fields))
+		for _, field := range model.Fields {
+			name := FieldName(naming, field.Name)
+			if other, ok := apiNames[name]; ok && other != field.Name {
+				p.addModel(i, model.Name, "fields %s and %s both map to field name %s", other, field.Name, name)
+			}
+			apiNames[name] = field.Name
+		}
+		for _, virtual := range model.VirtualFields {
+			name := FieldName(naming, virtual.Name)
+			if other, ok := apiNames[name]; ok && other != virtual.Name {
+				p.addModel(i, model.Name, "fields %s and %s both map to field name %s", other, virtual.Name, name)
+			}
+			apiNames[name] = virtual.Name
+		}
 	}
 
-	return nil
+	// Relations may reference models defined later, so check targets once all models are known
+	modelFields := make(map[string]map[string]bool)
+	modelDatasources := make(map[string]string)
+	for _, model := range cfg.Models {
+		modelFields[model.Name] = make(map[string]bool)
+		for _, field := range model.Fields {
+			modelFields[model.Name][field.Name] = true
+		}
+		modelDatasources[model.Name] = model.DatasourceName()
+	}
+	for i, model := range cfg.Models {
+		if model.Datasource != "" && model.Datasource != DefaultDatasource && !datasources[model.Datasource] {
+			p.addModel(i, model.Name, "datasource %s not found", model.Datasource)
+		}
+
+		for j, rel := range model.Relations {
+			if rel.TargetModel == "" {
+				continue // Reported by validateRelation
+			}
+			targetFields, ok := modelFields[rel.TargetModel]
+			if !ok {
+				p.addModel(i, model.Name, "relation[%d] %s: target model %s not found", j, rel.Name, rel.TargetModel)
+				continue
+			}
+			if rel.ReferenceKey != "" && !targetFields[rel.ReferenceKey] {
+				p.addModel(i, model.Name, "relation[%d] %s: referenceKey %s not found in model %s", j, rel.Name, rel.ReferenceKey, rel.TargetModel)
+			}
+			// Joins and lookups run inside one database, so relations cannot span datasources
+			if from, to := model.DatasourceName(), modelDatasources[rel.TargetModel]; from != to {
+				p.addModel(i, model.Name, "relation[%d] %s: target model %s is in datasource %s, not %s; relations cannot cross datasources", j, rel.Name, rel.TargetModel, to, from)
+			}
+		}
+	}
+
+	return p.err()
+}
+
+// validFieldNaming reports whether naming is a known field naming style or empty
+func validFieldNaming(naming string) bool {
+	return naming == "" || naming == FieldNamingColumn || naming == FieldNamingCamel
+}
+
+// DatasourceName returns the model's datasource, or DefaultDatasource when none is set
+func (m *Model) DatasourceName() string {
+	if m.Datasource == "" {
+		return DefaultDatasource
+	}
+	return m.Datasource
+}
+
+// validateDatasources checks the datasource list and returns the declared names
+func validateDatasources(datasources []Datasource, p *problems) map[string]bool {
+	names := make(map[string]bool, len(datasources))
+	for i, ds := range datasources {
+		switch {
+		case ds.Name == "":
+			p.add("datasource[%d]: name is required", i)
+		case ds.Name == DefaultDatasource:
+			p.add("datasource[%d]: name %s is reserved for the DB_TYPE database", i, ds.Name)
+		case names[ds.Name]:
+			p.add("duplicate datasource name: %s", ds.Name)
+		}
+		names[ds.Name] = true
+
+		switch ds.Type {
+		case "postgres":
+		case "mongodb":
+			if ds.Database == "" {
+				p.add("datasource[%d] %s: database is required for mongodb", i, ds.Name)
+			}
+		default:
+			p.add("datasource[%d] %s: invalid type %q (use postgres or mongodb)", i, ds.Name, ds.Type)
+		}
+
+		if ds.URL == "" {
+			p.add("datasource[%d] %s: url is required", i, ds.Name)
+		}
+
+		if (ds.ReadPreference != "" || ds.WriteConcern != nil) && ds.Type != "mongodb" {
+			p.add("datasource[%d] %s: readPreference and writeConcern are for mongodb", i, ds.Name)
+		}
+		if ds.ReadPreference != "" && !readPreferences[ds.ReadPreference] {
+			p.add("datasource[%d] %s: invalid readPreference %q (use primary, primaryPreferred, secondary, secondaryPreferred or nearest)", i, ds.Name, ds.ReadPreference)
+		}
+		if wc := ds.WriteConcern; wc != nil {
+			switch w := wc.W.(type) {
+			case nil:
+			case string:
+				if w == "" {
+					p.add("datasource[%d] %s: writeConcern w must not be empty", i, ds.Name)
+				}
+			case float64:
+				if w < 0 || w != math.Trunc(w) {
+					p.add("datasource[%d] %s: writeConcern w must be a non-negative integer, \"majority\" or a tag set name", i, ds.Name)
+				}
+			default:
+				p.add("datasource[%d] %s: writeConcern w must be a non-negative integer, \"majority\" or a tag set name", i, ds.Name)
+			}
+		}
+	}
+	return names
 }
 
 // ValidateModel validates a single model
 func ValidateModel(model *Model, index int) error {
+	var p problems
+	validateModel(model, index, &p)
+	return p.err()
+}
+
+func validateModel(model *Model, index int, p *problems) {
 	if model.Name == "" {
-		return fmt.Errorf("model[%d]: name is required", index)
+		p.record(index, fmt.Sprintf("model[%d]: name is required", index), "name is required")
 	}
 
 	if model.Table == "" {
-		return fmt.Errorf("model[%d] %s: table is required", index, model.Name)
+		p.addModel(index, model.Name, "table is required")
 	}
 
 	if model.PrimaryKey == "" {
-		return fmt.Errorf("model[%d] %s: primaryKey is required", index, model.Name)
+		p.addModel(index, model.Name, "primaryKey is required")
 	}
 
 	if len(model.Fields) == 0 {
-		return fmt.Errorf("model[%d] %s: at least one field is required", index, model.Name)
+		p.addModel(index, model.Name, "at least one field is required")
 	}
 
 	// Validate that primary key exists in fields
@@ -95,12 +575,10 @@ func ValidateModel(model *Model, index int) error {
 	fieldNames := make(map[string]bool)
 
 	for j, field := range model.Fields {
-		if err := ValidateField(&field, index, model.Name, j); err != nil {
-			return err
-		}
+		validateField(&field, index, model.Name, j, p)
 
-		if fieldNames[field.Name] {
-			return fmt.Errorf("model[%d] %s: duplicate field name: %s", index, model.Name, field.Name)
+		if field.Name != "" && fieldNames[field.Name] {
+			p.addModel(index, model.Name, "duplicate field name: %s", field.Name)
 		}
 		fieldNames[field.Name] = true
 
@@ -109,41 +587,253 @@ func ValidateModel(model *Model, index int) error {
 		}
 	}
 
-	if !primaryKeyExists {
-		return fmt.Errorf("model[%d] %s: primaryKey %s not found in fields", index, model.Name, model.PrimaryKey)
+	if model.PrimaryKey != "" && len(model.Fields) > 0 && !primaryKeyExists {
+		p.addModel(index, model.Name, "primaryKey %s not found in fields", model.PrimaryKey)
 	}
 
-	return nil
+	if model.GenerateUUID {
+		for _, field := range model.Fields {
+			if field.Name == model.PrimaryKey && (field.Type != "uuid" || field.Nullable) {
+				p.addModel(index, model.Name, "generateUUID requires a non-nullable uuid primary key")
+			}
+		}
+	}
+
+	for _, hidden := range model.HiddenFields {
+		if !fieldNames[hidden] {
+			p.addModel(index, model.Name, "hidden field %s not found in fields", hidden)
+		}
+	}
+
+	if model.SoftDeleteField != "" && !fieldNames[model.SoftDeleteField] {
+		p.addModel(index, model.Name, "softDeleteField %s not found in fields", model.SoftDeleteField)
+	}
+
+	if model.CreatedAtField != "" && !fieldNames[model.CreatedAtField] {
+		p.addModel(index, model.Name, "createdAtField %s not found in fields", model.CreatedAtField)
+	}
+
+	if model.UpdatedAtField != "" && !fieldNames[model.UpdatedAtField] {
+		p.addModel(index, model.Name, "updatedAtField %s not found in fields", model.UpdatedAtField)
+	}
+
+	if model.VersionField != "" {
+		var found bool
+		for _, field := range model.Fields {
+			if field.Name == model.VersionField {
+				found = true
+				if field.Type != "integer" && field.Type != "int" {
+					p.addModel(index, model.Name, "versionField %s must be an integer field", model.VersionField)
+				}
+			}
+		}
+		if !found {
+			p.addModel(index, model.Name, "versionField %s not found in fields", model.VersionField)
+		}
+	}
+
+	if model.Collation != nil {
+		if err := model.Collation.Validate(); err != nil {
+			p.addModel(index, model.Name, "%v", err)
+		}
+	}
+
+	if !validFieldNaming(model.FieldNaming) {
+		p.addModel(index, model.Name, "invalid fieldNaming %s", model.FieldNaming)
+	}
+
+	if model.CacheTTL != "" {
+		if ttl, err := time.ParseDuration(model.CacheTTL); err != nil || ttl <= 0 {
+			p.addModel(index, model.Name, "invalid cacheTTL %s", model.CacheTTL)
+		}
+	}
+
+	for _, search := range model.SearchFields {
+		if !fieldNames[search] {
+			p.addModel(index, model.Name, "search field %s not found in fields", search)
+		}
+	}
+
+	virtualNames := make(map[string]bool, len(model.VirtualFields))
+	for j, virtual := range model.VirtualFields {
+		validateVirtualField(&virtual, index, model.Name, j, fieldNames, p)
+
+		if virtual.Name != "" && (fieldNames[virtual.Name] || virtualNames[virtual.Name]) {
+			p.addModel(index, model.Name, "duplicate field name: %s", virtual.Name)
+		}
+		virtualNames[virtual.Name] = true
+	}
+
+	relationNames := make(map[string]bool)
+	for j, rel := range model.Relations {
+		validateRelation(&rel, index, model.Name, j, fieldNames, p)
+
+		if rel.Name != "" && relationNames[rel.Name] {
+			p.addModel(index, model.Name, "duplicate relation name: %s", rel.Name)
+		}
+		relationNames[rel.Name] = true
+	}
+}
+
+// validateVirtualField checks a virtual field's type and that its expressions only reference
+// the model's columns
+func validateVirtualField(virtual *VirtualField, modelIndex int, modelName string, virtualIndex int, fieldNames map[string]bool, p *problems) {
+	if virtual.Name == "" {
+		p.addModel(modelIndex, modelName, "virtualField[%d] name is required", virtualIndex)
+	}
+	if !fieldTypes[virtual.Type] {
+		p.addModel(modelIndex, modelName, "virtualField[%d] %s: invalid type %q", virtualIndex, virtual.Name, virtual.Type)
+	}
+	if virtual.SQL == "" && virtual.Mongo == nil {
+		p.addModel(modelIndex, modelName, "virtualField[%d] %s: sql or mongo expression is required", virtualIndex, virtual.Name)
+	}
+
+	for _, column := range append(virtual.SQLColumns(), virtual.MongoColumns()...) {
+		if !fieldNames[column] {
+			p.addModel(modelIndex, modelName, "virtualField[%d] %s: column %s not found in fields", virtualIndex, virtual.Name, column)
+		}
+	}
+}
+
+// ValidateRelation validates a single relation against its model's fields
+func ValidateRelation(rel *Relation, modelIndex int, modelName string, relIndex int, fieldNames map[string]bool) error {
+	var p problems
+	validateRelation(rel, modelIndex, modelName, relIndex, fieldNames, &p)
+	return p.err()
+}
+
+func validateRelation(rel *Relation, modelIndex int, modelName string, relIndex int, fieldNames map[string]bool, p *problems) {
+	if rel.Name == "" {
+		p.addModel(modelIndex, modelName, "relation[%d] name is required", relIndex)
+	}
+
+	validTypes := map[string]bool{
+		"one_to_one":   true,
+		"one_to_many":  true,
+		"many_to_one":  true,
+		"many_to_many": true,
+	}
+	if !validTypes[rel.Type] {
+		p.addModel(modelIndex, modelName, "relation[%d] %s: invalid type %q", relIndex, rel.Name, rel.Type)
+	}
+
+	if rel.TargetModel == "" {
+		p.addModel(modelIndex, modelName, "relation[%d] %s: targetModel is required", relIndex, rel.Name)
+	}
+
+	if rel.ForeignKey == "" || rel.ReferenceKey == "" {
+		p.addModel(modelIndex, modelName, "relation[%d] %s: foreignKey and referenceKey are required", relIndex, rel.Name)
+	}
+
+	if rel.ForeignKey != "" && !fieldNames[rel.ForeignKey] {
+		p.addModel(modelIndex, modelName, "relation[%d] %s: foreignKey %s not found in fields", relIndex, rel.Name, rel.ForeignKey)
+	}
+}
+
+// fieldTypes are the types a field may declare
+var fieldTypes = map[string]bool{
+	"string":    true,
+	"integer":   true,
+	"int":       true,
+	"float":     true,
+	"decimal":   true,
+	"boolean":   true,
+	"datetime":  true,
+	"timestamp": true,
+	"date":      true,
+	"uuid":      true,
+	"json":      true,
+	"interval":  true,
+	"inet":      true,
+	"cidr":      true,
+	"macaddr":   true,
+	"xml":       true,
 }
 
 // ValidateField validates a single field
 func ValidateField(field *Field, modelIndex int, modelName string, fieldIndex int) error {
+	var p problems
+	validateField(field, modelIndex, modelName, fieldIndex, &p)
+	return p.err()
+}
+
+func validateField(field *Field, modelIndex int, modelName string, fieldIndex int, p *problems) {
 	if field.Name == "" {
-		return fmt.Errorf("model[%d] %s: field[%d] name is required", modelIndex, modelName, fieldIndex)
+		p.addModel(modelIndex, modelName, "field[%d] name is required", fieldIndex)
 	}
 
 	if field.Type == "" {
-		return fmt.Errorf("model[%d] %s: field[%d] %s: type is required", modelIndex, modelName, fieldIndex, field.Name)
+		p.addModel(modelIndex, modelName, "field[%d] %s: type is required", fieldIndex, field.Name)
+		return
 	}
 
 	// Validate field type
-	validTypes := map[string]bool{
-		"string":    true,
-		"integer":   true,
-		"int":       true,
-		"float":     true,
-		"decimal":   true,
-		"boolean":   true,
-		"datetime":  true,
-		"timestamp": true,
-		"date":      true,
-		"uuid":      true,
-		"json":      true,
+	if !fieldTypes[field.Type] {
+		p.addModel(modelIndex, modelName, "field[%d] %s: invalid type %q", fieldIndex, field.Name, field.Type)
 	}
 
-	if !validTypes[field.Type] {
-		return fmt.Errorf("model[%d] %s: field[%d] %s: invalid type %q", modelIndex, modelName, fieldIndex, field.Name, field.Type)
+	if field.CaseInsensitive && field.Type != "string" {
+		p.addModel(modelIndex, modelName, "field[%d] %s: caseInsensitive requires a string field", fieldIndex, field.Name)
+	}
+	if field.Array && field.Type == "json" {
+		p.addModel(modelIndex, modelName, "field[%d] %s: array is for PostgreSQL array columns, json fields hold arrays already", fieldIndex, field.Name)
+	}
+	if field.Array && field.CaseInsensitive {
+		p.addModel(modelIndex, modelName, "field[%d] %s: caseInsensitive cannot be used on an array field", fieldIndex, field.Name)
 	}
 
-	return nil
+	if t := field.Transform; t != nil {
+		switch t.Type {
+		case TransformMaskEmail:
+		case TransformMask:
+			if t.Length < 0 {
+				p.addModel(modelIndex, modelName, "field[%d] %s: mask length must not be negative", fieldIndex, field.Name)
+			}
+		case TransformTruncate:
+			if t.Length <= 0 {
+				p.addModel(modelIndex, modelName, "field[%d] %s: truncate requires a positive length", fieldIndex, field.Name)
+			}
+		default:
+			p.addModel(modelIndex, modelName, "field[%d] %s: invalid transform %q (use %s, %s or %s)", fieldIndex, field.Name, t.Type, TransformMaskEmail, TransformMask, TransformTruncate)
+		}
+		if field.Type != "string" {
+			p.addModel(modelIndex, modelName, "field[%d] %s: transform requires a string field", fieldIndex, field.Name)
+		}
+	}
+
+	if field.ReadDefault != nil && !readDefaultMatches(field) {
+		p.addModel(modelIndex, modelName, "field[%d] %s: readDefault %v does not match type %s", fieldIndex, field.Name, field.ReadDefault, field.Type)
+	}
+}
+
+// readDefaultMatches reports whether a field's read default has the JSON kind its values are
+// returned as
+func readDefaultMatches(field *Field) bool {
+	if field.Array {
+		_, ok := field.ReadDefault.([]interface{})
+		return ok
+	}
+	switch v := field.ReadDefault.(type) {
+	case float64:
+		switch field.Type {
+		case "integer", "int":
+			return v == math.Trunc(v)
+		case "float", "decimal":
+			return true
+		}
+	case int, int64:
+		switch field.Type {
+		case "integer", "int", "float", "decimal":
+			return true
+		}
+	case bool:
+		return field.Type == "boolean"
+	case string:
+		switch field.Type {
+		case "integer", "int", "float", "decimal", "boolean":
+			return false
+		}
+		return true
+	}
+	return field.Type == "json"
 }
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -250,6 +252,345 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid relation",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "orders",
+						Table:      "orders",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+							{Name: "user_id", Type: "integer", Nullable: false},
+						},
+						Relations: []Relation{
+							{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "id"},
+						},
+					},
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "relation to unknown model",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "orders",
+						Table:      "orders",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+							{Name: "user_id", Type: "integer", Nullable: false},
+						},
+						Relations: []Relation{
+							{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "id"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "target model users not found",
+		},
+		{
+			name: "hidden field not in fields",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						HiddenFields: []string{"password_hash"},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "hidden field password_hash not found",
+		},
+		{
+			name: "soft delete field not in fields",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						SoftDeleteField: "deleted_at",
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "softDeleteField deleted_at not found",
+		},
+		{
+			name: "generate uuid on integer primary key",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						GenerateUUID: true,
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "generateUUID requires a non-nullable uuid primary key",
+		},
+		{
+			name: "invalid cache ttl",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						CacheTTL: "soon",
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid cacheTTL soon",
+		},
+		{
+			name: "updated at field not in fields",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						UpdatedAtField: "updated_at",
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "updatedAtField updated_at not found",
+		},
+		{
+			name: "version field not in fields",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						VersionField: "version",
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "versionField version not found",
+		},
+		{
+			name: "version field not an integer",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+							{Name: "version", Type: "string", Nullable: false},
+						},
+						VersionField: "version",
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "versionField version must be an integer field",
+		},
+		{
+			name: "unknown transform",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+							{Name: "email", Type: "string", Nullable: false, Transform: &FieldTransform{Type: "hash"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  `invalid transform "hash"`,
+		},
+		{
+			name: "truncate without length",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+							{Name: "bio", Type: "string", Nullable: true, Transform: &FieldTransform{Type: TransformTruncate}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "truncate requires a positive length",
+		},
+		{
+			name: "transform on non-string field",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false, Transform: &FieldTransform{Type: TransformMask}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "transform requires a string field",
+		},
+		{
+			name: "read default of the wrong type",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+							{Name: "status", Type: "string", Nullable: true, ReadDefault: "unknown"},
+							{Name: "score", Type: "integer", Nullable: true, ReadDefault: 1.5},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "readDefault 1.5 does not match type integer",
+		},
+		{
+			name: "invalid collation",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						Collation: &Collation{Locale: "de", Strength: 9},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "collation strength must be between 1 and 5",
+		},
+		{
+			name: "search field not in fields",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						SearchFields: []string{"bio"},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "search field bio not found",
+		},
+		{
+			name: "relation with unknown foreign key",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "orders",
+						Table:      "orders",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+						},
+						Relations: []Relation{
+							{Name: "user", Type: "many_to_one", TargetModel: "orders", ForeignKey: "user_id", ReferenceKey: "id"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "foreignKey user_id not found",
+		},
+		{
+			name: "caseInsensitive on non-string field",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "users",
+						Table:      "users",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false, CaseInsensitive: true},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "caseInsensitive requires a string field",
+		},
+		{
+			name: "array on json field",
+			config: &Config{
+				Models: []Model{
+					{
+						Name:       "posts",
+						Table:      "posts",
+						PrimaryKey: "id",
+						Fields: []Field{
+							{Name: "id", Type: "integer", Nullable: false},
+							{Name: "tags", Type: "json", Nullable: true, Array: true},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "json fields hold arrays already",
+		},
 	}
 
 	for _, tt := range tests {
@@ -266,6 +607,284 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_ReportsAllProblems(t *testing.T) {
+	cfg := &Config{
+		Models: []Model{
+			{
+				Name:       "users",
+				PrimaryKey: "uid",
+				Fields: []Field{
+					{Name: "id", Type: "integer"},
+					{Name: "email", Type: "varchar"},
+				},
+			},
+			{
+				Name:       "users",
+				Table:      "users_archive",
+				PrimaryKey: "id",
+				Fields:     []Field{{Name: "id", Type: "integer"}},
+				Relations: []Relation{
+					{Name: "owner", Type: "many_to_one", TargetModel: "accounts", ForeignKey: "id", ReferenceKey: "id"},
+				},
+			},
+		},
+	}
+
+	err := ValidateConfig(cfg)
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+
+	want := []string{
+		"model[0] users: table is required",
+		`model[0] users: field[1] email: invalid type "varchar"`,
+		"model[0] users: primaryKey uid not found in fields",
+		"duplicate model name: users",
+		"model[1] users: relation[0] owner: target model accounts not found",
+	}
+	if len(validationErr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d:\n%v", len(want), len(validationErr.Problems), err)
+	}
+	for i, problem := range want {
+		if validationErr.Problems[i] != problem {
+			t.Errorf("problem %d = %q, want %q", i, validationErr.Problems[i], problem)
+		}
+	}
+	if !contains(err.Error(), "5 config problems:") {
+		t.Errorf("expected a problem count in the message, got %q", err.Error())
+	}
+}
+
+func TestValidateConfig_Datasources(t *testing.T) {
+	model := func(name, datasource string, relations ...Relation) Model {
+		return Model{
+			Name:       name,
+			Table:      name,
+			PrimaryKey: "id",
+			Datasource: datasource,
+			Fields:     []Field{{Name: "id", Type: "integer"}, {Name: "user_id", Type: "integer"}},
+			Relations:  relations,
+		}
+	}
+	toUsers := Relation{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "id"}
+
+	valid := &Config{
+		Datasources: []Datasource{
+			{Name: "analytics", Type: "postgres", URL: "postgres://analytics"},
+			{Name: "events", Type: "mongodb", URL: "mongodb://events", Database: "events"},
+		},
+		Models: []Model{
+			model("users", ""),
+			model("orders", "default", toUsers),
+			model("page_views", "analytics"),
+			model("clicks", "events"),
+		},
+	}
+	if err := ValidateConfig(valid); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	invalid := &Config{
+		Datasources: []Datasource{
+			{Name: "default", Type: "postgres", URL: "postgres://x"},
+			{Name: "events", Type: "mongodb", URL: "mongodb://events"},
+			{Name: "events", Type: "mysql"},
+		},
+		Models: []Model{
+			model("users", ""),
+			model("clicks", "events", toUsers),
+			model("visits", "warehouse"),
+		},
+	}
+	err := ValidateConfig(invalid)
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+
+	want := []string{
+		"datasource[0]: name default is reserved for the DB_TYPE database",
+		"datasource[1] events: database is required for mongodb",
+		"duplicate datasource name: events",
+		`datasource[2] events: invalid type "mysql" (use postgres or mongodb)`,
+		"datasource[2] events: url is required",
+		"model[1] clicks: relation[0] user: target model users is in datasource default, not events; relations cannot cross datasources",
+		"model[2] visits: datasource warehouse not found",
+	}
+	if len(validationErr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d:\n%v", len(want), len(validationErr.Problems), err)
+	}
+	for i, problem := range want {
+		if validationErr.Problems[i] != problem {
+			t.Errorf("problem %d = %q, want %q", i, validationErr.Problems[i], problem)
+		}
+	}
+}
+
+func TestValidateConfig_DatasourceConsistency(t *testing.T) {
+	var cfg Config
+	err := json.Unmarshal([]byte(`{"datasources": [
+		{"name": "events", "type": "mongodb", "url": "mongodb://events", "database": "events", "readPreference": "secondaryPreferred", "writeConcern": {"w": "majority", "j": true}},
+		{"name": "audit", "type": "mongodb", "url": "mongodb://audit", "database": "audit", "writeConcern": {"w": 2}},
+		{"name": "bad", "type": "mongodb", "url": "mongodb://bad", "database": "bad", "readPreference": "any", "writeConcern": {"w": 1.5}},
+		{"name": "analytics", "type": "postgres", "url": "postgres://analytics", "readPreference": "nearest"}
+	]}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Datasources[0].WriteConcern.WString(); got != "majority" || !cfg.Datasources[0].WriteConcern.Journal {
+		t.Errorf("events write concern = %q, journal %v", got, cfg.Datasources[0].WriteConcern.Journal)
+	}
+	if got := cfg.Datasources[1].WriteConcern.WString(); got != "2" {
+		t.Errorf("audit write concern = %q, want 2", got)
+	}
+
+	var p problems
+	validateDatasources(cfg.Datasources, &p)
+	want := []string{
+		`datasource[2] bad: invalid readPreference "any" (use primary, primaryPreferred, secondary, secondaryPreferred or nearest)`,
+		`datasource[2] bad: writeConcern w must be a non-negative integer, "majority" or a tag set name`,
+		"datasource[3] analytics: readPreference and writeConcern are for mongodb",
+	}
+	if !reflect.DeepEqual(p.messages, want) {
+		t.Errorf("problems = %q, want %q", p.messages, want)
+	}
+}
+
+func TestDropRemoved(t *testing.T) {
+	cfg := &Config{Models: []Model{
+		{Name: "users", Fields: []Field{{Name: "id"}, {Name: "legacy_code", Removed: true}}},
+		{Name: "audit_log", Fields: []Field{{Name: "id"}}, Removed: true},
+	}}
+	cfg.DropRemoved()
+
+	if len(cfg.Models) != 1 || cfg.Models[0].Name != "users" {
+		t.Fatalf("expected only users to remain, got %+v", cfg.Models)
+	}
+	if fields := cfg.Models[0].Fields; len(fields) != 1 || fields[0].Name != "id" {
+		t.Errorf("expected the removed field to be dropped, got %+v", fields)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	tests := []struct {
+		naming, column, want string
+	}{
+		{"", "created_at", "created_at"},
+		{FieldNamingColumn, "created_at", "created_at"},
+		{FieldNamingCamel, "created_at", "createdAt"},
+		{FieldNamingCamel, "id", "id"},
+		{FieldNamingCamel, "_id", "_id"},
+		{FieldNamingCamel, "order__line_id", "orderLineId"},
+		{FieldNamingCamel, "alreadyCamel", "alreadyCamel"},
+	}
+	for _, tt := range tests {
+		if got := FieldName(tt.naming, tt.column); got != tt.want {
+			t.Errorf("FieldName(%q, %q) = %q, want %q", tt.naming, tt.column, got, tt.want)
+		}
+	}
+}
+
+func TestValidateConfig_FieldNaming(t *testing.T) {
+	cfg := &Config{
+		FieldNaming: "kebab",
+		Models: []Model{
+			{
+				Name:        "users",
+				Table:       "users",
+				PrimaryKey:  "id",
+				FieldNaming: FieldNamingCamel,
+				Fields: []Field{
+					{Name: "id", Type: "integer"},
+					{Name: "user_name", Type: "string"},
+					{Name: "userName", Type: "string"},
+				},
+			},
+			{
+				Name:        "orders",
+				Table:       "orders",
+				PrimaryKey:  "id",
+				FieldNaming: "pascal",
+				Fields:      []Field{{Name: "id", Type: "integer"}},
+			},
+		},
+	}
+
+	err := ValidateConfig(cfg)
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+	want := []string{
+		"invalid fieldNaming kebab",
+		"model[0] users: fields user_name and userName both map to field name userName",
+		"model[1] orders: invalid fieldNaming pascal",
+	}
+	if len(validationErr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d:\n%v", len(want), len(validationErr.Problems), err)
+	}
+	for i, problem := range want {
+		if validationErr.Problems[i] != problem {
+			t.Errorf("problem %d = %q, want %q", i, validationErr.Problems[i], problem)
+		}
+	}
+}
+
+func TestValidateConfig_VirtualFields(t *testing.T) {
+	fullName := VirtualField{
+		Name:  "full_name",
+		Type:  "string",
+		SQL:   "{first_name} || ' ' || {last_name}",
+		Mongo: map[string]interface{}{"$concat": []interface{}{"$first_name", " ", "$last_name"}},
+	}
+	model := Model{
+		Name:       "people",
+		Table:      "people",
+		PrimaryKey: "id",
+		Fields: []Field{
+			{Name: "id", Type: "integer"},
+			{Name: "first_name", Type: "string"},
+			{Name: "last_name", Type: "string"},
+		},
+		VirtualFields: []VirtualField{fullName},
+	}
+	if err := ValidateConfig(&Config{Models: []Model{model}}); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	model.VirtualFields = []VirtualField{
+		{Name: "initials", Type: "string", SQL: "left({first_name}, 1) || left({surname}, 1)"},
+		{Name: "display", Type: "string", Mongo: map[string]interface{}{"$toUpper": "$nick.name"}},
+		{Name: "first_name", Type: "text"},
+	}
+	err := ValidateConfig(&Config{Models: []Model{model}})
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+	want := []string{
+		"model[0] people: virtualField[0] initials: column surname not found in fields",
+		"model[0] people: virtualField[1] display: column nick not found in fields",
+		`model[0] people: virtualField[2] first_name: invalid type "text"`,
+		"model[0] people: virtualField[2] first_name: sql or mongo expression is required",
+		"model[0] people: duplicate field name: first_name",
+	}
+	if len(validationErr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d:\n%v", len(want), len(validationErr.Problems), err)
+	}
+	for i, problem := range want {
+		if validationErr.Problems[i] != problem {
+			t.Errorf("problem %d = %q, want %q", i, validationErr.Problems[i], problem)
+		}
+	}
+
+	expanded := fullName.ExpandSQL(func(column string) string { return "t0." + column })
+	if expanded != "t0.first_name || ' ' || t0.last_name" {
+		t.Errorf("unexpected expanded SQL: %s", expanded)
+	}
+}
+
 func TestLoadConfig(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envNamePattern matches valid environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExpandEnv expands ${VAR} placeholders in every string value of cfg, except fields tagged
+// env:"-" such as SQL expressions, whose "$$" and "$n" must reach the database as written.
+// Every variable that is not set and has no default is reported together.
+func ExpandEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	missing := make(map[string]bool)
+	if err := expandEnvValue(reflect.ValueOf(cfg).Elem(), lookup, missing); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("environment variables not set: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// expandEnvValue walks structs, slices, maps and pointers, expanding settable strings.
+// Struct fields tagged env:"-" are skipped.
+func expandEnvValue(v reflect.Value, lookup func(string) (string, bool), missing map[string]bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		expanded, err := expandEnv(v.String(), lookup, missing)
+		if err != nil {
+			return err
+		}
+		v.SetString(expanded)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.IsExported() && field.Tag.Get("env") != "-" {
+				if err := expandEnvValue(v.Field(i), lookup, missing); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandEnvValue(v.Index(i), lookup, missing); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return expandEnvValue(v.Elem(), lookup, missing)
+		}
+	case reflect.Map:
+		// Map values are not addressable, so each one is expanded in a copy and stored back
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			if err := expandEnvValue(value, lookup, missing); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+	return nil
+}
+
+// expandEnv replaces ${VAR} with the variable's value and ${VAR:-default} with the value,
+// or default when the variable is unset or empty. $$ is a literal $; any other $ is kept
+// as is. Unset variables without a default are added to missing.
+func expandEnv(s string, lookup func(string) (string, bool), missing map[string]bool) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			out.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated placeholder in %q", s)
+			}
+			expr := s[i+2 : i+end]
+			name, def, hasDefault := strings.Cut(expr, ":-")
+			if !envNamePattern.MatchString(name) {
+				return "", fmt.Errorf("invalid placeholder ${%s} in %q", expr, s)
+			}
+			value, ok := lookup(name)
+			switch {
+			case ok && (value != "" || !hasDefault):
+				out.WriteString(value)
+			case hasDefault:
+				out.WriteString(def)
+			default:
+				missing[name] = true
+			}
+			i += end
+		default:
+			out.WriteByte('$')
+		}
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"SCHEMA": "sales", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"${SCHEMA}.orders", "sales.orders", false},
+		{"${REGION:-eu}", "eu", false},
+		{"${EMPTY:-fallback}", "fallback", false},
+		{"${EMPTY}", "", false},
+		{"price$$", "price$", false},
+		{"$$${SCHEMA}", "$sales", false},
+		{"cost $5", "cost $5", false},
+		{"${SCHEMA", "", true},
+		{"${1BAD}", "", true},
+	}
+
+	for _, tt := range tests {
+		missing := make(map[string]bool)
+		got, err := expandEnv(tt.in, lookup, missing)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("expandEnv(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if len(missing) != 0 {
+			t.Errorf("expandEnv(%q) reported missing %v", tt.in, missing)
+		}
+	}
+}
+
+func TestExpandEnv_ReportsAllMissing(t *testing.T) {
+	cfg := &Config{
+		Models: []Model{
+			{Name: "orders", Table: "${SCHEMA}.orders", HiddenFields: []string{"${SECRET_FIELD}"}},
+		},
+	}
+	err := ExpandEnv(cfg, func(string) (string, bool) { return "", false })
+	if err == nil || err.Error() != "environment variables not set: SCHEMA, SECRET_FIELD" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestExpandEnv_SkipsSQLExpressions(t *testing.T) {
+	const sql = "$$ cost $$ || {amount} * $1"
+	cfg := &Config{
+		Models: []Model{
+			{
+				Name:          "orders",
+				Table:         "${SCHEMA}.orders",
+				VirtualFields: []VirtualField{{Name: "label", Type: "string", SQL: sql}},
+				Indexes:       []Index{{Name: "orders_open", Columns: []string{"lower(status)"}, Predicate: "status <> '$$closed$$'"}},
+			},
+		},
+	}
+	if err := ExpandEnv(cfg, func(string) (string, bool) { return "sales", true }); err != nil {
+		t.Fatalf("ExpandEnv error: %v", err)
+	}
+
+	model := cfg.Models[0]
+	if model.Table != "sales.orders" {
+		t.Errorf("Table = %q, want sales.orders", model.Table)
+	}
+	if model.VirtualFields[0].SQL != sql {
+		t.Errorf("virtual field SQL = %q, want it unchanged", model.VirtualFields[0].SQL)
+	}
+	if model.Indexes[0].Predicate != "status <> '$$closed$$'" {
+		t.Errorf("index predicate = %q, want it unchanged", model.Indexes[0].Predicate)
+	}
+}
+
+func TestLoadConfig_ExpandsEnv(t *testing.T) {
+	t.Setenv("UDV_TEST_TABLE", "orders_v2")
+	path := filepath.Join(t.TempDir(), "models.json")
+	data := `{"models":[{"name":"orders","table":"${UDV_TEST_TABLE}","primaryKey":"id","fields":[{"name":"id","type":"${UDV_TEST_ID_TYPE:-integer}"}]}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Models[0].Table != "orders_v2" || cfg.Models[0].Fields[0].Type != "integer" {
+		t.Errorf("placeholders not expanded: %+v", cfg.Models[0])
+	}
+}
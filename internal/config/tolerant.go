@@ -0,0 +1,58 @@
+package config
+
+import "errors"
+
+// ModelError is a model left out by a tolerant load, with the problems that disqualified it
+type ModelError struct {
+	Name     string   `json:"name"`
+	Index    int      `json:"index"` // Position of the model in the config file
+	Problems []string `json:"problems"`
+}
+
+// LoadConfigTolerant loads a config like LoadConfig, but leaves out models with problems
+// instead of failing, and returns them. Problems outside any model, such as an invalid
+// datasource, still fail the load, as does a file without a single valid model.
+func LoadConfigTolerant(filePath string) (*Config, []ModelError, error) {
+	cfg, err := readConfig(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	failed, err := DropInvalidModels(cfg)
+	if err != nil {
+		return nil, failed, err
+	}
+	return cfg, failed, nil
+}
+
+// DropInvalidModels removes every model with a validation problem from cfg and returns them.
+// Dropping a model can break relations that target it, so validation repeats until the
+// remaining models are valid. A problem outside any model returns the validation error.
+func DropInvalidModels(cfg *Config) ([]ModelError, error) {
+	// positions maps the index of each remaining model to its index in the file
+	positions := make([]int, len(cfg.Models))
+	for i := range positions {
+		positions[i] = i
+	}
+
+	var failed []ModelError
+	for {
+		err := ValidateConfig(cfg)
+		var validationErr *ValidationError
+		if err == nil || !errors.As(err, &validationErr) || validationErr.global {
+			return failed, err
+		}
+		byModel := validationErr.byModel
+
+		kept := make([]Model, 0, len(cfg.Models)-len(byModel))
+		keptPositions := make([]int, 0, len(kept))
+		for i, model := range cfg.Models {
+			if problems, ok := byModel[i]; ok {
+				failed = append(failed, ModelError{Name: model.Name, Index: positions[i], Problems: problems})
+				continue
+			}
+			kept = append(kept, model)
+			keptPositions = append(keptPositions, positions[i])
+		}
+		cfg.Models, positions = kept, keptPositions
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDropInvalidModels(t *testing.T) {
+	cfg := &Config{
+		Models: []Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields:     []Field{{Name: "id", Type: "integer"}},
+			},
+			{
+				Name:       "invoices",
+				PrimaryKey: "id",
+				Fields:     []Field{{Name: "id", Type: "integer"}},
+			},
+			{
+				Name:       "lines",
+				Table:      "lines",
+				PrimaryKey: "id",
+				Fields:     []Field{{Name: "id", Type: "integer"}, {Name: "invoice_id", Type: "integer"}},
+				Relations: []Relation{
+					{Name: "invoice", Type: "many_to_one", TargetModel: "invoices", ForeignKey: "invoice_id", ReferenceKey: "id"},
+				},
+			},
+			{
+				Name:       "users",
+				Table:      "users_v2",
+				PrimaryKey: "id",
+				Fields:     []Field{{Name: "id", Type: "integer"}},
+			},
+		},
+	}
+
+	failed, err := DropInvalidModels(cfg)
+	if err != nil {
+		t.Fatalf("DropInvalidModels failed: %v", err)
+	}
+
+	if len(cfg.Models) != 1 || cfg.Models[0].Table != "users" {
+		t.Errorf("expected only the first users model to remain, got %+v", cfg.Models)
+	}
+	want := []ModelError{
+		{Name: "invoices", Index: 1, Problems: []string{"table is required"}},
+		{Name: "users", Index: 3, Problems: []string{"duplicate model name: users"}},
+		{Name: "lines", Index: 2, Problems: []string{"relation[0] invoice: target model invoices not found"}},
+	}
+	if !reflect.DeepEqual(failed, want) {
+		t.Errorf("unexpected failed models:\n got %+v\nwant %+v", failed, want)
+	}
+}
+
+func TestDropInvalidModels_FileProblems(t *testing.T) {
+	cfg := &Config{
+		FieldNaming: "kebab",
+		Models: []Model{{
+			Name:       "users",
+			Table:      "users",
+			PrimaryKey: "id",
+			Fields:     []Field{{Name: "id", Type: "integer"}},
+		}},
+	}
+	if _, err := DropInvalidModels(cfg); err == nil {
+		t.Error("expected a problem outside any model to fail the load")
+	}
+
+	cfg = &Config{Models: []Model{{Name: "users", PrimaryKey: "id"}}}
+	if _, err := DropInvalidModels(cfg); err == nil {
+		t.Error("expected an error when no valid model remains")
+	}
+}
+
+func TestLoadConfigTolerant(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	data := `{"models": [
+		{"name": "users", "table": "users", "primaryKey": "id", "fields": [{"name": "id", "type": "integer"}]},
+		{"name": "orders", "table": "orders", "primaryKey": "id", "fields": [{"name": "id", "type": "money"}]}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected strict loading to fail")
+	}
+
+	cfg, failed, err := LoadConfigTolerant(path)
+	if err != nil {
+		t.Fatalf("LoadConfigTolerant failed: %v", err)
+	}
+	if len(cfg.Models) != 1 || cfg.Models[0].Name != "users" {
+		t.Errorf("expected users to load, got %+v", cfg.Models)
+	}
+	if len(failed) != 1 || failed[0].Name != "orders" {
+		t.Errorf("expected orders to fail, got %+v", failed)
+	}
+}
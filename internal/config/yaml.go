@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML files are decoded with yaml.v3 and converted to and from JSON, keeping mapping key
+// order, so both formats decode into the same structs through their json tags.
+
+// IsYAMLPath reports whether path has a .yaml or .yml extension
+func IsYAMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// YAMLToJSON converts a single YAML document into the equivalent JSON, keeping mapping key order
+func YAMLToJSON(data []byte) ([]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return []byte("null"), nil
+		}
+		return nil, err
+	}
+	var extra yaml.Node
+	if err := dec.Decode(&extra); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("yaml: line %d: multiple documents are not supported", extra.Line)
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONNode(&buf, &doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeJSONNode encodes a decoded YAML node as JSON
+func writeJSONNode(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSONNode(buf, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSONNode(buf, node.Alias)
+	case yaml.MappingNode:
+		pairs, err := mappingPairs(node)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i := 0; i < len(pairs); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(pairs[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSONNode(buf, pairs[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		// Timestamps keep their text, which the fields holding them parse themselves
+		var value interface{} = node.Value
+		if node.ShortTag() != "!!timestamp" {
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("yaml: line %d: %s has no JSON equivalent", node.Line, node.Value)
+		}
+		buf.Write(raw)
+	}
+	return nil
+}
+
+// mappingPairs returns a mapping's key and value nodes in order, with the entries of << merge
+// keys added after the mapping's own keys, which override them
+func mappingPairs(node *yaml.Node) ([]*yaml.Node, error) {
+	var pairs, merged []*yaml.Node
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			sources := []*yaml.Node{value}
+			if resolveAlias(value).Kind == yaml.SequenceNode {
+				sources = resolveAlias(value).Content
+			}
+			for _, source := range sources {
+				source = resolveAlias(source)
+				if source.Kind != yaml.MappingNode {
+					return nil, fmt.Errorf("yaml: line %d: merge key needs a mapping", value.Line)
+				}
+				sourcePairs, err := mappingPairs(source)
+				if err != nil {
+					return nil, err
+				}
+				merged = append(merged, sourcePairs...)
+			}
+			continue
+		}
+		if key = resolveAlias(key); key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("yaml: line %d: mapping keys must be scalars", key.Line)
+		}
+		if seen[key.Value] {
+			return nil, fmt.Errorf("yaml: line %d: mapping key %q already defined", key.Line, key.Value)
+		}
+		seen[key.Value] = true
+		pairs = append(pairs, key, value)
+	}
+	for i := 0; i < len(merged); i += 2 {
+		if !seen[merged[i].Value] {
+			seen[merged[i].Value] = true
+			pairs = append(pairs, merged[i], merged[i+1])
+		}
+	}
+	return pairs, nil
+}
+
+// resolveAlias returns the node an alias refers to, or node itself
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// JSONToYAML converts a JSON document into block-style YAML, keeping object key order
+func JSONToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := jsonToYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonToYAMLNode reads the next JSON value as a YAML node, keeping objects in key order
+func jsonToYAMLNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if t == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for dec.More() {
+			if t == '{' {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keyTok.(string)})
+			}
+			value, err := jsonToYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		_, err = dec.Token()
+		return node, err
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(t)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const modelsYAML = `# Orders service models
+defaults: &nullable
+  type: string
+  nullable: true
+models:
+  - name: orders
+    table: sales.orders   # schema-qualified
+    primaryKey: id
+    fields:
+      - {name: id, type: integer, nullable: false}
+      - <<: *nullable
+        name: status
+        nullable: false
+      - name: note
+        <<: *nullable
+    indexes:
+    - name: orders_status_idx
+      columns: ["status"]
+      predicate: |-
+        status <> 'void'
+`
+
+func TestYAMLToJSON(t *testing.T) {
+	data, err := YAMLToJSON([]byte("b: 1\na: [x, 'y']\nc: {e: 2024-01-02, d: ~}"))
+	if err != nil {
+		t.Fatalf("YAMLToJSON failed: %v", err)
+	}
+	if want := `{"b":1,"a":["x","y"],"c":{"e":"2024-01-02","d":null}}`; string(data) != want {
+		t.Errorf("YAMLToJSON = %s, want %s", data, want)
+	}
+
+	for _, doc := range []string{"a: 1\na: 2", "a: 1\n---\nb: 2", "a: .inf", "a: [1, 2"} {
+		if _, err := YAMLToJSON([]byte(doc)); err == nil {
+			t.Errorf("expected error for %q", doc)
+		}
+	}
+}
+
+func TestJSONToYAML_RoundTrip(t *testing.T) {
+	original := `{"models":[{"name":"orders","table":"orders","primaryKey":"id","fields":[{"name":"id","type":"integer","nullable":false}],"hiddenFields":["- x","true","123",""],"relations":[],"options":{},"indexes":[{"name":"orders_code_key","columns":["lower(code)"],"unique":true,"predicate":"code IS NOT NULL: #1"}]}]}`
+
+	yamlData, err := JSONToYAML([]byte(original))
+	if err != nil {
+		t.Fatalf("JSONToYAML failed: %v", err)
+	}
+	back, err := YAMLToJSON(yamlData)
+	if err != nil {
+		t.Fatalf("YAMLToJSON failed on generated YAML: %v\n%s", err, yamlData)
+	}
+	if string(back) != original {
+		t.Errorf("round trip changed the document:\n%s\nYAML:\n%s", back, yamlData)
+	}
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yml")
+	if err := os.WriteFile(path, []byte(modelsYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := YAMLToJSON([]byte(modelsYAML))
+	if err != nil {
+		t.Fatalf("YAMLToJSON failed: %v", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("converted JSON does not decode: %v\n%s", err, data)
+	}
+	m := cfg.Models[0]
+	if len(m.Fields) != 3 || m.Fields[1].Type != "string" || m.Fields[1].Nullable || !m.Fields[2].Nullable {
+		t.Errorf("merge keys not applied: %+v", m.Fields)
+	}
+	if len(m.Indexes) != 1 || m.Indexes[0].Predicate != "status <> 'void'" {
+		t.Errorf("unexpected indexes: %+v", m.Indexes)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(loaded.Models) != 1 || loaded.Models[0].Name != "orders" || loaded.Models[0].Table != "sales.orders" {
+		t.Errorf("unexpected config: %+v", loaded)
+	}
+}
@@ -0,0 +1,150 @@
+package dsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the accepted string formats for timestamp/date values
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// CoerceValue converts a decoded JSON value to the Go type matching a field's declared type,
+// so that e.g. "5" is written and compared as an integer for an integer field. Values of types
+// that need no conversion are returned unchanged. The planner coerces written data through it for
+// every builder, so data validation accepts exactly the values it can convert.
+func CoerceValue(value interface{}, fieldType string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch fieldType {
+	case "integer", "int":
+		return coerceInteger(value)
+	case "float":
+		return coerceFloat(value)
+	case "decimal":
+		return coerceDecimal(value)
+	case "boolean":
+		return coerceBoolean(value)
+	case "timestamp", "datetime", "date":
+		return coerceTimestamp(value)
+	default:
+		return value, nil
+	}
+}
+
+func coerceInteger(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return nil, fmt.Errorf("value %v is not a valid integer", v)
+		}
+		return int64(v), nil
+	case float32:
+		if float64(v) != math.Trunc(float64(v)) {
+			return nil, fmt.Errorf("value %v is not a valid integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a valid integer", v.String())
+		}
+		return n, nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a valid integer", v)
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("value of type %T is not a valid integer", value)
+	}
+}
+
+func coerceFloat(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a valid number", v.String())
+		}
+		return f, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a valid number", v)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("value of type %T is not a valid number", value)
+	}
+}
+
+// decimalPattern matches the plain and exponent notations PostgreSQL accepts for numeric
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// coerceDecimal keeps decimal strings as written, since parsing them into a float64 would round
+// digits it cannot represent. They are returned as a json.Number, which binds as text to
+// PostgreSQL's numeric and is encoded as a number by the MongoDB driver.
+func coerceDecimal(value interface{}) (interface{}, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = strings.TrimSpace(v)
+	default:
+		return coerceFloat(value)
+	}
+	if !decimalPattern.MatchString(s) {
+		return nil, fmt.Errorf("value %q is not a valid number", s)
+	}
+	return json.Number(s), nil
+}
+
+func coerceBoolean(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a valid boolean", v)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("value of type %T is not a valid boolean", value)
+	}
+}
+
+func coerceTimestamp(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("value %q is not a valid timestamp", v)
+	default:
+		return nil, fmt.Errorf("value of type %T is not a valid timestamp", value)
+	}
+}
@@ -0,0 +1,56 @@
+package dsl
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		fieldType string
+		expected  interface{}
+		wantErr   bool
+	}{
+		{"integer from string", "5", "integer", int64(5), false},
+		{"integer from whole float", float64(7), "integer", int64(7), false},
+		{"integer from fractional float", 7.5, "integer", nil, true},
+		{"integer from garbage", "abc", "integer", nil, true},
+		{"decimal from string", "10.25", "decimal", json.Number("10.25"), false},
+		{"decimal beyond float64 precision", "12345678901234567.89", "decimal", json.Number("12345678901234567.89"), false},
+		{"decimal from exponent", " 1.5e3 ", "decimal", json.Number("1.5e3"), false},
+		{"decimal from number", float64(3), "decimal", float64(3), false},
+		{"decimal from hex", "0x10", "decimal", nil, true},
+		{"float from string", "10.25", "float", 10.25, false},
+		{"decimal from garbage", "ten", "decimal", nil, true},
+		{"boolean from string", "true", "boolean", true, false},
+		{"boolean from garbage", "yes please", "boolean", nil, true},
+		{"timestamp from date", "2024-01-02", "timestamp", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{"timestamp from garbage", "yesterday", "timestamp", nil, true},
+		{"string unchanged", "5", "string", "5", false},
+		{"uuid unchanged", "550e8400-e29b-41d4-a716-446655440000", "uuid", "550e8400-e29b-41d4-a716-446655440000", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceValue(tt.value, tt.fieldType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CoerceValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if ts, ok := tt.expected.(time.Time); ok {
+				if gotTs, ok := got.(time.Time); !ok || !gotTs.Equal(ts) {
+					t.Errorf("CoerceValue() = %v, want %v", got, tt.expected)
+				}
+				return
+			}
+			if got != tt.expected {
+				t.Errorf("CoerceValue() = %v (%T), want %v (%T)", got, got, tt.expected, tt.expected)
+			}
+		})
+	}
+}
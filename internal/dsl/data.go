@@ -0,0 +1,228 @@
+package dsl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"udv/internal/schema"
+)
+
+// ExprKey tags a data value computed by the database, e.g. {"$expr": "now()"}
+const ExprKey = "$expr"
+
+// ValueExpr is an allowlisted SQL expression written in place of a bound data value
+type ValueExpr string
+
+const (
+	ExprNow         ValueExpr = "now()"
+	ExprCurrentDate ValueExpr = "current_date"
+	ExprUUID        ValueExpr = "gen_random_uuid()"
+	ExprUUIDv4      ValueExpr = "uuid_generate_v4()"
+	ExprDefault     ValueExpr = "DEFAULT"
+)
+
+// valueExprs maps the accepted spellings, lowercased, to their expression
+var valueExprs = map[string]ValueExpr{
+	"now()":              ExprNow,
+	"current_timestamp":  ExprNow,
+	"current_date":       ExprCurrentDate,
+	"gen_random_uuid()":  ExprUUID,
+	"uuid_generate_v4()": ExprUUIDv4,
+	"default":            ExprDefault,
+}
+
+// TaggedExpr reports whether a data value is an object holding only ExprKey, and returns the
+// expression text it names
+func TaggedExpr(value interface{}) (string, bool) {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return "", false
+	}
+	raw, ok := obj[ExprKey]
+	if !ok {
+		return "", false
+	}
+	text, _ := raw.(string)
+	return text, true
+}
+
+// ParseValueExpr returns the allowlisted expression spelled s, ignoring case and surrounding
+// space. Anything else is rejected, since expressions are written into SQL unparameterized.
+func ParseValueExpr(s string) (ValueExpr, error) {
+	expr, ok := valueExprs[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return "", fmt.Errorf("unsupported expression %q (use now(), current_timestamp, current_date, gen_random_uuid(), uuid_generate_v4() or DEFAULT)", s)
+	}
+	return expr, nil
+}
+
+// exprProblem describes why expr cannot be written to field, or returns "" when it can
+func exprProblem(field *schema.Field, expr ValueExpr) string {
+	if expr == ExprDefault {
+		return ""
+	}
+	wants := "uuid or string"
+	ok := !field.Array && (field.Type == "uuid" || field.Type == "string")
+	if expr == ExprNow || expr == ExprCurrentDate {
+		wants = "timestamp"
+		ok = !field.Array && (field.Type == "timestamp" || field.Type == "datetime" || field.Type == "date")
+	}
+	if !ok {
+		return fmt.Sprintf("expression %s needs a %s field, got %s", expr, wants, field.Type)
+	}
+	return ""
+}
+
+// FieldProblem is a data value that cannot be written to its field
+type FieldProblem struct {
+	Field   string
+	Problem string
+}
+
+// DataError lists every problem found in the data of a create or update, so a client can
+// fix them all at once instead of one database error at a time
+type DataError struct {
+	Problems []FieldProblem
+}
+
+func (e *DataError) Error() string {
+	return "invalid data: " + strings.Join(e.Details(), "; ")
+}
+
+// Details returns one "field: problem" line per problem
+func (e *DataError) Details() []string {
+	details := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		details[i] = p.Field + ": " + p.Problem
+	}
+	return details
+}
+
+// validateData checks the data of a create or update against the declared type and
+// nullability of each field. With requireAll (create), non-nullable fields must be set
+// unless they are the primary key, have a database default or are filled automatically.
+func (v *Validator) validateData(q *Query, requireAll bool) error {
+	model := v.registry.GetModel(q.Model)
+	if model == nil {
+		return fmt.Errorf("%w: %s", ErrModelNotFound, q.Model)
+	}
+
+	// Stripped nulls are never written, so they count as omitted
+	stripNulls := model.StripNulls && !q.UnsetNulls
+	if q.StripNulls != nil {
+		stripNulls = *q.StripNulls
+	}
+
+	names := make([]string, 0, len(q.Data))
+	for name := range q.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []FieldProblem
+	for _, name := range names {
+		field := model.Fields[name]
+		value := q.Data[name]
+		if field == nil || (value == nil && stripNulls) {
+			continue
+		}
+		if value == nil {
+			if !field.Nullable {
+				problems = append(problems, FieldProblem{name, "must not be null"})
+			}
+			continue
+		}
+		if text, ok := TaggedExpr(value); ok {
+			expr, err := ParseValueExpr(text)
+			if err != nil {
+				problems = append(problems, FieldProblem{name, err.Error()})
+			} else if problem := exprProblem(field, expr); problem != "" {
+				problems = append(problems, FieldProblem{name, problem})
+			}
+			continue
+		}
+		if problem := valueProblem(field, value); problem != "" {
+			problems = append(problems, FieldProblem{name, problem})
+		}
+	}
+
+	if requireAll {
+		automatic := map[string]bool{
+			model.PrimaryKey:      true,
+			model.CreatedAtField:  true,
+			model.UpdatedAtField:  true,
+			model.SoftDeleteField: true,
+			model.VersionField:    true,
+		}
+		for _, name := range model.FieldOrder {
+			field := model.Fields[name]
+			if field.Nullable || field.HasDefault || field.Virtual != nil || automatic[name] {
+				continue
+			}
+			if value, ok := q.Data[name]; !ok || (value == nil && stripNulls) {
+				problems = append(problems, FieldProblem{name, "required field missing"})
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &DataError{Problems: problems}
+	}
+	return nil
+}
+
+// valueProblem describes why value cannot be written to field, or returns "" when it can.
+// Strings holding numbers, booleans and timestamps are accepted, as the planner coerces them with CoerceValue.
+func valueProblem(field *schema.Field, value interface{}) string {
+	if !field.Array {
+		if !valueMatchesType(field.Type, value) {
+			return fmt.Sprintf("expected %s, got %s", field.Type, describeValue(value))
+		}
+		return ""
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return fmt.Sprintf("expected an array of %s, got %s", field.Type, describeValue(value))
+	}
+	for i, item := range items {
+		if item != nil && !valueMatchesType(field.Type, item) {
+			return fmt.Sprintf("element %d: expected %s, got %s", i, field.Type, describeValue(item))
+		}
+	}
+	return ""
+}
+
+// valueMatchesType reports whether a decoded JSON value can be stored in a field of fieldType
+func valueMatchesType(fieldType string, value interface{}) bool {
+	switch fieldType {
+	case "integer", "int", "float", "decimal", "boolean", "timestamp", "datetime", "date":
+		_, err := CoerceValue(value, fieldType)
+		return err == nil
+	case "uuid":
+		s, ok := value.(string)
+		return ok && isUUID(s)
+	case "json":
+		return true
+	default:
+		_, ok := value.(string)
+		return ok
+	}
+}
+
+// describeValue names the JSON kind of a value, with scalars shown for context
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("number %v", v)
+	}
+}
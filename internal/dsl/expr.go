@@ -0,0 +1,134 @@
+package dsl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ArithExpr is a parsed aggregate expression: a number, a reference to an aggregate alias,
+// or an operator applied to two operands
+type ArithExpr struct {
+	Op    string // "+", "-", "*" or "/"; empty for a leaf
+	Left  *ArithExpr
+	Right *ArithExpr
+	Ref   string  // Aggregate alias, for a reference leaf
+	Value float64 // Number, for a leaf without Ref
+}
+
+// Refs returns the aggregate aliases the expression references, in order of appearance
+func (e *ArithExpr) Refs() []string {
+	if e.Op == "" {
+		if e.Ref != "" {
+			return []string{e.Ref}
+		}
+		return nil
+	}
+	return append(e.Left.Refs(), e.Right.Refs()...)
+}
+
+// ParseArithExpr parses an arithmetic expression over aggregate aliases and numbers, e.g.
+// "paid / total * 100". It supports + - * / and parentheses, with * and / binding tighter.
+func ParseArithExpr(s string) (*ArithExpr, error) {
+	p := &arithParser{src: s}
+	expr, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if c := p.peek(); c != 0 {
+		return nil, fmt.Errorf("unexpected %q at position %d", c, p.pos)
+	}
+	return expr, nil
+}
+
+// arithParser is a recursive descent parser over the source of an ArithExpr
+type arithParser struct {
+	src string
+	pos int
+}
+
+// peek skips whitespace and returns the next character, or 0 at the end of the source
+func (p *arithParser) peek() byte {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos == len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// parseSum parses operands joined by + and -
+func (p *arithParser) parseSum() (*ArithExpr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &ArithExpr{Op: string(op), Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// parseProduct parses operands joined by * and /
+func (p *arithParser) parseProduct() (*ArithExpr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		left = &ArithExpr{Op: string(op), Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// parseOperand parses a parenthesized expression, a number or an alias
+func (p *arithParser) parseOperand() (*ArithExpr, error) {
+	c := p.peek()
+	start := p.pos
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+
+	case c == '(':
+		p.pos++
+		expr, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) for ( at position %d", start)
+		}
+		p.pos++
+		return expr, nil
+
+	case c == '-' || c == '.' || c >= '0' && c <= '9':
+		p.pos++
+		for p.pos < len(p.src) && (p.src[p.pos] == '.' || p.src[p.pos] >= '0' && p.src[p.pos] <= '9') {
+			p.pos++
+		}
+		value, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", p.src[start:p.pos], start)
+		}
+		return &ArithExpr{Value: value}, nil
+
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(p.src) && isIdentifier(p.src[start:p.pos+1]) {
+			p.pos++
+		}
+		return &ArithExpr{Ref: p.src[start:p.pos]}, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", c, start)
+	}
+}
@@ -1,11 +1,19 @@
 package dsl
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
+	"udv/internal/config"
+	"udv/internal/limits"
 	"udv/internal/schema"
 )
 
+// ErrModelNotFound is returned when a query references a model that is not in the registry
+var ErrModelNotFound = errors.New("model not found")
+
 // FilterOperator represents a comparison operator
 type FilterOperator string
 
@@ -22,6 +30,9 @@ const (
 	OpIsNull   FilterOperator = "is_null"
 	OpNotNull  FilterOperator = "not_null"
 
+	// OpNullSafeEqual is = where null matches null (IS NOT DISTINCT FROM)
+	OpNullSafeEqual FilterOperator = "null_safe_eq"
+
 	// String operators
 	OpLike       FilterOperator = "like"
 	OpILike      FilterOperator = "ilike"
@@ -29,12 +40,25 @@ const (
 	OpEndsWith   FilterOperator = "ends_with"
 	OpContains   FilterOperator = "contains"
 
+	// Regular expression operators (POSIX on PostgreSQL, PCRE on MongoDB)
+	OpRegex  FilterOperator = "regex"
+	OpIRegex FilterOperator = "iregex"
+
 	// Date operators
 	OpBefore  FilterOperator = "before"
 	OpAfter   FilterOperator = "after"
 	OpBetween FilterOperator = "between"
+
+	// Full-text search over the model's search fields; takes no field
+	OpSearch FilterOperator = "search"
+
+	// OpSize compares the number of elements of an array field
+	OpSize FilterOperator = "size"
 )
 
+// SearchScoreField is the pseudo-field used to sort by full-text search relevance
+const SearchScoreField = "_score"
+
 // AggregateFunc represents an aggregate function
 type AggregateFunc string
 
@@ -44,6 +68,9 @@ const (
 	AggAvg   AggregateFunc = "avg"
 	AggMin   AggregateFunc = "min"
 	AggMax   AggregateFunc = "max"
+
+	// AggCountDistinct counts the distinct non-null values of a field
+	AggCountDistinct AggregateFunc = "count_distinct"
 )
 
 // SortDirection represents sort order
@@ -54,6 +81,14 @@ const (
 	SortDesc SortDirection = "desc"
 )
 
+// LockMode represents the strength of a row lock on selected rows
+type LockMode string
+
+const (
+	LockForUpdate LockMode = "update"
+	LockForShare  LockMode = "share"
+)
+
 // Operation represents the type of operation to perform
 type Operation string
 
@@ -62,8 +97,46 @@ const (
 	OpCreate Operation = "create"
 	OpUpdate Operation = "update"
 	OpDelete Operation = "delete"
+	OpCount  Operation = "count"
+
+	// OpDistinct returns the unique values of the single field in Fields
+	OpDistinct Operation = "distinct"
+
+	// OpExists reports whether any row matches Filters
+	OpExists Operation = "exists"
+
+	// OpAggregate is a select that must group or aggregate, for clients that request aggregation explicitly
+	OpAggregate Operation = "aggregate"
+
+	// OpFirst and OpLast return the single row matching Filters with the lowest or highest primary key
+	OpFirst Operation = "first"
+	OpLast  Operation = "last"
 )
 
+// Selects reports whether the operation is a select, plain, aggregating or single-row
+func (op Operation) Selects() bool {
+	return op == OpSelect || op == OpAggregate || op.SingleRow()
+}
+
+// Known reports whether the operation is one the validator accepts
+func (op Operation) Known() bool {
+	switch op {
+	case OpSelect, OpCreate, OpUpdate, OpDelete, OpCount, OpDistinct, OpExists, OpAggregate, OpFirst, OpLast:
+		return true
+	}
+	return false
+}
+
+// Writes reports whether the operation creates, updates or deletes rows
+func (op Operation) Writes() bool {
+	return op == OpCreate || op == OpUpdate || op == OpDelete
+}
+
+// SingleRow reports whether the operation returns one row as an object instead of an array
+func (op Operation) SingleRow() bool {
+	return op == OpFirst || op == OpLast
+}
+
 // Query represents a complete query specification
 type Query struct {
 	Operation  Operation              `json:"operation,omitempty"` // NEW: Operation type (defaults to "select")
@@ -74,8 +147,52 @@ type Query struct {
 	Aggregates []Aggregate            `json:"aggregates,omitempty"`
 	Sort       []Sort                 `json:"sort,omitempty"`
 	Pagination *Pagination            `json:"pagination,omitempty"`
-	Data       map[string]interface{} `json:"data,omitempty"` // NEW: For create/update operations
-	ID         interface{}            `json:"id,omitempty"`   // NEW: For update/delete operations
+	Data       map[string]interface{} `json:"data,omitempty"`    // NEW: For create/update operations
+	ID         interface{}            `json:"id,omitempty"`      // NEW: For update/delete operations
+	Lock       *Lock                  `json:"lock,omitempty"`    // Row locking for select (transactions only)
+	Include    []Include              `json:"include,omitempty"` // Related models to join via model relations
+
+	// IncludeDeleted returns soft-deleted rows as well (select and count only)
+	IncludeDeleted bool `json:"include_deleted,omitempty"`
+
+	// Update operators beyond replacing whole fields (update only)
+	Increment  map[string]interface{} `json:"increment,omitempty"`   // Numeric amounts added to fields
+	Push       map[string]interface{} `json:"push,omitempty"`        // Values appended to array fields
+	UnsetNulls bool                   `json:"unset_nulls,omitempty"` // Remove fields set to null instead of storing null
+
+	// JSONSet sets keys inside json fields by dotted path, e.g. {"settings.theme": "dark"}, and
+	// JSONRemove deletes such keys, leaving the rest of the document in place (update only)
+	JSONSet    map[string]interface{} `json:"json_set,omitempty"`
+	JSONRemove []string               `json:"json_remove,omitempty"`
+
+	// StripNulls overrides the model's stripNulls setting for create and update
+	StripNulls *bool `json:"strip_nulls,omitempty"`
+
+	// Returning limits the fields of the written rows sent back by create and update. Deletes
+	// only send rows back when it is set.
+	Returning []string `json:"returning,omitempty"`
+
+	// AllowFullTable confirms an update or delete without id or filters, which affects every row
+	AllowFullTable bool `json:"allow_full_table,omitempty"`
+
+	// GroupByTime groups by timestamp fields truncated to a time bucket, alongside group_by (select only)
+	GroupByTime []TimeBucket `json:"group_by_time,omitempty"`
+
+	// FieldAliases returns selected fields under other names, e.g. {"amount": "total"}, which
+	// sort can then reference (select only)
+	FieldAliases map[string]string `json:"field_aliases,omitempty"`
+
+	// ExpectedVersion applies an update only to rows whose version column still holds this
+	// value, so a client holding a stale copy cannot overwrite newer changes (update only)
+	ExpectedVersion interface{} `json:"expected_version,omitempty"`
+
+	// Upsert inserts a row built from the id or equality filters and the update when none
+	// matches (update only)
+	Upsert bool `json:"upsert,omitempty"`
+
+	// Collation sorts strings by locale instead of the database default, overriding the
+	// model's collation (select only)
+	Collation *config.Collation `json:"collation,omitempty"`
 }
 
 // FilterExpr represents a filter expression (can be AND, OR, NOT, or atomic)
@@ -83,11 +200,11 @@ type FilterExpr interface {
 	isFilterExpr()
 }
 
-// LogicalFilter represents AND/OR/NOT operators
+// LogicalFilter represents AND/OR/NOT operators; children may themselves be logical filters
 type LogicalFilter struct {
-	And []*ComparisonFilter `json:"and,omitempty"`
-	Or  []*ComparisonFilter `json:"or,omitempty"`
-	Not *ComparisonFilter   `json:"not,omitempty"`
+	And []FilterExpr `json:"and,omitempty"`
+	Or  []FilterExpr `json:"or,omitempty"`
+	Not FilterExpr   `json:"not,omitempty"`
 }
 
 func (l *LogicalFilter) isFilterExpr() {}
@@ -97,15 +214,82 @@ type ComparisonFilter struct {
 	Field string         `json:"field"`
 	Op    FilterOperator `json:"op"`
 	Value interface{}    `json:"value,omitempty"`
+
+	// ValueField compares Field against another field of the same model instead of Value
+	ValueField string `json:"value_field,omitempty"`
+
+	// Subquery replaces Value for in and not_in with a field of another model's matching rows
+	Subquery *Subquery `json:"subquery,omitempty"`
+
+	// Bounds picks the ends a between range includes, in range notation: "[]" (default) both,
+	// "[)" the low one, "(]" the high one and "()" neither
+	Bounds string `json:"bounds,omitempty"`
 }
 
 func (c *ComparisonFilter) isFilterExpr() {}
 
-// Aggregate represents an aggregate function
+// ParseBounds reports whether a between range with the given bounds includes its low and
+// high ends; "" is the inclusive default
+func ParseBounds(bounds string) (low, high bool, err error) {
+	switch bounds {
+	case "", "[]":
+		return true, true, nil
+	case "[)":
+		return true, false, nil
+	case "(]":
+		return false, true, nil
+	case "()":
+		return false, false, nil
+	default:
+		return false, false, fmt.Errorf("bounds must be one of [], [), (] or (), got %q", bounds)
+	}
+}
+
+// Subquery selects Field from the rows of Model matching Filters, e.g. the ids of active users.
+// Model must live in the same datasource as the filtered model.
+type Subquery struct {
+	Model   string     `json:"model"`
+	Field   string     `json:"field"`
+	Filters FilterExpr `json:"filters,omitempty"`
+}
+
+// ElemMatchFilter matches documents where at least one element of an array field satisfies
+// every condition; condition fields name keys of the array's elements (MongoDB only)
+type ElemMatchFilter struct {
+	Field     string              `json:"field"`
+	ElemMatch []*ComparisonFilter `json:"elem_match"`
+}
+
+func (e *ElemMatchFilter) isFilterExpr() {}
+
+// Aggregate represents an aggregate function, or with Expr an arithmetic expression over
+// the aliases of other aggregates in the query, e.g. "paid / total * 100"
 type Aggregate struct {
 	Function AggregateFunc `json:"fn"`
 	Field    string        `json:"field,omitempty"`
 	Alias    string        `json:"alias"`
+	Expr     string        `json:"expr,omitempty"`
+}
+
+// TimeBucket groups a timestamp field by a unit of time, e.g. every row of the same day.
+// The bucket start is returned under Alias, which defaults to the field name.
+type TimeBucket struct {
+	Field       string `json:"field"`
+	Granularity string `json:"granularity"`
+	Alias       string `json:"alias,omitempty"`
+}
+
+// Name returns the column the bucket is returned as
+func (b TimeBucket) Name() string {
+	if b.Alias != "" {
+		return b.Alias
+	}
+	return b.Field
+}
+
+// timeGranularities are the units a TimeBucket can truncate to
+var timeGranularities = map[string]bool{
+	"minute": true, "hour": true, "day": true, "week": true, "month": true, "quarter": true, "year": true,
 }
 
 // Sort represents a sort specification
@@ -118,6 +302,46 @@ type Sort struct {
 type Pagination struct {
 	Limit  int `json:"limit"`
 	Offset int `json:"offset,omitempty"`
+
+	// Page (1-based) and PageSize are an alternative to Limit and Offset for page-numbered UIs
+	Page     int `json:"page,omitempty"`
+	PageSize int `json:"page_size,omitempty"`
+
+	// Cursor is a signed next_cursor token from a previous page; the API verifies it into After
+	Cursor string  `json:"cursor,omitempty"`
+	After  *Keyset `json:"-"`
+
+	// WithTotal adds the number of matching rows across all pages to the response
+	WithTotal bool `json:"with_total,omitempty"`
+}
+
+// Keyset is the position of the last row of a page in a unique order: the value of the sort
+// field and the primary key. Field is empty when rows are ordered by the primary key alone.
+type Keyset struct {
+	Field     string
+	Direction SortDirection
+	Value     interface{}
+	Key       interface{}
+}
+
+// ByPage reports whether the pagination is given as page and page_size
+func (p *Pagination) ByPage() bool {
+	return p.Page != 0 || p.PageSize != 0
+}
+
+// Include requests a related model to be joined in via a named relation.
+// Joined documents are nested under the relation name.
+type Include struct {
+	Relation string    `json:"relation"`
+	Unwind   bool      `json:"unwind,omitempty"`  // Flatten a to-one relation into an object
+	Include  []Include `json:"include,omitempty"` // Nested includes on the related model
+}
+
+// Lock represents a row-locking clause (SELECT ... FOR UPDATE / FOR SHARE)
+type Lock struct {
+	Mode       LockMode `json:"mode"`
+	SkipLocked bool     `json:"skip_locked,omitempty"`
+	NoWait     bool     `json:"nowait,omitempty"`
 }
 
 // Validator validates queries against schema
@@ -146,7 +370,75 @@ func (v *Validator) ValidateQuery(q *Query) error {
 		return fmt.Errorf("model is required")
 	}
 	if !v.registry.ModelExists(q.Model) {
-		return fmt.Errorf("model not found: %s", q.Model)
+		return fmt.Errorf("%w: %s", ErrModelNotFound, q.Model)
+	}
+
+	if q.Lock != nil && q.Operation != OpSelect {
+		return fmt.Errorf("lock is only supported for select operations")
+	}
+
+	if q.IncludeDeleted && !q.Operation.Selects() && q.Operation != OpCount && q.Operation != OpDistinct && q.Operation != OpExists {
+		return fmt.Errorf("include_deleted is only supported for select, aggregate, count, distinct and exists operations")
+	}
+
+	if (len(q.Increment) > 0 || len(q.Push) > 0 || q.UnsetNulls || len(q.JSONSet) > 0 || len(q.JSONRemove) > 0) && q.Operation != OpUpdate {
+		return fmt.Errorf("increment, push, unset_nulls, json_set and json_remove are only supported for update operations")
+	}
+
+	if q.StripNulls != nil && q.Operation != OpCreate && q.Operation != OpUpdate {
+		return fmt.Errorf("strip_nulls is only supported for create and update operations")
+	}
+	if q.StripNulls != nil && *q.StripNulls && q.UnsetNulls {
+		return fmt.Errorf("strip_nulls and unset_nulls cannot be combined")
+	}
+
+	if len(q.GroupByTime) > 0 && !q.Operation.Selects() {
+		return fmt.Errorf("group_by_time is only supported for select and aggregate operations")
+	}
+	if len(q.FieldAliases) > 0 && !q.Operation.Selects() {
+		return fmt.Errorf("field_aliases is only supported for select and aggregate operations")
+	}
+
+	if q.ExpectedVersion != nil && q.Operation != OpUpdate {
+		return fmt.Errorf("expected_version is only supported for update operations")
+	}
+
+	if q.Upsert {
+		if q.Operation != OpUpdate {
+			return fmt.Errorf("upsert is only supported for update operations")
+		}
+		if q.ExpectedVersion != nil || q.AllowFullTable {
+			return fmt.Errorf("upsert cannot be combined with expected_version or allow_full_table")
+		}
+	}
+
+	if q.Collation != nil {
+		if !q.Operation.Selects() {
+			return fmt.Errorf("collation is only supported for select and aggregate operations")
+		}
+		if err := q.Collation.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if q.AllowFullTable && q.Operation != OpUpdate && q.Operation != OpDelete {
+		return fmt.Errorf("allow_full_table is only supported for update and delete operations")
+	}
+
+	if len(q.Returning) > 0 {
+		if q.Operation != OpCreate && q.Operation != OpUpdate && q.Operation != OpDelete {
+			return fmt.Errorf("returning is only supported for create, update and delete operations")
+		}
+		if err := v.validateFields(q.Model, q.Returning); err != nil {
+			return fmt.Errorf("invalid returning field: %v", err)
+		}
+		if err := v.rejectVirtual(q.Model, "returned by a write", q.Returning...); err != nil {
+			return err
+		}
+	}
+
+	if err := v.rejectVirtual(q.Model, "written", writtenFields(q)...); err != nil {
+		return err
 	}
 
 	// Validate operation-specific requirements
@@ -157,8 +449,27 @@ func (v *Validator) ValidateQuery(q *Query) error {
 		return v.validateUpdate(q)
 	case OpDelete:
 		return v.validateDelete(q)
+	case OpCount, OpExists:
+		return v.validateCount(q)
+	case OpDistinct:
+		return v.validateDistinct(q)
 	case OpSelect:
 		// Continue with existing validation for select
+	case OpAggregate:
+		if len(q.GroupBy) == 0 && len(q.GroupByTime) == 0 && len(q.Aggregates) == 0 {
+			return fmt.Errorf("aggregate requires group_by, group_by_time or aggregates")
+		}
+		if len(q.Include) > 0 {
+			return fmt.Errorf("include is not supported for aggregate operations")
+		}
+	case OpFirst, OpLast:
+		// The operation fixes the order and the page: one row by primary key
+		if len(q.GroupBy) > 0 || len(q.GroupByTime) > 0 || len(q.Aggregates) > 0 {
+			return fmt.Errorf("%s does not support group_by, group_by_time or aggregates", q.Operation)
+		}
+		if len(q.Sort) > 0 || q.Pagination != nil {
+			return fmt.Errorf("%s orders by primary key and does not accept sort or pagination", q.Operation)
+		}
 	default:
 		return fmt.Errorf("invalid operation: %s", q.Operation)
 	}
@@ -180,13 +491,23 @@ func (v *Validator) ValidateQuery(q *Query) error {
 		return err
 	}
 
+	// Validate group_by_time
+	if err := v.validateGroupByTime(q); err != nil {
+		return err
+	}
+
 	// Validate aggregates
 	if err := v.validateAggregates(q.Model, q.Aggregates, len(q.GroupBy) > 0); err != nil {
 		return err
 	}
 
+	// Validate field aliases
+	if err := validateFieldAliases(q); err != nil {
+		return err
+	}
+
 	// Validate sort
-	if err := v.validateSort(q.Model, q.Sort); err != nil {
+	if err := v.validateSort(q); err != nil {
 		return err
 	}
 
@@ -194,6 +515,29 @@ func (v *Validator) ValidateQuery(q *Query) error {
 	if err := v.validatePagination(q.Pagination); err != nil {
 		return err
 	}
+	if q.Pagination != nil && q.Pagination.Cursor != "" {
+		if q.Pagination.Offset != 0 || q.Pagination.ByPage() {
+			return fmt.Errorf("pagination cursor cannot be combined with offset or page")
+		}
+		if _, _, ok := v.KeysetOrder(q); !ok {
+			return fmt.Errorf("pagination cursor requires sorting by the primary key, or by one non-nullable field followed by the primary key in the same direction")
+		}
+	}
+	if q.Pagination != nil && q.Pagination.WithTotal {
+		if q.Operation != OpSelect || len(q.GroupBy) > 0 || len(q.GroupByTime) > 0 || len(q.Aggregates) > 0 {
+			return fmt.Errorf("pagination with_total is only supported for selects without group_by, group_by_time or aggregates")
+		}
+	}
+
+	// Validate row locking
+	if err := v.validateLock(q); err != nil {
+		return err
+	}
+
+	// Validate relation includes
+	if err := v.validateIncludes(q.Model, q.Include, 1); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -206,7 +550,7 @@ func (v *Validator) validateCreate(q *Query) error {
 
 	model := v.registry.GetModel(q.Model)
 	if model == nil {
-		return fmt.Errorf("model not found: %s", q.Model)
+		return fmt.Errorf("%w: %s", ErrModelNotFound, q.Model)
 	}
 
 	// Validate all fields in data exist in model
@@ -216,32 +560,18 @@ func (v *Validator) validateCreate(q *Query) error {
 		}
 	}
 
-	// Check required fields (non-nullable fields that don't have defaults)
-	// Skip the primary key field as it's typically auto-generated
-	// Note: This is a basic check - full validation would require checking for default values
-	fields, _ := v.registry.GetModelFields(q.Model)
-	for _, field := range fields {
-		// Skip primary key fields (they're typically auto-generated)
-		if field.Name == model.PrimaryKey {
-			continue
-		}
-		if !field.Nullable && q.Data[field.Name] == nil {
-			// Field is required but not provided
-			return fmt.Errorf("required field missing: %s", field.Name)
-		}
-	}
-
-	return nil
+	// Check value types and required fields (non-nullable fields without defaults)
+	return v.validateData(q, true)
 }
 
 // validateUpdate validates an update operation
 func (v *Validator) validateUpdate(q *Query) error {
-	if q.ID == nil && q.Filters == nil {
-		return fmt.Errorf("id or filters required for update operation")
+	if q.ID == nil && q.Filters == nil && !q.AllowFullTable {
+		return fmt.Errorf("id or filters required for update operation (set allow_full_table to update every row)")
 	}
 
-	if q.Data == nil || len(q.Data) == 0 {
-		return fmt.Errorf("data is required for update operation")
+	if len(q.Data) == 0 && len(q.Increment) == 0 && len(q.Push) == 0 && len(q.JSONSet) == 0 && len(q.JSONRemove) == 0 {
+		return fmt.Errorf("data, increment, push, json_set or json_remove is required for update operation")
 	}
 
 	// Validate all fields being updated exist in model
@@ -250,18 +580,308 @@ func (v *Validator) validateUpdate(q *Query) error {
 			return fmt.Errorf("field not found in model %s: %s", q.Model, fieldName)
 		}
 	}
+	if err := v.validateData(q, false); err != nil {
+		return err
+	}
+
+	for fieldName, amount := range q.Increment {
+		field, err := v.registry.GetField(q.Model, fieldName)
+		if err != nil {
+			return fmt.Errorf("invalid increment field: %v", err)
+		}
+		if !isNumericType(field.Type) {
+			return fmt.Errorf("increment field %s must be numeric, got %s", fieldName, field.Type)
+		}
+		if !isNumericValue(amount) {
+			return fmt.Errorf("increment amount for %s must be a number", fieldName)
+		}
+		if _, ok := q.Data[fieldName]; ok {
+			return fmt.Errorf("field %s cannot be both set and incremented", fieldName)
+		}
+	}
 
+	for fieldName := range q.Push {
+		if !v.registry.FieldExists(q.Model, fieldName) {
+			return fmt.Errorf("invalid push field: field not found in model %s: %s", q.Model, fieldName)
+		}
+		if _, ok := q.Data[fieldName]; ok {
+			return fmt.Errorf("field %s cannot be both set and pushed to", fieldName)
+		}
+		if _, ok := q.Increment[fieldName]; ok {
+			return fmt.Errorf("field %s cannot be both incremented and pushed to", fieldName)
+		}
+	}
+
+	if err := v.validateVersion(q); err != nil {
+		return err
+	}
+	return v.validateJSONUpdates(q)
+}
+
+// validateVersion checks an update against the model's version column, which only the
+// update itself may change, and the expected version the client read
+func (v *Validator) validateVersion(q *Query) error {
+	model := v.registry.GetModel(q.Model)
+	if model.VersionField == "" {
+		if q.ExpectedVersion != nil {
+			return fmt.Errorf("expected_version requires model %s to have a versionField", q.Model)
+		}
+		return nil
+	}
+
+	_, inData := q.Data[model.VersionField]
+	_, inIncrement := q.Increment[model.VersionField]
+	_, inPush := q.Push[model.VersionField]
+	if inData || inIncrement || inPush {
+		return fmt.Errorf("field %s is the version column and is incremented by every update", model.VersionField)
+	}
+
+	if q.ExpectedVersion != nil && !isIntegerValue(q.ExpectedVersion) {
+		return fmt.Errorf("expected_version must be an integer")
+	}
 	return nil
 }
 
+// sizeComparisons are the operators a size filter can compare an array's length with
+var sizeComparisons = map[FilterOperator]bool{
+	OpEqual: true, OpNotEqual: true, OpGT: true, OpGTE: true, OpLT: true, OpLTE: true,
+}
+
+// SizeCondition returns the comparison and length of a size filter value, which is either a
+// length to match exactly or an object holding one comparison, e.g. {">": 3}
+func SizeCondition(value interface{}) (FilterOperator, int64, error) {
+	op := OpEqual
+	if cond, ok := value.(map[string]interface{}); ok {
+		if len(cond) != 1 {
+			return "", 0, fmt.Errorf("size value must be a length or hold exactly one comparison, e.g. {\">\": 3}")
+		}
+		for key, length := range cond {
+			op, value = FilterOperator(key), length
+		}
+		if !sizeComparisons[op] {
+			return "", 0, fmt.Errorf("size cannot compare with operator %s", op)
+		}
+	}
+
+	if !isIntegerValue(value) {
+		return "", 0, fmt.Errorf("size length must be a whole number, got %v", value)
+	}
+	var length int64
+	switch n := value.(type) {
+	case int:
+		length = int64(n)
+	case int32:
+		length = int64(n)
+	case int64:
+		length = n
+	case float64:
+		length = int64(n)
+	}
+	if length < 0 {
+		return "", 0, fmt.Errorf("size length must not be negative, got %d", length)
+	}
+	return op, length, nil
+}
+
+// isIntegerValue reports whether a decoded JSON value is a whole number
+func isIntegerValue(value interface{}) bool {
+	switch n := value.(type) {
+	case int, int32, int64:
+		return true
+	case float64:
+		return n == float64(int64(n))
+	}
+	return false
+}
+
+// validateJSONUpdates checks json_set and json_remove paths against the model's json fields
+func (v *Validator) validateJSONUpdates(q *Query) error {
+	paths := make([]string, 0, len(q.JSONSet)+len(q.JSONRemove))
+	for path := range q.JSONSet {
+		paths = append(paths, path)
+	}
+	removed := make(map[string]bool, len(q.JSONRemove))
+	for _, path := range q.JSONRemove {
+		if _, ok := q.JSONSet[path]; ok || removed[path] {
+			return fmt.Errorf("json path %s is set or removed more than once", path)
+		}
+		removed[path] = true
+		paths = append(paths, path)
+	}
+
+	for _, path := range paths {
+		fieldName, _, err := SplitJSONPath(path)
+		if err != nil {
+			return err
+		}
+		field, err := v.registry.GetField(q.Model, fieldName)
+		if err != nil {
+			return fmt.Errorf("invalid json path %s: %v", path, err)
+		}
+		if field.Type != "json" {
+			return fmt.Errorf("json path %s requires a json field, %s is %s", path, fieldName, field.Type)
+		}
+		_, inData := q.Data[fieldName]
+		_, inIncrement := q.Increment[fieldName]
+		_, inPush := q.Push[fieldName]
+		if inData || inIncrement || inPush {
+			return fmt.Errorf("field %s cannot be both replaced and updated by json path", fieldName)
+		}
+	}
+	return nil
+}
+
+// SplitJSONPath splits a dotted json_set or json_remove path into the field and the key
+// path inside it. Keys are limited to letters, digits, '_' and '-' so they can be
+// embedded in SQL path literals.
+func SplitJSONPath(path string) (string, []string, error) {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf("json path %s must name a field and at least one key", path)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return "", nil, fmt.Errorf("json path %s has an empty segment", path)
+		}
+		for _, r := range part {
+			if !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				return "", nil, fmt.Errorf("json path %s has an invalid character %q", path, r)
+			}
+		}
+	}
+	return parts[0], parts[1:], nil
+}
+
+// isUUID reports whether s is a UUID in canonical 8-4-4-4-12 hex form
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isNumericType reports whether a schema field type holds numbers
+func isNumericType(fieldType string) bool {
+	switch fieldType {
+	case "integer", "int", "float", "decimal":
+		return true
+	}
+	return false
+}
+
+// isNumericValue reports whether a decoded JSON value is a number
+func isNumericValue(value interface{}) bool {
+	switch value.(type) {
+	case float64, float32, int, int32, int64:
+		return true
+	}
+	return false
+}
+
 // validateDelete validates a delete operation
 func (v *Validator) validateDelete(q *Query) error {
-	if q.ID == nil && q.Filters == nil {
-		return fmt.Errorf("id or filters required for delete operation")
+	if q.ID == nil && q.Filters == nil && !q.AllowFullTable {
+		return fmt.Errorf("id or filters required for delete operation (set allow_full_table to delete every row)")
+	}
+	return nil
+}
+
+// validateCount validates a count or exists operation, which only accepts filters
+func (v *Validator) validateCount(q *Query) error {
+	if len(q.Fields) > 0 || len(q.GroupBy) > 0 || len(q.Aggregates) > 0 {
+		return fmt.Errorf("%s operation does not accept fields, group_by or aggregates", q.Operation)
+	}
+	if len(q.Sort) > 0 || q.Pagination != nil {
+		return fmt.Errorf("%s operation does not accept sort or pagination", q.Operation)
+	}
+
+	if q.Filters != nil {
+		return v.validateFilterExpr(q.Model, q.Filters)
 	}
 	return nil
 }
 
+// validateDistinct validates a distinct operation, which takes exactly one field and optional filters
+func (v *Validator) validateDistinct(q *Query) error {
+	if len(q.Fields) != 1 {
+		return fmt.Errorf("distinct operation requires exactly one field")
+	}
+	if len(q.GroupBy) > 0 || len(q.Aggregates) > 0 {
+		return fmt.Errorf("distinct operation does not accept group_by or aggregates")
+	}
+	if len(q.Sort) > 0 || q.Pagination != nil {
+		return fmt.Errorf("distinct operation does not accept sort or pagination")
+	}
+
+	if err := v.validateFields(q.Model, q.Fields); err != nil {
+		return err
+	}
+	if err := v.rejectVirtual(q.Model, "used with distinct", q.Fields...); err != nil {
+		return err
+	}
+
+	if q.Filters != nil {
+		return v.validateFilterExpr(q.Model, q.Filters)
+	}
+	return nil
+}
+
+// rejectVirtual returns an error for the first computed field among fields, which are only
+// ever selected; usage completes the message
+func (v *Validator) rejectVirtual(modelName, usage string, fields ...string) error {
+	for _, name := range fields {
+		if field, err := v.registry.GetField(modelName, name); err == nil && field.Virtual != nil {
+			return fmt.Errorf("field %s is computed and cannot be %s", name, usage)
+		}
+	}
+	return nil
+}
+
+// rejectTransformed returns an error for the first field among fields whose results are
+// rewritten by a transform. Filters, sorts, groups and aggregates run on the stored value,
+// so they would reveal what the transform masks; usage completes the message.
+func (v *Validator) rejectTransformed(modelName, usage string, fields ...string) error {
+	for _, name := range fields {
+		if field, err := v.registry.GetField(modelName, name); err == nil && field.Transform != nil {
+			return fmt.Errorf("field %s is masked by a %s transform and cannot be %s", name, field.Transform.Type, usage)
+		}
+	}
+	return nil
+}
+
+// writtenFields lists the fields a create or update assigns, increments, pushes to or
+// changes by json path
+func writtenFields(q *Query) []string {
+	fields := make([]string, 0, len(q.Data)+len(q.Increment)+len(q.Push))
+	for _, values := range []map[string]interface{}{q.Data, q.Increment, q.Push} {
+		for name := range values {
+			fields = append(fields, name)
+		}
+	}
+	paths := append([]string{}, q.JSONRemove...)
+	for path := range q.JSONSet {
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		if name, _, err := SplitJSONPath(path); err == nil {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
 func (v *Validator) validateFields(modelName string, fields []string) error {
 	if len(fields) == 0 {
 		return nil // Empty fields is allowed
@@ -271,9 +891,13 @@ func (v *Validator) validateFields(modelName string, fields []string) error {
 		if field == "" {
 			return fmt.Errorf("field name cannot be empty")
 		}
-		if !v.registry.FieldExists(modelName, field) {
+		f, err := v.registry.GetField(modelName, field)
+		if err != nil {
 			return fmt.Errorf("field not found in model %s: %s", modelName, field)
 		}
+		if !f.Selectable {
+			return fmt.Errorf("field is not selectable: %s", field)
+		}
 	}
 	return nil
 }
@@ -281,22 +905,18 @@ func (v *Validator) validateFields(modelName string, fields []string) error {
 func (v *Validator) validateFilterExpr(modelName string, expr FilterExpr) error {
 	switch e := expr.(type) {
 	case *LogicalFilter:
-		if e.And != nil {
-			for _, f := range e.And {
-				if err := v.validateComparisonFilter(modelName, f); err != nil {
-					return err
-				}
+		for _, f := range e.And {
+			if err := v.validateFilterExpr(modelName, f); err != nil {
+				return err
 			}
 		}
-		if e.Or != nil {
-			for _, f := range e.Or {
-				if err := v.validateComparisonFilter(modelName, f); err != nil {
-					return err
-				}
+		for _, f := range e.Or {
+			if err := v.validateFilterExpr(modelName, f); err != nil {
+				return err
 			}
 		}
 		if e.Not != nil {
-			if err := v.validateComparisonFilter(modelName, e.Not); err != nil {
+			if err := v.validateFilterExpr(modelName, e.Not); err != nil {
 				return err
 			}
 		}
@@ -305,6 +925,9 @@ func (v *Validator) validateFilterExpr(modelName string, expr FilterExpr) error
 	case *ComparisonFilter:
 		return v.validateComparisonFilter(modelName, e)
 
+	case *ElemMatchFilter:
+		return v.validateElemMatchFilter(modelName, e)
+
 	default:
 		return fmt.Errorf("invalid filter expression type")
 	}
@@ -315,6 +938,10 @@ func (v *Validator) validateComparisonFilter(modelName string, f *ComparisonFilt
 		return nil
 	}
 
+	if f.Op == OpSearch {
+		return v.validateSearchFilter(modelName, f)
+	}
+
 	if f.Field == "" {
 		return fmt.Errorf("filter field is required")
 	}
@@ -329,6 +956,30 @@ func (v *Validator) validateComparisonFilter(modelName string, f *ComparisonFilt
 	if !field.Filterable {
 		return fmt.Errorf("field is not filterable: %s", f.Field)
 	}
+	if err := v.rejectTransformed(modelName, "filtered", f.Field); err != nil {
+		return err
+	}
+
+	if err := validateBounds(f); err != nil {
+		return err
+	}
+
+	if f.ValueField != "" {
+		return v.validateFieldComparison(modelName, f)
+	}
+	if f.Subquery != nil {
+		return v.validateSubquery(modelName, f)
+	}
+
+	if f.Op == OpSize {
+		if field.Type != "json" && !field.Array {
+			return fmt.Errorf("size requires an array field, %s is %s", f.Field, field.Type)
+		}
+		if _, _, err := SizeCondition(f.Value); err != nil {
+			return fmt.Errorf("invalid size filter for field %s: %v", f.Field, err)
+		}
+		return nil
+	}
 
 	// Validate operator for field type
 	if err := v.validateOperatorForType(f.Op, field.Type, f.Value); err != nil {
@@ -338,6 +989,210 @@ func (v *Validator) validateComparisonFilter(modelName string, f *ComparisonFilt
 	return nil
 }
 
+// validateFieldComparison checks a filter comparing two fields of the model
+func (v *Validator) validateFieldComparison(modelName string, f *ComparisonFilter) error {
+	if !IsFieldComparisonOp(f.Op) {
+		return fmt.Errorf("operator %s cannot compare field %s to value_field", f.Op, f.Field)
+	}
+	if f.Value != nil {
+		return fmt.Errorf("filter on %s cannot set both value and value_field", f.Field)
+	}
+	if f.Subquery != nil {
+		return fmt.Errorf("filter on %s cannot set both value_field and subquery", f.Field)
+	}
+
+	other, err := v.registry.GetField(modelName, f.ValueField)
+	if err != nil {
+		return fmt.Errorf("invalid value_field: %v", err)
+	}
+	if !other.Filterable {
+		return fmt.Errorf("field is not filterable: %s", f.ValueField)
+	}
+	return v.rejectTransformed(modelName, "filtered", f.ValueField)
+}
+
+// validateSubquery checks an in/not_in filter whose values come from another model's rows
+func (v *Validator) validateSubquery(modelName string, f *ComparisonFilter) error {
+	if f.Op != OpIn && f.Op != OpNotIn {
+		return fmt.Errorf("subquery on %s requires the in or not_in operator, got %s", f.Field, f.Op)
+	}
+	if f.Value != nil {
+		return fmt.Errorf("filter on %s cannot set both value and subquery", f.Field)
+	}
+
+	sub := f.Subquery
+	if sub.Model == "" || sub.Field == "" {
+		return fmt.Errorf("subquery on %s requires a model and a field", f.Field)
+	}
+	model := v.registry.GetModel(sub.Model)
+	if model == nil {
+		return fmt.Errorf("%w: %s", ErrModelNotFound, sub.Model)
+	}
+	if outer := v.registry.GetModel(modelName); outer != nil && outer.Datasource != model.Datasource {
+		return fmt.Errorf("subquery model %s is not in the same datasource as %s", sub.Model, modelName)
+	}
+
+	// The subquery's values decide which rows match, so hidden fields cannot be used
+	field, err := v.registry.GetField(sub.Model, sub.Field)
+	if err != nil {
+		return fmt.Errorf("invalid subquery field: %v", err)
+	}
+	if !field.Selectable {
+		return fmt.Errorf("field is not selectable: %s", sub.Field)
+	}
+	if field.Virtual != nil {
+		return fmt.Errorf("field %s is computed and cannot be used in a subquery", sub.Field)
+	}
+	if err := v.rejectTransformed(sub.Model, "used in a subquery", sub.Field); err != nil {
+		return err
+	}
+
+	if sub.Filters == nil {
+		return nil
+	}
+	if HasSubquery(sub.Filters) {
+		return fmt.Errorf("subqueries cannot be nested")
+	}
+	return v.validateFilterExpr(sub.Model, sub.Filters)
+}
+
+// HasSubquery reports whether a filter expression contains a subquery condition
+func HasSubquery(expr FilterExpr) bool {
+	switch e := expr.(type) {
+	case *ComparisonFilter:
+		return e != nil && e.Subquery != nil
+	case *LogicalFilter:
+		if e == nil {
+			return false
+		}
+		for _, f := range e.And {
+			if HasSubquery(f) {
+				return true
+			}
+		}
+		for _, f := range e.Or {
+			if HasSubquery(f) {
+				return true
+			}
+		}
+		return HasSubquery(e.Not)
+	default:
+		return false
+	}
+}
+
+// IsFieldComparisonOp reports whether op can compare a field to another field via value_field
+func IsFieldComparisonOp(op FilterOperator) bool {
+	switch op {
+	case OpEqual, OpNotEqual, OpGT, OpGTE, OpLT, OpLTE, OpBefore, OpAfter:
+		return true
+	}
+	return false
+}
+
+// validateElemMatchFilter checks an element-match filter on an array field. Element keys are
+// not part of the schema, so conditions only get field and operator checks.
+func (v *Validator) validateElemMatchFilter(modelName string, f *ElemMatchFilter) error {
+	if f.Field == "" {
+		return fmt.Errorf("filter field is required")
+	}
+
+	field, err := v.registry.GetField(modelName, f.Field)
+	if err != nil {
+		return fmt.Errorf("invalid filter field: %v", err)
+	}
+	if !field.Filterable {
+		return fmt.Errorf("field is not filterable: %s", f.Field)
+	}
+	if err := v.rejectTransformed(modelName, "filtered", f.Field); err != nil {
+		return err
+	}
+	if field.Type != "json" {
+		return fmt.Errorf("elem_match requires an array field stored as json, %s is %s", f.Field, field.Type)
+	}
+
+	if len(f.ElemMatch) == 0 {
+		return fmt.Errorf("elem_match on %s needs at least one condition", f.Field)
+	}
+	for _, cond := range f.ElemMatch {
+		if cond == nil || cond.Field == "" {
+			return fmt.Errorf("elem_match condition field is required")
+		}
+		if cond.Op == OpSearch || cond.Op == OpSize {
+			return fmt.Errorf("%s is not allowed inside elem_match", cond.Op)
+		}
+		if cond.ValueField != "" {
+			return fmt.Errorf("value_field is not allowed inside elem_match")
+		}
+		if cond.Subquery != nil {
+			return fmt.Errorf("subquery is not allowed inside elem_match")
+		}
+		if err := validateBounds(cond); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateBounds checks that a filter's bounds are valid and only set on a between filter
+func validateBounds(f *ComparisonFilter) error {
+	if f.Bounds == "" {
+		return nil
+	}
+	if f.Op != OpBetween {
+		return fmt.Errorf("bounds on field %s requires the between operator, got %s", f.Field, f.Op)
+	}
+	if _, _, err := ParseBounds(f.Bounds); err != nil {
+		return fmt.Errorf("invalid between filter for field %s: %v", f.Field, err)
+	}
+	return nil
+}
+
+// validateSearchFilter checks a full-text search filter against the model's search fields
+func (v *Validator) validateSearchFilter(modelName string, f *ComparisonFilter) error {
+	if f.Field != "" {
+		return fmt.Errorf("search filter does not take a field")
+	}
+
+	model := v.registry.GetModel(modelName)
+	if model == nil || len(model.SearchFields) == 0 {
+		return fmt.Errorf("model %s has no search fields configured", modelName)
+	}
+
+	term, ok := f.Value.(string)
+	if !ok || strings.TrimSpace(term) == "" {
+		return fmt.Errorf("search filter value must be a non-empty string")
+	}
+
+	return nil
+}
+
+// HasSearch reports whether a filter expression contains a full-text search condition
+func HasSearch(expr FilterExpr) bool {
+	switch e := expr.(type) {
+	case *ComparisonFilter:
+		return e != nil && e.Op == OpSearch
+	case *LogicalFilter:
+		if e == nil {
+			return false
+		}
+		for _, f := range e.And {
+			if HasSearch(f) {
+				return true
+			}
+		}
+		for _, f := range e.Or {
+			if HasSearch(f) {
+				return true
+			}
+		}
+		return HasSearch(e.Not)
+	default:
+		return false
+	}
+}
+
 func (v *Validator) validateOperatorForType(op FilterOperator, fieldType string, value interface{}) error {
 	// NULL operators don't need a value
 	if op == OpIsNull || op == OpNotNull {
@@ -356,7 +1211,7 @@ func (v *Validator) validateOperatorForType(op FilterOperator, fieldType string,
 	}
 
 	// String operators
-	if op == OpLike || op == OpILike || op == OpStartsWith || op == OpEndsWith || op == OpContains {
+	if op == OpLike || op == OpILike || op == OpStartsWith || op == OpEndsWith || op == OpContains || op == OpRegex || op == OpIRegex {
 		if fieldType != "string" {
 			return fmt.Errorf("string operator %s not valid for type %s", op, fieldType)
 		}
@@ -373,6 +1228,8 @@ func (v *Validator) validateOperatorForType(op FilterOperator, fieldType string,
 		OpLTE:      true,
 		OpBefore:   true,
 		OpAfter:    true,
+
+		OpNullSafeEqual: true,
 	}
 
 	if comparisonOps[op] {
@@ -400,11 +1257,58 @@ func (v *Validator) validateGroupBy(modelName string, groupBy []string) error {
 		if !f.Groupable {
 			return fmt.Errorf("field is not groupable: %s", field)
 		}
+
+		// Grouped values are returned, so hidden fields cannot be grouped
+		if !f.Selectable {
+			return fmt.Errorf("field is not selectable: %s", field)
+		}
+		if err := v.rejectTransformed(modelName, "grouped", field); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// validateGroupByTime checks that each bucket truncates a groupable timestamp field and
+// that bucket names do not collide with group_by fields or aggregate aliases
+func (v *Validator) validateGroupByTime(q *Query) error {
+	names := make(map[string]bool, len(q.GroupBy)+len(q.Aggregates)+len(q.GroupByTime))
+	for _, field := range q.GroupBy {
+		names[field] = true
+	}
+	for _, agg := range q.Aggregates {
+		names[agg.Alias] = true
+	}
+
+	for i, bucket := range q.GroupByTime {
+		if bucket.Field == "" {
+			return fmt.Errorf("group_by_time[%d] field is required", i)
+		}
+		f, err := v.registry.GetField(q.Model, bucket.Field)
+		if err != nil {
+			return fmt.Errorf("invalid group_by_time field: %v", err)
+		}
+		if f.Type != "timestamp" {
+			return fmt.Errorf("group_by_time field %s must be a timestamp, got %s", bucket.Field, f.Type)
+		}
+		if !f.Groupable {
+			return fmt.Errorf("field is not groupable: %s", bucket.Field)
+		}
+		if !f.Selectable {
+			return fmt.Errorf("field is not selectable: %s", bucket.Field)
+		}
+		if !timeGranularities[bucket.Granularity] {
+			return fmt.Errorf("group_by_time[%d] invalid granularity: %s", i, bucket.Granularity)
+		}
+		if names[bucket.Name()] {
+			return fmt.Errorf("group_by_time[%d] name %s is already used; set a different alias", i, bucket.Name())
+		}
+		names[bucket.Name()] = true
+	}
+	return nil
+}
+
 func (v *Validator) validateAggregates(modelName string, aggs []Aggregate, hasGroupBy bool) error {
 	if len(aggs) == 0 {
 		return nil
@@ -416,6 +1320,16 @@ func (v *Validator) validateAggregates(modelName string, aggs []Aggregate, hasGr
 		AggAvg:   true,
 		AggMin:   true,
 		AggMax:   true,
+
+		AggCountDistinct: true,
+	}
+
+	// Expressions may reference any function aggregate of the query, listed before or after them
+	functionAliases := make(map[string]bool, len(aggs))
+	for _, agg := range aggs {
+		if agg.Expr == "" {
+			functionAliases[agg.Alias] = true
+		}
 	}
 
 	for i, agg := range aggs {
@@ -423,6 +1337,13 @@ func (v *Validator) validateAggregates(modelName string, aggs []Aggregate, hasGr
 			return fmt.Errorf("aggregate[%d] alias is required", i)
 		}
 
+		if agg.Expr != "" {
+			if err := validateAggregateExpr(agg, functionAliases); err != nil {
+				return fmt.Errorf("aggregate[%d] %v", i, err)
+			}
+			continue
+		}
+
 		if !validFuncs[agg.Function] {
 			return fmt.Errorf("aggregate[%d] unknown function: %s", i, agg.Function)
 		}
@@ -445,6 +1366,14 @@ func (v *Validator) validateAggregates(modelName string, aggs []Aggregate, hasGr
 			return fmt.Errorf("aggregate[%d] field is not aggregatable: %s", i, agg.Field)
 		}
 
+		// MIN/MAX would return hidden values directly
+		if !f.Selectable {
+			return fmt.Errorf("aggregate[%d] field is not selectable: %s", i, agg.Field)
+		}
+		if err := v.rejectTransformed(modelName, "aggregated", agg.Field); err != nil {
+			return fmt.Errorf("aggregate[%d] %v", i, err)
+		}
+
 		// Validate function for field type
 		if err := v.validateAggregateForType(agg.Function, f.Type); err != nil {
 			return fmt.Errorf("aggregate[%d] invalid for field %s: %v", i, agg.Field, err)
@@ -454,9 +1383,31 @@ func (v *Validator) validateAggregates(modelName string, aggs []Aggregate, hasGr
 	return nil
 }
 
+// validateAggregateExpr checks that an expression aggregate parses and references only
+// function aggregates, which are computed before it
+func validateAggregateExpr(agg Aggregate, functionAliases map[string]bool) error {
+	if agg.Function != "" || agg.Field != "" {
+		return fmt.Errorf("expr cannot be combined with fn or field")
+	}
+	expr, err := ParseArithExpr(agg.Expr)
+	if err != nil {
+		return fmt.Errorf("invalid expr: %v", err)
+	}
+	refs := expr.Refs()
+	if len(refs) == 0 {
+		return fmt.Errorf("expr must reference at least one aggregate")
+	}
+	for _, ref := range refs {
+		if !functionAliases[ref] {
+			return fmt.Errorf("expr references %s, which is not the alias of a fn aggregate", ref)
+		}
+	}
+	return nil
+}
+
 func (v *Validator) validateAggregateForType(fn AggregateFunc, fieldType string) error {
 	switch fn {
-	case AggCount:
+	case AggCount, AggCountDistinct:
 		return nil // count works on any type
 
 	case AggSum, AggAvg:
@@ -474,18 +1425,99 @@ func (v *Validator) validateAggregateForType(fn AggregateFunc, fieldType string)
 	}
 }
 
-func (v *Validator) validateSort(modelName string, sort []Sort) error {
+// validateFieldAliases checks that aliases rename selected fields to unused, plain identifiers
+func validateFieldAliases(q *Query) error {
+	if len(q.FieldAliases) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool, len(q.Fields)+len(q.Aggregates)+len(q.GroupByTime))
+	selected := make(map[string]bool, len(q.Fields))
+	for _, field := range q.Fields {
+		selected[field] = true
+		if _, aliased := q.FieldAliases[field]; !aliased {
+			names[field] = true
+		}
+	}
+	for _, agg := range q.Aggregates {
+		names[agg.Alias] = true
+	}
+	for _, bucket := range q.GroupByTime {
+		names[bucket.Name()] = true
+	}
+
+	// Sorted so that errors are reported deterministically
+	fields := make([]string, 0, len(q.FieldAliases))
+	for field := range q.FieldAliases {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		alias := q.FieldAliases[field]
+		if !selected[field] {
+			return fmt.Errorf("field_aliases field %s must be listed in fields", field)
+		}
+		if !isIdentifier(alias) {
+			return fmt.Errorf("field_aliases alias %q must start with a letter or '_' and contain only letters, digits and '_'", alias)
+		}
+		if names[alias] {
+			return fmt.Errorf("field_aliases alias %s is already used", alias)
+		}
+		names[alias] = true
+	}
+	return nil
+}
+
+// isIdentifier reports whether s is a plain SQL identifier that needs no quoting
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *Validator) validateSort(q *Query) error {
+	modelName, sort, hasSearch := q.Model, q.Sort, HasSearch(q.Filters)
 	if len(sort) == 0 {
 		return nil
 	}
 
+	aliases := make(map[string]bool, len(q.Aggregates)+len(q.GroupByTime)+len(q.FieldAliases))
+	for _, agg := range q.Aggregates {
+		aliases[agg.Alias] = true
+	}
+	for _, bucket := range q.GroupByTime {
+		aliases[bucket.Name()] = true
+	}
+	for _, alias := range q.FieldAliases {
+		aliases[alias] = true
+	}
+
 	for i, s := range sort {
 		if s.Field == "" {
 			return fmt.Errorf("sort[%d] field is required", i)
 		}
 
-		if !v.registry.FieldExists(modelName, s.Field) {
+		if s.Field == SearchScoreField && !v.registry.FieldExists(modelName, s.Field) {
+			if !hasSearch {
+				return fmt.Errorf("sort[%d] by %s requires a search filter", i, SearchScoreField)
+			}
+		} else if !aliases[s.Field] && !v.registry.FieldExists(modelName, s.Field) {
+			// Sorting by a field alias, aggregate alias or time bucket is allowed
 			return fmt.Errorf("sort[%d] field not found: %s", i, s.Field)
+		} else if !aliases[s.Field] {
+			if err := v.rejectVirtual(modelName, "sorted", s.Field); err != nil {
+				return fmt.Errorf("sort[%d]: %v", i, err)
+			}
+			if err := v.rejectTransformed(modelName, "sorted", s.Field); err != nil {
+				return fmt.Errorf("sort[%d]: %v", i, err)
+			}
 		}
 
 		// Validate direction
@@ -497,11 +1529,65 @@ func (v *Validator) validateSort(modelName string, sort []Sort) error {
 	return nil
 }
 
+// KeysetOrder reports whether a select orders rows uniquely, so a page can continue after the
+// last row of the previous one, and returns the sort field and direction. The order must be
+// the primary key alone (or no sort, on models sorted by it by default), or one non-nullable,
+// case-sensitive field followed by the primary key in the same direction. field is empty when
+// the order is the primary key alone.
+func (v *Validator) KeysetOrder(q *Query) (field string, direction SortDirection, ok bool) {
+	model := v.registry.GetModel(q.Model)
+	if model == nil || (q.Operation != "" && q.Operation != OpSelect) {
+		return "", "", false
+	}
+	if len(q.GroupBy) > 0 || len(q.GroupByTime) > 0 || len(q.Aggregates) > 0 {
+		return "", "", false
+	}
+
+	dir := func(s Sort) SortDirection {
+		if s.Direction == SortDesc {
+			return SortDesc
+		}
+		return SortAsc
+	}
+
+	switch len(q.Sort) {
+	case 0:
+		return "", SortAsc, model.DefaultSort
+	case 1:
+		return "", dir(q.Sort[0]), q.Sort[0].Field == model.PrimaryKey
+	case 2:
+		first, last := q.Sort[0], q.Sort[1]
+		if last.Field != model.PrimaryKey || first.Field == model.PrimaryKey || dir(first) != dir(last) {
+			return "", "", false
+		}
+		f := model.Fields[first.Field]
+		if f == nil || f.Nullable || f.CaseInsensitive || f.Virtual != nil {
+			return "", "", false
+		}
+		return first.Field, dir(first), true
+	default:
+		return "", "", false
+	}
+}
+
 func (v *Validator) validatePagination(p *Pagination) error {
 	if p == nil {
 		return nil
 	}
 
+	if p.ByPage() {
+		if p.Limit != 0 || p.Offset != 0 {
+			return fmt.Errorf("pagination page and page_size cannot be combined with limit and offset")
+		}
+		if p.Page < 1 {
+			return fmt.Errorf("pagination page must be at least 1")
+		}
+		if p.PageSize <= 0 {
+			return fmt.Errorf("pagination page_size must be greater than 0")
+		}
+		return nil
+	}
+
 	if p.Limit <= 0 {
 		return fmt.Errorf("pagination limit must be greater than 0")
 	}
@@ -513,6 +1599,59 @@ func (v *Validator) validatePagination(p *Pagination) error {
 	return nil
 }
 
+func (v *Validator) validateIncludes(modelName string, includes []Include, depth int) error {
+	if len(includes) == 0 {
+		return nil
+	}
+
+	if depth > limits.MaxIncludeDepth {
+		return fmt.Errorf("include depth exceeds maximum of %d", limits.MaxIncludeDepth)
+	}
+
+	seen := make(map[string]bool)
+	for i, inc := range includes {
+		if inc.Relation == "" {
+			return fmt.Errorf("include[%d] relation is required", i)
+		}
+		if seen[inc.Relation] {
+			return fmt.Errorf("include[%d] duplicate relation: %s", i, inc.Relation)
+		}
+		seen[inc.Relation] = true
+
+		rel, err := v.registry.GetRelation(modelName, inc.Relation)
+		if err != nil {
+			return fmt.Errorf("include[%d] invalid relation: %v", i, err)
+		}
+
+		if err := v.validateIncludes(rel.TargetModel, inc.Include, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *Validator) validateLock(q *Query) error {
+	if q.Lock == nil {
+		return nil
+	}
+
+	if q.Lock.Mode != LockForUpdate && q.Lock.Mode != LockForShare {
+		return fmt.Errorf("invalid lock mode: %s", q.Lock.Mode)
+	}
+
+	if q.Lock.SkipLocked && q.Lock.NoWait {
+		return fmt.Errorf("lock cannot use both skip_locked and nowait")
+	}
+
+	// Row locks apply to individual rows, not grouped results
+	if len(q.GroupBy) > 0 || len(q.GroupByTime) > 0 || len(q.Aggregates) > 0 {
+		return fmt.Errorf("lock is not allowed with group_by or aggregates")
+	}
+
+	return nil
+}
+
 // Helper function to create a simple comparison filter
 func NewComparisonFilter(field string, op FilterOperator, value interface{}) *ComparisonFilter {
 	return &ComparisonFilter{
@@ -1,9 +1,13 @@
 package dsl
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
 	"udv/internal/config"
+	"udv/internal/limits"
 	"udv/internal/schema"
 )
 
@@ -22,6 +26,10 @@ func setupTestRegistry() *schema.Registry {
 					{Name: "created_at", Type: "timestamp", Nullable: false},
 					{Name: "notes", Type: "string", Nullable: true},
 				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "id"},
+				},
+				SearchFields: []string{"notes", "status"},
 			},
 			{
 				Name:       "users",
@@ -32,7 +40,9 @@ func setupTestRegistry() *schema.Registry {
 					{Name: "name", Type: "string", Nullable: false},
 					{Name: "email", Type: "string", Nullable: false},
 					{Name: "age", Type: "integer", Nullable: true},
+					{Name: "password_hash", Type: "string", Nullable: false},
 				},
+				HiddenFields: []string{"password_hash"},
 			},
 		},
 	}
@@ -171,9 +181,9 @@ func TestValidateQuery_LogicalFilterAnd(t *testing.T) {
 	query := &Query{
 		Model: "orders",
 		Filters: &LogicalFilter{
-			And: []*ComparisonFilter{
-				{Field: "status", Op: OpEqual, Value: "PAID"},
-				{Field: "amount", Op: OpGT, Value: 1000},
+			And: []FilterExpr{
+				&ComparisonFilter{Field: "status", Op: OpEqual, Value: "PAID"},
+				&ComparisonFilter{Field: "amount", Op: OpGT, Value: 1000},
 			},
 		},
 	}
@@ -191,9 +201,9 @@ func TestValidateQuery_LogicalFilterOr(t *testing.T) {
 	query := &Query{
 		Model: "orders",
 		Filters: &LogicalFilter{
-			Or: []*ComparisonFilter{
-				{Field: "status", Op: OpEqual, Value: "PAID"},
-				{Field: "status", Op: OpEqual, Value: "PENDING"},
+			Or: []FilterExpr{
+				&ComparisonFilter{Field: "status", Op: OpEqual, Value: "PAID"},
+				&ComparisonFilter{Field: "status", Op: OpEqual, Value: "PENDING"},
 			},
 		},
 	}
@@ -274,6 +284,145 @@ func TestValidateQuery_ValidAggregate(t *testing.T) {
 	}
 }
 
+func TestValidateQuery_CountDistinct(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+
+	valid := &Query{Model: "orders", GroupBy: []string{"status"}, Aggregates: []Aggregate{{Function: AggCountDistinct, Field: "user_id", Alias: "buyers"}}}
+	if err := v.ValidateQuery(valid); err != nil {
+		t.Errorf("ValidateQuery() error = %v, want nil", err)
+	}
+
+	noField := &Query{Model: "orders", Aggregates: []Aggregate{{Function: AggCountDistinct, Alias: "buyers"}}}
+	if err := v.ValidateQuery(noField); err == nil {
+		t.Errorf("ValidateQuery() error = nil, want error for count_distinct without field")
+	}
+}
+
+func TestParseArithExpr(t *testing.T) {
+	expr, err := ParseArithExpr("paid / (total - 0.5) * 100")
+	if err != nil {
+		t.Fatalf("ParseArithExpr() error = %v", err)
+	}
+	want := &ArithExpr{
+		Op: "*",
+		Left: &ArithExpr{
+			Op:    "/",
+			Left:  &ArithExpr{Ref: "paid"},
+			Right: &ArithExpr{Op: "-", Left: &ArithExpr{Ref: "total"}, Right: &ArithExpr{Value: 0.5}},
+		},
+		Right: &ArithExpr{Value: 100},
+	}
+	if !reflect.DeepEqual(expr, want) {
+		t.Errorf("ParseArithExpr() = %+v, want %+v", expr, want)
+	}
+	if refs := expr.Refs(); !reflect.DeepEqual(refs, []string{"paid", "total"}) {
+		t.Errorf("Refs() = %v, want [paid total]", refs)
+	}
+
+	for _, invalid := range []string{"", "paid /", "(paid", "paid total", "paid % total", "1..2", "sum(paid)"} {
+		if _, err := ParseArithExpr(invalid); err == nil {
+			t.Errorf("ParseArithExpr(%q) error = nil, want error", invalid)
+		}
+	}
+}
+
+func TestValidateQuery_AggregateExpr(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+	aggs := func(expr Aggregate) []Aggregate {
+		return []Aggregate{
+			{Function: AggSum, Field: "amount", Alias: "revenue"},
+			{Function: AggCount, Alias: "order_count"},
+			expr,
+		}
+	}
+
+	valid := &Query{Model: "orders", GroupBy: []string{"status"}, Aggregates: aggs(Aggregate{Alias: "avg_order", Expr: "revenue / order_count"})}
+	if err := v.ValidateQuery(valid); err != nil {
+		t.Errorf("ValidateQuery() error = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name string
+		agg  Aggregate
+	}{
+		{"unknown alias", Aggregate{Alias: "pct", Expr: "paid / revenue"}},
+		{"no aggregate", Aggregate{Alias: "pct", Expr: "1 / 2"}},
+		{"with fn", Aggregate{Function: AggSum, Field: "amount", Alias: "pct", Expr: "revenue * 2"}},
+		{"syntax", Aggregate{Alias: "pct", Expr: "revenue /"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &Query{Model: "orders", GroupBy: []string{"status"}, Aggregates: aggs(tt.agg)}
+			if err := v.ValidateQuery(q); err == nil {
+				t.Errorf("ValidateQuery() error = nil, want error")
+			}
+		})
+	}
+
+	// An expression cannot build on another expression
+	chained := aggs(Aggregate{Alias: "avg_order", Expr: "revenue / order_count"})
+	chained = append(chained, Aggregate{Alias: "avg_pct", Expr: "avg_order * 100"})
+	if err := v.ValidateQuery(&Query{Model: "orders", Aggregates: chained}); err == nil {
+		t.Errorf("ValidateQuery() error = nil, want error for an expression referencing an expression")
+	}
+}
+
+func TestValidateQuery_GroupByTime(t *testing.T) {
+	count := []Aggregate{{Function: AggCount, Alias: "order_count"}}
+	day := TimeBucket{Field: "created_at", Granularity: "day", Alias: "day"}
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"daily bucket", &Query{Model: "orders", GroupByTime: []TimeBucket{day}, Aggregates: count, Sort: []Sort{{Field: "day"}}}, false},
+		{"with group_by", &Query{Model: "orders", GroupBy: []string{"status"}, GroupByTime: []TimeBucket{day}, Aggregates: count}, false},
+		{"default alias", &Query{Model: "orders", GroupByTime: []TimeBucket{{Field: "created_at", Granularity: "month"}}}, false},
+		{"non-timestamp field", &Query{Model: "orders", GroupByTime: []TimeBucket{{Field: "status", Granularity: "day"}}}, true},
+		{"unknown field", &Query{Model: "orders", GroupByTime: []TimeBucket{{Field: "missing", Granularity: "day"}}}, true},
+		{"invalid granularity", &Query{Model: "orders", GroupByTime: []TimeBucket{{Field: "created_at", Granularity: "fortnight"}}}, true},
+		{"alias clashes with aggregate", &Query{Model: "orders", GroupByTime: []TimeBucket{{Field: "created_at", Granularity: "day", Alias: "order_count"}}, Aggregates: count}, true},
+		{"alias clashes with group_by", &Query{Model: "orders", GroupBy: []string{"status"}, GroupByTime: []TimeBucket{{Field: "created_at", Granularity: "day", Alias: "status"}}}, true},
+		{"on count", &Query{Operation: OpCount, Model: "orders", GroupByTime: []TimeBucket{day}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(setupTestRegistry()).ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_FieldAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"sort by alias", &Query{Model: "orders", Fields: []string{"amount"}, FieldAliases: map[string]string{"amount": "total"}, Sort: []Sort{{Field: "total"}}}, false},
+		{"sort by unknown alias", &Query{Model: "orders", Fields: []string{"amount"}, FieldAliases: map[string]string{"amount": "total"}, Sort: []Sort{{Field: "grand_total"}}}, true},
+		{"alias of unselected field", &Query{Model: "orders", Fields: []string{"status"}, FieldAliases: map[string]string{"amount": "total"}}, true},
+		{"alias clashes with field", &Query{Model: "orders", Fields: []string{"status", "amount"}, FieldAliases: map[string]string{"amount": "status"}}, true},
+		{"alias clashes with aggregate", &Query{Model: "orders", Fields: []string{"status"}, FieldAliases: map[string]string{"status": "n"}, GroupBy: []string{"status"}, Aggregates: []Aggregate{{Function: AggCount, Alias: "n"}}}, true},
+		{"swapped names", &Query{Model: "orders", Fields: []string{"status", "amount"}, FieldAliases: map[string]string{"amount": "status", "status": "amount"}}, false},
+		{"unsafe alias", &Query{Model: "orders", Fields: []string{"amount"}, FieldAliases: map[string]string{"amount": "total; drop"}}, true},
+		{"on count", &Query{Operation: OpCount, Model: "orders", FieldAliases: map[string]string{"amount": "total"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(setupTestRegistry()).ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidateQuery_AggregateInvalidFunction(t *testing.T) {
 	reg := setupTestRegistry()
 	v := NewValidator(reg)
@@ -360,6 +509,28 @@ func TestValidateQuery_SortInvalidField(t *testing.T) {
 	}
 }
 
+func TestValidateQuery_SortByAggregateAlias(t *testing.T) {
+	reg := setupTestRegistry()
+	v := NewValidator(reg)
+
+	query := &Query{
+		Model:   "orders",
+		GroupBy: []string{"status"},
+		Aggregates: []Aggregate{
+			{Function: AggSum, Field: "amount", Alias: "total"},
+		},
+		Sort: []Sort{
+			{Field: "total", Direction: SortDesc},
+			{Field: "status", Direction: SortAsc},
+		},
+	}
+
+	err := v.ValidateQuery(query)
+	if err != nil {
+		t.Errorf("ValidateQuery() error = %v, want nil", err)
+	}
+}
+
 func TestValidateQuery_SortInvalidDirection(t *testing.T) {
 	reg := setupTestRegistry()
 	v := NewValidator(reg)
@@ -431,6 +602,29 @@ func TestValidateQuery_PaginationNegativeOffset(t *testing.T) {
 	}
 }
 
+func TestValidateQuery_PageNumber(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination *Pagination
+		wantErr    bool
+	}{
+		{"page and size", &Pagination{Page: 2, PageSize: 20}, false},
+		{"page zero", &Pagination{Page: 0, PageSize: 20}, true},
+		{"size without page", &Pagination{PageSize: 20}, true},
+		{"page without size", &Pagination{Page: 2}, true},
+		{"with offset", &Pagination{Page: 2, PageSize: 20, Offset: 40}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(setupTestRegistry()).ValidateQuery(&Query{Model: "orders", Pagination: tt.pagination})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidateQuery_ComplexQuery(t *testing.T) {
 	reg := setupTestRegistry()
 	v := NewValidator(reg)
@@ -440,9 +634,9 @@ func TestValidateQuery_ComplexQuery(t *testing.T) {
 		Fields:  []string{"status", "amount"},
 		GroupBy: []string{"status"},
 		Filters: &LogicalFilter{
-			And: []*ComparisonFilter{
-				{Field: "created_at", Op: OpAfter, Value: "2024-01-01"},
-				{Field: "amount", Op: OpGTE, Value: 100},
+			And: []FilterExpr{
+				&ComparisonFilter{Field: "created_at", Op: OpAfter, Value: "2024-01-01"},
+				&ComparisonFilter{Field: "amount", Op: OpGTE, Value: 100},
 			},
 		},
 		Aggregates: []Aggregate{
@@ -492,3 +686,958 @@ func indexOfSubstring(s, substr string) int {
 	}
 	return -1
 }
+
+func TestValidateQuery_HiddenFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"select all", &Query{Model: "users"}, false},
+		{"select visible field", &Query{Model: "users", Fields: []string{"id", "email"}}, false},
+		{"select hidden field", &Query{Model: "users", Fields: []string{"id", "password_hash"}}, true},
+		{"group by hidden field", &Query{Model: "users", GroupBy: []string{"password_hash"}}, true},
+		{"aggregate hidden field", &Query{Model: "users", Aggregates: []Aggregate{{Function: AggMax, Field: "password_hash", Alias: "m"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(setupTestRegistry())
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_Lock(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"for update", &Query{Model: "orders", Lock: &Lock{Mode: LockForUpdate}}, false},
+		{"for share skip locked", &Query{Model: "orders", Lock: &Lock{Mode: LockForShare, SkipLocked: true}}, false},
+		{"invalid mode", &Query{Model: "orders", Lock: &Lock{Mode: "exclusive"}}, true},
+		{"skip locked and nowait", &Query{Model: "orders", Lock: &Lock{Mode: LockForUpdate, SkipLocked: true, NoWait: true}}, true},
+		{"with group by", &Query{Model: "orders", GroupBy: []string{"status"}, Lock: &Lock{Mode: LockForUpdate}}, true},
+		{"on delete", &Query{Operation: OpDelete, Model: "orders", ID: 1, Lock: &Lock{Mode: LockForUpdate}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(setupTestRegistry())
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_IncludeDeleted(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"select", &Query{Model: "orders", IncludeDeleted: true}, false},
+		{"count", &Query{Operation: OpCount, Model: "orders", IncludeDeleted: true}, false},
+		{"delete", &Query{Operation: OpDelete, Model: "orders", ID: 1, IncludeDeleted: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(setupTestRegistry())
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_AllowFullTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"delete without filters", &Query{Operation: OpDelete, Model: "orders"}, true},
+		{"update without filters", &Query{Operation: OpUpdate, Model: "orders", Data: map[string]interface{}{"status": "PAID"}}, true},
+		{"delete confirmed", &Query{Operation: OpDelete, Model: "orders", AllowFullTable: true}, false},
+		{"update confirmed", &Query{Operation: OpUpdate, Model: "orders", Data: map[string]interface{}{"status": "PAID"}, AllowFullTable: true}, false},
+		{"select", &Query{Model: "orders", AllowFullTable: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(setupTestRegistry())
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_Search(t *testing.T) {
+	search := &ComparisonFilter{Op: OpSearch, Value: "late delivery"}
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"search", &Query{Model: "orders", Filters: search}, false},
+		{"search sorted by score", &Query{Model: "orders", Filters: search, Sort: []Sort{{Field: SearchScoreField, Direction: SortDesc}}}, false},
+		{"nested search sorted by score", &Query{Model: "orders", Filters: &LogicalFilter{And: []FilterExpr{search}}, Sort: []Sort{{Field: SearchScoreField}}}, false},
+		{"score sort without search", &Query{Model: "orders", Sort: []Sort{{Field: SearchScoreField}}}, true},
+		{"model without search fields", &Query{Model: "users", Filters: search}, true},
+		{"search with field", &Query{Model: "orders", Filters: &ComparisonFilter{Field: "notes", Op: OpSearch, Value: "late"}}, true},
+		{"empty search text", &Query{Model: "orders", Filters: &ComparisonFilter{Op: OpSearch, Value: "  "}}, true},
+		{"non-string search text", &Query{Model: "orders", Filters: &ComparisonFilter{Op: OpSearch, Value: 42}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(setupTestRegistry())
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_ElemMatch(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "carts",
+				Table:      "carts",
+				PrimaryKey: "_id",
+				Fields: []config.Field{
+					{Name: "_id", Type: "string", Nullable: false},
+					{Name: "status", Type: "string", Nullable: false},
+					{Name: "items", Type: "json", Nullable: true},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+
+	sku := &ComparisonFilter{Field: "sku", Op: OpEqual, Value: "A1"}
+	qty := &ComparisonFilter{Field: "qty", Op: OpGTE, Value: 2}
+
+	tests := []struct {
+		name    string
+		filter  FilterExpr
+		wantErr bool
+	}{
+		{"elem match", &ElemMatchFilter{Field: "items", ElemMatch: []*ComparisonFilter{sku, qty}}, false},
+		{"nested in logical", &LogicalFilter{Not: &ElemMatchFilter{Field: "items", ElemMatch: []*ComparisonFilter{sku}}}, false},
+		{"missing field", &ElemMatchFilter{ElemMatch: []*ComparisonFilter{sku}}, true},
+		{"unknown field", &ElemMatchFilter{Field: "lines", ElemMatch: []*ComparisonFilter{sku}}, true},
+		{"non-json field", &ElemMatchFilter{Field: "status", ElemMatch: []*ComparisonFilter{sku}}, true},
+		{"no conditions", &ElemMatchFilter{Field: "items"}, true},
+		{"condition without field", &ElemMatchFilter{Field: "items", ElemMatch: []*ComparisonFilter{{Op: OpEqual, Value: 1}}}, true},
+		{"search condition", &ElemMatchFilter{Field: "items", ElemMatch: []*ComparisonFilter{{Field: "sku", Op: OpSearch, Value: "A"}}}, true},
+		{"bad condition bounds", &ElemMatchFilter{Field: "items", ElemMatch: []*ComparisonFilter{{Field: "qty", Op: OpBetween, Value: []interface{}{1, 5}, Bounds: "[["}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(reg).ValidateQuery(&Query{Model: "carts", Filters: tt.filter})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_UpdateOperators(t *testing.T) {
+	strip := true
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"increment only", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Increment: map[string]interface{}{"amount": 5.0}}, false},
+		{"push only", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Push: map[string]interface{}{"notes": "shipped"}}, false},
+		{"unset nulls", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"notes": nil}, UnsetNulls: true}, false},
+		{"increment non-numeric field", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Increment: map[string]interface{}{"status": 1.0}}, true},
+		{"increment non-numeric amount", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Increment: map[string]interface{}{"amount": "5"}}, true},
+		{"increment unknown field", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Increment: map[string]interface{}{"missing": 1.0}}, true},
+		{"set and increment", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"amount": 1.0}, Increment: map[string]interface{}{"amount": 1.0}}, true},
+		{"push unknown field", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Push: map[string]interface{}{"missing": "x"}}, true},
+		{"increment on create", &Query{Operation: OpCreate, Model: "orders", Data: map[string]interface{}{"status": "NEW"}, Increment: map[string]interface{}{"amount": 1.0}}, true},
+		{"nothing to update", &Query{Operation: OpUpdate, Model: "orders", ID: 1}, true},
+		{"strip nulls on update", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"notes": nil}, StripNulls: &strip}, false},
+		{"strip nulls on select", &Query{Model: "orders", StripNulls: &strip}, true},
+		{"strip and unset nulls", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"notes": nil}, StripNulls: &strip, UnsetNulls: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(setupTestRegistry())
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_JSONUpdates(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "accounts",
+				Table:      "accounts",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "name", Type: "string", Nullable: false},
+					{Name: "settings", Type: "json", Nullable: true},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	v := NewValidator(reg)
+
+	set := map[string]interface{}{"settings.theme": "dark"}
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"set", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, JSONSet: set}, false},
+		{"remove", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, JSONRemove: []string{"settings.legacy.flag"}}, false},
+		{"with data on other field", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, Data: map[string]interface{}{"name": "x"}, JSONSet: set}, false},
+		{"non-json field", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, JSONSet: map[string]interface{}{"name.first": "x"}}, true},
+		{"unknown field", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, JSONRemove: []string{"prefs.theme"}}, true},
+		{"field without key", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, JSONRemove: []string{"settings"}}, true},
+		{"invalid key", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, JSONSet: map[string]interface{}{"settings.the'me": "x"}}, true},
+		{"set and remove same path", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, JSONSet: set, JSONRemove: []string{"settings.theme"}}, true},
+		{"data on same field", &Query{Operation: OpUpdate, Model: "accounts", ID: 1, Data: map[string]interface{}{"settings": nil}, JSONSet: set}, true},
+		{"json_set on select", &Query{Model: "accounts", JSONSet: set}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_UUIDValues(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "tokens",
+				Table:      "tokens",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "uuid", Nullable: false},
+					{Name: "owner_id", Type: "uuid", Nullable: true},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	v := NewValidator(reg)
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"valid uuid", &Query{Operation: OpCreate, Model: "tokens", Data: map[string]interface{}{"id": "550E8400-e29b-41d4-a716-446655440000"}}, false},
+		{"null uuid", &Query{Operation: OpCreate, Model: "tokens", Data: map[string]interface{}{"owner_id": nil}}, false},
+		{"malformed uuid", &Query{Operation: OpCreate, Model: "tokens", Data: map[string]interface{}{"id": "550e8400-e29b-41d4-a716"}}, true},
+		{"non-string uuid", &Query{Operation: OpCreate, Model: "tokens", Data: map[string]interface{}{"id": 42.0}}, true},
+		{"malformed uuid on update", &Query{Operation: OpUpdate, Model: "tokens", ID: "x", Data: map[string]interface{}{"owner_id": "not-a-uuid"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_Returning(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"update returning", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}, Returning: []string{"id", "status"}}, false},
+		{"delete returning", &Query{Operation: OpDelete, Model: "orders", ID: 1, Returning: []string{"id"}}, false},
+		{"returning on select", &Query{Model: "orders", Returning: []string{"id"}}, true},
+		{"returning unknown field", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}, Returning: []string{"missing"}}, true},
+		{"returning hidden field", &Query{Operation: OpUpdate, Model: "users", ID: 1, Data: map[string]interface{}{"name": "Ann"}, Returning: []string{"password_hash"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(setupTestRegistry()).ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_Count(t *testing.T) {
+	reg := setupTestRegistry()
+	v := NewValidator(reg)
+
+	valid := &Query{
+		Operation: OpCount,
+		Model:     "orders",
+		Filters:   &ComparisonFilter{Field: "status", Op: OpEqual, Value: "PAID"},
+	}
+	if err := v.ValidateQuery(valid); err != nil {
+		t.Errorf("ValidateQuery() error = %v, want nil", err)
+	}
+
+	withFields := &Query{Operation: OpCount, Model: "orders", Fields: []string{"id"}}
+	if err := v.ValidateQuery(withFields); err == nil {
+		t.Errorf("ValidateQuery() should reject fields on count")
+	}
+
+	withPagination := &Query{Operation: OpCount, Model: "orders", Pagination: &Pagination{Limit: 10}}
+	if err := v.ValidateQuery(withPagination); err == nil {
+		t.Errorf("ValidateQuery() should reject pagination on count")
+	}
+}
+
+func TestValidateQuery_Exists(t *testing.T) {
+	reg := setupTestRegistry()
+	v := NewValidator(reg)
+
+	valid := &Query{
+		Operation:      OpExists,
+		Model:          "orders",
+		Filters:        &ComparisonFilter{Field: "status", Op: OpEqual, Value: "PAID"},
+		IncludeDeleted: true,
+	}
+	if err := v.ValidateQuery(valid); err != nil {
+		t.Errorf("ValidateQuery() error = %v, want nil", err)
+	}
+
+	withSort := &Query{Operation: OpExists, Model: "orders", Sort: []Sort{{Field: "id", Direction: "asc"}}}
+	err := v.ValidateQuery(withSort)
+	if err == nil || err.Error() != "exists operation does not accept sort or pagination" {
+		t.Errorf("ValidateQuery() error = %v, want sort rejected on exists", err)
+	}
+
+	badFilter := &Query{Operation: OpExists, Model: "orders", Filters: &ComparisonFilter{Field: "missing", Op: OpEqual, Value: 1}}
+	if err := v.ValidateQuery(badFilter); err == nil {
+		t.Errorf("ValidateQuery() should reject unknown filter fields on exists")
+	}
+}
+
+func TestValidateQuery_Distinct(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"distinct", &Query{Operation: OpDistinct, Model: "orders", Fields: []string{"status"}}, false},
+		{"distinct with filter", &Query{Operation: OpDistinct, Model: "orders", Fields: []string{"status"}, Filters: &ComparisonFilter{Field: "amount", Op: OpGT, Value: 10}}, false},
+		{"distinct including deleted", &Query{Operation: OpDistinct, Model: "orders", Fields: []string{"status"}, IncludeDeleted: true}, false},
+		{"no field", &Query{Operation: OpDistinct, Model: "orders"}, true},
+		{"two fields", &Query{Operation: OpDistinct, Model: "orders", Fields: []string{"status", "user_id"}}, true},
+		{"unknown field", &Query{Operation: OpDistinct, Model: "orders", Fields: []string{"missing"}}, true},
+		{"hidden field", &Query{Operation: OpDistinct, Model: "users", Fields: []string{"password_hash"}}, true},
+		{"with sort", &Query{Operation: OpDistinct, Model: "orders", Fields: []string{"status"}, Sort: []Sort{{Field: "status"}}}, true},
+		{"with group_by", &Query{Operation: OpDistinct, Model: "orders", Fields: []string{"status"}, GroupBy: []string{"status"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(setupTestRegistry()).ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_ValueField(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  FilterExpr
+		wantErr bool
+	}{
+		{"field comparison", &ComparisonFilter{Field: "amount", Op: OpGT, ValueField: "user_id"}, false},
+		{"nested field comparison", &LogicalFilter{Or: []FilterExpr{&ComparisonFilter{Field: "created_at", Op: OpBefore, ValueField: "created_at"}}}, false},
+		{"unknown value_field", &ComparisonFilter{Field: "amount", Op: OpGT, ValueField: "missing"}, true},
+		{"value and value_field", &ComparisonFilter{Field: "amount", Op: OpGT, Value: 1, ValueField: "user_id"}, true},
+		{"unsupported operator", &ComparisonFilter{Field: "status", Op: OpLike, ValueField: "notes"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(setupTestRegistry()).ValidateQuery(&Query{Model: "orders", Filters: tt.filter})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_Include(t *testing.T) {
+	reg := setupTestRegistry()
+	v := NewValidator(reg)
+
+	valid := &Query{Model: "orders", Include: []Include{{Relation: "user", Unwind: true}}}
+	if err := v.ValidateQuery(valid); err != nil {
+		t.Errorf("ValidateQuery() error = %v, want nil", err)
+	}
+
+	unknown := &Query{Model: "orders", Include: []Include{{Relation: "customer"}}}
+	if err := v.ValidateQuery(unknown); err == nil {
+		t.Errorf("ValidateQuery() should reject unknown relation")
+	}
+
+	// users has no relations, so a nested include under user is invalid
+	nested := &Query{Model: "orders", Include: []Include{{Relation: "user", Include: []Include{{Relation: "orders"}}}}}
+	if err := v.ValidateQuery(nested); err == nil {
+		t.Errorf("ValidateQuery() should reject unknown nested relation")
+	}
+}
+
+func TestValidateQuery_IncludeDepthLimit(t *testing.T) {
+	reg := setupTestRegistry()
+	v := NewValidator(reg)
+
+	original := limits.MaxIncludeDepth
+	limits.MaxIncludeDepth = 0
+	defer func() { limits.MaxIncludeDepth = original }()
+
+	query := &Query{Model: "orders", Include: []Include{{Relation: "user"}}}
+	if err := v.ValidateQuery(query); err == nil {
+		t.Errorf("ValidateQuery() should reject includes beyond the depth limit")
+	}
+}
+
+func TestValidateQuery_Subquery(t *testing.T) {
+	adults := &ComparisonFilter{Field: "age", Op: OpGTE, Value: 18}
+	tests := []struct {
+		name    string
+		filter  FilterExpr
+		wantErr bool
+	}{
+		{"in subquery", &ComparisonFilter{Field: "user_id", Op: OpIn, Subquery: &Subquery{Model: "users", Field: "id", Filters: adults}}, false},
+		{"not_in without filters", &ComparisonFilter{Field: "user_id", Op: OpNotIn, Subquery: &Subquery{Model: "users", Field: "id"}}, false},
+		{"unsupported operator", &ComparisonFilter{Field: "user_id", Op: OpEqual, Subquery: &Subquery{Model: "users", Field: "id"}}, true},
+		{"value and subquery", &ComparisonFilter{Field: "user_id", Op: OpIn, Value: []interface{}{1}, Subquery: &Subquery{Model: "users", Field: "id"}}, true},
+		{"unknown model", &ComparisonFilter{Field: "user_id", Op: OpIn, Subquery: &Subquery{Model: "accounts", Field: "id"}}, true},
+		{"missing field", &ComparisonFilter{Field: "user_id", Op: OpIn, Subquery: &Subquery{Model: "users"}}, true},
+		{"hidden field", &ComparisonFilter{Field: "user_id", Op: OpIn, Subquery: &Subquery{Model: "users", Field: "password_hash"}}, true},
+		{"invalid inner filter", &ComparisonFilter{Field: "user_id", Op: OpIn, Subquery: &Subquery{Model: "users", Field: "id", Filters: &ComparisonFilter{Field: "status", Op: OpEqual, Value: "x"}}}, true},
+		{"nested subquery", &ComparisonFilter{Field: "user_id", Op: OpIn, Subquery: &Subquery{Model: "users", Field: "id", Filters: &ComparisonFilter{Field: "id", Op: OpIn, Subquery: &Subquery{Model: "orders", Field: "user_id"}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(setupTestRegistry()).ValidateQuery(&Query{Model: "orders", Filters: tt.filter})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_ExpectedVersion(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "status", Type: "string", Nullable: false},
+					{Name: "version", Type: "integer", Nullable: false},
+				},
+				VersionField: "version",
+			},
+			{
+				Name:       "notes",
+				Table:      "notes",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "body", Type: "string", Nullable: true},
+				},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	v := NewValidator(reg)
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"versioned update", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}, ExpectedVersion: 3.0}, false},
+		{"update without expected version", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}}, false},
+		{"fractional version", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}, ExpectedVersion: 3.5}, true},
+		{"string version", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"status": "PAID"}, ExpectedVersion: "3"}, true},
+		{"version column in data", &Query{Operation: OpUpdate, Model: "orders", ID: 1, Data: map[string]interface{}{"version": 4.0}, ExpectedVersion: 3.0}, true},
+		{"unversioned model", &Query{Operation: OpUpdate, Model: "notes", ID: 1, Data: map[string]interface{}{"body": "x"}, ExpectedVersion: 3.0}, true},
+		{"not an update", &Query{Operation: OpDelete, Model: "orders", ID: 1, ExpectedVersion: 3.0}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_Collation(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"valid collation", &Query{Model: "orders", Sort: []Sort{{Field: "status"}}, Collation: &config.Collation{Locale: "fr_CA", Strength: 1}}, false},
+		{"missing locale", &Query{Model: "orders", Collation: &config.Collation{Strength: 2}}, true},
+		{"quoted locale", &Query{Model: "orders", Collation: &config.Collation{Locale: `de" DESC`}}, true},
+		{"strength out of range", &Query{Model: "orders", Collation: &config.Collation{Locale: "de", Strength: 6}}, true},
+		{"not a select", &Query{Operation: OpCount, Model: "orders", Collation: &config.Collation{Locale: "de"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_Aggregate(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+	sum := []Aggregate{{Function: AggSum, Field: "amount", Alias: "total"}}
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"grouped", &Query{Operation: OpAggregate, Model: "orders", GroupBy: []string{"status"}, Aggregates: sum}, false},
+		{"global aggregate", &Query{Operation: OpAggregate, Model: "orders", Aggregates: sum}, false},
+		{"sorted by aggregate", &Query{Operation: OpAggregate, Model: "orders", GroupBy: []string{"status"}, Aggregates: sum, Sort: []Sort{{Field: "total", Direction: SortDesc}}}, false},
+		{"no grouping", &Query{Operation: OpAggregate, Model: "orders", Fields: []string{"status"}}, true},
+		{"with lock", &Query{Operation: OpAggregate, Model: "orders", Aggregates: sum, Lock: &Lock{Mode: LockForUpdate}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_FirstLast(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+	paid := &ComparisonFilter{Field: "status", Op: OpEqual, Value: "PAID"}
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"first", &Query{Operation: OpFirst, Model: "orders", Filters: paid}, false},
+		{"last with fields", &Query{Operation: OpLast, Model: "orders", Fields: []string{"id", "status"}}, false},
+		{"unknown field", &Query{Operation: OpFirst, Model: "orders", Fields: []string{"missing"}}, true},
+		{"with sort", &Query{Operation: OpFirst, Model: "orders", Sort: []Sort{{Field: "amount", Direction: SortDesc}}}, true},
+		{"with pagination", &Query{Operation: OpLast, Model: "orders", Pagination: &Pagination{Limit: 5}}, true},
+		{"with aggregates", &Query{Operation: OpFirst, Model: "orders", Aggregates: []Aggregate{{Function: AggCount, Alias: "n"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_VirtualField(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{{
+			Name:       "people",
+			Table:      "people",
+			PrimaryKey: "id",
+			Fields: []config.Field{
+				{Name: "id", Type: "integer"},
+				{Name: "first_name", Type: "string"},
+				{Name: "last_name", Type: "string"},
+			},
+			VirtualFields: []config.VirtualField{
+				{Name: "full_name", Type: "string", SQL: "{first_name} || ' ' || {last_name}"},
+			},
+		}},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	v := NewValidator(reg)
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"selected", &Query{Model: "people", Fields: []string{"id", "full_name"}}, false},
+		{"filtered", &Query{Model: "people", Filters: &ComparisonFilter{Field: "full_name", Op: OpEqual, Value: "Ada Lovelace"}}, true},
+		{"sorted", &Query{Model: "people", Sort: []Sort{{Field: "full_name", Direction: SortAsc}}}, true},
+		{"grouped", &Query{Model: "people", GroupBy: []string{"full_name"}}, true},
+		{"distinct", &Query{Operation: OpDistinct, Model: "people", Fields: []string{"full_name"}}, true},
+		{"written", &Query{Operation: OpCreate, Model: "people", Data: map[string]interface{}{"full_name": "Ada Lovelace"}}, true},
+		{"returned", &Query{Operation: OpCreate, Model: "people", Data: map[string]interface{}{"first_name": "Ada"}, Returning: []string{"full_name"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSizeCondition(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		op      FilterOperator
+		length  int64
+		wantErr bool
+	}{
+		{float64(3), OpEqual, 3, false},
+		{map[string]interface{}{">": float64(3)}, OpGT, 3, false},
+		{map[string]interface{}{"<=": 0}, OpLTE, 0, false},
+		{float64(2.5), "", 0, true},
+		{float64(-1), "", 0, true},
+		{"3", "", 0, true},
+		{map[string]interface{}{"in": float64(3)}, "", 0, true},
+		{map[string]interface{}{">": float64(1), "<": float64(5)}, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		op, length, err := SizeCondition(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SizeCondition(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if op != tt.op || length != tt.length {
+			t.Errorf("SizeCondition(%v) = %s %d, want %s %d", tt.value, op, length, tt.op, tt.length)
+		}
+	}
+}
+
+func TestValidateQuery_Size(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{{
+			Name:       "posts",
+			Table:      "posts",
+			PrimaryKey: "id",
+			Fields: []config.Field{
+				{Name: "id", Type: "integer"},
+				{Name: "tags", Type: "string", Array: true},
+				{Name: "links", Type: "json"},
+				{Name: "title", Type: "string"},
+			},
+		}},
+	})
+	v := NewValidator(reg)
+
+	tests := []struct {
+		name    string
+		filter  *ComparisonFilter
+		wantErr bool
+	}{
+		{"array exact", &ComparisonFilter{Field: "tags", Op: OpSize, Value: float64(3)}, false},
+		{"json comparison", &ComparisonFilter{Field: "links", Op: OpSize, Value: map[string]interface{}{">": float64(3)}}, false},
+		{"not an array", &ComparisonFilter{Field: "title", Op: OpSize, Value: float64(3)}, true},
+		{"invalid length", &ComparisonFilter{Field: "tags", Op: OpSize, Value: "many"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(&Query{Model: "posts", Filters: tt.filter})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_PaginationWithTotal(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+
+	valid := &Query{Model: "orders", Pagination: &Pagination{Limit: 10, WithTotal: true}}
+	if err := v.ValidateQuery(valid); err != nil {
+		t.Errorf("ValidateQuery() error = %v, want nil", err)
+	}
+
+	grouped := &Query{
+		Operation:  OpAggregate,
+		Model:      "orders",
+		GroupBy:    []string{"status"},
+		Aggregates: []Aggregate{{Function: AggCount, Alias: "n"}},
+		Pagination: &Pagination{Limit: 10, WithTotal: true},
+	}
+	if err := v.ValidateQuery(grouped); err == nil {
+		t.Errorf("ValidateQuery() error = nil, want error for with_total on an aggregate")
+	}
+}
+
+func TestValidateQuery_PaginationCursor(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+	cursor := func(sort []Sort, p Pagination) *Query {
+		p.Cursor = "token"
+		if p.Limit == 0 && p.PageSize == 0 {
+			p.Limit = 10
+		}
+		return &Query{Model: "orders", Sort: sort, Pagination: &p}
+	}
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"primary key", cursor([]Sort{{Field: "id", Direction: SortDesc}}, Pagination{}), false},
+		{"field then primary key", cursor([]Sort{{Field: "created_at"}, {Field: "id"}}, Pagination{}), false},
+		{"default primary key sort", cursor(nil, Pagination{}), false},
+		{"field without primary key", cursor([]Sort{{Field: "created_at"}}, Pagination{}), true},
+		{"mixed directions", cursor([]Sort{{Field: "created_at", Direction: SortDesc}, {Field: "id"}}, Pagination{}), true},
+		{"nullable field", cursor([]Sort{{Field: "notes"}, {Field: "id"}}, Pagination{}), true},
+		{"with offset", cursor([]Sort{{Field: "id"}}, Pagination{Offset: 10}), true},
+		{"with page", cursor([]Sort{{Field: "id"}}, Pagination{Page: 2, PageSize: 10}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_Upsert(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+	data := map[string]interface{}{"status": "PAID"}
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"update by id", &Query{Operation: OpUpdate, Model: "orders", ID: 1.0, Data: data, Upsert: true}, false},
+		{"not an update", &Query{Operation: OpCreate, Model: "orders", Data: data, Upsert: true}, true},
+		{"with expected version", &Query{Operation: OpUpdate, Model: "orders", ID: 1.0, Data: data, Upsert: true, ExpectedVersion: 3.0}, true},
+		{"full table", &Query{Operation: OpUpdate, Model: "orders", Data: data, Upsert: true, AllowFullTable: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_BetweenBounds(t *testing.T) {
+	v := NewValidator(setupTestRegistry())
+	filter := func(op FilterOperator, bounds string) *Query {
+		return &Query{Model: "orders", Filters: &ComparisonFilter{Field: "amount", Op: op, Value: []interface{}{1.0, 10.0}, Bounds: bounds}}
+	}
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr bool
+	}{
+		{"default", filter(OpBetween, ""), false},
+		{"half open", filter(OpBetween, "[)"), false},
+		{"open", filter(OpBetween, "()"), false},
+		{"unknown bounds", filter(OpBetween, "[["), true},
+		{"not between", filter(OpIn, "[)"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_DataTypes(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "status", Type: "string", Nullable: false},
+					{Name: "amount", Type: "decimal", Nullable: false},
+					{Name: "paid", Type: "boolean", Nullable: false, HasDefault: true},
+					{Name: "placed_at", Type: "timestamp", Nullable: true},
+					{Name: "tags", Type: "string", Nullable: true, Array: true},
+					{Name: "created_at", Type: "timestamp", Nullable: false},
+				},
+				CreatedAtField: "created_at",
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	v := NewValidator(reg)
+
+	create := func(data map[string]interface{}) *Query {
+		return &Query{Operation: OpCreate, Model: "orders", Data: data}
+	}
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  []string
+	}{
+		{"valid", create(map[string]interface{}{"status": "NEW", "amount": 9.5, "placed_at": "2024-01-02T03:04:05Z", "tags": []interface{}{"a", "b"}}), nil},
+		{"coercible strings", create(map[string]interface{}{"status": "NEW", "amount": "9.50", "paid": "true"}), nil},
+		{"type mismatches", create(map[string]interface{}{"status": 5.0, "amount": "lots", "placed_at": "yesterday", "tags": []interface{}{"a", 1.0}}), []string{
+			`amount: expected decimal, got string "lots"`,
+			`placed_at: expected timestamp, got string "yesterday"`,
+			`status: expected string, got number 5`,
+			`tags: element 1: expected string, got number 1`,
+		}},
+		{"missing required", create(map[string]interface{}{"placed_at": nil}), []string{
+			"status: required field missing",
+			"amount: required field missing",
+		}},
+		{"null in non-nullable", create(map[string]interface{}{"status": nil, "amount": 1.0}), []string{"status: must not be null"}},
+		{"update checks types only", &Query{Operation: OpUpdate, Model: "orders", ID: 1.0, Data: map[string]interface{}{"amount": true}}, []string{"amount: expected decimal, got boolean true"}},
+		{"value expressions", create(map[string]interface{}{"status": map[string]interface{}{"$expr": "DEFAULT"}, "amount": 1.0, "placed_at": map[string]interface{}{"$expr": "NOW()"}}), nil},
+		{"bad value expressions", create(map[string]interface{}{"status": map[string]interface{}{"$expr": "now()"}, "amount": map[string]interface{}{"$expr": "pg_sleep(10)"}, "placed_at": map[string]interface{}{"$expr": "gen_random_uuid()"}, "tags": map[string]interface{}{"$expr": "current_date"}}), []string{
+			`amount: unsupported expression "pg_sleep(10)" (use now(), current_timestamp, current_date, gen_random_uuid(), uuid_generate_v4() or DEFAULT)`,
+			"placed_at: expression gen_random_uuid() needs a uuid or string field, got timestamp",
+			"status: expression now() needs a timestamp field, got string",
+			"tags: expression current_date needs a timestamp field, got string",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ValidateQuery() error = %v, want nil", err)
+				}
+				return
+			}
+			var dataErr *DataError
+			if !errors.As(err, &dataErr) {
+				t.Fatalf("ValidateQuery() error = %v, want a DataError", err)
+			}
+			if got := dataErr.Details(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Details() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateQuery_TransformedFields(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "name", Type: "string"},
+					{Name: "email", Type: "string", Transform: &config.FieldTransform{Type: config.TransformMaskEmail}},
+				},
+			},
+		},
+	})
+	v := NewValidator(reg)
+
+	tests := []struct {
+		name    string
+		query   *Query
+		wantErr string
+	}{
+		{"select", &Query{Model: "users", Fields: []string{"id", "email"}}, ""},
+		{"filter", &Query{Model: "users", Filters: &ComparisonFilter{Field: "email", Op: OpStartsWith, Value: "jane"}},
+			"field email is masked by a mask_email transform and cannot be filtered"},
+		{"value_field", &Query{Model: "users", Filters: &ComparisonFilter{Field: "name", Op: OpEqual, ValueField: "email"}},
+			"field email is masked by a mask_email transform and cannot be filtered"},
+		{"sort", &Query{Model: "users", Sort: []Sort{{Field: "email"}}},
+			"sort[0]: field email is masked by a mask_email transform and cannot be sorted"},
+		{"group_by", &Query{Model: "users", GroupBy: []string{"email"}, Aggregates: []Aggregate{{Function: AggCount, Alias: "n"}}},
+			"field email is masked by a mask_email transform and cannot be grouped"},
+		{"max", &Query{Model: "users", Aggregates: []Aggregate{{Function: AggMax, Field: "email", Alias: "top"}}},
+			"aggregate[0] field email is masked by a mask_email transform and cannot be aggregated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateQuery(tt.query)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateQuery() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateQuery() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,3 +1,28 @@
 package limits
 
 // Package limits enforces system-wide constraints
+
+// MaxIncludeDepth is the deepest level of nested relation includes a query may request
+var MaxIncludeDepth = 3
+
+// MaxFilterDepth is the deepest level of nested and/or/not filters a query may use
+var MaxFilterDepth = 5
+
+// MaxRequestBodyBytes is the largest request body the query endpoint will read
+var MaxRequestBodyBytes int64 = 1 << 20
+
+// MaxPageLimit is the largest pagination limit a query may request
+var MaxPageLimit = 1000
+
+// ClampPageLimit lowers limits above MaxPageLimit to it instead of rejecting the query
+var ClampPageLimit = false
+
+// MaxPageOffset is the largest pagination offset a query may request; deeper pages
+// should be reached with filters instead
+var MaxPageOffset = 100000
+
+// MaxBatchSize is the largest number of queries a single /batch request may contain
+var MaxBatchSize = 50
+
+// BatchConcurrency is how many queries of one batch run at the same time
+var BatchConcurrency = 4
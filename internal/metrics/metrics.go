@@ -0,0 +1,128 @@
+// Package metrics exposes Prometheus instrumentation for queries and database calls
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Unknown is the label value for models and operations that do not exist. Callers pass it
+// instead of client-supplied values, which would otherwise add a series per distinct value.
+const Unknown = "unknown"
+
+// ErrorType classifies where in the query pipeline an error occurred
+type ErrorType string
+
+const (
+	ErrDecode     ErrorType = "decode"
+	ErrValidation ErrorType = "validation"
+	ErrPlanning   ErrorType = "planning"
+	ErrBuild      ErrorType = "build"
+	ErrExecution  ErrorType = "execution"
+
+	// ErrConflict counts updates rejected because the row changed since the client read it
+	ErrConflict ErrorType = "conflict"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	queriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "udv_queries_total",
+			Help: "Total number of queries handled, by model and operation.",
+		},
+		[]string{"model", "operation"},
+	)
+
+	queryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "udv_query_duration_seconds",
+			Help:    "End-to-end duration of query requests, by model and operation.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"model", "operation"},
+	)
+
+	queryErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "udv_query_errors_total",
+			Help: "Total number of failed queries, by model, operation and error type.",
+		},
+		[]string{"model", "operation", "type"},
+	)
+
+	dbCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "udv_db_call_duration_seconds",
+			Help:    "Duration of database adapter calls, by backend and method.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"backend", "method"},
+	)
+
+	dbCallErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "udv_db_call_errors_total",
+			Help: "Total number of failed database adapter calls, by backend and method.",
+		},
+		[]string{"backend", "method"},
+	)
+
+	cacheLookupsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "udv_cache_lookups_total",
+			Help: "Total number of query result cache lookups, by model and result (hit or miss).",
+		},
+		[]string{"model", "result"},
+	)
+)
+
+func init() {
+	registry.MustRegister(
+		queriesTotal,
+		queryDuration,
+		queryErrorsTotal,
+		dbCallDuration,
+		dbCallErrorsTotal,
+		cacheLookupsTotal,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler returns an HTTP handler serving metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveQuery records a handled query and its duration since start
+func ObserveQuery(model, operation string, start time.Time) {
+	queriesTotal.WithLabelValues(model, operation).Inc()
+	queryDuration.WithLabelValues(model, operation).Observe(time.Since(start).Seconds())
+}
+
+// RecordQueryError counts a failed query by the pipeline stage that failed
+func RecordQueryError(model, operation string, errType ErrorType) {
+	queryErrorsTotal.WithLabelValues(model, operation, string(errType)).Inc()
+}
+
+// ObserveDBCall records the duration of an adapter call and counts it as failed if err is non-nil
+func ObserveDBCall(backend, method string, start time.Time, err error) {
+	dbCallDuration.WithLabelValues(backend, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		dbCallErrorsTotal.WithLabelValues(backend, method).Inc()
+	}
+}
+
+// RecordCacheLookup counts a query result cache lookup as a hit or a miss
+func RecordCacheLookup(model string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookupsTotal.WithLabelValues(model, result).Inc()
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_ExposesRecordedMetrics(t *testing.T) {
+	ObserveQuery("orders", "select", time.Now())
+	RecordQueryError("orders", "select", ErrValidation)
+	ObserveDBCall("postgres", "query", time.Now(), errors.New("boom"))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := ioutil.ReadAll(rec.Body)
+	out := string(body)
+
+	expected := []string{
+		`udv_queries_total{model="orders",operation="select"}`,
+		`udv_query_duration_seconds_count{model="orders",operation="select"}`,
+		`udv_query_errors_total{model="orders",operation="select",type="validation"}`,
+		`udv_db_call_duration_seconds_count{backend="postgres",method="query"}`,
+		`udv_db_call_errors_total{backend="postgres",method="query"}`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("metrics output missing %s", e)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package planner
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 
+	"udv/internal/config"
 	"udv/internal/dsl"
+	"udv/internal/limits"
 	"udv/internal/schema"
 )
 
@@ -35,6 +39,15 @@ const (
 	TypeUUID      FieldType = "uuid"
 	TypeJSON      FieldType = "json"
 	TypeBinary    FieldType = "binary"
+
+	// Duration type, kept apart from the date/time types
+	TypeInterval FieldType = "interval"
+
+	// Network and document types
+	TypeInet    FieldType = "inet"
+	TypeCIDR    FieldType = "cidr"
+	TypeMacAddr FieldType = "macaddr"
+	TypeXML     FieldType = "xml"
 )
 
 // ColumnRef represents a resolved column reference
@@ -42,6 +55,19 @@ type ColumnRef struct {
 	TableAlias string
 	ColumnName string
 	DataType   FieldType
+
+	// CaseInsensitive marks string columns whose =, !=, in, not_in and starts_with ignore case
+	CaseInsensitive bool
+
+	// PrimaryKey marks the model's primary key column
+	PrimaryKey bool
+
+	// Array marks a PostgreSQL array column of DataType elements
+	Array bool
+
+	// Virtual is set for a computed field, which builders render from its expression and
+	// return under ColumnName; it only appears in selects
+	Virtual *config.VirtualField
 }
 
 // SelectExpr represents a column in the SELECT clause
@@ -72,6 +98,13 @@ type JoinPlan struct {
 	ToTable   string
 	ToAlias   string
 	On        JoinCondition
+	Relation  string // Relation name; joined rows are nested under it
+	Unwind    bool   // Flatten the joined rows into a single object
+
+	HiddenFields []string // Fields of the joined model that must not be returned
+
+	// Virtual lists the computed fields of the joined model
+	Virtual []ColumnRef
 }
 
 // FilterExpr is the interface for filter expressions in IR
@@ -84,6 +117,17 @@ type ComparisonFilterIR struct {
 	Left     ColumnRef
 	Operator dsl.FilterOperator
 	Value    *ValueExpr
+
+	// Right is set instead of Value when Left is compared to another column
+	Right *ColumnRef
+
+	// Subquery is set instead of Value for in/not_in against another model's rows. It is a
+	// distinct plan selecting a single column under the alias s0.
+	Subquery *QueryPlan
+
+	// ExcludeLow and ExcludeHigh leave the ends out of a between range, which is closed by default
+	ExcludeLow  bool
+	ExcludeHigh bool
 }
 
 func (c *ComparisonFilterIR) isFilterExpr() {}
@@ -96,6 +140,31 @@ type LogicalFilterIR struct {
 
 func (l *LogicalFilterIR) isFilterExpr() {}
 
+// SearchFilterIR represents a full-text search over a model's search fields
+type SearchFilterIR struct {
+	Columns []ColumnRef
+	Query   string
+}
+
+func (s *SearchFilterIR) isFilterExpr() {}
+
+// ElemMatchFilterIR matches rows where one element of an array column satisfies all Conditions.
+// Condition columns name keys of the element and carry no table alias or type.
+type ElemMatchFilterIR struct {
+	Column     ColumnRef
+	Conditions []*ComparisonFilterIR
+}
+
+func (e *ElemMatchFilterIR) isFilterExpr() {}
+
+// JSONUpdate sets the key at Path inside a json column to Value, or deletes it when Remove is set
+type JSONUpdate struct {
+	Column string
+	Path   []string
+	Value  interface{}
+	Remove bool
+}
+
 // ValueExpr represents a strongly typed value
 type ValueExpr struct {
 	Value any
@@ -105,6 +174,11 @@ type ValueExpr struct {
 // GroupExpr represents a GROUP BY expression
 type GroupExpr struct {
 	Column ColumnRef
+
+	// Granularity truncates a timestamp column to a time bucket (day, month, ...) returned
+	// under Alias; empty groups by the column itself
+	Granularity string
+	Alias       string
 }
 
 // AggregateFn represents an aggregate function
@@ -116,6 +190,8 @@ const (
 	AggAvgFn   AggregateFn = "AVG"
 	AggMinFn   AggregateFn = "MIN"
 	AggMaxFn   AggregateFn = "MAX"
+
+	AggCountDistinctFn AggregateFn = "COUNT_DISTINCT"
 )
 
 // AggregateExpr represents an aggregate function in IR
@@ -123,6 +199,19 @@ type AggregateExpr struct {
 	Function AggregateFn
 	Column   *ColumnRef
 	Alias    string
+
+	// Expr is set instead of Function for an aggregate computed from other aggregates
+	Expr *ArithExpr
+}
+
+// ArithExpr is arithmetic over aggregates: a number, an aggregate, or an operator applied
+// to two operands. Division by zero yields null.
+type ArithExpr struct {
+	Op        string // "+", "-", "*" or "/"; empty for a leaf
+	Left      *ArithExpr
+	Right     *ArithExpr
+	Aggregate *AggregateExpr // Referenced aggregate, for an aggregate leaf
+	Value     float64        // Number, for a leaf without Aggregate
 }
 
 // SortTarget represents what we're sorting by
@@ -131,6 +220,9 @@ type SortTarget string
 const (
 	SortColumn    SortTarget = "COLUMN"
 	SortAggregate SortTarget = "AGGREGATE"
+	SortScore     SortTarget = "SCORE" // Full-text search relevance
+	SortBucket    SortTarget = "BUCKET" // Time bucket alias from group_by_time
+	SortSelect    SortTarget = "SELECT" // Alias of a renamed selected field
 )
 
 // SortExpr represents a sort specification
@@ -138,15 +230,39 @@ type SortExpr struct {
 	Target    SortTarget
 	Column    *ColumnRef
 	Aggregate *AggregateExpr
-	Direction string // "ASC", "DESC"
+	Search    *SearchFilterIR // Set for SortScore
+	Direction string          // "ASC", "DESC"
+
+	// Bucket is the time bucket group sorted by, set for SortBucket
+	Bucket *GroupExpr
+	// Select is the renamed selected field sorted by, set for SortSelect
+	Select *SelectExpr
 }
 
-// Pagination represents pagination parameters
+// Pagination represents pagination parameters. A zero Limit means no limit.
 type Pagination struct {
 	Limit  int
 	Offset int
 }
 
+// Validate rejects negative bounds, which databases refuse with opaque errors
+func (p Pagination) Validate() error {
+	if p.Limit < 0 {
+		return fmt.Errorf("pagination limit must be non-negative, got %d", p.Limit)
+	}
+	if p.Offset < 0 {
+		return fmt.Errorf("pagination offset must be non-negative, got %d", p.Offset)
+	}
+	return nil
+}
+
+// LockClause represents a row-locking clause in IR
+type LockClause struct {
+	Strength   string // "UPDATE", "SHARE"
+	SkipLocked bool
+	NoWait     bool
+}
+
 // QueryPlan represents the complete query plan IR
 type QueryPlan struct {
 	Operation  dsl.Operation         // NEW: Operation type
@@ -160,6 +276,44 @@ type QueryPlan struct {
 	Pagination Pagination
 	Data       map[string]interface{} // NEW: For create/update operations
 	ID         interface{}            // NEW: For update/delete operations
+	Lock       *LockClause            // Row locking, only set for transactional selects
+
+	// SoftDelete is the soft-delete column of the root model. When set, deleted rows are
+	// excluded and deletes mark rows instead of removing them.
+	SoftDelete *ColumnRef
+
+	// CreatedAt and UpdatedAt are timestamp columns the builder fills with the current
+	// time when the client did not supply them. Only set for create and update.
+	CreatedAt *ColumnRef
+	UpdatedAt *ColumnRef
+
+	// Update operators applied alongside Data: numeric increments, array appends,
+	// and whether null values in Data remove the field instead of storing null
+	Increment  map[string]interface{}
+	Push       map[string]interface{}
+	UnsetNulls bool
+
+	// JSONUpdates change keys inside json columns without replacing the whole value.
+	// They never touch columns assigned through Data.
+	JSONUpdates []JSONUpdate
+
+	// Returning lists the columns sent back after create and update; empty means all
+	// selectable columns. Deletes only return rows when it is set.
+	Returning []ColumnRef
+
+	// AllowFullTable lets an update or delete without id or filters affect every row
+	AllowFullTable bool
+
+	// Upsert inserts a row when an update matches none
+	Upsert bool
+
+	// Version is the root model's version column, incremented by every update. With
+	// ExpectedVersion set, only rows still holding that version are updated.
+	Version         *ColumnRef
+	ExpectedVersion interface{}
+
+	// Collation orders string sorts by locale (select only), nil for the database default
+	Collation *config.Collation
 }
 
 // ModelRef represents a model in the query plan
@@ -168,6 +322,11 @@ type ModelRef struct {
 	Table      string
 	Alias      string
 	PrimaryKey ColumnRef
+
+	Columns      []ColumnRef // Selectable columns in declaration order
+	HiddenFields []string    // Fields that must not be returned
+
+	ReadOnly bool // Writes are rejected, e.g. for a model backed by a view
 }
 
 // Planner converts DSL queries into execution plans
@@ -180,8 +339,41 @@ func NewPlanner(reg *schema.Registry) *Planner {
 	return &Planner{registry: reg}
 }
 
-// PlanQuery converts a validated DSL query into a QueryPlan IR
+// PlanQuery converts a validated DSL query into a QueryPlan IR.
+// Row locking is rejected because a lock is released as soon as the
+// single statement completes; use PlanQueryInTransaction instead.
 func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
+	if q != nil && q.Lock != nil {
+		return nil, fmt.Errorf("row locking requires a transaction")
+	}
+	return p.planQuery(q)
+}
+
+// PlanQueryInTransaction plans a query that runs inside an explicit transaction,
+// where row locking is permitted
+func (p *Planner) PlanQueryInTransaction(q *dsl.Query) (*QueryPlan, error) {
+	plan, err := p.planQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Lock != nil {
+		strength := "UPDATE"
+		if q.Lock.Mode == dsl.LockForShare {
+			strength = "SHARE"
+		}
+		plan.Lock = &LockClause{
+			Strength:   strength,
+			SkipLocked: q.Lock.SkipLocked,
+			NoWait:     q.Lock.NoWait,
+		}
+	}
+
+	return plan, nil
+}
+
+// planQuery builds the QueryPlan IR shared by all planning entry points
+func (p *Planner) planQuery(q *dsl.Query) (*QueryPlan, error) {
 	if q == nil {
 		return nil, fmt.Errorf("query is nil")
 	}
@@ -200,12 +392,19 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 		Sort:       []SortExpr{},
 		Data:       q.Data, // NEW: Pass data for create/update
 		ID:         q.ID,   // NEW: Pass id for update/delete
+
+		Increment:  q.Increment,
+		Push:       q.Push,
+		UnsetNulls: q.UnsetNulls,
+
+		AllowFullTable: q.AllowFullTable,
+		Upsert:         q.Upsert,
 	}
 
 	// 1. Create root model reference
 	model := p.registry.GetModel(q.Model)
 	if model == nil {
-		return nil, fmt.Errorf("model not found: %s", q.Model)
+		return nil, fmt.Errorf("%w: %s", dsl.ErrModelNotFound, q.Model)
 	}
 
 	rootPrimaryKey := p.schemaFieldToColumnRef(model.Name, model.PrimaryKey, "t0")
@@ -214,12 +413,117 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 		Table:      model.Table,
 		Alias:      "t0",
 		PrimaryKey: rootPrimaryKey,
+		ReadOnly:   model.ReadOnly,
+	}
+	plan.RootModel.Columns, plan.RootModel.HiddenFields = p.modelColumns(model, "t0")
+
+	// Stripped nulls leave columns to their defaults (insert) or unchanged (update).
+	// An explicit unset_nulls request takes precedence over the model default.
+	stripNulls := model.StripNulls && !q.UnsetNulls
+	if q.StripNulls != nil {
+		stripNulls = *q.StripNulls
+	}
+	if stripNulls && (operation == dsl.OpCreate || operation == dsl.OpUpdate) {
+		plan.Data = withoutNulls(q.Data)
+	}
+
+	// Generated primary keys only fill a missing or null id; client-supplied ids are kept
+	if model.GenerateUUID && operation == dsl.OpCreate && plan.Data[model.PrimaryKey] == nil {
+		id, err := NewUUID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate primary key: %w", err)
+		}
+		data := make(map[string]interface{}, len(plan.Data)+1)
+		for field, value := range plan.Data {
+			data[field] = value
+		}
+		data[model.PrimaryKey] = id
+		plan.Data = data
+	}
+
+	// Typed strings such as "10" are converted first, so every backend stores the declared type
+	data, err := coerceData(model, plan.Data)
+	if err != nil {
+		return nil, err
+	}
+	// Builders write columns, so written values are keyed by column rather than field name
+	data, err = resolveValueExprs(columnKeys(model, data))
+	if err != nil {
+		return nil, err
+	}
+	plan.Data = data
+	plan.Increment = columnKeys(model, plan.Increment)
+	plan.Push = columnKeys(model, plan.Push)
+
+	// Soft-deleted rows are hidden from everything except inserts and explicit include_deleted reads
+	if model.SoftDeleteField != "" && operation != dsl.OpCreate && !q.IncludeDeleted {
+		colRef := p.schemaFieldToColumnRef(model.Name, model.SoftDeleteField, "t0")
+		plan.SoftDelete = &colRef
+	}
+
+	if model.CreatedAtField != "" && operation == dsl.OpCreate {
+		colRef := p.schemaFieldToColumnRef(model.Name, model.CreatedAtField, "t0")
+		plan.CreatedAt = &colRef
+	}
+	if model.UpdatedAtField != "" && (operation == dsl.OpCreate || operation == dsl.OpUpdate) {
+		colRef := p.schemaFieldToColumnRef(model.Name, model.UpdatedAtField, "t0")
+		plan.UpdatedAt = &colRef
+	}
+
+	if model.VersionField != "" && operation == dsl.OpUpdate {
+		colRef := p.schemaFieldToColumnRef(model.Name, model.VersionField, "t0")
+		plan.Version = &colRef
+		plan.ExpectedVersion = q.ExpectedVersion
+	}
+
+	if operation.Selects() {
+		plan.Collation = model.Collation
+		if q.Collation != nil {
+			plan.Collation = q.Collation
+		}
+	}
+
+	for _, field := range q.Returning {
+		plan.Returning = append(plan.Returning, p.schemaFieldToColumnRef(model.Name, field, "t0"))
+	}
+
+	if operation == dsl.OpUpdate {
+		jsonUpdates, err := p.planJSONUpdates(model.Name, q)
+		if err != nil {
+			return nil, err
+		}
+		plan.JSONUpdates = jsonUpdates
 	}
 
 	// For create/update/delete operations, we can skip some planning steps
 	if operation == dsl.OpCreate || operation == dsl.OpUpdate || operation == dsl.OpDelete {
 		// Set default pagination for mutation operations
 		plan.Pagination = Pagination{Limit: 1, Offset: 0}
+
+		// Update and delete may target rows by filter instead of id
+		if operation != dsl.OpCreate && q.Filters != nil {
+			filterIR, err := p.convertFilterExpr(model.Name, "t0", q.Filters, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert filters: %w", err)
+			}
+			plan.Filters = filterIR
+		}
+		return plan, nil
+	}
+
+	// Count and exists only need the WHERE clause, and distinct adds its single column
+	if operation == dsl.OpCount || operation == dsl.OpExists || operation == dsl.OpDistinct {
+		if operation == dsl.OpDistinct && len(q.Fields) == 1 {
+			colRef := p.schemaFieldToColumnRef(model.Name, q.Fields[0], "t0")
+			plan.Select = []SelectExpr{{Column: colRef, Alias: colRef.ColumnName}}
+		}
+		if q.Filters != nil {
+			filterIR, err := p.convertFilterExpr(model.Name, "t0", q.Filters, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert filters: %w", err)
+			}
+			plan.Filters = filterIR
+		}
 		return plan, nil
 	}
 
@@ -227,9 +531,13 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 	if len(q.Fields) > 0 {
 		for _, field := range q.Fields {
 			colRef := p.schemaFieldToColumnRef(model.Name, field, "t0")
+			alias := colRef.ColumnName
+			if renamed, ok := q.FieldAliases[field]; ok {
+				alias = renamed
+			}
 			plan.Select = append(plan.Select, SelectExpr{
 				Column:      colRef,
-				Alias:       field,
+				Alias:       alias,
 				IsAggregate: false,
 			})
 		}
@@ -237,7 +545,7 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 
 	// 3. Process WHERE filters
 	if q.Filters != nil {
-		filterIR, err := p.convertFilterExpr(model.Name, "t0", q.Filters)
+		filterIR, err := p.convertFilterExpr(model.Name, "t0", q.Filters, 0)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert filters: %w", err)
 		}
@@ -251,6 +559,13 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 			plan.GroupBy = append(plan.GroupBy, GroupExpr{Column: colRef})
 		}
 	}
+	for _, bucket := range q.GroupByTime {
+		plan.GroupBy = append(plan.GroupBy, GroupExpr{
+			Column:      p.schemaFieldToColumnRef(model.Name, bucket.Field, "t0"),
+			Granularity: bucket.Granularity,
+			Alias:       bucket.Name(),
+		})
+	}
 
 	// 5. Process AGGREGATES
 	if len(q.Aggregates) > 0 {
@@ -261,6 +576,12 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 				colRef = &ref
 			}
 
+			if agg.Expr != "" {
+				// Filled in below, once every aggregate it may reference is planned
+				plan.Aggregates = append(plan.Aggregates, AggregateExpr{Alias: agg.Alias})
+				continue
+			}
+
 			aggFn := p.dslAggToIRAgg(agg.Function)
 			plan.Aggregates = append(plan.Aggregates, AggregateExpr{
 				Function: aggFn,
@@ -268,6 +589,41 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 				Alias:    agg.Alias,
 			})
 		}
+
+		byAlias := make(map[string]*AggregateExpr, len(plan.Aggregates))
+		for i := range plan.Aggregates {
+			byAlias[plan.Aggregates[i].Alias] = &plan.Aggregates[i]
+		}
+		for i, agg := range q.Aggregates {
+			if agg.Expr == "" {
+				continue
+			}
+			parsed, err := dsl.ParseArithExpr(agg.Expr)
+			if err != nil {
+				return nil, fmt.Errorf("aggregate %s: %v", agg.Alias, err)
+			}
+			expr, err := arithToIR(parsed, byAlias)
+			if err != nil {
+				return nil, fmt.Errorf("aggregate %s: %v", agg.Alias, err)
+			}
+			plan.Aggregates[i].Expr = expr
+		}
+	}
+
+	// Selected columns must be grouped when the query aggregates, as in SQL
+	if len(plan.GroupBy) > 0 || len(plan.Aggregates) > 0 {
+		grouped := make(map[string]bool, len(plan.GroupBy))
+		for _, group := range plan.GroupBy {
+			// A time bucket groups the truncated value, not the column itself
+			if group.Granularity == "" {
+				grouped[group.Column.ColumnName] = true
+			}
+		}
+		for _, sel := range plan.Select {
+			if !grouped[sel.Column.ColumnName] {
+				return nil, fmt.Errorf("selected field %s must appear in group_by or be aggregated", sel.Alias)
+			}
+		}
 	}
 
 	// 6. Process SORT
@@ -278,6 +634,49 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 				direction = "DESC"
 			}
 
+			if sort.Field == dsl.SearchScoreField && !p.registry.FieldExists(model.Name, sort.Field) {
+				search := findSearch(plan.Filters)
+				if search == nil {
+					return nil, fmt.Errorf("sorting by %s requires a search filter", dsl.SearchScoreField)
+				}
+				if len(plan.GroupBy) > 0 || len(plan.Aggregates) > 0 {
+					return nil, fmt.Errorf("sorting by %s is not supported with aggregation", dsl.SearchScoreField)
+				}
+				plan.Sort = append(plan.Sort, SortExpr{
+					Target:    SortScore,
+					Search:    search,
+					Direction: direction,
+				})
+				continue
+			}
+
+			// Aliases take precedence over model fields, as in SQL ORDER BY
+			if sel := findSelectAlias(plan.Select, sort.Field); sel != nil {
+				plan.Sort = append(plan.Sort, SortExpr{
+					Target:    SortSelect,
+					Column:    &sel.Column,
+					Select:    sel,
+					Direction: direction,
+				})
+				continue
+			}
+			if bucket := findBucket(plan.GroupBy, sort.Field); bucket != nil {
+				plan.Sort = append(plan.Sort, SortExpr{
+					Target:    SortBucket,
+					Bucket:    bucket,
+					Direction: direction,
+				})
+				continue
+			}
+			if agg := findAggregate(plan.Aggregates, sort.Field); agg != nil {
+				plan.Sort = append(plan.Sort, SortExpr{
+					Target:    SortAggregate,
+					Aggregate: agg,
+					Direction: direction,
+				})
+				continue
+			}
+
 			colRef := p.schemaFieldToColumnRef(model.Name, sort.Field, "t0")
 			plan.Sort = append(plan.Sort, SortExpr{
 				Target:    SortColumn,
@@ -287,11 +686,52 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 		}
 	}
 
+	// Without an order, rows may move between pages; the primary key gives a unique one
+	if len(q.Sort) == 0 && q.Pagination != nil && model.DefaultSort && len(plan.GroupBy) == 0 && len(plan.Aggregates) == 0 {
+		colRef := p.schemaFieldToColumnRef(model.Name, model.PrimaryKey, "t0")
+		plan.Sort = append(plan.Sort, SortExpr{
+			Target:    SortColumn,
+			Column:    &colRef,
+			Direction: "ASC",
+		})
+	}
+
+	// First and last take the single row at either end of the primary key order
+	if operation.SingleRow() {
+		direction := "ASC"
+		if operation == dsl.OpLast {
+			direction = "DESC"
+		}
+		colRef := p.schemaFieldToColumnRef(model.Name, model.PrimaryKey, "t0")
+		plan.Sort = append(plan.Sort, SortExpr{
+			Target:    SortColumn,
+			Column:    &colRef,
+			Direction: direction,
+		})
+	}
+
 	// 7. Process PAGINATION
-	if q.Pagination != nil {
-		plan.Pagination = Pagination{
-			Limit:  q.Pagination.Limit,
-			Offset: q.Pagination.Offset,
+	if operation.SingleRow() {
+		plan.Pagination = Pagination{Limit: 1}
+	} else if q.Pagination != nil {
+		pagination, err := planPagination(q.Pagination)
+		if err != nil {
+			return nil, err
+		}
+		plan.Pagination = pagination
+
+		// A cursor page starts after the previous page's last row
+		if after := q.Pagination.After; after != nil {
+			keyset, err := p.keysetFilter(model, after)
+			if err != nil {
+				return nil, err
+			}
+			if plan.Filters != nil {
+				keyset = &LogicalFilterIR{Op: "AND", Nodes: []FilterExpr{plan.Filters, keyset}}
+			}
+			plan.Filters = keyset
+		} else if q.Pagination.Cursor != "" {
+			return nil, fmt.Errorf("pagination cursor was not verified")
 		}
 	} else {
 		// Default pagination
@@ -301,17 +741,41 @@ func (p *Planner) PlanQuery(q *dsl.Query) (*QueryPlan, error) {
 		}
 	}
 
+	// 8. Process relation INCLUDES
+	if len(q.Include) > 0 {
+		aliasCount := 0
+		if err := p.planIncludes(model.Name, "t0", q.Include, &aliasCount, plan); err != nil {
+			return nil, fmt.Errorf("failed to plan includes: %w", err)
+		}
+	}
+
 	return plan, nil
 }
 
-// convertFilterExpr recursively converts a DSL filter to IR format
-func (p *Planner) convertFilterExpr(modelName, tableAlias string, expr dsl.FilterExpr) (FilterExpr, error) {
+// convertFilterExpr recursively converts a DSL filter to IR format.
+// depth is the number of logical filters enclosing expr.
+func (p *Planner) convertFilterExpr(modelName, tableAlias string, expr dsl.FilterExpr, depth int) (FilterExpr, error) {
 	switch e := expr.(type) {
 	case *dsl.ComparisonFilter:
+		if e == nil {
+			return nil, fmt.Errorf("filter condition is empty")
+		}
+		if e.Op == dsl.OpSearch {
+			return p.convertSearchFilter(modelName, tableAlias, e)
+		}
 		return p.convertComparisonFilter(modelName, tableAlias, e)
 
 	case *dsl.LogicalFilter:
-		return p.convertLogicalFilter(modelName, tableAlias, e)
+		if e == nil {
+			return nil, fmt.Errorf("filter condition is empty")
+		}
+		return p.convertLogicalFilter(modelName, tableAlias, e, depth+1)
+
+	case *dsl.ElemMatchFilter:
+		if e == nil {
+			return nil, fmt.Errorf("filter condition is empty")
+		}
+		return p.convertElemMatchFilter(modelName, tableAlias, e)
 
 	default:
 		return nil, fmt.Errorf("unknown filter expression type")
@@ -322,6 +786,23 @@ func (p *Planner) convertFilterExpr(modelName, tableAlias string, expr dsl.Filte
 func (p *Planner) convertComparisonFilter(modelName, tableAlias string, f *dsl.ComparisonFilter) (*ComparisonFilterIR, error) {
 	colRef := p.schemaFieldToColumnRef(modelName, f.Field, tableAlias)
 
+	// PostgreSQL array columns are typed by their elements, so size skips the type check
+	if f.Op != dsl.OpSize || !colRef.Array {
+		if err := validateOperator(f.Op, f.Field, colRef.DataType); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.ValueField != "" {
+		return p.convertFieldComparison(modelName, tableAlias, f, colRef)
+	}
+	if f.Subquery != nil {
+		return p.convertSubquery(f, colRef)
+	}
+	if err := validatePattern(f.Op, f.Field, f.Value); err != nil {
+		return nil, err
+	}
+
 	var valueExpr *ValueExpr
 	if f.Op != dsl.OpIsNull && f.Op != dsl.OpNotNull {
 		valueExpr = &ValueExpr{
@@ -330,40 +811,207 @@ func (p *Planner) convertComparisonFilter(modelName, tableAlias string, f *dsl.C
 		}
 	}
 
-	return &ComparisonFilterIR{
+	filter := &ComparisonFilterIR{
 		Left:     colRef,
 		Operator: f.Op,
 		Value:    valueExpr,
+	}
+	setBetweenBounds(filter, f)
+	return filter, nil
+}
+
+// setBetweenBounds carries a between filter's bounds into the IR; the validator has checked them
+func setBetweenBounds(filter *ComparisonFilterIR, f *dsl.ComparisonFilter) {
+	low, high, _ := dsl.ParseBounds(f.Bounds)
+	filter.ExcludeLow, filter.ExcludeHigh = !low, !high
+}
+
+// convertFieldComparison converts a filter comparing two columns of the same model to IR
+func (p *Planner) convertFieldComparison(modelName, tableAlias string, f *dsl.ComparisonFilter, left ColumnRef) (*ComparisonFilterIR, error) {
+	if !dsl.IsFieldComparisonOp(f.Op) {
+		return nil, fmt.Errorf("operator %s cannot compare field %s to value_field", f.Op, f.Field)
+	}
+	if !p.registry.FieldExists(modelName, f.ValueField) {
+		return nil, fmt.Errorf("value_field %s not found in model %s", f.ValueField, modelName)
+	}
+
+	right := p.schemaFieldToColumnRef(modelName, f.ValueField, tableAlias)
+	if err := validateOperator(f.Op, f.ValueField, right.DataType); err != nil {
+		return nil, err
+	}
+	if !comparableTypes(left.DataType, right.DataType) {
+		return nil, fmt.Errorf("cannot compare field %s of type %s to field %s of type %s", f.Field, left.DataType, f.ValueField, right.DataType)
+	}
+
+	return &ComparisonFilterIR{
+		Left:     left,
+		Operator: f.Op,
+		Right:    &right,
+	}, nil
+}
+
+// subqueryAlias is the table alias inside a subquery; subqueries do not nest, so one suffices
+const subqueryAlias = "s0"
+
+// convertSubquery converts an in/not_in filter against another model's rows to IR. The nested
+// plan selects the subquery field from rows matching its filters, skipping soft-deleted ones.
+func (p *Planner) convertSubquery(f *dsl.ComparisonFilter, left ColumnRef) (*ComparisonFilterIR, error) {
+	if f.Op != dsl.OpIn && f.Op != dsl.OpNotIn {
+		return nil, fmt.Errorf("subquery on %s requires the in or not_in operator, got %s", f.Field, f.Op)
+	}
+
+	sub := f.Subquery
+	model := p.registry.GetModel(sub.Model)
+	if model == nil {
+		return nil, fmt.Errorf("%w: %s", dsl.ErrModelNotFound, sub.Model)
+	}
+	if !p.registry.FieldExists(model.Name, sub.Field) {
+		return nil, fmt.Errorf("subquery field %s not found in model %s", sub.Field, model.Name)
+	}
+	right := p.schemaFieldToColumnRef(model.Name, sub.Field, subqueryAlias)
+	if !comparableTypes(left.DataType, right.DataType) {
+		return nil, fmt.Errorf("cannot compare field %s of type %s to subquery field %s of type %s", f.Field, left.DataType, sub.Field, right.DataType)
+	}
+
+	plan := &QueryPlan{
+		Operation: dsl.OpDistinct,
+		RootModel: &ModelRef{
+			Name:       model.Name,
+			Table:      model.Table,
+			Alias:      subqueryAlias,
+			PrimaryKey: p.schemaFieldToColumnRef(model.Name, model.PrimaryKey, subqueryAlias),
+		},
+		Select: []SelectExpr{{Column: right, Alias: right.ColumnName}},
+	}
+	if model.SoftDeleteField != "" {
+		colRef := p.schemaFieldToColumnRef(model.Name, model.SoftDeleteField, subqueryAlias)
+		plan.SoftDelete = &colRef
+	}
+	if sub.Filters != nil {
+		if dsl.HasSubquery(sub.Filters) {
+			return nil, fmt.Errorf("subqueries cannot be nested")
+		}
+		filterIR, err := p.convertFilterExpr(model.Name, subqueryAlias, sub.Filters, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert subquery filters: %w", err)
+		}
+		plan.Filters = filterIR
+	}
+
+	return &ComparisonFilterIR{
+		Left:     left,
+		Operator: f.Op,
+		Subquery: plan,
 	}, nil
 }
 
-// convertLogicalFilter converts a DSL logical filter to IR
-func (p *Planner) convertLogicalFilter(modelName, tableAlias string, f *dsl.LogicalFilter) (*LogicalFilterIR, error) {
+// comparableTypes reports whether columns of types a and b can be compared directly:
+// the same type, two numeric types, or two date/time types
+func comparableTypes(a, b FieldType) bool {
+	if a == b {
+		return true
+	}
+	numeric := map[FieldType]bool{TypeInteger: true, TypeInt: true, TypeFloat: true, TypeDecimal: true}
+	temporal := map[FieldType]bool{TypeTimestamp: true, TypeDateTime: true, TypeDate: true}
+	return (numeric[a] && numeric[b]) || (temporal[a] && temporal[b])
+}
+
+// convertElemMatchFilter converts a DSL element-match filter to IR
+func (p *Planner) convertElemMatchFilter(modelName, tableAlias string, f *dsl.ElemMatchFilter) (*ElemMatchFilterIR, error) {
+	if len(f.ElemMatch) == 0 {
+		return nil, fmt.Errorf("elem_match on %s needs at least one condition", f.Field)
+	}
+
+	elemMatch := &ElemMatchFilterIR{Column: p.schemaFieldToColumnRef(modelName, f.Field, tableAlias)}
+	for _, cond := range f.ElemMatch {
+		if cond == nil || cond.Field == "" {
+			return nil, fmt.Errorf("elem_match condition field is required")
+		}
+		if cond.Op == dsl.OpSearch {
+			return nil, fmt.Errorf("search is not allowed inside elem_match")
+		}
+		if cond.ValueField != "" {
+			return nil, fmt.Errorf("value_field is not allowed inside elem_match")
+		}
+		if cond.Subquery != nil {
+			return nil, fmt.Errorf("subquery is not allowed inside elem_match")
+		}
+		if err := validateOperator(cond.Op, cond.Field, ""); err != nil {
+			return nil, err
+		}
+
+		if err := validatePattern(cond.Op, cond.Field, cond.Value); err != nil {
+			return nil, err
+		}
+
+		condIR := &ComparisonFilterIR{Left: ColumnRef{ColumnName: cond.Field}, Operator: cond.Op}
+		if cond.Op != dsl.OpIsNull && cond.Op != dsl.OpNotNull {
+			condIR.Value = &ValueExpr{Value: cond.Value}
+		}
+		setBetweenBounds(condIR, cond)
+		elemMatch.Conditions = append(elemMatch.Conditions, condIR)
+	}
+	return elemMatch, nil
+}
+
+// convertSearchFilter converts a DSL search filter to IR over the model's search fields
+func (p *Planner) convertSearchFilter(modelName, tableAlias string, f *dsl.ComparisonFilter) (*SearchFilterIR, error) {
+	model := p.registry.GetModel(modelName)
+	if model == nil || len(model.SearchFields) == 0 {
+		return nil, fmt.Errorf("model %s has no search fields configured", modelName)
+	}
+
+	query, ok := f.Value.(string)
+	if !ok {
+		return nil, fmt.Errorf("search filter value must be a string")
+	}
+
+	search := &SearchFilterIR{Query: query}
+	for _, field := range model.SearchFields {
+		search.Columns = append(search.Columns, p.schemaFieldToColumnRef(modelName, field, tableAlias))
+	}
+	return search, nil
+}
+
+// convertLogicalFilter converts a DSL logical filter at the given nesting depth to IR,
+// enforcing operator arity and limits.MaxFilterDepth
+func (p *Planner) convertLogicalFilter(modelName, tableAlias string, f *dsl.LogicalFilter, depth int) (*LogicalFilterIR, error) {
+	if depth > limits.MaxFilterDepth {
+		return nil, fmt.Errorf("filter nesting exceeds maximum depth of %d", limits.MaxFilterDepth)
+	}
+
+	ops := 0
+	for _, set := range []bool{f.And != nil, f.Or != nil, f.Not != nil} {
+		if set {
+			ops++
+		}
+	}
+	if ops != 1 {
+		return nil, fmt.Errorf("logical filter must set exactly one of and, or, not")
+	}
+
 	logicalIR := &LogicalFilterIR{
 		Nodes: []FilterExpr{},
 	}
 
-	if len(f.And) > 0 {
+	var children []dsl.FilterExpr
+	switch {
+	case f.And != nil:
 		logicalIR.Op = "AND"
-		for _, cond := range f.And {
-			irCond, err := p.convertComparisonFilter(modelName, tableAlias, cond)
-			if err != nil {
-				return nil, err
-			}
-			logicalIR.Nodes = append(logicalIR.Nodes, irCond)
-		}
-	} else if len(f.Or) > 0 {
+		children = f.And
+	case f.Or != nil:
 		logicalIR.Op = "OR"
-		for _, cond := range f.Or {
-			irCond, err := p.convertComparisonFilter(modelName, tableAlias, cond)
-			if err != nil {
-				return nil, err
-			}
-			logicalIR.Nodes = append(logicalIR.Nodes, irCond)
-		}
-	} else if f.Not != nil {
+		children = f.Or
+	default:
 		logicalIR.Op = "NOT"
-		irCond, err := p.convertComparisonFilter(modelName, tableAlias, f.Not)
+		children = []dsl.FilterExpr{f.Not}
+	}
+	if len(children) == 0 {
+		return nil, fmt.Errorf("%s filter requires at least one condition", logicalIR.Op)
+	}
+
+	for _, cond := range children {
+		irCond, err := p.convertFilterExpr(modelName, tableAlias, cond, depth)
 		if err != nil {
 			return nil, err
 		}
@@ -373,6 +1021,509 @@ func (p *Planner) convertLogicalFilter(modelName, tableAlias string, f *dsl.Logi
 	return logicalIR, nil
 }
 
+// planIncludes recursively converts relation includes into LEFT joins.
+// Each joined model gets the next tN alias, and nested includes join from it.
+func (p *Planner) planIncludes(modelName, fromAlias string, includes []dsl.Include, aliasCount *int, plan *QueryPlan) error {
+	for _, inc := range includes {
+		rel, err := p.registry.GetRelation(modelName, inc.Relation)
+		if err != nil {
+			return err
+		}
+
+		target := p.registry.GetModel(rel.TargetModel)
+		if target == nil {
+			return fmt.Errorf("%w: %s", dsl.ErrModelNotFound, rel.TargetModel)
+		}
+
+		*aliasCount++
+		toAlias := fmt.Sprintf("t%d", *aliasCount)
+		columns, hiddenFields := p.modelColumns(target, toAlias)
+
+		plan.Joins = append(plan.Joins, JoinPlan{
+			Type:      JoinLeft,
+			FromAlias: fromAlias,
+			ToTable:   target.Table,
+			ToAlias:   toAlias,
+			On: JoinCondition{
+				Left:  p.schemaFieldToColumnRef(modelName, rel.ForeignKey, fromAlias),
+				Right: p.schemaFieldToColumnRef(target.Name, rel.ReferenceKey, toAlias),
+			},
+			Relation: inc.Relation,
+			Unwind:   inc.Unwind,
+
+			HiddenFields: hiddenFields,
+			Virtual:      virtualColumns(columns),
+		})
+
+		if err := p.planIncludes(target.Name, toAlias, inc.Include, aliasCount, plan); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// operatorClass groups filter operators by the field types they accept
+type operatorClass int
+
+const (
+	opClassAny      operatorClass = iota // Any field type
+	opClassOrdered                       // Types with a meaningful ordering
+	opClassString                        // String fields only
+	opClassTemporal                      // Date/time fields only
+	opClassArray                         // Array fields only (json or PostgreSQL arrays)
+)
+
+// knownOperators is the complete set of filter operators builders may receive
+var knownOperators = map[dsl.FilterOperator]operatorClass{
+	dsl.OpEqual:      opClassAny,
+	dsl.OpNotEqual:   opClassAny,
+	dsl.OpIn:         opClassAny,
+	dsl.OpNotIn:      opClassAny,
+	dsl.OpIsNull:     opClassAny,
+	dsl.OpNotNull:    opClassAny,
+	dsl.OpGT:         opClassOrdered,
+	dsl.OpGTE:        opClassOrdered,
+	dsl.OpLT:         opClassOrdered,
+	dsl.OpLTE:        opClassOrdered,
+	dsl.OpBetween:    opClassOrdered,
+	dsl.OpLike:       opClassString,
+	dsl.OpILike:      opClassString,
+	dsl.OpStartsWith: opClassString,
+	dsl.OpEndsWith:   opClassString,
+	dsl.OpContains:   opClassString,
+	dsl.OpRegex:      opClassString,
+	dsl.OpIRegex:     opClassString,
+	dsl.OpBefore:     opClassTemporal,
+	dsl.OpAfter:      opClassTemporal,
+
+	dsl.OpNullSafeEqual: opClassAny,
+
+	dsl.OpSize: opClassArray,
+}
+
+// validateOperator checks that op is a known filter operator and is valid for the field type.
+// Fields with no resolved type only get the known-operator check.
+func validateOperator(op dsl.FilterOperator, fieldName string, fieldType FieldType) error {
+	class, ok := knownOperators[op]
+	if !ok {
+		return fmt.Errorf("unknown operator %q on field %s", op, fieldName)
+	}
+
+	if fieldType == "" {
+		return nil
+	}
+
+	valid := true
+	switch class {
+	case opClassOrdered:
+		valid = fieldType != TypeJSON && fieldType != TypeBinary && fieldType != TypeXML
+	case opClassString:
+		valid = fieldType == TypeString
+	case opClassTemporal:
+		valid = fieldType == TypeTimestamp || fieldType == TypeDateTime || fieldType == TypeDate || fieldType == TypeTime
+	case opClassArray:
+		valid = fieldType == TypeJSON
+	}
+
+	if !valid {
+		return fmt.Errorf("operator %q is not valid for field %s of type %s", op, fieldName, fieldType)
+	}
+	return nil
+}
+
+// planJSONUpdates converts json_set and json_remove paths into JSONUpdates. Sets come first
+// in path order, followed by removals in request order, so builders emit stable statements.
+func (p *Planner) planJSONUpdates(modelName string, q *dsl.Query) ([]JSONUpdate, error) {
+	setPaths := make([]string, 0, len(q.JSONSet))
+	for path := range q.JSONSet {
+		setPaths = append(setPaths, path)
+	}
+	sort.Strings(setPaths)
+
+	updates := make([]JSONUpdate, 0, len(setPaths)+len(q.JSONRemove))
+	add := func(path string, value interface{}, remove bool) error {
+		column, keys, err := dsl.SplitJSONPath(path)
+		if err != nil {
+			return err
+		}
+		colRef := p.schemaFieldToColumnRef(modelName, column, "t0")
+		if colRef.DataType != TypeJSON {
+			return fmt.Errorf("json path %s requires a json field", path)
+		}
+		updates = append(updates, JSONUpdate{Column: colRef.ColumnName, Path: keys, Value: value, Remove: remove})
+		return nil
+	}
+
+	for _, path := range setPaths {
+		if err := add(path, q.JSONSet[path], false); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range q.JSONRemove {
+		if err := add(path, nil, true); err != nil {
+			return nil, err
+		}
+	}
+	return updates, nil
+}
+
+// validatePattern checks that regex and iregex filters carry a non-empty string pattern
+func validatePattern(op dsl.FilterOperator, fieldName string, value interface{}) error {
+	if op != dsl.OpRegex && op != dsl.OpIRegex {
+		return nil
+	}
+	if pattern, ok := value.(string); !ok || pattern == "" {
+		return fmt.Errorf("%s operator on field %s requires a non-empty string pattern", op, fieldName)
+	}
+	return nil
+}
+
+// modelColumns splits a model's fields into selectable columns and hidden columns
+func (p *Planner) modelColumns(model *schema.Model, tableAlias string) ([]ColumnRef, []string) {
+	var columns []ColumnRef
+	var hidden []string
+	for _, name := range model.FieldOrder {
+		field := model.Fields[name]
+		if !field.Selectable {
+			hidden = append(hidden, field.Column)
+			continue
+		}
+		columns = append(columns, ColumnRef{
+			TableAlias: tableAlias,
+			ColumnName: field.Column,
+			DataType:   FieldType(field.Type),
+			Virtual:    field.Virtual,
+		})
+	}
+	return columns, hidden
+}
+
+// virtualColumns returns the computed fields among columns
+func virtualColumns(columns []ColumnRef) []ColumnRef {
+	var virtual []ColumnRef
+	for _, col := range columns {
+		if col.Virtual != nil {
+			virtual = append(virtual, col)
+		}
+	}
+	return virtual
+}
+
+// resolveValueExprs replaces tagged expression values such as {"$expr": "now()"} with their
+// dsl.ValueExpr, copying values only when one is present
+func resolveValueExprs(values map[string]interface{}) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	for name, value := range values {
+		text, ok := dsl.TaggedExpr(value)
+		if !ok {
+			continue
+		}
+		expr, err := dsl.ParseValueExpr(text)
+		if err != nil {
+			return nil, fmt.Errorf("data field %s: %v", name, err)
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(values))
+			for k, v := range values {
+				out[k] = v
+			}
+		}
+		out[name] = expr
+	}
+	if out == nil {
+		return values, nil
+	}
+	return out, nil
+}
+
+// coerceData converts written values, and the elements of array values, to their fields' declared
+// types with dsl.CoerceValue. Tagged expressions are left for resolveValueExprs.
+func coerceData(model *schema.Model, values map[string]interface{}) (map[string]interface{}, error) {
+	if len(values) == 0 {
+		return values, nil
+	}
+	out := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		field := model.Fields[name]
+		if _, tagged := dsl.TaggedExpr(value); field == nil || tagged {
+			out[name] = value
+			continue
+		}
+		coerced, err := coerceFieldValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for field %s: %w", name, err)
+		}
+		out[name] = coerced
+	}
+	return out, nil
+}
+
+// coerceFieldValue converts a scalar, or each element of an array field's value
+func coerceFieldValue(field *schema.Field, value interface{}) (interface{}, error) {
+	items, ok := value.([]interface{})
+	if !field.Array || !ok {
+		return dsl.CoerceValue(value, field.Type)
+	}
+	coerced := make([]interface{}, len(items))
+	for i, item := range items {
+		c, err := dsl.CoerceValue(item, field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		coerced[i] = c
+	}
+	return coerced, nil
+}
+
+// columnKeys returns values keyed by column instead of field name. Values is returned as is
+// when none of its fields is stored under a different column.
+func columnKeys(model *schema.Model, values map[string]interface{}) map[string]interface{} {
+	renamed := false
+	for name := range values {
+		if field := model.Fields[name]; field != nil && field.Column != name {
+			renamed = true
+			break
+		}
+	}
+	if !renamed {
+		return values
+	}
+
+	out := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if field := model.Fields[name]; field != nil {
+			name = field.Column
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// arithToIR converts a parsed aggregate expression, resolving its references to aggregates
+func arithToIR(expr *dsl.ArithExpr, byAlias map[string]*AggregateExpr) (*ArithExpr, error) {
+	if expr.Op == "" {
+		if expr.Ref == "" {
+			return &ArithExpr{Value: expr.Value}, nil
+		}
+		agg := byAlias[expr.Ref]
+		if agg == nil || agg.Expr != nil || agg.Function == "" {
+			return nil, fmt.Errorf("aggregate %s not found", expr.Ref)
+		}
+		return &ArithExpr{Aggregate: agg}, nil
+	}
+
+	left, err := arithToIR(expr.Left, byAlias)
+	if err != nil {
+		return nil, err
+	}
+	right, err := arithToIR(expr.Right, byAlias)
+	if err != nil {
+		return nil, err
+	}
+	return &ArithExpr{Op: expr.Op, Left: left, Right: right}, nil
+}
+
+// findAggregate returns the aggregate with the given alias, or nil
+func findAggregate(aggs []AggregateExpr, alias string) *AggregateExpr {
+	for i := range aggs {
+		if aggs[i].Alias == alias {
+			return &aggs[i]
+		}
+	}
+	return nil
+}
+
+// findSelectAlias returns the selected field renamed to alias, or nil
+func findSelectAlias(sel []SelectExpr, alias string) *SelectExpr {
+	for i := range sel {
+		if sel[i].Alias != sel[i].Column.ColumnName && sel[i].Alias == alias {
+			return &sel[i]
+		}
+	}
+	return nil
+}
+
+// keysetFilter matches the rows after a keyset position: pk > key, or, with a sort field,
+// field > value OR (field = value AND pk > key); descending orders compare with <.
+// The keyset holds values as the previous page returned them, e.g. timestamps as RFC 3339
+// strings, so they are coerced back to the columns' types to compare as stored.
+func (p *Planner) keysetFilter(model *schema.Model, after *dsl.Keyset) (FilterExpr, error) {
+	op := dsl.OpGT
+	if after.Direction == dsl.SortDesc {
+		op = dsl.OpLT
+	}
+
+	pk := p.schemaFieldToColumnRef(model.Name, model.PrimaryKey, "t0")
+	key, err := dsl.CoerceValue(after.Key, string(pk.DataType))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pagination cursor: %w", err)
+	}
+	byKey := &ComparisonFilterIR{Left: pk, Operator: op, Value: &ValueExpr{Value: key, Type: pk.DataType}}
+	if after.Field == "" {
+		return byKey, nil
+	}
+
+	col := p.schemaFieldToColumnRef(model.Name, after.Field, "t0")
+	coerced, err := dsl.CoerceValue(after.Value, string(col.DataType))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pagination cursor: %w", err)
+	}
+	value := &ValueExpr{Value: coerced, Type: col.DataType}
+	return &LogicalFilterIR{Op: "OR", Nodes: []FilterExpr{
+		&ComparisonFilterIR{Left: col, Operator: op, Value: value},
+		&LogicalFilterIR{Op: "AND", Nodes: []FilterExpr{
+			&ComparisonFilterIR{Left: col, Operator: dsl.OpEqual, Value: value},
+			byKey,
+		}},
+	}}, nil
+}
+
+// findBucket returns the time bucket group with the given alias, or nil
+func findBucket(groups []GroupExpr, alias string) *GroupExpr {
+	for i := range groups {
+		if groups[i].Granularity != "" && groups[i].Alias == alias {
+			return &groups[i]
+		}
+	}
+	return nil
+}
+
+// planPagination checks requested bounds against the configured limits, clamping
+// oversized limits when limits.ClampPageLimit is set. Page numbers become limit and offset.
+func planPagination(p *dsl.Pagination) (Pagination, error) {
+	if p.ByPage() {
+		byOffset, err := pageToOffset(p)
+		if err != nil {
+			return Pagination{}, err
+		}
+		p = byOffset
+	}
+
+	if p.Limit <= 0 {
+		return Pagination{}, fmt.Errorf("pagination limit must be greater than 0")
+	}
+	if p.Offset < 0 {
+		return Pagination{}, fmt.Errorf("pagination offset must be non-negative")
+	}
+	if p.Offset > limits.MaxPageOffset {
+		return Pagination{}, fmt.Errorf("pagination offset %d exceeds maximum of %d", p.Offset, limits.MaxPageOffset)
+	}
+
+	limit := p.Limit
+	if limit > limits.MaxPageLimit {
+		if !limits.ClampPageLimit {
+			return Pagination{}, fmt.Errorf("pagination limit %d exceeds maximum of %d", limit, limits.MaxPageLimit)
+		}
+		limit = limits.MaxPageLimit
+	}
+	return Pagination{Limit: limit, Offset: p.Offset}, nil
+}
+
+// pageToOffset converts page and page_size into limit and offset. Oversized page sizes
+// are left to the limit checks, so they are clamped or rejected like limits.
+func pageToOffset(p *dsl.Pagination) (*dsl.Pagination, error) {
+	if p.Limit != 0 || p.Offset != 0 {
+		return nil, fmt.Errorf("pagination page and page_size cannot be combined with limit and offset")
+	}
+	if p.Page < 1 {
+		return nil, fmt.Errorf("pagination page must be at least 1")
+	}
+	if p.PageSize <= 0 {
+		return nil, fmt.Errorf("pagination page_size must be greater than 0")
+	}
+
+	size := p.PageSize
+	if size > limits.MaxPageLimit && limits.ClampPageLimit {
+		size = limits.MaxPageLimit
+	}
+	// Compare before multiplying so huge page numbers cannot overflow
+	if p.Page-1 > limits.MaxPageOffset/size {
+		return nil, fmt.Errorf("pagination page %d exceeds maximum offset of %d", p.Page, limits.MaxPageOffset)
+	}
+	return &dsl.Pagination{Limit: p.PageSize, Offset: (p.Page - 1) * size}, nil
+}
+
+// withoutNulls returns a copy of data without its null values
+func withoutNulls(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(data))
+	for field, value := range data {
+		if value != nil {
+			out[field] = value
+		}
+	}
+	return out
+}
+
+// findSearch returns the first full-text search filter in expr, or nil
+func findSearch(expr FilterExpr) *SearchFilterIR {
+	switch e := expr.(type) {
+	case *SearchFilterIR:
+		return e
+	case *LogicalFilterIR:
+		for _, node := range e.Nodes {
+			if search := findSearch(node); search != nil {
+				return search
+			}
+		}
+	}
+	return nil
+}
+
+// SubqueryTables returns the tables read by subqueries in the plan's filters
+func (plan *QueryPlan) SubqueryTables() []string {
+	var tables []string
+	var walk func(expr FilterExpr)
+	walk = func(expr FilterExpr) {
+		switch e := expr.(type) {
+		case *ComparisonFilterIR:
+			if e.Subquery != nil {
+				tables = append(tables, e.Subquery.RootModel.Table)
+			}
+		case *LogicalFilterIR:
+			for _, node := range e.Nodes {
+				walk(node)
+			}
+		}
+	}
+	walk(plan.Filters)
+	return tables
+}
+
+// AutoTimestamps returns the plan's timestamp columns that the client did not supply in Data
+func (plan *QueryPlan) AutoTimestamps() []string {
+	var cols []string
+	for _, ref := range []*ColumnRef{plan.CreatedAt, plan.UpdatedAt} {
+		if ref == nil {
+			continue
+		}
+		if _, ok := plan.Data[ref.ColumnName]; ok {
+			continue
+		}
+		if len(cols) > 0 && cols[0] == ref.ColumnName {
+			continue
+		}
+		cols = append(cols, ref.ColumnName)
+	}
+	return cols
+}
+
+// BetweenBounds extracts the low and high bounds from a between value,
+// which must be a two-element array
+func BetweenBounds(value any) (any, any, error) {
+	rv := reflect.ValueOf(value)
+	if value == nil || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return nil, nil, fmt.Errorf("between operator requires a [low, high] array, got %T", value)
+	}
+	if rv.Len() != 2 {
+		return nil, nil, fmt.Errorf("between operator requires exactly 2 values, got %d", rv.Len())
+	}
+	return rv.Index(0).Interface(), rv.Index(1).Interface(), nil
+}
+
 // schemaFieldToColumnRef converts a schema field to a ColumnRef
 func (p *Planner) schemaFieldToColumnRef(modelName, fieldName, tableAlias string) ColumnRef {
 	field, err := p.registry.GetField(modelName, fieldName)
@@ -382,9 +1533,13 @@ func (p *Planner) schemaFieldToColumnRef(modelName, fieldName, tableAlias string
 	}
 
 	return ColumnRef{
-		TableAlias: tableAlias,
-		ColumnName: fieldName,
-		DataType:   FieldType(field.Type),
+		TableAlias:      tableAlias,
+		ColumnName:      field.Column,
+		DataType:        FieldType(field.Type),
+		CaseInsensitive: field.CaseInsensitive,
+		PrimaryKey:      p.registry.GetModel(modelName).PrimaryKey == fieldName,
+		Array:           field.Array,
+		Virtual:         field.Virtual,
 	}
 }
 
@@ -401,6 +1556,8 @@ func (p *Planner) dslAggToIRAgg(fn dsl.AggregateFunc) AggregateFn {
 		return AggMinFn
 	case dsl.AggMax:
 		return AggMaxFn
+	case dsl.AggCountDistinct:
+		return AggCountDistinctFn
 	default:
 		return AggCountFn
 	}
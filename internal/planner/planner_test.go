@@ -1,10 +1,12 @@
 package planner
 
 import (
+	"strings"
 	"testing"
 
 	"udv/internal/config"
 	"udv/internal/dsl"
+	"udv/internal/limits"
 	"udv/internal/schema"
 )
 
@@ -22,6 +24,9 @@ func setupTestRegistry() *schema.Registry {
 					{Name: "amount", Type: "decimal", Nullable: false},
 					{Name: "created_at", Type: "timestamp", Nullable: false},
 				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "id"},
+				},
 			},
 			{
 				Name:       "users",
@@ -152,9 +157,9 @@ func TestPlanQuery_QueryWithLogicalFilter(t *testing.T) {
 	query := &dsl.Query{
 		Model: "orders",
 		Filters: &dsl.LogicalFilter{
-			And: []*dsl.ComparisonFilter{
-				{Field: "status", Op: dsl.OpEqual, Value: "PAID"},
-				{Field: "amount", Op: dsl.OpGT, Value: 1000},
+			And: []dsl.FilterExpr{
+				&dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"},
+				&dsl.ComparisonFilter{Field: "amount", Op: dsl.OpGT, Value: 1000},
 			},
 		},
 	}
@@ -313,6 +318,84 @@ func TestPlanQuery_QueryWithSort(t *testing.T) {
 	}
 }
 
+func TestPlanQuery_CountDistinct(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+
+	plan, err := planner.PlanQuery(&dsl.Query{
+		Model:      "orders",
+		Aggregates: []dsl.Aggregate{{Function: dsl.AggCountDistinct, Field: "user_id", Alias: "buyers"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+	if len(plan.Aggregates) != 1 || plan.Aggregates[0].Function != AggCountDistinctFn || plan.Aggregates[0].Column == nil || plan.Aggregates[0].Column.ColumnName != "user_id" {
+		t.Errorf("unexpected aggregates: %+v", plan.Aggregates)
+	}
+}
+
+func TestPlanQuery_GroupByTime(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+
+	plan, err := planner.PlanQuery(&dsl.Query{
+		Model:       "orders",
+		GroupByTime: []dsl.TimeBucket{{Field: "created_at", Granularity: "day", Alias: "day"}},
+		Aggregates:  []dsl.Aggregate{{Function: dsl.AggCount, Alias: "order_count"}},
+		Sort:        []dsl.Sort{{Field: "day", Direction: dsl.SortDesc}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+	if len(plan.GroupBy) != 1 || plan.GroupBy[0].Granularity != "day" || plan.GroupBy[0].Alias != "day" || plan.GroupBy[0].Column.ColumnName != "created_at" {
+		t.Errorf("unexpected group by: %+v", plan.GroupBy)
+	}
+	if len(plan.Sort) != 1 || plan.Sort[0].Target != SortBucket || plan.Sort[0].Bucket == nil || plan.Sort[0].Direction != "DESC" {
+		t.Errorf("unexpected sort: %+v", plan.Sort)
+	}
+
+	// The raw column is not grouped by its bucket
+	_, err = planner.PlanQuery(&dsl.Query{
+		Model:       "orders",
+		Fields:      []string{"created_at"},
+		GroupByTime: []dsl.TimeBucket{{Field: "created_at", Granularity: "day", Alias: "day"}},
+	})
+	if err == nil {
+		t.Error("expected error selecting a bucketed column")
+	}
+}
+
+func TestPlanQuery_SortByAggregateAlias(t *testing.T) {
+	reg := setupTestRegistry()
+	planner := NewPlanner(reg)
+
+	query := &dsl.Query{
+		Model:   "orders",
+		GroupBy: []string{"status"},
+		Aggregates: []dsl.Aggregate{
+			{Function: dsl.AggSum, Field: "amount", Alias: "total"},
+		},
+		Sort: []dsl.Sort{
+			{Field: "total", Direction: dsl.SortDesc},
+			{Field: "status", Direction: dsl.SortAsc},
+		},
+	}
+
+	plan, err := planner.PlanQuery(query)
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v, want nil", err)
+	}
+
+	if len(plan.Sort) != 2 {
+		t.Fatalf("Sort has %d items, want 2", len(plan.Sort))
+	}
+
+	if plan.Sort[0].Target != SortAggregate || plan.Sort[0].Aggregate == nil || plan.Sort[0].Aggregate.Alias != "total" {
+		t.Errorf("Sort[0] = %+v, want aggregate sort on total", plan.Sort[0])
+	}
+	if plan.Sort[1].Target != SortColumn || plan.Sort[1].Column.ColumnName != "status" {
+		t.Errorf("Sort[1] = %+v, want column sort on status", plan.Sort[1])
+	}
+}
+
 func TestPlanQuery_QueryWithPagination(t *testing.T) {
 	reg := setupTestRegistry()
 	planner := NewPlanner(reg)
@@ -346,11 +429,11 @@ func TestPlanQuery_ComplexQuery(t *testing.T) {
 	query := &dsl.Query{
 		Model:   "orders",
 		Fields:  []string{"status", "amount"},
-		GroupBy: []string{"status"},
+		GroupBy: []string{"status", "amount"},
 		Filters: &dsl.LogicalFilter{
-			And: []*dsl.ComparisonFilter{
-				{Field: "created_at", Op: dsl.OpAfter, Value: "2024-01-01"},
-				{Field: "amount", Op: dsl.OpGTE, Value: 100},
+			And: []dsl.FilterExpr{
+				&dsl.ComparisonFilter{Field: "created_at", Op: dsl.OpAfter, Value: "2024-01-01"},
+				&dsl.ComparisonFilter{Field: "amount", Op: dsl.OpGTE, Value: 100},
 			},
 		},
 		Aggregates: []dsl.Aggregate{
@@ -382,8 +465,8 @@ func TestPlanQuery_ComplexQuery(t *testing.T) {
 	if plan.Filters == nil {
 		t.Errorf("Filters is nil")
 	}
-	if len(plan.GroupBy) != 1 {
-		t.Errorf("GroupBy has %d items, want 1", len(plan.GroupBy))
+	if len(plan.GroupBy) != 2 {
+		t.Errorf("GroupBy has %d items, want 2", len(plan.GroupBy))
 	}
 	if len(plan.Aggregates) != 2 {
 		t.Errorf("Aggregates has %d items, want 2", len(plan.Aggregates))
@@ -445,3 +528,588 @@ func TestNewPlanner(t *testing.T) {
 		t.Errorf("NewPlanner() registry not set correctly")
 	}
 }
+
+func TestPlanQuery_LockRequiresTransaction(t *testing.T) {
+	reg := setupTestRegistry()
+	planner := NewPlanner(reg)
+
+	query := &dsl.Query{
+		Model: "orders",
+		Lock:  &dsl.Lock{Mode: dsl.LockForUpdate},
+	}
+
+	if _, err := planner.PlanQuery(query); err == nil {
+		t.Errorf("PlanQuery() with lock should error outside a transaction")
+	}
+
+	plan, err := planner.PlanQueryInTransaction(query)
+	if err != nil {
+		t.Fatalf("PlanQueryInTransaction() error = %v, want nil", err)
+	}
+	if plan.Lock == nil || plan.Lock.Strength != "UPDATE" {
+		t.Errorf("Lock = %+v, want FOR UPDATE", plan.Lock)
+	}
+}
+
+func TestPlanQuery_Include(t *testing.T) {
+	reg := setupTestRegistry()
+	planner := NewPlanner(reg)
+
+	plan, err := planner.PlanQuery(&dsl.Query{
+		Model:   "orders",
+		Include: []dsl.Include{{Relation: "user", Unwind: true}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+
+	if len(plan.Joins) != 1 {
+		t.Fatalf("Joins has %d entries, want 1", len(plan.Joins))
+	}
+
+	join := plan.Joins[0]
+	if join.Type != JoinLeft || join.FromAlias != "t0" || join.ToAlias != "t1" || join.ToTable != "users" {
+		t.Errorf("Join = %+v, unexpected aliases or table", join)
+	}
+	if join.On.Left.ColumnName != "user_id" || join.On.Right.ColumnName != "id" {
+		t.Errorf("Join.On = %+v, want user_id = id", join.On)
+	}
+	if join.Relation != "user" || !join.Unwind {
+		t.Errorf("Join relation/unwind = %s/%v, want user/true", join.Relation, join.Unwind)
+	}
+}
+
+func TestPlanQuery_OperatorValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		op      dsl.FilterOperator
+		value   interface{}
+		wantErr bool
+	}{
+		{"equal on string", "status", dsl.OpEqual, "PAID", false},
+		{"in on integer", "user_id", dsl.OpIn, []int{1, 2}, false},
+		{"is_null on decimal", "amount", dsl.OpIsNull, nil, false},
+		{"gt on decimal", "amount", dsl.OpGT, 10, false},
+		{"between on timestamp", "created_at", dsl.OpBetween, []string{"2024-01-01", "2024-02-01"}, false},
+		{"like on string", "status", dsl.OpLike, "P%", false},
+		{"before on timestamp", "created_at", dsl.OpBefore, "2024-01-01", false},
+		{"after on timestamp", "created_at", dsl.OpAfter, "2024-01-01", false},
+		{"like on integer", "user_id", dsl.OpLike, "1%", true},
+		{"contains on decimal", "amount", dsl.OpContains, "1", true},
+		{"before on string", "status", dsl.OpBefore, "x", true},
+		{"after on integer", "user_id", dsl.OpAfter, 1, true},
+		{"unknown operator", "status", dsl.FilterOperator("~="), "x", true},
+		{"null_safe_eq with null", "status", dsl.OpNullSafeEqual, nil, false},
+		{"regex on string", "status", dsl.OpRegex, "^PA(ID|YING)$", false},
+		{"iregex on string", "status", dsl.OpIRegex, "paid", false},
+		{"regex on integer", "user_id", dsl.OpRegex, "^1", true},
+		{"regex with number pattern", "status", dsl.OpRegex, 1, true},
+		{"iregex with empty pattern", "status", dsl.OpIRegex, "", true},
+		{"size on string", "status", dsl.OpSize, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planner := NewPlanner(setupTestRegistry())
+			_, err := planner.PlanQuery(&dsl.Query{
+				Model:   "orders",
+				Filters: &dsl.ComparisonFilter{Field: tt.field, Op: tt.op, Value: tt.value},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PlanQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOperator_PostgresSpecificTypes(t *testing.T) {
+	tests := []struct {
+		op        dsl.FilterOperator
+		fieldType FieldType
+		wantErr   bool
+	}{
+		{dsl.OpGT, TypeInterval, false},
+		{dsl.OpBefore, TypeInterval, true},
+		{dsl.OpLT, TypeInet, false},
+		{dsl.OpLike, TypeCIDR, true},
+		{dsl.OpEqual, TypeMacAddr, false},
+		{dsl.OpIsNull, TypeXML, false},
+		{dsl.OpGT, TypeXML, true},
+		{dsl.OpSize, TypeJSON, false},
+		{dsl.OpSize, TypeInteger, true},
+	}
+
+	for _, tt := range tests {
+		err := validateOperator(tt.op, "f", tt.fieldType)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateOperator(%s, %s) error = %v, wantErr %v", tt.op, tt.fieldType, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPlanQuery_SoftDelete(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "deleted_at", Type: "timestamp", Nullable: true},
+				},
+				SoftDeleteField: "deleted_at",
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	planner := NewPlanner(reg)
+
+	tests := []struct {
+		name  string
+		query *dsl.Query
+		want  bool
+	}{
+		{"select", &dsl.Query{Model: "orders"}, true},
+		{"select including deleted", &dsl.Query{Model: "orders", IncludeDeleted: true}, false},
+		{"delete", &dsl.Query{Operation: dsl.OpDelete, Model: "orders", ID: 1}, true},
+		{"create", &dsl.Query{Operation: dsl.OpCreate, Model: "orders", Data: map[string]interface{}{"id": 1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery() error = %v", err)
+			}
+			if (plan.SoftDelete != nil) != tt.want {
+				t.Fatalf("SoftDelete = %+v, want set=%v", plan.SoftDelete, tt.want)
+			}
+			if tt.want && (plan.SoftDelete.ColumnName != "deleted_at" || plan.SoftDelete.TableAlias != "t0") {
+				t.Errorf("SoftDelete = %+v, want t0.deleted_at", plan.SoftDelete)
+			}
+		})
+	}
+}
+
+// nestedFilter wraps a comparison in depth levels of single-child AND filters
+func nestedFilter(depth int) dsl.FilterExpr {
+	var f dsl.FilterExpr = &dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"}
+	for i := 0; i < depth; i++ {
+		f = &dsl.LogicalFilter{And: []dsl.FilterExpr{f}}
+	}
+	return f
+}
+
+func TestPlanQuery_FilterDepthLimit(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+
+	if _, err := planner.PlanQuery(&dsl.Query{Model: "orders", Filters: nestedFilter(limits.MaxFilterDepth)}); err != nil {
+		t.Errorf("PlanQuery() at max depth error = %v, want nil", err)
+	}
+
+	_, err := planner.PlanQuery(&dsl.Query{Model: "orders", Filters: nestedFilter(limits.MaxFilterDepth + 1)})
+	if err == nil || !strings.Contains(err.Error(), "maximum depth") {
+		t.Errorf("PlanQuery() beyond max depth error = %v, want depth error", err)
+	}
+}
+
+func TestPlanQuery_LogicalFilterArity(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+	cond := &dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"}
+
+	tests := []struct {
+		name    string
+		filter  *dsl.LogicalFilter
+		wantErr bool
+	}{
+		{"nested or in and", &dsl.LogicalFilter{And: []dsl.FilterExpr{cond, &dsl.LogicalFilter{Or: []dsl.FilterExpr{cond, cond}}}}, false},
+		{"not of and", &dsl.LogicalFilter{Not: &dsl.LogicalFilter{And: []dsl.FilterExpr{cond}}}, false},
+		{"empty and", &dsl.LogicalFilter{And: []dsl.FilterExpr{}}, true},
+		{"empty or", &dsl.LogicalFilter{Or: []dsl.FilterExpr{}}, true},
+		{"no operator", &dsl.LogicalFilter{}, true},
+		{"and with or", &dsl.LogicalFilter{And: []dsl.FilterExpr{cond}, Or: []dsl.FilterExpr{cond}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planner.PlanQuery(&dsl.Query{Model: "orders", Filters: tt.filter})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PlanQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanQuery_Search(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "articles",
+				Table:      "articles",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "title", Type: "string", Nullable: false},
+					{Name: "body", Type: "string", Nullable: false},
+				},
+				SearchFields: []string{"title", "body"},
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	planner := NewPlanner(reg)
+
+	plan, err := planner.PlanQuery(&dsl.Query{
+		Model:   "articles",
+		Filters: &dsl.ComparisonFilter{Op: dsl.OpSearch, Value: "postgres"},
+		Sort:    []dsl.Sort{{Field: dsl.SearchScoreField, Direction: dsl.SortDesc}},
+	})
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+
+	search, ok := plan.Filters.(*SearchFilterIR)
+	if !ok {
+		t.Fatalf("Filters = %T, want *SearchFilterIR", plan.Filters)
+	}
+	if search.Query != "postgres" || len(search.Columns) != 2 || search.Columns[0].ColumnName != "title" || search.Columns[1].ColumnName != "body" {
+		t.Errorf("SearchFilterIR = %+v, want title and body columns", search)
+	}
+
+	if len(plan.Sort) != 1 || plan.Sort[0].Target != SortScore || plan.Sort[0].Search != search {
+		t.Errorf("Sort = %+v, want score sort referencing the search filter", plan.Sort)
+	}
+
+	if _, err := planner.PlanQuery(&dsl.Query{Model: "articles", Sort: []dsl.Sort{{Field: dsl.SearchScoreField}}}); err == nil {
+		t.Errorf("PlanQuery() sorting by score without a search filter should error")
+	}
+}
+
+func TestPlanQuery_StripNulls(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer", Nullable: false},
+					{Name: "status", Type: "string", Nullable: true},
+					{Name: "note", Type: "string", Nullable: true},
+				},
+				StripNulls: true,
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	planner := NewPlanner(reg)
+	keep := false
+
+	tests := []struct {
+		name     string
+		query    *dsl.Query
+		wantNote bool
+	}{
+		{"model default strips", &dsl.Query{Operation: dsl.OpCreate, Model: "orders"}, false},
+		{"request override keeps", &dsl.Query{Operation: dsl.OpCreate, Model: "orders", StripNulls: &keep}, true},
+		{"unset nulls keeps", &dsl.Query{Operation: dsl.OpUpdate, Model: "orders", ID: 1, UnsetNulls: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{"status": "NEW", "note": nil}
+			tt.query.Data = data
+
+			plan, err := planner.PlanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("PlanQuery() error = %v", err)
+			}
+			if _, ok := plan.Data["note"]; ok != tt.wantNote {
+				t.Errorf("Data = %v, want note present=%v", plan.Data, tt.wantNote)
+			}
+			if plan.Data["status"] != "NEW" {
+				t.Errorf("Data = %v, want status kept", plan.Data)
+			}
+			if len(data) != 2 {
+				t.Errorf("PlanQuery() modified the request data: %v", data)
+			}
+		})
+	}
+}
+
+func TestPlanQuery_GenerateUUID(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "tokens",
+				Table:      "tokens",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "uuid", Nullable: false},
+					{Name: "label", Type: "string", Nullable: true},
+				},
+				GenerateUUID: true,
+			},
+		},
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+	planner := NewPlanner(reg)
+
+	data := map[string]interface{}{"label": "api"}
+	plan, err := planner.PlanQuery(&dsl.Query{Operation: dsl.OpCreate, Model: "tokens", Data: data})
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+	id, ok := plan.Data["id"].(string)
+	if !ok || len(id) != 36 || id[14] != '4' {
+		t.Errorf("Data[id] = %v, want a generated version 4 uuid", plan.Data["id"])
+	}
+	if _, ok := data["id"]; ok {
+		t.Errorf("PlanQuery() modified the request data: %v", data)
+	}
+
+	supplied := "550e8400-e29b-41d4-a716-446655440000"
+	plan, err = planner.PlanQuery(&dsl.Query{Operation: dsl.OpCreate, Model: "tokens", Data: map[string]interface{}{"id": supplied}})
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+	if plan.Data["id"] != supplied {
+		t.Errorf("Data[id] = %v, want client-supplied %s", plan.Data["id"], supplied)
+	}
+}
+
+func TestPlanQuery_PaginationBounds(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+	page := func(limit, offset int) *dsl.Query {
+		return &dsl.Query{Model: "orders", Pagination: &dsl.Pagination{Limit: limit, Offset: offset}}
+	}
+
+	tests := []struct {
+		name      string
+		query     *dsl.Query
+		clamp     bool
+		wantLimit int
+		wantErr   bool
+	}{
+		{"within bounds", page(50, 10), false, 50, false},
+		{"negative offset", page(50, -1), false, 0, true},
+		{"zero limit", page(0, 0), false, 0, true},
+		{"offset too deep", page(50, limits.MaxPageOffset+1), false, 0, true},
+		{"limit too large", page(limits.MaxPageLimit+1, 0), false, 0, true},
+		{"limit clamped", page(limits.MaxPageLimit+1, 0), true, limits.MaxPageLimit, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(clamp bool) { limits.ClampPageLimit = clamp }(limits.ClampPageLimit)
+			limits.ClampPageLimit = tt.clamp
+
+			plan, err := planner.PlanQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlanQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && plan.Pagination.Limit != tt.wantLimit {
+				t.Errorf("Pagination.Limit = %d, want %d", plan.Pagination.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestPlanQuery_PageNumber(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+	page := func(page, size int) *dsl.Query {
+		return &dsl.Query{Model: "orders", Pagination: &dsl.Pagination{Page: page, PageSize: size}}
+	}
+
+	tests := []struct {
+		name       string
+		query      *dsl.Query
+		clamp      bool
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{"first page", page(1, 25), false, 25, 0, false},
+		{"third page", page(3, 25), false, 25, 50, false},
+		{"page zero", page(0, 25), false, 0, 0, true},
+		{"missing page size", page(2, 0), false, 0, 0, true},
+		{"page size too large", page(1, limits.MaxPageLimit+1), false, 0, 0, true},
+		{"page size clamped", page(2, limits.MaxPageLimit+1), true, limits.MaxPageLimit, limits.MaxPageLimit, false},
+		{"page too deep", page(limits.MaxPageOffset, 10), false, 0, 0, true},
+		{"mixed with limit", &dsl.Query{Model: "orders", Pagination: &dsl.Pagination{Page: 1, PageSize: 10, Limit: 10}}, false, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(clamp bool) { limits.ClampPageLimit = clamp }(limits.ClampPageLimit)
+			limits.ClampPageLimit = tt.clamp
+
+			plan, err := planner.PlanQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlanQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (plan.Pagination.Limit != tt.wantLimit || plan.Pagination.Offset != tt.wantOffset) {
+				t.Errorf("Pagination = %+v, want limit %d offset %d", plan.Pagination, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestPlanQuery_SelectWithAggregates(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+	count := []dsl.Aggregate{{Function: dsl.AggCount, Alias: "order_count"}}
+
+	tests := []struct {
+		name    string
+		query   *dsl.Query
+		wantErr bool
+	}{
+		{"grouped column", &dsl.Query{Model: "orders", Fields: []string{"status"}, GroupBy: []string{"status"}, Aggregates: count}, false},
+		{"subset of group columns", &dsl.Query{Model: "orders", Fields: []string{"status"}, GroupBy: []string{"status", "user_id"}, Aggregates: count}, false},
+		{"ungrouped column", &dsl.Query{Model: "orders", Fields: []string{"status", "amount"}, GroupBy: []string{"status"}, Aggregates: count}, true},
+		{"column with aggregate and no group by", &dsl.Query{Model: "orders", Fields: []string{"status"}, Aggregates: count}, true},
+		{"plain select", &dsl.Query{Model: "orders", Fields: []string{"status", "amount"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planner.PlanQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PlanQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanQuery_FieldComparison(t *testing.T) {
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "tasks",
+				Table:      "tasks",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "title", Type: "string"},
+					{Name: "created_at", Type: "timestamp"},
+					{Name: "due_date", Type: "date"},
+					{Name: "payload", Type: "json"},
+				},
+			},
+		},
+	})
+	planner := NewPlanner(reg)
+
+	plan, err := planner.PlanQuery(&dsl.Query{Model: "tasks", Filters: &dsl.ComparisonFilter{Field: "due_date", Op: dsl.OpGTE, ValueField: "created_at"}})
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+	filter, ok := plan.Filters.(*ComparisonFilterIR)
+	if !ok || filter.Right == nil {
+		t.Fatalf("expected a column comparison, got %+v", plan.Filters)
+	}
+	if filter.Value != nil {
+		t.Errorf("expected no value, got %+v", filter.Value)
+	}
+	want := ColumnRef{TableAlias: "t0", ColumnName: "created_at", DataType: TypeTimestamp}
+	if *filter.Right != want || filter.Left.ColumnName != "due_date" {
+		t.Errorf("unexpected column refs: left %+v, right %+v", filter.Left, *filter.Right)
+	}
+
+	for _, f := range []*dsl.ComparisonFilter{
+		{Field: "title", Op: dsl.OpEqual, ValueField: "missing"},
+		{Field: "title", Op: dsl.OpEqual, ValueField: "id"},
+		{Field: "id", Op: dsl.OpIn, ValueField: "id"},
+		{Field: "payload", Op: dsl.OpGT, ValueField: "payload"},
+	} {
+		if _, err := planner.PlanQuery(&dsl.Query{Model: "tasks", Filters: f}); err == nil {
+			t.Errorf("expected error for %s %s value_field %s", f.Field, f.Op, f.ValueField)
+		}
+	}
+}
+
+func TestPlanQuery_Subquery(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+
+	filter := &dsl.ComparisonFilter{Field: "user_id", Op: dsl.OpIn, Subquery: &dsl.Subquery{
+		Model:   "users",
+		Field:   "id",
+		Filters: &dsl.ComparisonFilter{Field: "age", Op: dsl.OpGTE, Value: 18},
+	}}
+	plan, err := planner.PlanQuery(&dsl.Query{Model: "orders", Filters: filter})
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+
+	cond, ok := plan.Filters.(*ComparisonFilterIR)
+	if !ok || cond.Subquery == nil || cond.Value != nil {
+		t.Fatalf("expected a subquery condition, got %+v", plan.Filters)
+	}
+	sub := cond.Subquery
+	if sub.RootModel.Table != "users" || sub.RootModel.Alias != "s0" {
+		t.Errorf("unexpected subquery model: %+v", sub.RootModel)
+	}
+	want := ColumnRef{TableAlias: "s0", ColumnName: "id", DataType: TypeInteger, PrimaryKey: true}
+	if len(sub.Select) != 1 || sub.Select[0].Column != want {
+		t.Errorf("unexpected subquery select: %+v", sub.Select)
+	}
+	inner, ok := sub.Filters.(*ComparisonFilterIR)
+	if !ok || inner.Left.TableAlias != "s0" || inner.Left.ColumnName != "age" {
+		t.Errorf("unexpected subquery filters: %+v", sub.Filters)
+	}
+	if tables := plan.SubqueryTables(); len(tables) != 1 || tables[0] != "users" {
+		t.Errorf("SubqueryTables() = %v, want [users]", tables)
+	}
+
+	for _, f := range []*dsl.ComparisonFilter{
+		{Field: "user_id", Op: dsl.OpEqual, Subquery: &dsl.Subquery{Model: "users", Field: "id"}},
+		{Field: "user_id", Op: dsl.OpIn, Subquery: &dsl.Subquery{Model: "missing", Field: "id"}},
+		{Field: "user_id", Op: dsl.OpIn, Subquery: &dsl.Subquery{Model: "users", Field: "missing"}},
+		{Field: "user_id", Op: dsl.OpIn, Subquery: &dsl.Subquery{Model: "users", Field: "name"}},
+	} {
+		if _, err := planner.PlanQuery(&dsl.Query{Model: "orders", Filters: f}); err == nil {
+			t.Errorf("expected error for %s %s subquery %+v", f.Field, f.Op, *f.Subquery)
+		}
+	}
+}
+
+func TestPlanQuery_KeysetCursor(t *testing.T) {
+	planner := NewPlanner(setupTestRegistry())
+
+	q := &dsl.Query{
+		Model:   "orders",
+		Filters: &dsl.ComparisonFilter{Field: "status", Op: dsl.OpEqual, Value: "PAID"},
+		Sort:    []dsl.Sort{{Field: "created_at", Direction: dsl.SortDesc}, {Field: "id", Direction: dsl.SortDesc}},
+		Pagination: &dsl.Pagination{Limit: 10, Cursor: "token", After: &dsl.Keyset{
+			Field: "created_at", Direction: dsl.SortDesc, Value: "2024-01-01T00:00:00Z", Key: float64(42),
+		}},
+	}
+	plan, err := planner.PlanQuery(q)
+	if err != nil {
+		t.Fatalf("PlanQuery() error = %v", err)
+	}
+
+	and, ok := plan.Filters.(*LogicalFilterIR)
+	if !ok || and.Op != "AND" || len(and.Nodes) != 2 {
+		t.Fatalf("expected the keyset filter to be ANDed with the query's filter, got %#v", plan.Filters)
+	}
+	or, ok := and.Nodes[1].(*LogicalFilterIR)
+	if !ok || or.Op != "OR" || len(or.Nodes) != 2 {
+		t.Fatalf("expected field < value OR (field = value AND id < key), got %#v", and.Nodes[1])
+	}
+	if cmp := or.Nodes[0].(*ComparisonFilterIR); cmp.Left.ColumnName != "created_at" || cmp.Operator != dsl.OpLT {
+		t.Errorf("unexpected first comparison: %#v", cmp)
+	}
+
+	q.Pagination.After = nil
+	if _, err := planner.PlanQuery(q); err == nil {
+		t.Error("expected an error for a cursor that was not verified")
+	}
+}
@@ -2,7 +2,9 @@ package schema
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"udv/internal/config"
 )
@@ -19,12 +21,26 @@ const (
 
 // Field represents a model field
 type Field struct {
-	Name          string
-	Type          string
-	Nullable      bool
-	Filterable    bool
-	Groupable     bool
-	Aggregatable  bool
+	Name            string
+	Type            string
+	Nullable        bool
+	Filterable      bool
+	Groupable       bool
+	Aggregatable    bool
+	Selectable      bool // False for hidden fields, which are never returned
+	CaseInsensitive bool // Equality, in and starts_with comparisons ignore case
+	Array           bool // PostgreSQL array of Type elements
+	HasDefault      bool // The database fills the column when an insert omits it
+
+	Transform   *config.FieldTransform // Rewrites the value in query results, nil to return it as stored
+	ReadDefault interface{}            // Returned in place of a null or missing value, nil for none
+
+	// Column is the database column holding the field; it differs from Name under a field naming style
+	Column string
+
+	// Virtual holds the expressions of a computed field, which can be selected but not
+	// filtered, sorted, grouped or written; nil for stored columns. Column is its result key.
+	Virtual *config.VirtualField
 }
 
 // Relation represents a relationship to another model
@@ -43,6 +59,32 @@ type Model struct {
 	Fields      map[string]*Field
 	Relations   map[string]*Relation
 	FieldOrder  []string // Preserve field order
+
+	SoftDeleteField string        // Column marking soft-deleted rows, empty if deletes are physical
+	CreatedAtField  string        // Column set to the current time on insert
+	UpdatedAtField  string        // Column set to the current time on insert and update
+	SearchFields    []string      // Fields matched by full-text search
+	StripNulls      bool          // Drop null values from inserts and updates by default
+	GenerateUUID    bool          // Generate the uuid primary key on insert when the client omits it
+	CacheTTL        time.Duration // How long select and count results are cached, zero to disable
+
+	// Indexes is informational index metadata from the config, served by /schema
+	Indexes []config.Index
+
+	// Datasource is the database holding the model, config.DefaultDatasource unless the config names one
+	Datasource string
+
+	// VersionField is the integer column updates increment and can be conditioned on, empty if unversioned
+	VersionField string
+
+	// Collation is the default locale for sorting select results, nil for the database default
+	Collation *config.Collation
+
+	// DefaultSort orders paginated selects without a sort by the primary key, so pages are stable
+	DefaultSort bool
+
+	// ReadOnly rejects writes, as for a model backed by a view or materialized view
+	ReadOnly bool
 }
 
 // Registry is the in-memory schema registry
@@ -63,35 +105,118 @@ func (r *Registry) LoadFromConfig(cfg *config.Config) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	// Config references fields by column; the registry holds them under their API names
+	namings := make(map[string]string, len(cfg.Models))
+	for i := range cfg.Models {
+		namings[cfg.Models[i].Name] = cfg.FieldNamingOf(&cfg.Models[i])
+	}
+
 	// First pass: create all models
 	for _, cfgModel := range cfg.Models {
+		naming := namings[cfgModel.Name]
+		fieldName := func(column string) string { return config.FieldName(naming, column) }
+
+		var searchFields []string
+		for _, column := range cfgModel.SearchFields {
+			searchFields = append(searchFields, fieldName(column))
+		}
+
 		model := &Model{
 			Name:       cfgModel.Name,
 			Table:      cfgModel.Table,
-			PrimaryKey: cfgModel.PrimaryKey,
+			PrimaryKey: fieldName(cfgModel.PrimaryKey),
 			Fields:     make(map[string]*Field),
 			Relations:  make(map[string]*Relation),
 			FieldOrder: []string{},
+
+			SoftDeleteField: fieldName(cfgModel.SoftDeleteField),
+			CreatedAtField:  fieldName(cfgModel.CreatedAtField),
+			UpdatedAtField:  fieldName(cfgModel.UpdatedAtField),
+			SearchFields:    searchFields,
+			StripNulls:      cfgModel.StripNulls,
+			GenerateUUID:    cfgModel.GenerateUUID,
+			Indexes:         cfgModel.Indexes,
+			Datasource:      cfgModel.DatasourceName(),
+
+			VersionField: fieldName(cfgModel.VersionField),
+
+			Collation: cfgModel.Collation,
+
+			DefaultSort: !cfgModel.DisableDefaultSort,
+
+			ReadOnly: cfgModel.ReadOnly,
+		}
+
+		if cfgModel.CacheTTL != "" {
+			ttl, err := time.ParseDuration(cfgModel.CacheTTL)
+			if err != nil {
+				return fmt.Errorf("invalid cacheTTL for model %s: %w", cfgModel.Name, err)
+			}
+			model.CacheTTL = ttl
+		}
+
+		hidden := make(map[string]bool, len(cfgModel.HiddenFields))
+		for _, name := range cfgModel.HiddenFields {
+			hidden[name] = true
 		}
 
 		// Add fields with sensible defaults
 		for _, cfgField := range cfgModel.Fields {
 			field := &Field{
-				Name:          cfgField.Name,
-				Type:          cfgField.Type,
-				Nullable:      cfgField.Nullable,
-				Filterable:    true,  // Default: fields are filterable
-				Groupable:     true,  // Default: fields are groupable
-				Aggregatable:  true,  // All fields are aggregatable; validateAggregateForType validates function-type compatibility
+				Name:            fieldName(cfgField.Name),
+				Type:            cfgField.Type,
+				Nullable:        cfgField.Nullable,
+				Filterable:      true, // Default: fields are filterable
+				Groupable:       true, // Default: fields are groupable
+				Aggregatable:    true, // All fields are aggregatable; validateAggregateForType validates function-type compatibility
+				Selectable:      !hidden[cfgField.Name],
+				CaseInsensitive: cfgField.CaseInsensitive,
+				Array:           cfgField.Array,
+				HasDefault:      cfgField.HasDefault,
+
+				Transform:   cfgField.Transform,
+				ReadDefault: cfgField.ReadDefault,
+
+				Column: cfgField.Name,
 			}
 
-			model.Fields[cfgField.Name] = field
-			model.FieldOrder = append(model.FieldOrder, cfgField.Name)
+			model.Fields[field.Name] = field
+			model.FieldOrder = append(model.FieldOrder, field.Name)
+		}
+
+		for i := range cfgModel.VirtualFields {
+			virtual := &cfgModel.VirtualFields[i]
+			field := &Field{
+				Name:       fieldName(virtual.Name),
+				Type:       virtual.Type,
+				Nullable:   true,
+				Selectable: true,
+				Column:     virtual.Name,
+				Virtual:    virtual,
+			}
+			model.Fields[field.Name] = field
+			model.FieldOrder = append(model.FieldOrder, field.Name)
 		}
 
 		r.models[cfgModel.Name] = model
 	}
 
+	// Second pass: attach relations now that all target models exist
+	for _, cfgModel := range cfg.Models {
+		model := r.models[cfgModel.Name]
+		for _, cfgRel := range cfgModel.Relations {
+			if r.models[cfgRel.TargetModel] == nil {
+				return fmt.Errorf("relation %s.%s: target model %s not found", cfgModel.Name, cfgRel.Name, cfgRel.TargetModel)
+			}
+			model.Relations[cfgRel.Name] = &Relation{
+				Type:         RelationType(cfgRel.Type),
+				TargetModel:  cfgRel.TargetModel,
+				ForeignKey:   config.FieldName(namings[cfgModel.Name], cfgRel.ForeignKey),
+				ReferenceKey: config.FieldName(namings[cfgRel.TargetModel], cfgRel.ReferenceKey),
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -121,6 +246,24 @@ func (r *Registry) GetField(modelName, fieldName string) (*Field, error) {
 	return field, nil
 }
 
+// GetRelation returns a named relation of a model
+func (r *Registry) GetRelation(modelName, relationName string) (*Relation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	model, exists := r.models[modelName]
+	if !exists {
+		return nil, fmt.Errorf("model not found: %s", modelName)
+	}
+
+	relation, exists := model.Relations[relationName]
+	if !exists {
+		return nil, fmt.Errorf("relation not found: %s.%s", modelName, relationName)
+	}
+
+	return relation, nil
+}
+
 // ModelExists checks if a model exists in the registry
 func (r *Registry) ModelExists(name string) bool {
 	r.mu.RLock()
@@ -172,3 +315,85 @@ func (r *Registry) GetModelFields(modelName string) ([]*Field, error) {
 	}
 	return fields, nil
 }
+
+// ExportModel returns the model as a config.Model, with fields in their defined order
+// and relations sorted by name
+func (r *Registry) ExportModel(name string) (*config.Model, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	model, exists := r.models[name]
+	if !exists {
+		return nil, fmt.Errorf("model not found: %s", name)
+	}
+
+	out := &config.Model{
+		Name:            model.Name,
+		Table:           model.Table,
+		PrimaryKey:      model.PrimaryKey,
+		Fields:          make([]config.Field, 0, len(model.FieldOrder)),
+		SoftDeleteField: model.SoftDeleteField,
+		CreatedAtField:  model.CreatedAtField,
+		UpdatedAtField:  model.UpdatedAtField,
+		SearchFields:    model.SearchFields,
+		StripNulls:      model.StripNulls,
+		GenerateUUID:    model.GenerateUUID,
+		Indexes:         model.Indexes,
+
+		VersionField: model.VersionField,
+
+		Collation: model.Collation,
+
+		DisableDefaultSort: !model.DefaultSort,
+
+		ReadOnly: model.ReadOnly,
+	}
+	if model.CacheTTL > 0 {
+		out.CacheTTL = model.CacheTTL.String()
+	}
+	if model.Datasource != config.DefaultDatasource {
+		out.Datasource = model.Datasource
+	}
+
+	for _, fieldName := range model.FieldOrder {
+		field := model.Fields[fieldName]
+		if field.Virtual != nil {
+			virtual := *field.Virtual
+			virtual.Name = field.Name
+			out.VirtualFields = append(out.VirtualFields, virtual)
+			continue
+		}
+		out.Fields = append(out.Fields, config.Field{
+			Name:            field.Name,
+			Type:            field.Type,
+			Nullable:        field.Nullable,
+			CaseInsensitive: field.CaseInsensitive,
+			Array:           field.Array,
+			HasDefault:      field.HasDefault,
+
+			Transform:   field.Transform,
+			ReadDefault: field.ReadDefault,
+		})
+		if !field.Selectable {
+			out.HiddenFields = append(out.HiddenFields, field.Name)
+		}
+	}
+
+	relationNames := make([]string, 0, len(model.Relations))
+	for relName := range model.Relations {
+		relationNames = append(relationNames, relName)
+	}
+	sort.Strings(relationNames)
+	for _, relName := range relationNames {
+		rel := model.Relations[relName]
+		out.Relations = append(out.Relations, config.Relation{
+			Name:         relName,
+			Type:         string(rel.Type),
+			TargetModel:  rel.TargetModel,
+			ForeignKey:   rel.ForeignKey,
+			ReferenceKey: rel.ReferenceKey,
+		})
+	}
+
+	return out, nil
+}
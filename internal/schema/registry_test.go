@@ -1,6 +1,8 @@
 package schema
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 
 	"udv/internal/config"
@@ -337,3 +339,183 @@ func TestAggregatableFields(t *testing.T) {
 		}
 	}
 }
+
+func TestGetRelation(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "user_id", Type: "integer"},
+				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "id"},
+				},
+			},
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+				},
+			},
+		},
+	}
+
+	reg := NewRegistry()
+	if err := reg.LoadFromConfig(cfg); err != nil {
+		t.Fatalf("LoadFromConfig() error = %v", err)
+	}
+
+	rel, err := reg.GetRelation("orders", "user")
+	if err != nil {
+		t.Fatalf("GetRelation() error = %v", err)
+	}
+	if rel.Type != ManyToOne || rel.TargetModel != "users" || rel.ForeignKey != "user_id" || rel.ReferenceKey != "id" {
+		t.Errorf("GetRelation() = %+v, unexpected relation", rel)
+	}
+
+	if _, err := reg.GetRelation("orders", "missing"); err == nil {
+		t.Errorf("GetRelation() expected error for unknown relation")
+	}
+	if _, err := reg.GetRelation("missing", "user"); err == nil {
+		t.Errorf("GetRelation() expected error for unknown model")
+	}
+}
+
+func TestExportModel(t *testing.T) {
+	cfg := &config.Config{
+		Models: []config.Model{
+			{
+				Name:       "orders",
+				Table:      "orders",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "user_id", Type: "integer"},
+					{Name: "deleted_at", Type: "timestamp", Nullable: true},
+				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "id"},
+				},
+				SoftDeleteField: "deleted_at",
+				Indexes: []config.Index{
+					{Name: "orders_user_id_idx", Columns: []string{"user_id"}},
+					{Name: "orders_live_idx", Columns: []string{"user_id", "id"}, Unique: true, Predicate: "deleted_at IS NULL"},
+				},
+			},
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+				},
+			},
+		},
+	}
+
+	reg := NewRegistry()
+	if err := reg.LoadFromConfig(cfg); err != nil {
+		t.Fatalf("LoadFromConfig() error = %v", err)
+	}
+
+	got, err := reg.ExportModel("orders")
+	if err != nil {
+		t.Fatalf("ExportModel() error = %v", err)
+	}
+	if !reflect.DeepEqual(*got, cfg.Models[0]) {
+		t.Errorf("ExportModel() = %+v, want %+v", *got, cfg.Models[0])
+	}
+
+	if _, err := reg.ExportModel("missing"); err == nil {
+		t.Errorf("ExportModel() expected error for unknown model")
+	}
+}
+
+func TestLoadFromConfig_FieldNaming(t *testing.T) {
+	cfg := &config.Config{
+		FieldNaming: config.FieldNamingCamel,
+		Models: []config.Model{
+			{
+				Name:           "users",
+				Table:          "users",
+				PrimaryKey:     "user_id",
+				UpdatedAtField: "updated_at",
+				SearchFields:   []string{"display_name"},
+				HiddenFields:   []string{"password_hash"},
+				Fields: []config.Field{
+					{Name: "user_id", Type: "integer"},
+					{Name: "display_name", Type: "string"},
+					{Name: "password_hash", Type: "string"},
+					{Name: "updated_at", Type: "timestamp"},
+				},
+			},
+			{
+				Name:        "orders",
+				Table:       "orders",
+				PrimaryKey:  "id",
+				FieldNaming: config.FieldNamingColumn,
+				Fields: []config.Field{
+					{Name: "id", Type: "integer"},
+					{Name: "user_id", Type: "integer"},
+				},
+				Relations: []config.Relation{
+					{Name: "user", Type: "many_to_one", TargetModel: "users", ForeignKey: "user_id", ReferenceKey: "user_id"},
+				},
+			},
+		},
+	}
+
+	reg := NewRegistry()
+	if err := reg.LoadFromConfig(cfg); err != nil {
+		t.Fatalf("LoadFromConfig() error = %v", err)
+	}
+
+	users := reg.GetModel("users")
+	if !reflect.DeepEqual(users.FieldOrder, []string{"userId", "displayName", "passwordHash", "updatedAt"}) {
+		t.Errorf("unexpected field names: %v", users.FieldOrder)
+	}
+	if field := users.Fields["displayName"]; field == nil || field.Column != "display_name" {
+		t.Errorf("expected displayName stored in display_name, got %+v", field)
+	}
+	if users.PrimaryKey != "userId" || users.UpdatedAtField != "updatedAt" || !reflect.DeepEqual(users.SearchFields, []string{"displayName"}) {
+		t.Errorf("expected column references to use field names, got %+v", users)
+	}
+	if users.Fields["passwordHash"].Selectable {
+		t.Errorf("expected passwordHash to stay hidden")
+	}
+
+	orders := reg.GetModel("orders")
+	if field := orders.Fields["user_id"]; field == nil || field.Column != "user_id" {
+		t.Errorf("expected the model's own naming to keep user_id, got %v", orders.FieldOrder)
+	}
+	rel, _ := reg.GetRelation("orders", "user")
+	if rel.ForeignKey != "user_id" || rel.ReferenceKey != "userId" {
+		t.Errorf("expected relation keys in each model's field names, got %+v", rel)
+	}
+}
+
+func TestLoadFromConfig_UnknownRelationTarget(t *testing.T) {
+	reg := NewRegistry()
+	err := reg.LoadFromConfig(&config.Config{
+		Models: []config.Model{
+			{
+				Name:       "users",
+				Table:      "users",
+				PrimaryKey: "id",
+				Fields:     []config.Field{{Name: "id", Type: "integer"}},
+				Relations: []config.Relation{
+					{Name: "audits", Type: "one_to_many", TargetModel: "audit_log", ForeignKey: "id", ReferenceKey: "user_id"},
+				},
+			},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "target model audit_log not found") {
+		t.Errorf("expected an unknown target model error, got %v", err)
+	}
+}
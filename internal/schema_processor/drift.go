@@ -0,0 +1,57 @@
+package schema_processor
+
+import (
+	"fmt"
+
+	"udv/internal/config"
+)
+
+// SchemaDrift lists where configured models no longer match the database
+type SchemaDrift struct {
+	MissingTables  []string
+	MissingColumns []string // table.column
+	TypeMismatches []string // Columns whose database type maps to a different model type
+}
+
+// Empty reports whether the models match the database
+func (d *SchemaDrift) Empty() bool {
+	return len(d.MissingTables) == 0 && len(d.MissingColumns) == 0 && len(d.TypeMismatches) == 0
+}
+
+// CheckModels compares each model's table and fields with the columns in the database
+func (sp *SchemaProcessor) CheckModels(models []config.Model) (*SchemaDrift, error) {
+	drift := &SchemaDrift{}
+	for _, model := range models {
+		table := ParseTableRefs([]string{DefaultSchema}, []string{model.Table})[0]
+		columns, err := sp.GetTableColumns(table.Schema, table.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get columns for table %s: %w", model.Table, err)
+		}
+		compareColumns(model, columns, drift)
+	}
+	return drift, nil
+}
+
+// compareColumns records the differences between a model and its table's columns;
+// a table without columns does not exist
+func compareColumns(model config.Model, columns []ColumnInfo, drift *SchemaDrift) {
+	if len(columns) == 0 {
+		drift.MissingTables = append(drift.MissingTables, model.Table)
+		return
+	}
+
+	byName := make(map[string]ColumnInfo, len(columns))
+	for _, col := range columns {
+		byName[col.ColumnName] = col
+	}
+	for _, field := range model.Fields {
+		col, ok := byName[field.Name]
+		if !ok {
+			drift.MissingColumns = append(drift.MissingColumns, model.Table+"."+field.Name)
+			continue
+		}
+		if dbType := mapPostgreSQLTypeToJSON(col.DataType); string(dbType) != field.Type {
+			drift.TypeMismatches = append(drift.TypeMismatches, fmt.Sprintf("%s.%s: type is %s in config, %s (%s) in database", model.Table, field.Name, field.Type, dbType, col.DataType))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package schema_processor
+
+import (
+	"reflect"
+	"testing"
+
+	"udv/internal/config"
+)
+
+func TestCompareColumns(t *testing.T) {
+	orders := config.Model{
+		Name:  "orders",
+		Table: "sales.orders",
+		Fields: []config.Field{
+			{Name: "id", Type: "integer"},
+			{Name: "amount", Type: "integer"},
+			{Name: "note", Type: "string"},
+		},
+	}
+	columns := []ColumnInfo{
+		{ColumnName: "id", DataType: "bigint"},
+		{ColumnName: "amount", DataType: "numeric"},
+		{ColumnName: "status", DataType: "text"},
+	}
+
+	drift := &SchemaDrift{}
+	compareColumns(orders, columns, drift)
+	compareColumns(config.Model{Name: "gone", Table: "gone"}, nil, drift)
+
+	want := &SchemaDrift{
+		MissingTables:  []string{"gone"},
+		MissingColumns: []string{"sales.orders.note"},
+		TypeMismatches: []string{"sales.orders.amount: type is integer in config, decimal (numeric) in database"},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("compareColumns() = %+v, want %+v", drift, want)
+	}
+	if drift.Empty() {
+		t.Error("expected drift not to be empty")
+	}
+
+	clean := &SchemaDrift{}
+	compareColumns(orders, []ColumnInfo{
+		{ColumnName: "id", DataType: "integer"},
+		{ColumnName: "amount", DataType: "int8"},
+		{ColumnName: "note", DataType: "character varying"},
+	}, clean)
+	if !clean.Empty() {
+		t.Errorf("expected no drift, got %+v", clean)
+	}
+}
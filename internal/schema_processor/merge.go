@@ -0,0 +1,288 @@
+package schema_processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// removedKey marks models and fields in a merged file that no longer exist in the database
+const removedKey = "removed"
+
+// MergeReport lists what a merge changed in an existing models.json
+type MergeReport struct {
+	AddedModels   []string
+	AddedFields   []string // model.field
+	RemovedModels []string
+	RemovedFields []string // model.field
+	Conflicts     []string // Fields whose introspected type differs from the file
+}
+
+// orderedObject is a JSON object that keeps its keys, including unknown ones, in file order
+type orderedObject []objectMember
+
+type objectMember struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// UnmarshalJSON decodes an object without losing key order
+func (o *orderedObject) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected JSON object")
+	}
+
+	*o = nil
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		*o = append(*o, objectMember{Key: tok.(string), Value: value})
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// MarshalJSON encodes the object with its keys in order
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(member.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(member.Value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// get returns the value of key, or nil when absent
+func (o orderedObject) get(key string) json.RawMessage {
+	for _, member := range o {
+		if member.Key == key {
+			return member.Value
+		}
+	}
+	return nil
+}
+
+// getString returns the string value of key, or "" when absent or not a string
+func (o orderedObject) getString(key string) string {
+	var s string
+	_ = json.Unmarshal(o.get(key), &s)
+	return s
+}
+
+// set replaces the value of key, appending it when absent
+func (o *orderedObject) set(key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	for i, member := range *o {
+		if member.Key == key {
+			(*o)[i].Value = raw
+			return nil
+		}
+	}
+	*o = append(*o, objectMember{Key: key, Value: raw})
+	return nil
+}
+
+// remove deletes key if present
+func (o *orderedObject) remove(key string) {
+	for i, member := range *o {
+		if member.Key == key {
+			*o = append((*o)[:i], (*o)[i+1:]...)
+			return
+		}
+	}
+}
+
+// toOrderedObject converts a generated value into an orderedObject in struct field order
+func toOrderedObject(v interface{}) (orderedObject, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj orderedObject
+	err = json.Unmarshal(raw, &obj)
+	return obj, err
+}
+
+// MergeModels merges generated models into an existing models.json document. Models are
+// matched by table and fields by name; existing entries keep every hand-edited key, new
+// ones are appended, and entries missing from the database are marked "removed": true.
+// A changed field type keeps the file's type and is reported as a conflict. With partial
+// set (only some tables were introspected), models that were not generated are left alone.
+func MergeModels(existing []byte, generated []Model, partial bool) ([]byte, *MergeReport, error) {
+	var root orderedObject
+	if err := json.Unmarshal(existing, &root); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse existing models file: %w", err)
+	}
+	var models []orderedObject
+	if raw := root.get("models"); raw != nil {
+		if err := json.Unmarshal(raw, &models); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse existing models: %w", err)
+		}
+	}
+
+	report := &MergeReport{}
+	byTable := make(map[string]int, len(models))
+	for i, m := range models {
+		byTable[m.getString("table")] = i
+	}
+
+	seen := make(map[string]bool, len(generated))
+	for _, gen := range generated {
+		seen[gen.Table] = true
+
+		i, ok := byTable[gen.Table]
+		if !ok {
+			obj, err := toOrderedObject(gen)
+			if err != nil {
+				return nil, nil, err
+			}
+			models = append(models, obj)
+			report.AddedModels = append(report.AddedModels, gen.Name)
+			continue
+		}
+
+		if err := mergeFields(&models[i], gen, report); err != nil {
+			return nil, nil, err
+		}
+		models[i].remove(removedKey)
+		// A table replaced by a view becomes read-only; the reverse is left to the file
+		if gen.ReadOnly {
+			if err := models[i].set("readOnly", true); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Indexes are introspected metadata rather than hand-edited config, so they follow the database
+		if len(gen.Indexes) > 0 {
+			if err := models[i].set("indexes", gen.Indexes); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			models[i].remove("indexes")
+		}
+	}
+
+	if !partial {
+		for i := range models {
+			if seen[models[i].getString("table")] || models[i].get(removedKey) != nil {
+				continue
+			}
+			if err := models[i].set(removedKey, true); err != nil {
+				return nil, nil, err
+			}
+			report.RemovedModels = append(report.RemovedModels, models[i].getString("name"))
+		}
+	}
+
+	if err := root.set("models", models); err != nil {
+		return nil, nil, err
+	}
+	compact, err := json.Marshal(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact, "", "  "); err != nil {
+		return nil, nil, err
+	}
+	return out.Bytes(), report, nil
+}
+
+// mergeFields merges the generated fields of gen into an existing model object
+func mergeFields(model *orderedObject, gen Model, report *MergeReport) error {
+	modelName := model.getString("name")
+
+	var fields []orderedObject
+	if raw := model.get("fields"); raw != nil {
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return fmt.Errorf("failed to parse fields of model %s: %w", modelName, err)
+		}
+	}
+
+	byName := make(map[string]int, len(fields))
+	for i, f := range fields {
+		byName[f.getString("name")] = i
+	}
+
+	seen := make(map[string]bool, len(gen.Fields))
+	for _, genField := range gen.Fields {
+		seen[genField.Name] = true
+
+		i, ok := byName[genField.Name]
+		if !ok {
+			obj, err := toOrderedObject(genField)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, obj)
+			report.AddedFields = append(report.AddedFields, modelName+"."+genField.Name)
+			continue
+		}
+
+		fields[i].remove(removedKey)
+		// Presence describes the sampled data rather than hand-edited config, so it follows the database
+		if genField.Presence > 0 {
+			if err := fields[i].set("presence", genField.Presence); err != nil {
+				return err
+			}
+		}
+		if current := fields[i].getString("type"); current != string(genField.Type) {
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("%s.%s: type is %s in file, %s in database", modelName, genField.Name, current, genField.Type))
+		}
+	}
+
+	for i := range fields {
+		if seen[fields[i].getString("name")] || fields[i].get(removedKey) != nil {
+			continue
+		}
+		if err := fields[i].set(removedKey, true); err != nil {
+			return err
+		}
+		report.RemovedFields = append(report.RemovedFields, modelName+"."+fields[i].getString("name"))
+	}
+
+	return model.set("fields", fields)
+}
+
+// MergeModelsFile merges generated models into the models file at path and writes it back.
+// YAML files keep their key order but lose their comments.
+func MergeModelsFile(path string, generated []Model, partial bool) (*MergeReport, error) {
+	existing, err := readModelsData(path)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, report, err := MergeModels(existing, generated, partial)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeModelsData(path, merged); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,277 @@
+package schema_processor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"udv/internal/config"
+	"udv/internal/schema"
+)
+
+const existingModelsJSON = `{
+  "models": [
+    {
+      "name": "Customers",
+      "table": "users",
+      "primaryKey": "id",
+      "fields": [
+        {"name": "id", "type": "integer", "nullable": false},
+        {"name": "email", "type": "string", "nullable": false},
+        {"name": "legacy_code", "type": "string", "nullable": true},
+        {"name": "age", "type": "integer", "nullable": true}
+      ],
+      "hiddenFields": ["email"],
+      "relations": [
+        {"name": "orders", "type": "one_to_many", "targetModel": "orders", "foreignKey": "user_id"}
+      ]
+    },
+    {
+      "name": "audit_log",
+      "table": "audit_log",
+      "primaryKey": "id",
+      "fields": [
+        {"name": "id", "type": "integer", "nullable": false}
+      ]
+    }
+  ]
+}`
+
+func generatedUsers() Model {
+	return Model{
+		Name:       "users",
+		Table:      "users",
+		PrimaryKey: "id",
+		Fields: []Field{
+			{Name: "id", Type: TypeInteger},
+			{Name: "email", Type: TypeString},
+			{Name: "age", Type: TypeString, Nullable: true},
+			{Name: "created_at", Type: TypeTimestamp},
+		},
+		Indexes: []Index{{Name: "users_email_idx", Columns: []string{"email"}, Unique: true}},
+	}
+}
+
+func generatedOrders() Model {
+	return Model{
+		Name:       "orders",
+		Table:      "orders",
+		PrimaryKey: "id",
+		Fields:     []Field{{Name: "id", Type: TypeInteger}},
+	}
+}
+
+// decodeMerged parses merged output into generic maps keyed by table
+func decodeMerged(t *testing.T, data []byte) map[string]map[string]interface{} {
+	t.Helper()
+	var doc struct {
+		Models []map[string]interface{} `json:"models"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("merged output is not valid JSON: %v", err)
+	}
+	byTable := make(map[string]map[string]interface{})
+	for _, m := range doc.Models {
+		byTable[m["table"].(string)] = m
+	}
+	return byTable
+}
+
+// fieldByName finds a field object in a decoded model
+func fieldByName(model map[string]interface{}, name string) map[string]interface{} {
+	for _, f := range model["fields"].([]interface{}) {
+		field := f.(map[string]interface{})
+		if field["name"] == name {
+			return field
+		}
+	}
+	return nil
+}
+
+func TestMergeModels(t *testing.T) {
+	merged, report, err := MergeModels([]byte(existingModelsJSON), []Model{generatedUsers(), generatedOrders()}, false)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+	models := decodeMerged(t, merged)
+
+	users := models["users"]
+	if users["name"] != "Customers" {
+		t.Errorf("expected hand-edited name to be kept, got %v", users["name"])
+	}
+	if users["hiddenFields"] == nil || users["relations"] == nil {
+		t.Errorf("expected hiddenFields and relations to be kept, got %v", users)
+	}
+
+	if fieldByName(users, "created_at") == nil {
+		t.Error("expected new column created_at to be added")
+	}
+	if legacy := fieldByName(users, "legacy_code"); legacy == nil || legacy[removedKey] != true {
+		t.Errorf("expected legacy_code to be marked removed, got %v", legacy)
+	}
+	if age := fieldByName(users, "age"); age["type"] != "integer" {
+		t.Errorf("expected conflicting field to keep the file's type, got %v", age["type"])
+	}
+
+	indexes, _ := users["indexes"].([]interface{})
+	if len(indexes) != 1 || indexes[0].(map[string]interface{})["name"] != "users_email_idx" {
+		t.Errorf("expected indexes to follow the database, got %v", users["indexes"])
+	}
+
+	if models["orders"] == nil {
+		t.Error("expected new table orders to be added")
+	}
+	if models["audit_log"][removedKey] != true {
+		t.Errorf("expected audit_log to be marked removed, got %v", models["audit_log"])
+	}
+
+	if len(report.AddedModels) != 1 || report.AddedModels[0] != "orders" {
+		t.Errorf("unexpected added models: %v", report.AddedModels)
+	}
+	if len(report.AddedFields) != 1 || report.AddedFields[0] != "Customers.created_at" {
+		t.Errorf("unexpected added fields: %v", report.AddedFields)
+	}
+	if len(report.RemovedModels) != 1 || report.RemovedModels[0] != "audit_log" {
+		t.Errorf("unexpected removed models: %v", report.RemovedModels)
+	}
+	if len(report.RemovedFields) != 1 || report.RemovedFields[0] != "Customers.legacy_code" {
+		t.Errorf("unexpected removed fields: %v", report.RemovedFields)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0] != "Customers.age: type is integer in file, string in database" {
+		t.Errorf("unexpected conflicts: %v", report.Conflicts)
+	}
+}
+
+func TestMergeModels_Partial(t *testing.T) {
+	merged, report, err := MergeModels([]byte(existingModelsJSON), []Model{generatedUsers()}, true)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+	models := decodeMerged(t, merged)
+
+	if _, ok := models["audit_log"][removedKey]; ok {
+		t.Error("expected models outside a partial run to be left alone")
+	}
+	if len(report.RemovedModels) != 0 {
+		t.Errorf("expected no removed models, got %v", report.RemovedModels)
+	}
+}
+
+func TestMergeModels_RestoresReappearingField(t *testing.T) {
+	first, _, err := MergeModels([]byte(existingModelsJSON), []Model{generatedUsers()}, true)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+
+	users := generatedUsers()
+	users.Fields = append(users.Fields, Field{Name: "legacy_code", Type: TypeString, Nullable: true})
+	second, report, err := MergeModels(first, []Model{users}, true)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+
+	legacy := fieldByName(decodeMerged(t, second)["users"], "legacy_code")
+	if _, ok := legacy[removedKey]; ok {
+		t.Errorf("expected removed marker to be cleared, got %v", legacy)
+	}
+	if len(report.AddedFields) != 0 || len(report.RemovedFields) != 0 {
+		t.Errorf("expected no field changes, got added %v removed %v", report.AddedFields, report.RemovedFields)
+	}
+}
+
+func TestMergeModelsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	if err := os.WriteFile(path, []byte(existingModelsJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MergeModelsFile(path, []Model{generatedUsers()}, false); err != nil {
+		t.Fatalf("MergeModelsFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fieldByName(decodeMerged(t, data)["users"], "created_at") == nil {
+		t.Error("expected merged file to be written back")
+	}
+}
+
+func TestMergeModels_InvalidExisting(t *testing.T) {
+	if _, _, err := MergeModels([]byte("not json"), nil, false); err == nil {
+		t.Error("expected error for invalid existing file")
+	}
+}
+
+func TestMergeModels_UpdatesPresence(t *testing.T) {
+	users := generatedUsers()
+	users.Fields[2].Presence = 40
+	merged, _, err := MergeModels([]byte(existingModelsJSON), []Model{users}, true)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+
+	model := decodeMerged(t, merged)["users"]
+	if age := fieldByName(model, "age"); age["presence"] != float64(40) {
+		t.Errorf("expected presence to follow the samples, got %v", age)
+	}
+	if email := fieldByName(model, "email"); email["presence"] != nil {
+		t.Errorf("expected no presence without a sampled value, got %v", email)
+	}
+}
+
+func TestMergeModels_MarksViewsReadOnly(t *testing.T) {
+	users := generatedUsers()
+	users.ReadOnly = true
+	merged, _, err := MergeModels([]byte(existingModelsJSON), []Model{users}, true)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+
+	if model := decodeMerged(t, merged)["users"]; model["readOnly"] != true {
+		t.Errorf("expected a model now backed by a view to be read-only, got %v", model)
+	}
+}
+
+func TestMergeModels_RemovedEntriesAreNotLoaded(t *testing.T) {
+	existing := `{"models": [
+		{"name": "users", "table": "users", "primaryKey": "id", "fields": [
+			{"name": "id", "type": "integer", "nullable": false},
+			{"name": "legacy_code", "type": "string", "nullable": true}
+		]},
+		{"name": "audit_log", "table": "audit_log", "primaryKey": "id", "fields": [
+			{"name": "id", "type": "integer", "nullable": false}
+		]}
+	]}`
+	merged, _, err := MergeModels([]byte(existing), []Model{generatedUsers()}, false)
+	if err != nil {
+		t.Fatalf("MergeModels failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "models.json")
+	if err := os.WriteFile(path, merged, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed on merged output: %v", err)
+	}
+	reg := schema.NewRegistry()
+	reg.LoadFromConfig(cfg)
+
+	if reg.GetModel("audit_log") != nil {
+		t.Error("expected the removed audit_log model not to be loaded")
+	}
+	users := reg.GetModel("users")
+	if users == nil {
+		t.Fatal("expected the users model to be loaded")
+	}
+	if _, ok := users.Fields["legacy_code"]; ok {
+		t.Error("expected the removed legacy_code field not to be loaded")
+	}
+	if _, ok := users.Fields["created_at"]; !ok {
+		t.Error("expected the added created_at field to be loaded")
+	}
+}
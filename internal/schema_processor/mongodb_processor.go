@@ -2,10 +2,8 @@ package schema_processor
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -14,6 +12,8 @@ import (
 type MongoDBProcessor struct {
 	sampler *MongoDBSampler
 	ctx     context.Context
+
+	reportPresence bool // Fill in Field.Presence on generated models
 }
 
 func NewMongoDBProcessor(uri string, dbName string) (*MongoDBProcessor, error) {
@@ -39,6 +39,26 @@ func NewMongoDBProcessor(uri string, dbName string) (*MongoDBProcessor, error) {
 	}, nil
 }
 
+// SetSampleLimits trims sampled documents to maxDepth levels of nesting and skips documents
+// over maxDocumentBytes, so collections with large blobs infer faster. 0 disables a limit.
+func (mp *MongoDBProcessor) SetSampleLimits(maxDepth, maxDocumentBytes int) error {
+	if maxDepth < 0 {
+		return fmt.Errorf("sample depth must not be negative, got %d", maxDepth)
+	}
+	if maxDocumentBytes < 0 {
+		return fmt.Errorf("sample document size must not be negative, got %d", maxDocumentBytes)
+	}
+	mp.sampler.maxDepth = maxDepth
+	mp.sampler.maxDocumentBytes = maxDocumentBytes
+	return nil
+}
+
+// SetReportPresence records on each generated field the percentage of sampled documents
+// holding it. Off by default to keep the output compact.
+func (mp *MongoDBProcessor) SetReportPresence(enabled bool) {
+	mp.reportPresence = enabled
+}
+
 // GenerateModels generates models from MongoDB collections
 func (mp *MongoDBProcessor) GenerateModels(collectionNames []string, sampleSize int) ([]Model, error) {
 	var collections []string
@@ -82,6 +102,9 @@ func (mp *MongoDBProcessor) GenerateModels(collectionNames []string, sampleSize
 
 		// Generate model
 		model := GenerateModelFromSchema(collectionName, schema)
+		if mp.reportPresence {
+			applyPresence(&model, schema)
+		}
 
 		models = append(models, model)
 
@@ -106,21 +129,8 @@ func (mp *MongoDBProcessor) GenerateAndSaveModels(
 		return fmt.Errorf("no valid models generated")
 	}
 
-	// Create config
-	config := ModelConfig{
-		Models: models,
-	}
-
-	// Marshal to JSON
-	jsonData, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal models to JSON: %w", err)
-	}
-
-	// Write to file
-	err = os.WriteFile(outputPath, jsonData, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write models file: %w", err)
+	if err := WriteModelsFile(outputPath, models); err != nil {
+		return err
 	}
 
 	log.Printf("Successfully generated models.json with %d models at %s", len(models), outputPath)
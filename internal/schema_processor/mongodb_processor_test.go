@@ -417,3 +417,29 @@ func TestAnalyzeDocument_NestedFields(t *testing.T) {
 		t.Error("Expected fields to be analyzed")
 	}
 }
+
+func TestApplyPresence(t *testing.T) {
+	schema := &CollectionSchema{
+		DocumentCount: 8,
+		Fields: map[string]*FieldStats{
+			"name":     {TypeCounts: map[FieldType]int{TypeString: 8}, TotalCount: 8},
+			"nickname": {TypeCounts: map[FieldType]int{TypeString: 4}, TotalCount: 5, NullCount: 1},
+			"legacy":   {TypeCounts: map[FieldType]int{TypeString: 1}, TotalCount: 1},
+		},
+	}
+
+	model := GenerateModelFromSchema("users", schema)
+	for _, f := range model.Fields {
+		if f.Presence != 0 {
+			t.Errorf("Expected no presence by default, got %v on %s", f.Presence, f.Name)
+		}
+	}
+
+	applyPresence(&model, schema)
+	want := map[string]float64{"_id": 100, "name": 100, "nickname": 62.5, "legacy": 12.5}
+	for _, f := range model.Fields {
+		if f.Presence != want[f.Name] {
+			t.Errorf("Expected presence %v for %s, got %v", want[f.Name], f.Name, f.Presence)
+		}
+	}
+}
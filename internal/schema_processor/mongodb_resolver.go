@@ -1,6 +1,7 @@
 package schema_processor
 
 import (
+	"math"
 	"strconv"
 	"strings"
 )
@@ -103,3 +104,19 @@ func GenerateModelFromSchema(collectionName string, schema *CollectionSchema) Mo
 		Fields:     fields,
 	}
 }
+
+// applyPresence sets each field's presence to the percentage of sampled documents that hold
+// it, null or not, rounded to one decimal
+func applyPresence(model *Model, schema *CollectionSchema) {
+	if schema.DocumentCount == 0 {
+		return
+	}
+	for i := range model.Fields {
+		field := &model.Fields[i]
+		count := schema.DocumentCount // _id is added when missing from the samples
+		if stats := schema.Fields[field.Name]; stats != nil {
+			count = stats.TotalCount
+		}
+		field.Presence = math.Round(float64(count)*1000/float64(schema.DocumentCount)) / 10
+	}
+}
@@ -2,6 +2,7 @@ package schema_processor
 
 import (
 	"context"
+	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -11,6 +12,11 @@ type MongoDBSampler struct {
 	client   *mongo.Client
 	database *mongo.Database
 	ctx      context.Context
+
+	// maxDepth trims sampled documents below this nesting level; 0 keeps them whole
+	maxDepth int
+	// maxDocumentBytes drops sampled documents larger than this BSON size; 0 keeps all
+	maxDocumentBytes int
 }
 
 func NewMongoDBSampler(client *mongo.Client, dbName string) *MongoDBSampler {
@@ -25,12 +31,7 @@ func NewMongoDBSampler(client *mongo.Client, dbName string) *MongoDBSampler {
 func (s *MongoDBSampler) SampleDocuments(collectionName string, sampleSize int) ([]bson.M, error) {
 	collection := s.database.Collection(collectionName)
 
-	// Use aggregation with $sample for random sampling
-	pipeline := []bson.M{
-		{"$sample": bson.M{"size": sampleSize}},
-	}
-
-	cursor, err := collection.Aggregate(s.ctx, pipeline)
+	cursor, err := collection.Aggregate(s.ctx, s.samplePipeline(sampleSize))
 	if err != nil {
 		return nil, err
 	}
@@ -44,6 +45,67 @@ func (s *MongoDBSampler) SampleDocuments(collectionName string, sampleSize int)
 	return documents, nil
 }
 
+// samplePipeline builds the aggregation that samples documents. Size and depth limits run
+// on the server, so oversized documents and trimmed blobs are never transferred.
+func (s *MongoDBSampler) samplePipeline(sampleSize int) []bson.M {
+	// Use aggregation with $sample for random sampling
+	pipeline := []bson.M{
+		{"$sample": bson.M{"size": sampleSize}},
+	}
+	if s.maxDocumentBytes > 0 {
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"$expr": bson.M{"$lte": bson.A{bson.M{"$bsonSize": "$$ROOT"}, s.maxDocumentBytes}}}})
+	}
+	if s.maxDepth > 0 {
+		pipeline = append(pipeline, bson.M{"$replaceWith": trimDocument("$$ROOT", s.maxDepth, 0)})
+	}
+	return pipeline
+}
+
+// trimDocument returns an expression rebuilding the document expr with every key kept, so
+// inference still sees which fields are present, and values nested depth levels down
+// replaced by empty placeholders of the same kind. level names the $map variables apart.
+func trimDocument(expr string, depth, level int) bson.M {
+	field := fmt.Sprintf("f%d", level)
+	return bson.M{"$arrayToObject": bson.M{"$map": bson.M{
+		"input": bson.M{"$objectToArray": expr},
+		"as":    field,
+		"in":    bson.M{"k": "$$" + field + ".k", "v": trimValue("$$"+field+".v", depth-1, level+1)},
+	}}}
+}
+
+// trimValue returns an expression for a field value trimmed to depth more levels. Arrays keep
+// only their first element, the one inference looks at, with nested values emptied.
+func trimValue(expr string, depth, level int) bson.M {
+	object := bson.M{"$literal": bson.M{}}
+	if depth > 0 {
+		object = trimDocument(expr, depth, level)
+	}
+	elem := fmt.Sprintf("e%d", level)
+	array := bson.M{"$map": bson.M{
+		"input": bson.M{"$slice": bson.A{expr, 1}},
+		"as":    elem,
+		"in":    emptyNested("$$" + elem),
+	}}
+	return bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": bson.M{"$eq": bson.A{bson.M{"$type": expr}, "object"}}, "then": object},
+			bson.M{"case": bson.M{"$isArray": expr}, "then": array},
+		},
+		"default": expr,
+	}}
+}
+
+// emptyNested returns an expression replacing a document or array value with an empty one
+func emptyNested(expr string) bson.M {
+	return bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": bson.M{"$eq": bson.A{bson.M{"$type": expr}, "object"}}, "then": bson.M{"$literal": bson.M{}}},
+			bson.M{"case": bson.M{"$isArray": expr}, "then": bson.M{"$literal": bson.A{}}},
+		},
+		"default": expr,
+	}}
+}
+
 // GetAllCollections lists all collections in the database
 func (s *MongoDBSampler) GetAllCollections() ([]string, error) {
 	collections, err := s.database.ListCollectionNames(s.ctx, bson.M{})
@@ -0,0 +1,80 @@
+package schema_processor
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSamplePipeline_NoLimits(t *testing.T) {
+	s := &MongoDBSampler{}
+	want := []bson.M{{"$sample": bson.M{"size": 50}}}
+	if got := s.samplePipeline(50); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected pipeline: %v", got)
+	}
+}
+
+func TestSamplePipeline_Limits(t *testing.T) {
+	s := &MongoDBSampler{maxDepth: 1, maxDocumentBytes: 4096}
+	pipeline := s.samplePipeline(10)
+	if len(pipeline) != 3 {
+		t.Fatalf("expected $sample, $match and $replaceWith stages, got %v", pipeline)
+	}
+
+	wantMatch := bson.M{"$match": bson.M{"$expr": bson.M{"$lte": bson.A{bson.M{"$bsonSize": "$$ROOT"}, 4096}}}}
+	if !reflect.DeepEqual(pipeline[1], wantMatch) {
+		t.Errorf("unexpected size stage: %v", pipeline[1])
+	}
+
+	wantReplace := bson.M{"$replaceWith": bson.M{"$arrayToObject": bson.M{"$map": bson.M{
+		"input": bson.M{"$objectToArray": "$$ROOT"},
+		"as":    "f0",
+		"in": bson.M{"k": "$$f0.k", "v": bson.M{"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{"case": bson.M{"$eq": bson.A{bson.M{"$type": "$$f0.v"}, "object"}}, "then": bson.M{"$literal": bson.M{}}},
+				bson.M{"case": bson.M{"$isArray": "$$f0.v"}, "then": bson.M{"$map": bson.M{
+					"input": bson.M{"$slice": bson.A{"$$f0.v", 1}},
+					"as":    "e1",
+					"in":    emptyNested("$$e1"),
+				}}},
+			},
+			"default": "$$f0.v",
+		}}},
+	}}}}
+	if !reflect.DeepEqual(pipeline[2], wantReplace) {
+		t.Errorf("unexpected trim stage: %v", pipeline[2])
+	}
+}
+
+func TestTrimDocument_NestsVariables(t *testing.T) {
+	expr := trimDocument("$$ROOT", 2, 0)
+	inner := expr["$arrayToObject"].(bson.M)["$map"].(bson.M)["in"].(bson.M)["v"].(bson.M)["$switch"].(bson.M)["branches"].(bson.A)[0].(bson.M)["then"].(bson.M)
+	innerMap := inner["$arrayToObject"].(bson.M)["$map"].(bson.M)
+	if innerMap["input"].(bson.M)["$objectToArray"] != "$$f0.v" || innerMap["as"] != "f1" {
+		t.Errorf("expected second level to map over $$f0.v as f1, got %v", innerMap)
+	}
+}
+
+func TestInferSchema_TrimmedDocuments(t *testing.T) {
+	// Documents as returned with -sample-depth 1: nested values are emptied but keys remain
+	docs := []bson.M{
+		{"_id": 1, "name": "a", "payload": bson.M{}, "tags": bson.A{"x"}},
+		{"_id": 2, "payload": bson.M{}, "tags": bson.A{}},
+	}
+
+	model := GenerateModelFromSchema("events", InferSchema(docs))
+	types := make(map[string]FieldType)
+	for _, f := range model.Fields {
+		types[f.Name] = f.Type
+	}
+	if types["payload"] != TypeJSON || types["tags"] != TypeJSON {
+		t.Errorf("expected trimmed nested values to keep the json type, got %v", types)
+	}
+	if _, ok := types["name"]; !ok {
+		t.Errorf("expected fields present in some documents to be kept, got %v", types)
+	}
+	if len(types) != 4 {
+		t.Errorf("expected no nested fields from trimmed documents, got %v", types)
+	}
+}
@@ -0,0 +1,47 @@
+package schema_processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"udv/internal/config"
+)
+
+// WriteModelsFile writes generated models to path as indented JSON, or as YAML when the
+// path ends in .yaml or .yml
+func WriteModelsFile(path string, models []Model) error {
+	data, err := json.MarshalIndent(ModelConfig{Models: models}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal models to JSON: %w", err)
+	}
+	return writeModelsData(path, data)
+}
+
+// readModelsData reads a models file, converting YAML to JSON
+func readModelsData(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models file: %w", err)
+	}
+	if config.IsYAMLPath(path) {
+		if data, err = config.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to parse models file: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// writeModelsData writes JSON models data to path, converting it to YAML for YAML paths
+func writeModelsData(path string, data []byte) error {
+	if config.IsYAMLPath(path) {
+		var err error
+		if data, err = config.JSONToYAML(data); err != nil {
+			return fmt.Errorf("failed to convert models to YAML: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write models file: %w", err)
+	}
+	return nil
+}
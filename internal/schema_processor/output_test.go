@@ -0,0 +1,57 @@
+package schema_processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"udv/internal/config"
+)
+
+func TestWriteModelsFile_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	if err := WriteModelsFile(path, []Model{generatedUsers()}); err != nil {
+		t.Fatalf("WriteModelsFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "models:\n  - name: users\n") {
+		t.Errorf("expected block-style YAML, got:\n%s", data)
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("generated YAML does not load: %v", err)
+	}
+	if len(cfg.Models) != 1 || len(cfg.Models[0].Fields) != 4 || len(cfg.Models[0].Indexes) != 1 {
+		t.Errorf("unexpected config: %+v", cfg.Models)
+	}
+}
+
+func TestMergeModelsFile_YAML(t *testing.T) {
+	existing, err := config.JSONToYAML([]byte(existingModelsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "models.yml")
+	if err := os.WriteFile(path, existing, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MergeModelsFile(path, []Model{generatedUsers()}, true); err != nil {
+		t.Fatalf("MergeModelsFile failed: %v", err)
+	}
+
+	data, err := readModelsData(path)
+	if err != nil {
+		t.Fatalf("merged file is not valid YAML: %v", err)
+	}
+	users := decodeMerged(t, data)["users"]
+	if users["name"] != "Customers" || fieldByName(users, "created_at") == nil {
+		t.Errorf("expected merge to keep edits and add fields, got %v", users)
+	}
+}
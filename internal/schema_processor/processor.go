@@ -2,10 +2,8 @@ package schema_processor
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 )
 
@@ -21,6 +19,11 @@ const (
 	TypeJSON      FieldType = "json"
 	TypeUUID      FieldType = "uuid"
 	TypeBinary    FieldType = "binary"
+	TypeInterval  FieldType = "interval"
+	TypeInet      FieldType = "inet"
+	TypeCIDR      FieldType = "cidr"
+	TypeMacAddr   FieldType = "macaddr"
+	TypeXML       FieldType = "xml"
 )
 
 // Field represents a table column in the JSON config
@@ -28,6 +31,10 @@ type Field struct {
 	Name     string `json:"name"`
 	Type     FieldType `json:"type"`
 	Nullable bool   `json:"nullable"`
+	// HasDefault marks a column with a database default (PostgreSQL only)
+	HasDefault bool `json:"hasDefault,omitempty"`
+	// Presence is the percentage of sampled documents holding the field (MongoDB only, opt-in)
+	Presence float64 `json:"presence,omitempty"`
 }
 
 // Model represents a database table in the JSON config
@@ -36,6 +43,20 @@ type Model struct {
 	Table      string  `json:"table"`
 	PrimaryKey string  `json:"primaryKey"`
 	Fields     []Field `json:"fields"`
+	// UniqueConstraints lists the columns of each unique index, one group per index
+	UniqueConstraints [][]string `json:"uniqueConstraints,omitempty"`
+	// Indexes describes the table's non-primary indexes, including expression and partial ones
+	Indexes []Index `json:"indexes,omitempty"`
+	// ReadOnly marks a model generated from a view or materialized view
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// Index describes a database index in the JSON config
+type Index struct {
+	Name      string   `json:"name"`
+	Columns   []string `json:"columns"`
+	Unique    bool     `json:"unique,omitempty"`
+	Predicate string   `json:"predicate,omitempty"`
 }
 
 // ModelConfig represents the complete models.json structure
@@ -52,6 +73,47 @@ type ColumnInfo struct {
 	OrdinalPos    int
 }
 
+// DefaultSchema is the PostgreSQL schema introspected when none is given
+const DefaultSchema = "public"
+
+// TableRef identifies a table within a PostgreSQL schema
+type TableRef struct {
+	Schema string
+	Name   string
+}
+
+// QualifiedName returns the table name as referenced in SQL, schema-qualified outside the public schema
+func (t TableRef) QualifiedName() string {
+	if t.Schema == "" || t.Schema == DefaultSchema {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+// ModelName returns the generated model name, prefixed with the schema outside the public schema
+func (t TableRef) ModelName() string {
+	if t.Schema == "" || t.Schema == DefaultSchema {
+		return t.Name
+	}
+	return t.Schema + "_" + t.Name
+}
+
+// ParseTableRefs resolves table names against the given schemas.
+// Names of the form "schema.table" keep their schema; plain names are looked up in every schema.
+func ParseTableRefs(schemas []string, tableNames []string) []TableRef {
+	var refs []TableRef
+	for _, name := range tableNames {
+		if schema, table, ok := strings.Cut(name, "."); ok {
+			refs = append(refs, TableRef{Schema: schema, Name: table})
+			continue
+		}
+		for _, schema := range schemas {
+			refs = append(refs, TableRef{Schema: schema, Name: name})
+		}
+	}
+	return refs
+}
+
 // TableInfo holds PostgreSQL table metadata
 type TableInfo struct {
 	TableName string
@@ -59,14 +121,96 @@ type TableInfo struct {
 	PrimaryKey string
 }
 
+// PrimaryKeyFallback selects how GenerateModels handles a table without a primary key
+type PrimaryKeyFallback string
+
+const (
+	// FallbackColumn uses the configured fallback column, skipping tables that lack it
+	FallbackColumn PrimaryKeyFallback = "column"
+	// FallbackSkip leaves the table out of the generated models
+	FallbackSkip PrimaryKeyFallback = "skip"
+	// FallbackError fails generation
+	FallbackError PrimaryKeyFallback = "error"
+)
+
+// DefaultFallbackColumn is the primary key assumed for tables without one unless configured otherwise
+const DefaultFallbackColumn = "id"
+
+// MissingPrimaryKey records a table introspected without a primary key and how it was handled
+type MissingPrimaryKey struct {
+	Table  string
+	Column string // Fallback column used, empty when the table was skipped
+}
+
+// PostgreSQL relkind values of views, which are generated as read-only models
+const (
+	relkindView             = "v"
+	relkindMaterializedView = "m"
+)
+
 // SchemaProcessor handles database schema introspection
 type SchemaProcessor struct {
 	db *sql.DB
+
+	pkFallback       PrimaryKeyFallback
+	pkFallbackColumn string
+	missingPKs       []MissingPrimaryKey
+
+	includeViews bool
 }
 
 // NewSchemaProcessor creates a new schema processor
 func NewSchemaProcessor(db *sql.DB) *SchemaProcessor {
-	return &SchemaProcessor{db: db}
+	return &SchemaProcessor{db: db, pkFallback: FallbackColumn, pkFallbackColumn: DefaultFallbackColumn}
+}
+
+// SetPrimaryKeyFallback configures how tables without a primary key are handled.
+// column is only used, and then required, with FallbackColumn.
+func (sp *SchemaProcessor) SetPrimaryKeyFallback(mode PrimaryKeyFallback, column string) error {
+	switch mode {
+	case FallbackColumn:
+		if column == "" {
+			return fmt.Errorf("primary key fallback column is required")
+		}
+	case FallbackSkip, FallbackError:
+	default:
+		return fmt.Errorf("invalid primary key fallback %q: must be column, skip or error", mode)
+	}
+	sp.pkFallback = mode
+	sp.pkFallbackColumn = column
+	return nil
+}
+
+// SetIncludeViews makes GetAllTables list views and materialized views along with tables
+func (sp *SchemaProcessor) SetIncludeViews(include bool) {
+	sp.includeViews = include
+}
+
+// MissingPrimaryKeys returns the tables the last GenerateModels call found without a primary key
+func (sp *SchemaProcessor) MissingPrimaryKeys() []MissingPrimaryKey {
+	return sp.missingPKs
+}
+
+// fallbackPrimaryKey picks the primary key for a table without one. ok is false when the
+// table should be skipped.
+func (sp *SchemaProcessor) fallbackPrimaryKey(tableName string, columns []ColumnInfo) (string, bool, error) {
+	switch sp.pkFallback {
+	case FallbackError:
+		return "", false, fmt.Errorf("table %s has no primary key", tableName)
+	case FallbackColumn:
+		for _, col := range columns {
+			if col.ColumnName == sp.pkFallbackColumn {
+				log.Printf("Warning: Table '%s' has no primary key, using column '%s'", tableName, sp.pkFallbackColumn)
+				sp.missingPKs = append(sp.missingPKs, MissingPrimaryKey{Table: tableName, Column: sp.pkFallbackColumn})
+				return sp.pkFallbackColumn, true, nil
+			}
+		}
+		log.Printf("Warning: Table '%s' has no primary key and no '%s' column, skipping", tableName, sp.pkFallbackColumn)
+	default:
+		log.Printf("Warning: Table '%s' has no primary key, skipping", tableName)
+	}
+	sp.missingPKs = append(sp.missingPKs, MissingPrimaryKey{Table: tableName})
+	return "", false, nil
 }
 
 // mapPostgreSQLTypeToJSON maps PostgreSQL data types to JSON model types
@@ -81,10 +225,15 @@ func mapPostgreSQLTypeToJSON(pgType string) FieldType {
 	basePGType := strings.Split(pgType, "(")[0]
 	basePGType = strings.TrimSpace(basePGType)
 
+	// Handle interval field qualifiers (e.g., "interval day to second" → "interval")
+	if strings.HasPrefix(basePGType, "interval ") {
+		basePGType = "interval"
+	}
+
 	switch basePGType {
 	case "integer", "int", "int4", "smallint", "int2", "bigint", "int8", "serial", "serial4", "bigserial", "serial8":
 		return TypeInteger
-	case "text", "character varying", "varchar", "character", "char", "name":
+	case "text", "character varying", "varchar", "character", "char", "name", "citext":
 		return TypeString
 	case "numeric", "decimal", "money", "double precision", "float8", "real", "float4":
 		return TypeDecimal
@@ -98,6 +247,16 @@ func mapPostgreSQLTypeToJSON(pgType string) FieldType {
 		return TypeUUID
 	case "bytea", "bit", "bit varying", "varbit":
 		return TypeBinary
+	case "interval":
+		return TypeInterval
+	case "inet":
+		return TypeInet
+	case "cidr":
+		return TypeCIDR
+	case "macaddr":
+		return TypeMacAddr
+	case "xml":
+		return TypeXML
 	default:
 		// Default to string for unknown types
 		log.Printf("Warning: Unknown PostgreSQL type '%s', defaulting to string", pgType)
@@ -105,8 +264,8 @@ func mapPostgreSQLTypeToJSON(pgType string) FieldType {
 	}
 }
 
-// GetTableColumns fetches column information for a table
-func (sp *SchemaProcessor) GetTableColumns(tableName string) ([]ColumnInfo, error) {
+// GetTableColumns fetches column information for a table in the given schema
+func (sp *SchemaProcessor) GetTableColumns(schemaName, tableName string) ([]ColumnInfo, error) {
 	query := `
 		SELECT
 			column_name,
@@ -117,12 +276,12 @@ func (sp *SchemaProcessor) GetTableColumns(tableName string) ([]ColumnInfo, erro
 		FROM
 			information_schema.columns
 		WHERE
-			table_name = $1 AND table_schema = 'public'
+			table_schema = $1 AND table_name = $2
 		ORDER BY
 			ordinal_position ASC
 	`
 
-	rows, err := sp.db.Query(query, tableName)
+	rows, err := sp.db.Query(query, schemaName, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query columns: %w", err)
 	}
@@ -147,40 +306,242 @@ func (sp *SchemaProcessor) GetTableColumns(tableName string) ([]ColumnInfo, erro
 	return columns, nil
 }
 
-// GetPrimaryKey fetches the primary key for a table
-func (sp *SchemaProcessor) GetPrimaryKey(tableName string) (string, error) {
+// GetMaterializedViewColumns fetches column information for a materialized view, which
+// information_schema.columns does not list
+func (sp *SchemaProcessor) GetMaterializedViewColumns(schemaName, viewName string) ([]ColumnInfo, error) {
+	query := `
+		SELECT a.attname, format_type(a.atttypid, NULL), NOT a.attnotnull, a.attnum
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname = $2 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum ASC
+	`
+
+	rows, err := sp.db.Query(query, schemaName, viewName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var col ColumnInfo
+		if err := rows.Scan(&col.ColumnName, &col.DataType, &col.IsNullable, &col.OrdinalPos); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, col)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// GetRelationKind fetches the pg_class relkind of a table, view or materialized view, or ""
+// when none exists
+func (sp *SchemaProcessor) GetRelationKind(schemaName, tableName string) (string, error) {
+	query := `
+		SELECT c.relkind
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname = $2
+	`
+
+	var kind string
+	err := sp.db.QueryRow(query, schemaName, tableName).Scan(&kind)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query relation kind: %w", err)
+	}
+	return kind, nil
+}
+
+// isView reports whether a relkind is a view or materialized view
+func isView(kind string) bool {
+	return kind == relkindView || kind == relkindMaterializedView
+}
+
+// viewPrimaryKey picks the key of a view from its unique indexes, which only materialized
+// views can have: the first one on a single column, or "" when there is none
+func viewPrimaryKey(uniqueConstraints [][]string) string {
+	for _, columns := range uniqueConstraints {
+		if len(columns) == 1 {
+			return columns[0]
+		}
+	}
+	return ""
+}
+
+// GetPrimaryKey fetches the primary key for a table in the given schema, or "" when it has none
+func (sp *SchemaProcessor) GetPrimaryKey(schemaName, tableName string) (string, error) {
 	query := `
 		SELECT a.attname
 		FROM pg_index i
 		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
 		JOIN pg_class t ON t.oid = i.indrelid
-		WHERE t.relname = $1 AND i.indisprimary
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1 AND t.relname = $2 AND i.indisprimary
 		LIMIT 1
 	`
 
 	var pkName string
-	err := sp.db.QueryRow(query, tableName).Scan(&pkName)
-	if err != nil && err != sql.ErrNoRows {
-		return "", fmt.Errorf("failed to query primary key: %w", err)
-	}
+	err := sp.db.QueryRow(query, schemaName, tableName).Scan(&pkName)
 	if err == sql.ErrNoRows {
-		// Fallback: assume "id" if no primary key found
-		return "id", nil
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query primary key: %w", err)
 	}
 
 	return pkName, nil
 }
 
-// GetAllTables fetches all table names from the database
-func (sp *SchemaProcessor) GetAllTables() ([]string, error) {
+// uniqueIndexColumn is one column of a unique index, in index key order
+type uniqueIndexColumn struct {
+	IndexName  string
+	ColumnName string
+}
+
+// GetUniqueConstraints fetches the column groups of non-primary unique indexes for a table in the given schema
+func (sp *SchemaProcessor) GetUniqueConstraints(schemaName, tableName string) ([][]string, error) {
+	// Expression and partial indexes are skipped since they cannot serve as plain conflict targets
+	query := `
+		SELECT ic.relname, a.attname
+		FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN LATERAL unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = $1 AND t.relname = $2
+			AND i.indisunique AND NOT i.indisprimary
+			AND i.indexprs IS NULL AND i.indpred IS NULL
+		ORDER BY ic.relname ASC, k.ord ASC
+	`
+
+	rows, err := sp.db.Query(query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unique constraints: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []uniqueIndexColumn
+	for rows.Next() {
+		var col uniqueIndexColumn
+		if err := rows.Scan(&col.IndexName, &col.ColumnName); err != nil {
+			return nil, fmt.Errorf("failed to scan unique constraint: %w", err)
+		}
+		columns = append(columns, col)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unique constraints: %w", err)
+	}
+
+	return groupUniqueColumns(columns), nil
+}
+
+// groupUniqueColumns groups index columns into one column list per index, preserving order
+func groupUniqueColumns(columns []uniqueIndexColumn) [][]string {
+	var groups [][]string
+	indexPos := make(map[string]int)
+	for _, col := range columns {
+		pos, ok := indexPos[col.IndexName]
+		if !ok {
+			pos = len(groups)
+			indexPos[col.IndexName] = pos
+			groups = append(groups, nil)
+		}
+		groups[pos] = append(groups[pos], col.ColumnName)
+	}
+	return groups
+}
+
+// indexColumn is one key of an index, in index key order
+type indexColumn struct {
+	IndexName string
+	Unique    bool
+	Predicate string
+	Column    string // Column name, or the expression text for expression keys
+}
+
+// GetIndexes fetches the non-primary indexes of a table in the given schema.
+// Expression keys are reported by their expression text and partial indexes carry their predicate.
+func (sp *SchemaProcessor) GetIndexes(schemaName, tableName string) ([]Index, error) {
+	// INCLUDE columns come after indnkeyatts and are not part of the key
+	query := `
+		SELECT ic.relname, i.indisunique,
+			COALESCE(pg_get_expr(i.indpred, i.indrelid, true), ''),
+			pg_get_indexdef(i.indexrelid, k.ord::int, true)
+		FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN LATERAL unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord) ON true
+		WHERE n.nspname = $1 AND t.relname = $2
+			AND NOT i.indisprimary
+			AND k.ord <= i.indnkeyatts
+		ORDER BY ic.relname ASC, k.ord ASC
+	`
+
+	rows, err := sp.db.Query(query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []indexColumn
+	for rows.Next() {
+		var col indexColumn
+		if err := rows.Scan(&col.IndexName, &col.Unique, &col.Predicate, &col.Column); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+		columns = append(columns, col)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating indexes: %w", err)
+	}
+
+	return groupIndexColumns(columns), nil
+}
+
+// groupIndexColumns groups index keys into one Index per index, preserving key order
+func groupIndexColumns(columns []indexColumn) []Index {
+	var indexes []Index
+	indexPos := make(map[string]int)
+	for _, col := range columns {
+		pos, ok := indexPos[col.IndexName]
+		if !ok {
+			pos = len(indexes)
+			indexPos[col.IndexName] = pos
+			indexes = append(indexes, Index{Name: col.IndexName, Unique: col.Unique, Predicate: col.Predicate})
+		}
+		indexes[pos].Columns = append(indexes[pos].Columns, col.Column)
+	}
+	return indexes
+}
+
+// GetAllTables fetches all table names in the given schema, and the views and materialized
+// views too when SetIncludeViews is on
+func (sp *SchemaProcessor) GetAllTables(schemaName string) ([]string, error) {
 	query := `
 		SELECT table_name
 		FROM information_schema.tables
-		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'
-		ORDER BY table_name ASC
+		WHERE table_schema = $1 AND (table_type = 'BASE TABLE' OR ($2 AND table_type = 'VIEW'))
+		UNION ALL
+		SELECT matviewname
+		FROM pg_matviews
+		WHERE schemaname = $1 AND $2
+		ORDER BY 1 ASC
 	`
 
-	rows, err := sp.db.Query(query)
+	rows, err := sp.db.Query(query, schemaName, sp.includeViews)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tables: %w", err)
 	}
@@ -203,12 +564,25 @@ func (sp *SchemaProcessor) GetAllTables() ([]string, error) {
 }
 
 // GenerateModels creates Model objects from database schema
-func (sp *SchemaProcessor) GenerateModels(tableNames []string) ([]Model, error) {
+func (sp *SchemaProcessor) GenerateModels(tables []TableRef) ([]Model, error) {
 	var models []Model
+	sp.missingPKs = nil
+
+	for _, table := range tables {
+		tableName := table.QualifiedName()
+
+		kind, err := sp.GetRelationKind(table.Schema, table.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relation kind for table %s: %w", tableName, err)
+		}
 
-	for _, tableName := range tableNames {
 		// Get columns
-		columns, err := sp.GetTableColumns(tableName)
+		var columns []ColumnInfo
+		if kind == relkindMaterializedView {
+			columns, err = sp.GetMaterializedViewColumns(table.Schema, table.Name)
+		} else {
+			columns, err = sp.GetTableColumns(table.Schema, table.Name)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to get columns for table %s: %w", tableName, err)
 		}
@@ -218,11 +592,36 @@ func (sp *SchemaProcessor) GenerateModels(tableNames []string) ([]Model, error)
 			continue
 		}
 
-		// Get primary key
-		pkName, err := sp.GetPrimaryKey(tableName)
+		// Get unique constraints
+		uniqueConstraints, err := sp.GetUniqueConstraints(table.Schema, table.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get unique constraints for table %s: %w", tableName, err)
+		}
+
+		// Get primary key; views have none, so a unique index stands in where there is one
+		pkName, err := sp.GetPrimaryKey(table.Schema, table.Name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get primary key for table %s: %w", tableName, err)
 		}
+		if pkName == "" && isView(kind) {
+			pkName = viewPrimaryKey(uniqueConstraints)
+		}
+		if pkName == "" {
+			var ok bool
+			pkName, ok, err = sp.fallbackPrimaryKey(tableName, columns)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		// Get indexes
+		indexes, err := sp.GetIndexes(table.Schema, table.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get indexes for table %s: %w", tableName, err)
+		}
 
 		// Convert columns to fields
 		var fields []Field
@@ -231,16 +630,20 @@ func (sp *SchemaProcessor) GenerateModels(tableNames []string) ([]Model, error)
 				Name:     col.ColumnName,
 				Type:     mapPostgreSQLTypeToJSON(col.DataType),
 				Nullable: col.IsNullable,
+				HasDefault: col.ColumnDefault != nil,
 			}
 			fields = append(fields, field)
 		}
 
 		// Create model
 		model := Model{
-			Name:       tableName,
-			Table:      tableName,
-			PrimaryKey: pkName,
-			Fields:     fields,
+			Name:              table.ModelName(),
+			Table:             tableName,
+			PrimaryKey:        pkName,
+			Fields:            fields,
+			UniqueConstraints: uniqueConstraints,
+			Indexes:           indexes,
+			ReadOnly:          isView(kind),
 		}
 
 		models = append(models, model)
@@ -249,25 +652,40 @@ func (sp *SchemaProcessor) GenerateModels(tableNames []string) ([]Model, error)
 	return models, nil
 }
 
-// GenerateAndSaveModels generates models from database and saves to file
-func (sp *SchemaProcessor) GenerateAndSaveModels(outputPath string, tableNames []string) error {
-	var tables []string
-	var err error
+// ResolveTables returns the tables to introspect: the named ones, or every table in the schemas
+func (sp *SchemaProcessor) ResolveTables(schemas []string, tableNames []string) ([]TableRef, error) {
+	if len(schemas) == 0 {
+		schemas = []string{DefaultSchema}
+	}
+
+	if len(tableNames) > 0 {
+		return ParseTableRefs(schemas, tableNames), nil
+	}
 
-	// If no tables specified, get all tables
-	if len(tableNames) == 0 {
-		tables, err = sp.GetAllTables()
+	var tables []TableRef
+	for _, schemaName := range schemas {
+		names, err := sp.GetAllTables(schemaName)
 		if err != nil {
-			return fmt.Errorf("failed to get all tables: %w", err)
+			return nil, fmt.Errorf("failed to get all tables in schema %s: %w", schemaName, err)
 		}
-
-		if len(tables) == 0 {
-			return fmt.Errorf("no tables found in database")
+		for _, name := range names {
+			tables = append(tables, TableRef{Schema: schemaName, Name: name})
 		}
+	}
 
-		log.Printf("Found %d tables in database", len(tables))
-	} else {
-		tables = tableNames
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables found in schemas %v", schemas)
+	}
+
+	log.Printf("Found %d tables in schemas %v", len(tables), schemas)
+	return tables, nil
+}
+
+// GenerateAndSaveModels generates models from the given schemas and saves to file
+func (sp *SchemaProcessor) GenerateAndSaveModels(outputPath string, schemas []string, tableNames []string) error {
+	tables, err := sp.ResolveTables(schemas, tableNames)
+	if err != nil {
+		return err
 	}
 
 	// Generate models
@@ -280,25 +698,17 @@ func (sp *SchemaProcessor) GenerateAndSaveModels(outputPath string, tableNames [
 		return fmt.Errorf("no valid models generated from database")
 	}
 
-	// Create config
-	config := ModelConfig{
-		Models: models,
+	if err := WriteModelsFile(outputPath, models); err != nil {
+		return err
 	}
 
-	// Marshal to JSON with pretty printing
-	jsonData, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal models to JSON: %w", err)
-	}
-
-	// Write to file
-	err = os.WriteFile(outputPath, jsonData, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write models file: %w", err)
+	generated := make([]string, 0, len(models))
+	for _, m := range models {
+		generated = append(generated, m.Table)
 	}
 
 	log.Printf("Successfully generated models.json with %d models at %s", len(models), outputPath)
-	fmt.Printf("Generated models for: %v\n", tables)
+	fmt.Printf("Generated models for: %v\n", generated)
 
 	return nil
 }
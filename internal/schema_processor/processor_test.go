@@ -1,6 +1,7 @@
 package schema_processor
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -60,6 +61,19 @@ func TestMapPostgreSQLTypeToJSON(t *testing.T) {
 		{"bytea", TypeBinary},
 		{"bit", TypeBinary},
 
+		// Interval (distinct from timestamp)
+		{"interval", TypeInterval},
+		{"interval day to second", TypeInterval},
+		{"interval(6)", TypeInterval},
+
+		// Network types
+		{"inet", TypeInet},
+		{"cidr", TypeCIDR},
+		{"macaddr", TypeMacAddr},
+
+		// XML
+		{"xml", TypeXML},
+
 		// Unknown type (should default to string)
 		{"unknown_type", TypeString},
 		{"custom_type", TypeString},
@@ -89,6 +103,11 @@ func TestFieldTypeValues(t *testing.T) {
 		{TypeJSON, "json"},
 		{TypeUUID, "uuid"},
 		{TypeBinary, "binary"},
+		{TypeInterval, "interval"},
+		{TypeInet, "inet"},
+		{TypeCIDR, "cidr"},
+		{TypeMacAddr, "macaddr"},
+		{TypeXML, "xml"},
 	}
 
 	for _, test := range tests {
@@ -97,3 +116,182 @@ func TestFieldTypeValues(t *testing.T) {
 		}
 	}
 }
+
+// TestGroupUniqueColumns tests grouping of unique index columns
+func TestGroupUniqueColumns(t *testing.T) {
+	columns := []uniqueIndexColumn{
+		{IndexName: "users_email_key", ColumnName: "email"},
+		{IndexName: "users_tenant_slug_key", ColumnName: "tenant_id"},
+		{IndexName: "users_tenant_slug_key", ColumnName: "slug"},
+	}
+
+	groups := groupUniqueColumns(columns)
+	expected := [][]string{{"email"}, {"tenant_id", "slug"}}
+
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d: %v", len(expected), len(groups), groups)
+	}
+	for i := range expected {
+		if strings.Join(groups[i], ",") != strings.Join(expected[i], ",") {
+			t.Errorf("Group %d: expected %v, got %v", i, expected[i], groups[i])
+		}
+	}
+
+	if groups := groupUniqueColumns(nil); len(groups) != 0 {
+		t.Errorf("Expected no groups for no columns, got %v", groups)
+	}
+}
+
+func TestViewPrimaryKey(t *testing.T) {
+	tests := []struct {
+		unique [][]string
+		want   string
+	}{
+		{[][]string{{"tenant_id", "day"}, {"id"}, {"slug"}}, "id"},
+		{[][]string{{"tenant_id", "day"}}, ""},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := viewPrimaryKey(tt.unique); got != tt.want {
+			t.Errorf("viewPrimaryKey(%v) = %q, want %q", tt.unique, got, tt.want)
+		}
+	}
+
+	if !isView(relkindView) || !isView(relkindMaterializedView) || isView("r") {
+		t.Error("expected only views and materialized views to be read-only")
+	}
+}
+
+// TestTableRefNames tests schema qualification of table and model names
+func TestTableRefNames(t *testing.T) {
+	tests := []struct {
+		ref           TableRef
+		wantQualified string
+		wantModel     string
+	}{
+		{TableRef{Schema: "public", Name: "orders"}, "orders", "orders"},
+		{TableRef{Schema: "", Name: "orders"}, "orders", "orders"},
+		{TableRef{Schema: "tenant_a", Name: "orders"}, "tenant_a.orders", "tenant_a_orders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantQualified, func(t *testing.T) {
+			if got := tt.ref.QualifiedName(); got != tt.wantQualified {
+				t.Errorf("QualifiedName() = %q, want %q", got, tt.wantQualified)
+			}
+			if got := tt.ref.ModelName(); got != tt.wantModel {
+				t.Errorf("ModelName() = %q, want %q", got, tt.wantModel)
+			}
+		})
+	}
+}
+
+// TestParseTableRefs tests resolving table names against schemas
+func TestParseTableRefs(t *testing.T) {
+	refs := ParseTableRefs([]string{"public", "tenant_a"}, []string{"orders", "billing.invoices"})
+	expected := []TableRef{
+		{Schema: "public", Name: "orders"},
+		{Schema: "tenant_a", Name: "orders"},
+		{Schema: "billing", Name: "invoices"},
+	}
+
+	if len(refs) != len(expected) {
+		t.Fatalf("Expected %d refs, got %d: %v", len(expected), len(refs), refs)
+	}
+	for i := range expected {
+		if refs[i] != expected[i] {
+			t.Errorf("Ref %d: expected %v, got %v", i, expected[i], refs[i])
+		}
+	}
+}
+
+// TestFallbackPrimaryKey tests handling of tables without a primary key
+func TestFallbackPrimaryKey(t *testing.T) {
+	withID := []ColumnInfo{{ColumnName: "id"}, {ColumnName: "name"}}
+	withoutID := []ColumnInfo{{ColumnName: "name"}}
+
+	tests := []struct {
+		name    string
+		mode    PrimaryKeyFallback
+		column  string
+		columns []ColumnInfo
+		wantPK  string
+		wantOK  bool
+		wantErr bool
+	}{
+		{"default column present", FallbackColumn, DefaultFallbackColumn, withID, "id", true, false},
+		{"default column missing", FallbackColumn, DefaultFallbackColumn, withoutID, "", false, false},
+		{"custom column", FallbackColumn, "name", withoutID, "name", true, false},
+		{"skip", FallbackSkip, "", withID, "", false, false},
+		{"error", FallbackError, "", withID, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := NewSchemaProcessor(nil)
+			if err := sp.SetPrimaryKeyFallback(tt.mode, tt.column); err != nil {
+				t.Fatalf("SetPrimaryKeyFallback failed: %v", err)
+			}
+
+			pk, ok, err := sp.fallbackPrimaryKey("events", tt.columns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fallbackPrimaryKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if pk != tt.wantPK || ok != tt.wantOK {
+				t.Errorf("fallbackPrimaryKey() = %q, %v, want %q, %v", pk, ok, tt.wantPK, tt.wantOK)
+			}
+
+			missing := sp.MissingPrimaryKeys()
+			if tt.wantErr {
+				if len(missing) != 0 {
+					t.Errorf("expected nothing recorded on error, got %v", missing)
+				}
+				return
+			}
+			if len(missing) != 1 || missing[0].Table != "events" || missing[0].Column != tt.wantPK {
+				t.Errorf("unexpected missing primary keys: %v", missing)
+			}
+		})
+	}
+}
+
+// TestSetPrimaryKeyFallback tests fallback configuration validation
+func TestSetPrimaryKeyFallback(t *testing.T) {
+	sp := NewSchemaProcessor(nil)
+	if err := sp.SetPrimaryKeyFallback(FallbackColumn, ""); err == nil {
+		t.Error("expected error for column fallback without a column")
+	}
+	if err := sp.SetPrimaryKeyFallback("guess", ""); err == nil {
+		t.Error("expected error for unknown fallback")
+	}
+	if err := sp.SetPrimaryKeyFallback(FallbackSkip, ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestGroupIndexColumns tests grouping of index keys into indexes
+func TestGroupIndexColumns(t *testing.T) {
+	columns := []indexColumn{
+		{IndexName: "orders_status_created_idx", Column: "status"},
+		{IndexName: "orders_status_created_idx", Column: "created_at"},
+		{IndexName: "orders_lower_email_idx", Unique: true, Column: "lower(email)"},
+		{IndexName: "orders_open_idx", Predicate: "status = 'open'::text", Column: "user_id"},
+	}
+
+	indexes := groupIndexColumns(columns)
+	if len(indexes) != 3 {
+		t.Fatalf("expected 3 indexes, got %d", len(indexes))
+	}
+
+	composite := indexes[0]
+	if composite.Name != "orders_status_created_idx" || strings.Join(composite.Columns, ",") != "status,created_at" || composite.Unique {
+		t.Errorf("unexpected composite index: %+v", composite)
+	}
+	if expr := indexes[1]; !expr.Unique || expr.Columns[0] != "lower(email)" {
+		t.Errorf("unexpected expression index: %+v", expr)
+	}
+	if partial := indexes[2]; partial.Predicate != "status = 'open'::text" || partial.Columns[0] != "user_id" {
+		t.Errorf("unexpected partial index: %+v", partial)
+	}
+}